@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"lava-vm/pkg/assembler"
+	"lava-vm/pkg/class"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// runAsm implements `lava asm`, which assembles text assembly into class
+// files, or with -d disassembles class files into assembly. It returns the
+// exit status.
+func runAsm(args []string) int {
+	flags := flag.NewFlagSet("asm", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s asm [-o <classfile>] <file.j>...\n       %s asm -d <classfile>...\n", os.Args[0], os.Args[0])
+		flags.PrintDefaults()
+	}
+	disassemble := flags.Bool("d", false, "disassemble class files into assembly on standard output")
+	output := flags.String("o", "", "write the class file to this path instead of next to the source")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 || (*output != "" && flags.NArg() > 1) {
+		flags.Usage()
+		return 2
+	}
+
+	status := 0
+	for _, path := range flags.Args() {
+		var err error
+		if *disassemble {
+			err = disassembleFile(path)
+		} else {
+			out := *output
+			if out == "" {
+				out = strings.TrimSuffix(path, filepath.Ext(path)) + ".class"
+			}
+			err = assembleFile(path, out)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", path, err)
+			status = 1
+		}
+	}
+	return status
+}
+
+func assembleFile(path, out string) error {
+	source, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	data, err := assembler.Assemble(source)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(out, data, 0644)
+}
+
+func disassembleFile(path string) error {
+	classFile, err := class.Parse(path)
+	if err != nil {
+		return err
+	}
+	return assembler.Disassemble(os.Stdout, classFile)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"lava-vm/pkg/class"
+	"lava-vm/pkg/javap"
+	"os"
+	"path/filepath"
+)
+
+// runJavap implements `lava javap`, which disassembles class files like the
+// JDK javap tool. It returns the exit status.
+func runJavap(args []string) int {
+	flags := flag.NewFlagSet("javap", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s javap [-c] [-v] [-p] [-l] [-s] <classfile>...\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	var opts javap.Options
+	flags.BoolVar(&opts.Code, "c", false, "disassemble the code")
+	flags.BoolVar(&opts.Verbose, "v", false, "print additional information")
+	flags.BoolVar(&opts.Private, "p", false, "show all classes and members")
+	flags.BoolVar(&opts.Lines, "l", false, "print line number and local variable tables")
+	flags.BoolVar(&opts.Descriptors, "s", false, "print internal type signatures")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	status := 0
+	for _, path := range flags.Args() {
+		if err := disassemble(path, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", path, err)
+			status = 1
+		}
+	}
+	return status
+}
+
+func disassemble(path string, opts javap.Options) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	classFile, err := class.ParseBytes(data)
+	if err != nil {
+		return err
+	}
+
+	if opts.Verbose {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		if err := javap.FileHeader(os.Stdout, path, info.ModTime(), data); err != nil {
+			return err
+		}
+	}
+	return javap.Disassemble(os.Stdout, classFile, opts)
+}
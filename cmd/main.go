@@ -8,17 +8,31 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "javap":
+			os.Exit(runJavap(os.Args[2:]))
+		case "asm":
+			os.Exit(runAsm(os.Args[2:]))
+		}
+	}
+
 	if len(os.Args) != 2 {
-		fmt.Fprintf(os.Stderr, "Usage: %s <classfile>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s <classfile>\n       %s javap [-c] [-v] [-p] [-l] [-s] <classfile>...\n       %s asm [-d] [-o <classfile>] <file>...\n", os.Args[0], os.Args[0], os.Args[0])
 		os.Exit(1)
 	}
 
-	class, err := class.Parse(os.Args[1])
+	classFile, err := class.Parse(os.Args[1])
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error reading class file: %v\n", err)
 		os.Exit(1)
 	}
 
-	executionEngine := execution_engine.NewExectuionEngine(class)
+	if err := class.Check(classFile); err != nil {
+		fmt.Fprintf(os.Stderr, "Refusing to run %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+
+	executionEngine := execution_engine.NewExectuionEngine(classFile)
 	err = executionEngine.Execute()
 }
@@ -0,0 +1,230 @@
+// Package analysis builds control-flow graphs of method bodies, for the
+// verifier, optimizations, coverage and static checks to share. A Graph splits
+// the bytecode of a Code attribute into basic blocks joined by fall-through,
+// branch, switch, exception handler and subroutine edges, and gives their
+// dominator and post-dominator trees and natural loops.
+package analysis
+
+import (
+	"errors"
+	"fmt"
+	"lava-vm/pkg/bytecode"
+	"lava-vm/pkg/class"
+)
+
+// EdgeKind is the way control passes along an edge.
+type EdgeKind uint8
+
+const (
+	// FallThrough continues with the instruction that follows.
+	FallThrough EdgeKind = iota
+	// Branch is a goto or the taken side of a conditional branch.
+	Branch
+	// Switch is a case or the default of a tableswitch or lookupswitch.
+	Switch
+	// Exception goes from a block covered by an exception table entry to its
+	// handler.
+	Exception
+	// Jsr calls a subroutine.
+	Jsr
+	// Ret returns from a subroutine to the instruction after a jsr that
+	// called it.
+	Ret
+)
+
+var edgeKindNames = [...]string{"fallthrough", "branch", "switch", "exception", "jsr", "ret"}
+
+func (k EdgeKind) String() string {
+	if int(k) < len(edgeKindNames) {
+		return edgeKindNames[k]
+	}
+	return fmt.Sprintf("EdgeKind(%d)", k)
+}
+
+// Block is a basic block: a run of instructions that is only entered at the
+// first and only left after the last, or by an exception.
+type Block struct {
+	// Index is the position of the block in Graph.Blocks.
+	Index int
+	// Start is the pc of the first instruction and End the pc after the last.
+	Start int
+	End   int
+	// Instructions share their backing array with Graph.Instructions.
+	Instructions []bytecode.Instruction
+	Succs        []*Edge
+	Preds        []*Edge
+}
+
+// Last returns the last instruction of the block.
+func (b *Block) Last() *bytecode.Instruction {
+	return &b.Instructions[len(b.Instructions)-1]
+}
+
+func (b *Block) String() string {
+	return fmt.Sprintf("block %d [%d, %d)", b.Index, b.Start, b.End)
+}
+
+// Edge is a transfer of control between blocks. There is at most one edge of
+// each kind between two blocks; the switch keys and exception table entries
+// that share it are collected on it.
+type Edge struct {
+	From *Block
+	To   *Block
+	Kind EdgeKind
+	// Keys are the switch keys that take a Switch edge, and Default is set if
+	// the switch default takes it too.
+	Keys    []int32
+	Default bool
+	// CatchTypes are the catch types of the exception table entries that give
+	// an Exception edge, in table order: class constant pool indexes, or 0 for
+	// entries that catch everything.
+	CatchTypes []uint16
+}
+
+// Graph is the control-flow graph of a method body.
+type Graph struct {
+	// Blocks are in increasing order of Start; Blocks[0] is the entry block
+	// at pc 0. Blocks unreachable from the entry are kept.
+	Blocks       []*Block
+	Subroutines  []*Subroutine
+	Instructions *bytecode.Index
+	// blockOf holds the position in Blocks of the block covering each pc.
+	blockOf []int32
+}
+
+// NewGraph builds the control-flow graph of code. It fails if the bytecode
+// cannot be decoded, if a branch does not land on an instruction, or if an
+// exception table entry does not cover whole instructions or its handler is
+// not the start of one.
+func NewGraph(code *class.Code) (*Graph, error) {
+	index, err := code.Instructions()
+	if err != nil {
+		return nil, err
+	}
+	if len(index.Instructions) == 0 {
+		return nil, errors.New("code is empty")
+	}
+	for i, entry := range code.ExceptionTable {
+		start, end, handler := int(entry.StartPc), int(entry.EndPc), int(entry.HandlerPc)
+		if start >= end || !index.IsBoundary(start) || (end != index.Len() && !index.IsBoundary(end)) {
+			return nil, fmt.Errorf("exception table entry %d: range [%d, %d) does not cover whole instructions", i, start, end)
+		}
+		if !index.IsBoundary(handler) {
+			return nil, fmt.Errorf("exception table entry %d: handler %d is not the start of an instruction", i, handler)
+		}
+	}
+
+	g := &Graph{Instructions: index}
+	g.split(code.ExceptionTable)
+	g.connect(code.ExceptionTable)
+	g.findSubroutines()
+	return g, nil
+}
+
+// split divides the instructions into blocks. A block starts at pc 0, at the
+// target of a branch or switch, after an instruction that does not simply
+// fall through, at an exception handler and where a protected range starts
+// or ends, so that each block is either wholly covered by an entry or not.
+func (g *Graph) split(table []class.ExceptionTableEntry) {
+	index := g.Instructions
+	leaders := make([]bool, index.Len()+1)
+	leaders[0] = true
+	for _, instruction := range index.Instructions {
+		for _, target := range instruction.Targets() {
+			leaders[target] = true
+		}
+		if instruction.Opcode.IsBranch() || instruction.Opcode.IsSwitch() || !instruction.FallsThrough() {
+			leaders[instruction.Offset+instruction.Length] = true
+		}
+	}
+	for _, entry := range table {
+		leaders[entry.StartPc] = true
+		leaders[entry.EndPc] = true
+		leaders[entry.HandlerPc] = true
+	}
+
+	g.blockOf = make([]int32, index.Len())
+	for i, instruction := range index.Instructions {
+		if leaders[instruction.Offset] {
+			g.Blocks = append(g.Blocks, &Block{Index: len(g.Blocks), Start: instruction.Offset})
+		}
+		block := g.Blocks[len(g.Blocks)-1]
+		block.End = instruction.Offset + instruction.Length
+		first, _ := index.Position(block.Start)
+		block.Instructions = index.Instructions[first : i+1 : i+1]
+		for pc := instruction.Offset; pc < block.End; pc++ {
+			g.blockOf[pc] = int32(block.Index)
+		}
+	}
+}
+
+// connect adds every edge except the returns from subroutines.
+func (g *Graph) connect(table []class.ExceptionTableEntry) {
+	for _, block := range g.Blocks {
+		last := block.Last()
+		switch {
+		case last.Switch != nil:
+			for i, target := range last.Switch.Targets {
+				edge := g.addEdge(block, g.BlockAt(target), Switch)
+				edge.Keys = append(edge.Keys, last.Switch.Keys[i])
+			}
+			g.addEdge(block, g.BlockAt(last.Switch.DefaultTarget), Switch).Default = true
+		case last.Opcode == bytecode.Jsr || last.Opcode == bytecode.JsrW:
+			// The instruction after the jsr is reached by the Ret edges
+			// added by findSubroutines.
+			g.addEdge(block, g.BlockAt(last.Target), Jsr)
+			continue
+		case last.Opcode.IsBranch():
+			g.addEdge(block, g.BlockAt(last.Target), Branch)
+		}
+		if last.FallsThrough() && block.End < g.Instructions.Len() {
+			g.addEdge(block, g.BlockAt(block.End), FallThrough)
+		}
+	}
+
+	for _, block := range g.Blocks {
+		for _, entry := range table {
+			if block.Start >= int(entry.StartPc) && block.Start < int(entry.EndPc) {
+				edge := g.addEdge(block, g.BlockAt(int(entry.HandlerPc)), Exception)
+				edge.CatchTypes = append(edge.CatchTypes, entry.CatchType)
+			}
+		}
+	}
+}
+
+// addEdge returns the edge of the given kind from one block to another,
+// adding it if there is none yet.
+func (g *Graph) addEdge(from, to *Block, kind EdgeKind) *Edge {
+	for _, edge := range from.Succs {
+		if edge.To == to && edge.Kind == kind {
+			return edge
+		}
+	}
+	edge := &Edge{From: from, To: to, Kind: kind}
+	from.Succs = append(from.Succs, edge)
+	to.Preds = append(to.Preds, edge)
+	return edge
+}
+
+// BlockAt returns the block covering pc, or nil if pc is outside the code.
+func (g *Graph) BlockAt(pc int) *Block {
+	if pc < 0 || pc >= len(g.blockOf) {
+		return nil
+	}
+	return g.Blocks[g.blockOf[pc]]
+}
+
+// successors returns the positions of the distinct successors of each block.
+func (g *Graph) successors() [][]int {
+	succs := make([][]int, len(g.Blocks))
+	for _, block := range g.Blocks {
+		seen := make(map[int]bool, len(block.Succs))
+		for _, edge := range block.Succs {
+			if !seen[edge.To.Index] {
+				seen[edge.To.Index] = true
+				succs[block.Index] = append(succs[block.Index], edge.To.Index)
+			}
+		}
+	}
+	return succs
+}
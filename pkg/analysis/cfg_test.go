@@ -0,0 +1,127 @@
+package analysis
+
+import (
+	"lava-vm/pkg/assembler"
+	"lava-vm/pkg/class"
+	"strings"
+	"testing"
+)
+
+// newTestGraph assembles a static method m with the given descriptor and body
+// into a version 49 class, so that jsr and ret are allowed, and returns the
+// graph of its code along with the class.
+func newTestGraph(t *testing.T, descriptor, body string) (*Graph, *class.Class) {
+	t.Helper()
+	source := ".version 49 0\n.class T\n.method static m" + descriptor + "\n" + body + ".end method\n"
+	data, err := assembler.Assemble(strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := class.ParseBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := c.FindMethod("m", descriptor).GetCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := NewGraph(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g, c
+}
+
+// blockAt returns the block starting at pc.
+func blockAt(t *testing.T, g *Graph, pc int) *Block {
+	t.Helper()
+	block := g.BlockAt(pc)
+	if block == nil || block.Start != pc {
+		t.Fatalf("no block starts at pc %d", pc)
+	}
+	return block
+}
+
+// edge returns the edge of the given kind from the block at pc from to the
+// block at pc to, or nil.
+func edge(t *testing.T, g *Graph, from, to int, kind EdgeKind) *Edge {
+	t.Helper()
+	for _, e := range blockAt(t, g, from).Succs {
+		if e.To.Start == to && e.Kind == kind {
+			return e
+		}
+	}
+	return nil
+}
+
+// starts returns the Start of each block, or -1 for nil.
+func starts(blocks ...*Block) []int {
+	pcs := make([]int, len(blocks))
+	for i, block := range blocks {
+		pcs[i] = -1
+		if block != nil {
+			pcs[i] = block.Start
+		}
+	}
+	return pcs
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestExceptionEdges(t *testing.T) {
+	g, c := newTestGraph(t, "(Ljava/lang/String;)I", `
+    .catch java/lang/NumberFormatException from Start to End using Invalid
+    .catch all from Start to End using Any
+Start:
+    aload_0
+    invokestatic java/lang/Integer/parseInt(Ljava/lang/String;)I
+End:
+    ireturn
+Invalid:
+    pop
+    iconst_m1
+    ireturn
+Any:
+    athrow
+`)
+	if got, want := starts(g.Blocks...), []int{0, 4, 5, 8}; !equalInts(got, want) {
+		t.Fatalf("block starts = %v, want %v", got, want)
+	}
+
+	if edge(t, g, 0, 4, FallThrough) == nil {
+		t.Error("no fallthrough edge from the protected block")
+	}
+	invalid := edge(t, g, 0, 5, Exception)
+	if invalid == nil || len(invalid.CatchTypes) != 1 {
+		t.Fatalf("exception edge to the NumberFormatException handler = %+v", invalid)
+	}
+	if name, err := c.ConstantPool.ClassRef(invalid.CatchTypes[0]); err != nil || name != "java/lang/NumberFormatException" {
+		t.Errorf("CatchTypes[0] = %q, %v, want java/lang/NumberFormatException", name, err)
+	}
+	if any := edge(t, g, 0, 8, Exception); any == nil || len(any.CatchTypes) != 1 || any.CatchTypes[0] != 0 {
+		t.Errorf("exception edge to the catch-all handler = %+v, want CatchTypes [0]", any)
+	}
+	// The return after the protected range is not covered
+	if e := edge(t, g, 4, 5, Exception); e != nil {
+		t.Errorf("unexpected exception edge %+v from the uncovered block", e)
+	}
+
+	// The handlers are only reached through exceptions, yet dominated by
+	// the protected block
+	dominators := g.Dominators()
+	for _, pc := range []int{5, 8} {
+		if got := dominators.Immediate(blockAt(t, g, pc)); got != blockAt(t, g, 0) {
+			t.Errorf("Immediate(block at %d) = %v, want block 0", pc, got)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package analysis
+
+import "lava-vm/pkg/bytecode"
+
+// DominatorTree is the dominator or post-dominator tree of a graph. A block
+// dominates another if every path from the entry to the other passes through
+// it, and post-dominates it if every path from the other to an exit does.
+// The exits are the blocks ending in a return or athrow, which may leave the
+// method even when they are covered by a handler, and the blocks with no
+// successors; the post-dominator tree is rooted at a virtual exit node that
+// follows them all.
+type DominatorTree struct {
+	graph *Graph
+	// idom holds the immediate dominator of each node, the root for the root
+	// itself and -1 for nodes outside the tree. Nodes are the positions of
+	// blocks in graph.Blocks, and for a post-dominator tree the virtual exit
+	// after them.
+	idom     []int
+	root     int
+	children [][]int
+	// enter and leave number the nodes in a depth-first walk of the tree, so
+	// that a dominates b exactly when a's interval contains b's.
+	enter []int
+	leave []int
+}
+
+// Dominators returns the dominator tree of the graph, rooted at the entry
+// block. Blocks unreachable from the entry are not in the tree.
+func (g *Graph) Dominators() *DominatorTree {
+	return newDominatorTree(g, g.successors(), 0)
+}
+
+// PostDominators returns the post-dominator tree of the graph. Blocks that
+// reach no exit, such as those of an infinite loop, are not in the tree.
+func (g *Graph) PostDominators() *DominatorTree {
+	exit := len(g.Blocks)
+	reversed := make([][]int, exit+1)
+	for from, succs := range g.successors() {
+		if len(succs) == 0 || isExit(g.Blocks[from].Last().Opcode) {
+			reversed[exit] = append(reversed[exit], from)
+		}
+		for _, to := range succs {
+			reversed[to] = append(reversed[to], from)
+		}
+	}
+	return newDominatorTree(g, reversed, exit)
+}
+
+func isExit(op bytecode.Opcode) bool {
+	switch op {
+	case bytecode.Ireturn, bytecode.Lreturn, bytecode.Freturn, bytecode.Dreturn,
+		bytecode.Areturn, bytecode.Return, bytecode.Athrow:
+		return true
+	}
+	return false
+}
+
+func newDominatorTree(g *Graph, succs [][]int, root int) *DominatorTree {
+	t := &DominatorTree{graph: g, idom: dominators(succs, root), root: root}
+	t.children = make([][]int, len(succs))
+	for node, parent := range t.idom {
+		if parent >= 0 && node != root {
+			t.children[parent] = append(t.children[parent], node)
+		}
+	}
+
+	t.enter = make([]int, len(succs))
+	t.leave = make([]int, len(succs))
+	for node := range t.enter {
+		t.enter[node] = -1
+	}
+	type frame struct{ node, next int }
+	stack := []frame{{root, 0}}
+	t.enter[root] = 0
+	clock := 1
+	for len(stack) > 0 {
+		top := &stack[len(stack)-1]
+		if top.next < len(t.children[top.node]) {
+			child := t.children[top.node][top.next]
+			top.next++
+			t.enter[child] = clock
+			clock++
+			stack = append(stack, frame{child, 0})
+			continue
+		}
+		t.leave[top.node] = clock
+		clock++
+		stack = stack[:len(stack)-1]
+	}
+	return t
+}
+
+// dominators computes the immediate dominators of a graph of len(succs) nodes
+// with the iterative algorithm of Cooper, Harvey and Kennedy, "A Simple, Fast
+// Dominance Algorithm". The result holds the immediate dominator of each node,
+// root for root itself and -1 for nodes unreachable from root.
+func dominators(succs [][]int, root int) []int {
+	preds := make([][]int, len(succs))
+	for from, tos := range succs {
+		for _, to := range tos {
+			preds[to] = append(preds[to], from)
+		}
+	}
+
+	// Number the reachable nodes in postorder; the root comes last.
+	order := make([]int, len(succs))
+	for node := range order {
+		order[node] = -1
+	}
+	var postorder []int
+	visited := make([]bool, len(succs))
+	type frame struct{ node, next int }
+	stack := []frame{{root, 0}}
+	visited[root] = true
+	for len(stack) > 0 {
+		top := &stack[len(stack)-1]
+		if top.next < len(succs[top.node]) {
+			to := succs[top.node][top.next]
+			top.next++
+			if !visited[to] {
+				visited[to] = true
+				stack = append(stack, frame{to, 0})
+			}
+			continue
+		}
+		order[top.node] = len(postorder)
+		postorder = append(postorder, top.node)
+		stack = stack[:len(stack)-1]
+	}
+
+	idom := make([]int, len(succs))
+	for node := range idom {
+		idom[node] = -1
+	}
+	idom[root] = root
+	intersect := func(a, b int) int {
+		for a != b {
+			for order[a] < order[b] {
+				a = idom[a]
+			}
+			for order[b] < order[a] {
+				b = idom[b]
+			}
+		}
+		return a
+	}
+	for changed := true; changed; {
+		changed = false
+		for i := len(postorder) - 2; i >= 0; i-- {
+			node := postorder[i]
+			dominator := -1
+			for _, pred := range preds[node] {
+				if idom[pred] < 0 {
+					continue
+				}
+				if dominator < 0 {
+					dominator = pred
+				} else {
+					dominator = intersect(pred, dominator)
+				}
+			}
+			if dominator != idom[node] {
+				idom[node] = dominator
+				changed = true
+			}
+		}
+	}
+	return idom
+}
+
+// Immediate returns the immediate dominator of b, or nil if b is the root of
+// the tree or not in it. In a post-dominator tree it is also nil for the
+// blocks immediately post-dominated by the virtual exit, see Roots.
+func (t *DominatorTree) Immediate(b *Block) *Block {
+	parent := t.idom[b.Index]
+	if parent < 0 || parent == b.Index || parent == len(t.graph.Blocks) {
+		return nil
+	}
+	return t.graph.Blocks[parent]
+}
+
+// Children returns the blocks b immediately dominates, in increasing order of
+// Start.
+func (t *DominatorTree) Children(b *Block) []*Block {
+	return t.blocks(t.children[b.Index])
+}
+
+// Roots returns the blocks with no immediate dominator in the tree: the entry
+// block of a dominator tree, or the blocks immediately post-dominated by the
+// virtual exit of a post-dominator tree.
+func (t *DominatorTree) Roots() []*Block {
+	if t.root < len(t.graph.Blocks) {
+		return []*Block{t.graph.Blocks[t.root]}
+	}
+	return t.blocks(t.children[t.root])
+}
+
+// Contains reports whether b is in the tree, that is, whether it is reachable
+// from the entry in a dominator tree or reaches an exit in a post-dominator
+// tree.
+func (t *DominatorTree) Contains(b *Block) bool {
+	return t.idom[b.Index] >= 0
+}
+
+// Dominates reports whether a dominates b, or post-dominates it in a
+// post-dominator tree. Every block in the tree dominates itself; blocks that
+// are not in the tree dominate nothing and are dominated by nothing.
+func (t *DominatorTree) Dominates(a, b *Block) bool {
+	if !t.Contains(a) || !t.Contains(b) {
+		return false
+	}
+	return t.enter[a.Index] <= t.enter[b.Index] && t.leave[b.Index] <= t.leave[a.Index]
+}
+
+func (t *DominatorTree) blocks(nodes []int) []*Block {
+	blocks := make([]*Block, len(nodes))
+	for i, node := range nodes {
+		blocks[i] = t.graph.Blocks[node]
+	}
+	return blocks
+}
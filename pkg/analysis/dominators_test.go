@@ -0,0 +1,100 @@
+package analysis
+
+import "testing"
+
+// infiniteLoopBody returns when its argument is not zero and otherwise spins
+// forever, with the blocks starting at pcs 0, 4 and 5.
+const infiniteLoopBody = `
+    iload_0
+    ifeq Spin
+    return
+Spin:
+    iinc 0 1
+    goto Spin
+`
+
+func TestDominators(t *testing.T) {
+	g, _ := newTestGraph(t, "(I)V", nestedLoopBody)
+	dominators := g.Dominators()
+
+	// The immediate dominator of the block starting at each pc, -1 for none
+	immediate := map[int]int{0: -1, 2: 0, 7: 2, 9: 7, 14: 9, 20: 9, 26: 2}
+	for pc, want := range immediate {
+		if got := starts(dominators.Immediate(blockAt(t, g, pc)))[0]; got != want {
+			t.Errorf("Immediate(block at %d) = %d, want %d", pc, got, want)
+		}
+	}
+	if got := starts(dominators.Roots()...); !equalInts(got, []int{0}) {
+		t.Errorf("Roots() = %v, want [0]", got)
+	}
+	if got := starts(dominators.Children(blockAt(t, g, 2))...); !equalInts(got, []int{7, 26}) {
+		t.Errorf("Children(block at 2) = %v, want [7 26]", got)
+	}
+
+	tests := []struct {
+		a, b int
+		want bool
+	}{
+		{0, 26, true},
+		{2, 20, true},
+		{9, 9, true},
+		{14, 20, false},
+		{20, 2, false},
+		{7, 26, false},
+	}
+	for _, test := range tests {
+		if got := dominators.Dominates(blockAt(t, g, test.a), blockAt(t, g, test.b)); got != test.want {
+			t.Errorf("Dominates(block at %d, block at %d) = %v, want %v", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestPostDominators(t *testing.T) {
+	g, _ := newTestGraph(t, "(I)V", nestedLoopBody)
+	postDominators := g.PostDominators()
+
+	immediate := map[int]int{0: 2, 2: 26, 7: 9, 9: 20, 14: 9, 20: 2, 26: -1}
+	for pc, want := range immediate {
+		if got := starts(postDominators.Immediate(blockAt(t, g, pc)))[0]; got != want {
+			t.Errorf("Immediate(block at %d) = %d, want %d", pc, got, want)
+		}
+	}
+	if got := starts(postDominators.Roots()...); !equalInts(got, []int{26}) {
+		t.Errorf("Roots() = %v, want [26]", got)
+	}
+	if !postDominators.Dominates(blockAt(t, g, 26), blockAt(t, g, 0)) {
+		t.Error("the return does not post-dominate the entry")
+	}
+	if postDominators.Dominates(blockAt(t, g, 14), blockAt(t, g, 9)) {
+		t.Error("the inner loop body post-dominates its header")
+	}
+}
+
+func TestPostDominatorsInfiniteLoop(t *testing.T) {
+	g, _ := newTestGraph(t, "(I)V", infiniteLoopBody)
+	postDominators := g.PostDominators()
+	entry, ret, spin := blockAt(t, g, 0), blockAt(t, g, 4), blockAt(t, g, 5)
+
+	if postDominators.Contains(spin) {
+		t.Error("Contains() is true for the infinite loop, which reaches no exit")
+	}
+	if !postDominators.Contains(entry) || !postDominators.Contains(ret) {
+		t.Error("Contains() is false for a block that reaches the return")
+	}
+	// Every path from the entry that leaves the method goes through the
+	// return, so it post-dominates the entry despite the other successor
+	if got := postDominators.Immediate(entry); got != ret {
+		t.Errorf("Immediate(entry) = %v, want %v", got, ret)
+	}
+	if got := starts(postDominators.Roots()...); !equalInts(got, []int{4}) {
+		t.Errorf("Roots() = %v, want [4]", got)
+	}
+	if postDominators.Dominates(spin, spin) || postDominators.Dominates(ret, spin) {
+		t.Error("Dominates() is true for a block outside the tree")
+	}
+
+	// The loop is still reachable, so it is in the dominator tree
+	if dominators := g.Dominators(); !dominators.Contains(spin) || dominators.Immediate(spin) != entry {
+		t.Error("the infinite loop is not immediately dominated by the entry")
+	}
+}
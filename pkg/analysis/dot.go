@@ -0,0 +1,79 @@
+package analysis
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteDOT writes the graph in the Graphviz DOT language as a digraph with
+// the given name. Each block is a node listing its instructions. Switch edges
+// are labelled with their keys, exception edges are dashed and labelled with
+// their catch types, and subroutine calls and returns are dotted.
+func (g *Graph) WriteDOT(w io.Writer, name string) error {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "digraph %s {\n", quoteDOT(name))
+	builder.WriteString("\tnode [shape=box, fontname=\"monospace\"];\n")
+	for _, block := range g.Blocks {
+		var label strings.Builder
+		for _, instruction := range block.Instructions {
+			label.WriteString(escapeDOT(fmt.Sprintf("%d: %s", instruction.Offset, instruction)))
+			label.WriteString(`\l`)
+		}
+		fmt.Fprintf(&builder, "\tb%d [label=\"%s\"];\n", block.Index, label.String())
+	}
+	for _, block := range g.Blocks {
+		for _, edge := range block.Succs {
+			fmt.Fprintf(&builder, "\tb%d -> b%d%s;\n", edge.From.Index, edge.To.Index, edgeAttributes(edge))
+		}
+	}
+	builder.WriteString("}\n")
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+func edgeAttributes(edge *Edge) string {
+	var labels []string
+	style := ""
+	switch edge.Kind {
+	case Switch:
+		for _, key := range edge.Keys {
+			labels = append(labels, fmt.Sprint(key))
+		}
+		if edge.Default {
+			labels = append(labels, "default")
+		}
+	case Exception:
+		style = "dashed"
+		for _, catchType := range edge.CatchTypes {
+			if catchType == 0 {
+				labels = append(labels, "any")
+			} else {
+				labels = append(labels, fmt.Sprintf("#%d", catchType))
+			}
+		}
+	case Jsr, Ret:
+		style = "dotted"
+		labels = append(labels, edge.Kind.String())
+	}
+
+	var attributes []string
+	if len(labels) > 0 {
+		attributes = append(attributes, "label="+quoteDOT(strings.Join(labels, ", ")))
+	}
+	if style != "" {
+		attributes = append(attributes, "style="+style)
+	}
+	if len(attributes) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(attributes, ", ") + "]"
+}
+
+func quoteDOT(s string) string {
+	return `"` + escapeDOT(s) + `"`
+}
+
+func escapeDOT(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,102 @@
+package analysis
+
+import "sort"
+
+// Loop is a natural loop: the blocks that can reach a back edge, an edge to a
+// block that dominates its source, without passing through the block it goes
+// to, the header. Back edges to the same header make up a single loop.
+type Loop struct {
+	Header *Block
+	// Blocks are the blocks of the loop in increasing order of Start, the
+	// header and those of nested loops included.
+	Blocks    []*Block
+	BackEdges []*Edge
+	// Parent is the innermost loop containing this one, or nil, and Children
+	// the loops it immediately contains. Depth is 1 for an outermost loop.
+	Parent   *Loop
+	Children []*Loop
+	Depth    int
+}
+
+// Contains reports whether b is in the loop.
+func (l *Loop) Contains(b *Block) bool {
+	i := sort.Search(len(l.Blocks), func(i int) bool { return l.Blocks[i].Start >= b.Start })
+	return i < len(l.Blocks) && l.Blocks[i] == b
+}
+
+// Loops returns the natural loops of the graph by nesting depth and then
+// header pc, so that outer loops come before the loops they contain. A cycle
+// that can be entered at more than one block, which javac never emits, has no
+// header dominating it and is not a natural loop.
+func (g *Graph) Loops() []*Loop {
+	dominators := g.Dominators()
+	byHeader := make(map[*Block]*Loop)
+	var loops []*Loop
+	for _, block := range g.Blocks {
+		for _, edge := range block.Succs {
+			if !dominators.Dominates(edge.To, block) {
+				continue
+			}
+			loop, ok := byHeader[edge.To]
+			if !ok {
+				loop = &Loop{Header: edge.To}
+				byHeader[edge.To] = loop
+				loops = append(loops, loop)
+			}
+			loop.BackEdges = append(loop.BackEdges, edge)
+		}
+	}
+
+	for _, loop := range loops {
+		members := map[*Block]bool{loop.Header: true}
+		var work []*Block
+		for _, edge := range loop.BackEdges {
+			work = append(work, edge.From)
+		}
+		for len(work) > 0 {
+			block := work[len(work)-1]
+			work = work[:len(work)-1]
+			if members[block] {
+				continue
+			}
+			members[block] = true
+			for _, edge := range block.Preds {
+				if dominators.Contains(edge.From) {
+					work = append(work, edge.From)
+				}
+			}
+		}
+		for block := range members {
+			loop.Blocks = append(loop.Blocks, block)
+		}
+		sort.Slice(loop.Blocks, func(i, j int) bool { return loop.Blocks[i].Start < loop.Blocks[j].Start })
+	}
+
+	// Natural loops with different headers are nested or disjoint, so the
+	// innermost loop containing another is the smallest larger one that
+	// contains its header.
+	sort.SliceStable(loops, func(i, j int) bool { return len(loops[i].Blocks) > len(loops[j].Blocks) })
+	for i, loop := range loops {
+		loop.Depth = 1
+		for j := i - 1; j >= 0; j-- {
+			if loops[j].Contains(loop.Header) {
+				loop.Parent = loops[j]
+				loop.Depth = loops[j].Depth + 1
+				loops[j].Children = append(loops[j].Children, loop)
+				break
+			}
+		}
+	}
+	sort.SliceStable(loops, func(i, j int) bool {
+		if loops[i].Depth != loops[j].Depth {
+			return loops[i].Depth < loops[j].Depth
+		}
+		return loops[i].Header.Start < loops[j].Header.Start
+	})
+	for _, loop := range loops {
+		sort.Slice(loop.Children, func(i, j int) bool {
+			return loop.Children[i].Header.Start < loop.Children[j].Header.Start
+		})
+	}
+	return loops
+}
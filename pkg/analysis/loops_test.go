@@ -0,0 +1,109 @@
+package analysis
+
+import "testing"
+
+// nestedLoopBody is
+//
+//	for (int i = 0; i < n; i++)
+//		for (int j = 0; j < n; j++) {}
+//
+// with the blocks starting at pcs 0, 2 (outer header), 7, 9 (inner header),
+// 14, 20 and 26.
+const nestedLoopBody = `
+    iconst_0
+    istore_1
+Outer:
+    iload_1
+    iload_0
+    if_icmpge Done
+    iconst_0
+    istore_2
+Inner:
+    iload_2
+    iload_0
+    if_icmpge Next
+    iinc 2 1
+    goto Inner
+Next:
+    iinc 1 1
+    goto Outer
+Done:
+    return
+`
+
+func TestLoops(t *testing.T) {
+	g, _ := newTestGraph(t, "(I)V", nestedLoopBody)
+	if got, want := starts(g.Blocks...), []int{0, 2, 7, 9, 14, 20, 26}; !equalInts(got, want) {
+		t.Fatalf("block starts = %v, want %v", got, want)
+	}
+
+	loops := g.Loops()
+	if len(loops) != 2 {
+		t.Fatalf("len(Loops()) = %d, want 2", len(loops))
+	}
+	outer, inner := loops[0], loops[1]
+	tests := []struct {
+		name string
+		loop *Loop
+		// header, blocks and back edge sources by pc
+		header    int
+		blocks    []int
+		backEdges []int
+		parent    *Loop
+		children  []*Loop
+		depth     int
+	}{
+		{"outer", outer, 2, []int{2, 7, 9, 14, 20}, []int{20}, nil, []*Loop{inner}, 1},
+		{"inner", inner, 9, []int{9, 14}, []int{14}, outer, nil, 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			loop := test.loop
+			if loop.Header.Start != test.header {
+				t.Errorf("Header = %v, want the block at %d", loop.Header, test.header)
+			}
+			if got := starts(loop.Blocks...); !equalInts(got, test.blocks) {
+				t.Errorf("Blocks = %v, want %v", got, test.blocks)
+			}
+			var sources []int
+			for _, edge := range loop.BackEdges {
+				if edge.To != loop.Header {
+					t.Errorf("back edge %v goes to %v, not the header", edge.From, edge.To)
+				}
+				sources = append(sources, edge.From.Start)
+			}
+			if !equalInts(sources, test.backEdges) {
+				t.Errorf("BackEdges from %v, want %v", sources, test.backEdges)
+			}
+			if loop.Parent != test.parent {
+				t.Errorf("Parent = %p, want %p", loop.Parent, test.parent)
+			}
+			if len(loop.Children) != len(test.children) || (len(test.children) > 0 && loop.Children[0] != test.children[0]) {
+				t.Errorf("Children = %v, want %v", loop.Children, test.children)
+			}
+			if loop.Depth != test.depth {
+				t.Errorf("Depth = %d, want %d", loop.Depth, test.depth)
+			}
+		})
+	}
+
+	if outer.Contains(blockAt(t, g, 26)) || inner.Contains(blockAt(t, g, 20)) {
+		t.Error("Contains() is true for a block after the loop")
+	}
+}
+
+func TestLoopsSelfLoop(t *testing.T) {
+	g, _ := newTestGraph(t, "(I)V", infiniteLoopBody)
+	loops := g.Loops()
+	if len(loops) != 1 {
+		t.Fatalf("len(Loops()) = %d, want 1", len(loops))
+	}
+	spin := blockAt(t, g, 5)
+	loop := loops[0]
+	if loop.Header != spin || !equalInts(starts(loop.Blocks...), []int{5}) {
+		t.Errorf("loop = %v with blocks %v, want the block at 5 alone", loop.Header, starts(loop.Blocks...))
+	}
+	if len(loop.BackEdges) != 1 || loop.BackEdges[0].From != spin || loop.BackEdges[0].Kind != Branch {
+		t.Errorf("BackEdges = %+v, want the goto from the block to itself", loop.BackEdges)
+	}
+}
@@ -0,0 +1,103 @@
+package analysis
+
+import (
+	"lava-vm/pkg/bytecode"
+	"sort"
+)
+
+// Subroutine is the code called by jsr, which older compilers emit for
+// finally blocks. It returns with ret to the instruction after the jsr.
+type Subroutine struct {
+	// Entry is the block the jsr instructions jump to.
+	Entry *Block
+	// Blocks are the blocks of the subroutine in increasing order of Start,
+	// Entry included. Subroutines it calls are not included.
+	Blocks []*Block
+	// Callers are the blocks ending in a jsr to Entry, and Returns the blocks
+	// ending in a ret that returns from the subroutine.
+	Callers []*Block
+	Returns []*Block
+}
+
+// findSubroutines collects the subroutines and adds the Ret edges from each
+// of their ret instructions to the instructions after the calls. The blocks
+// of a subroutine are those reached from its entry without following
+// exceptions, stepping over the jsr calls to nested subroutines.
+func (g *Graph) findSubroutines() {
+	byEntry := make(map[*Block]*Subroutine)
+	for _, block := range g.Blocks {
+		for _, edge := range block.Succs {
+			if edge.Kind != Jsr {
+				continue
+			}
+			subroutine, ok := byEntry[edge.To]
+			if !ok {
+				subroutine = &Subroutine{Entry: edge.To}
+				byEntry[edge.To] = subroutine
+				g.Subroutines = append(g.Subroutines, subroutine)
+			}
+			subroutine.Callers = append(subroutine.Callers, block)
+		}
+	}
+	sort.Slice(g.Subroutines, func(i, j int) bool {
+		return g.Subroutines[i].Entry.Start < g.Subroutines[j].Entry.Start
+	})
+
+	for _, subroutine := range g.Subroutines {
+		seen := map[*Block]bool{subroutine.Entry: true}
+		work := []*Block{subroutine.Entry}
+		for len(work) > 0 {
+			block := work[len(work)-1]
+			work = work[:len(work)-1]
+			subroutine.Blocks = append(subroutine.Blocks, block)
+			if block.Last().Opcode == bytecode.Ret {
+				subroutine.Returns = append(subroutine.Returns, block)
+			}
+			for _, next := range g.within(block) {
+				if !seen[next] {
+					seen[next] = true
+					work = append(work, next)
+				}
+			}
+		}
+		sort.Slice(subroutine.Blocks, func(i, j int) bool {
+			return subroutine.Blocks[i].Start < subroutine.Blocks[j].Start
+		})
+		sort.Slice(subroutine.Returns, func(i, j int) bool {
+			return subroutine.Returns[i].Start < subroutine.Returns[j].Start
+		})
+	}
+
+	for _, subroutine := range g.Subroutines {
+		for _, ret := range subroutine.Returns {
+			for _, caller := range subroutine.Callers {
+				if site := g.returnSite(caller); site != nil {
+					g.addEdge(ret, site, Ret)
+				}
+			}
+		}
+	}
+}
+
+// within returns the blocks that control passes to from block without
+// leaving the subroutine it is in.
+func (g *Graph) within(block *Block) []*Block {
+	var next []*Block
+	for _, edge := range block.Succs {
+		switch edge.Kind {
+		case FallThrough, Branch, Switch:
+			next = append(next, edge.To)
+		case Jsr:
+			if site := g.returnSite(block); site != nil {
+				next = append(next, site)
+			}
+		}
+	}
+	return next
+}
+
+// returnSite returns the block after a block ending in jsr, or nil if the jsr
+// is the last instruction of the code.
+func (g *Graph) returnSite(caller *Block) *Block {
+	return g.BlockAt(caller.End)
+}
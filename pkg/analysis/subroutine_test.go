@@ -0,0 +1,105 @@
+package analysis
+
+import "testing"
+
+// finallyBody calls the subroutine at Finally from two places, as older
+// compilers do for a finally block on two paths out of a try.
+const finallyBody = `
+    iload_0
+    ifeq Zero
+    jsr Finally
+    iconst_1
+    ireturn
+Zero:
+    jsr Finally
+    iconst_0
+    ireturn
+Finally:
+    astore_1
+    iinc 0 1
+    ret 1
+`
+
+func TestSubroutines(t *testing.T) {
+	g, _ := newTestGraph(t, "(I)I", finallyBody)
+	if got, want := starts(g.Blocks...), []int{0, 4, 7, 9, 12, 14}; !equalInts(got, want) {
+		t.Fatalf("block starts = %v, want %v", got, want)
+	}
+	if len(g.Subroutines) != 1 {
+		t.Fatalf("len(Subroutines) = %d, want 1", len(g.Subroutines))
+	}
+
+	subroutine := g.Subroutines[0]
+	tests := []struct {
+		name string
+		got  []int
+		want []int
+	}{
+		{"Entry", starts(subroutine.Entry), []int{14}},
+		{"Blocks", starts(subroutine.Blocks...), []int{14}},
+		{"Callers", starts(subroutine.Callers...), []int{4, 9}},
+		{"Returns", starts(subroutine.Returns...), []int{14}},
+	}
+	for _, test := range tests {
+		if !equalInts(test.got, test.want) {
+			t.Errorf("%s = %v, want %v", test.name, test.got, test.want)
+		}
+	}
+
+	// Each jsr gets a Jsr edge to the subroutine, and the ret a Ret edge back
+	// to the instruction after every call
+	for _, caller := range []int{4, 9} {
+		if edge(t, g, caller, 14, Jsr) == nil {
+			t.Errorf("no jsr edge from block at %d", caller)
+		}
+	}
+	for _, site := range []int{7, 12} {
+		if edge(t, g, 14, site, Ret) == nil {
+			t.Errorf("no ret edge to block at %d", site)
+		}
+	}
+	if n := len(blockAt(t, g, 14).Succs); n != 2 {
+		t.Errorf("len(Succs) of the subroutine = %d, want 2", n)
+	}
+
+	// Control only reaches the return sites through the subroutine
+	dominators := g.Dominators()
+	for _, site := range []int{7, 12} {
+		if got := dominators.Immediate(blockAt(t, g, site)); got != blockAt(t, g, 14) {
+			t.Errorf("Immediate(block at %d) = %v, want the subroutine", site, got)
+		}
+	}
+}
+
+func TestNestedSubroutines(t *testing.T) {
+	g, _ := newTestGraph(t, "(I)I", `
+    jsr Outer
+    iload_0
+    ireturn
+Outer:
+    astore_1
+    jsr Inner
+    ret 1
+Inner:
+    astore_2
+    iinc 0 1
+    ret 2
+`)
+	if len(g.Subroutines) != 2 {
+		t.Fatalf("len(Subroutines) = %d, want 2", len(g.Subroutines))
+	}
+	outer, inner := g.Subroutines[0], g.Subroutines[1]
+	// The outer subroutine steps over its call to the inner one
+	if got, want := starts(outer.Blocks...), []int{5, 9}; !equalInts(got, want) {
+		t.Errorf("outer Blocks = %v, want %v", got, want)
+	}
+	if got, want := starts(inner.Blocks...), []int{11}; !equalInts(got, want) {
+		t.Errorf("inner Blocks = %v, want %v", got, want)
+	}
+	if edge(t, g, 11, 9, Ret) == nil {
+		t.Error("no ret edge from the inner subroutine to the outer one")
+	}
+	if edge(t, g, 9, 3, Ret) == nil {
+		t.Error("no ret edge from the outer subroutine to its caller")
+	}
+}
@@ -0,0 +1,488 @@
+package assembler
+
+import (
+	"fmt"
+	"lava-vm/pkg/class"
+	"strconv"
+)
+
+// openAnnotation is an annotation between .annotation and .end annotation.
+type openAnnotation struct {
+	annotation class.Annotation
+	// attributes is the list of the class, field or method it is added to.
+	attributes *[]class.Attribute
+	visible    bool
+	// parameter is the method parameter annotated, or -1 for the member
+	// itself; parameters is the number of parameters of the method.
+	parameter, parameters int
+}
+
+// elementTypes maps the words of the element values that need one to their
+// tags. They all refer to Integer constants.
+var elementTypes = map[string]byte{
+	"byte":    'B',
+	"char":    'C',
+	"short":   'S',
+	"boolean": 'Z',
+}
+
+// elementConstantTags maps the tags of the element values written as numeric
+// constants to the constant pool tags they refer to.
+var elementConstantTags = map[byte]uint8{
+	'I': class.TagInteger,
+	'J': class.TagLong,
+	'F': class.TagFloat,
+	'D': class.TagDouble,
+}
+
+// beginAnnotation handles .annotation visible|invisible [parameter <n>] <descriptor>,
+// which starts an annotation added to attributes. parameters is the number of
+// parameters of the method the directive is in, or -1 outside of a method.
+func (a *assembler) beginAnnotation(attributes *[]class.Attribute, args []string, parameters int) error {
+	const usage = "usage: .annotation visible|invisible [parameter <n>] <descriptor>"
+	if len(args) != 2 && len(args) != 4 || args[0] != "visible" && args[0] != "invisible" {
+		return fmt.Errorf(usage)
+	}
+	open := &openAnnotation{attributes: attributes, visible: args[0] == "visible", parameter: -1, parameters: parameters}
+	if len(args) == 4 {
+		if args[1] != "parameter" {
+			return fmt.Errorf(usage)
+		}
+		if parameters < 0 {
+			return fmt.Errorf("parameter annotations are only allowed in a method")
+		}
+		n, err := parseInt(args[2], 0, int64(parameters)-1)
+		if err != nil {
+			return fmt.Errorf("parameter %s: the method has %d parameters", args[2], parameters)
+		}
+		open.parameter = int(n)
+	}
+	desc, err := fieldDescriptor(args[len(args)-1])
+	if err != nil {
+		return err
+	}
+	open.annotation.Type = desc
+	if open.annotation.TypeIndex, err = a.cp.AddUtf8(desc); err != nil {
+		return err
+	}
+	a.annotation = open
+	return nil
+}
+
+// annotationStatement handles a line between .annotation and .end annotation,
+// which is either the end or an element of the form <name> = <value>.
+func (a *assembler) annotationStatement(tokens []string) error {
+	if tokens[0] == ".end" {
+		if len(tokens) != 2 || tokens[1] != "annotation" {
+			return fmt.Errorf("usage: .end annotation")
+		}
+		return a.endAnnotation()
+	}
+	if tokens[0][0] == '.' {
+		return fmt.Errorf("missing .end annotation before %s", tokens[0])
+	}
+	pair, rest, err := a.elementValuePair(tokens)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("element %s: unexpected %q", pair.ElementName, rest[0])
+	}
+	a.annotation.annotation.ElementValuePairs = append(a.annotation.annotation.ElementValuePairs, pair)
+	return nil
+}
+
+// elementValuePair parses the <name> = <value> at the start of tokens and
+// returns it and the remaining tokens.
+func (a *assembler) elementValuePair(tokens []string) (class.ElementValuePair, []string, error) {
+	var pair class.ElementValuePair
+	if len(tokens) < 3 || tokens[1] != "=" {
+		return pair, nil, fmt.Errorf("expected <name> = <value>")
+	}
+	pair.ElementName = tokens[0]
+	var err error
+	if pair.ElementNameIndex, err = a.cp.AddUtf8(tokens[0]); err != nil {
+		return pair, nil, err
+	}
+	var rest []string
+	if pair.Value, rest, err = a.elementValue(tokens[2:]); err != nil {
+		return pair, nil, fmt.Errorf("element %s: %w", tokens[0], err)
+	}
+	return pair, rest, nil
+}
+
+// elementValue parses the element value at the start of tokens and returns
+// it and the remaining tokens. The forms are:
+//
+//	123, 123L, 1.5f, 1.5d              int, long, float and double
+//	byte|char|short|boolean <integer>  the other primitive types
+//	"text"                             String
+//	enum <descriptor> <name>           an enum constant
+//	class <return descriptor>          a class literal, such as V for void.class
+//	annotation <descriptor> { <name> = <value> ... }
+//	[ <value> ... ]                    an array
+//
+// The brackets and braces are separate tokens.
+func (a *assembler) elementValue(tokens []string) (class.ElementValue, []string, error) {
+	var value class.ElementValue
+	if len(tokens) == 0 {
+		return value, nil, fmt.Errorf("expected an element value")
+	}
+	token, rest := tokens[0], tokens[1:]
+	var err error
+	switch token {
+	case "enum":
+		if len(rest) < 2 {
+			return value, nil, fmt.Errorf("expected a descriptor and a name after enum")
+		}
+		value.Tag = 'e'
+		if _, err = fieldDescriptor(rest[0]); err != nil {
+			return value, nil, err
+		}
+		if value.TypeNameIndex, err = a.cp.AddUtf8(rest[0]); err != nil {
+			return value, nil, err
+		}
+		value.ConstNameIndex, err = a.cp.AddUtf8(rest[1])
+		return value, rest[2:], err
+	case "class":
+		if len(rest) == 0 {
+			return value, nil, fmt.Errorf("expected a descriptor after class")
+		}
+		if rest[0] != "V" {
+			if _, err = fieldDescriptor(rest[0]); err != nil {
+				return value, nil, err
+			}
+		}
+		value.Tag = 'c'
+		value.ClassInfoIndex, err = a.cp.AddUtf8(rest[0])
+		return value, rest[1:], err
+	case "annotation":
+		if len(rest) < 2 || rest[1] != "{" {
+			return value, nil, fmt.Errorf("usage: annotation <descriptor> { <name> = <value> ... }")
+		}
+		nested := &class.Annotation{Type: rest[0]}
+		if _, err = fieldDescriptor(rest[0]); err != nil {
+			return value, nil, err
+		}
+		if nested.TypeIndex, err = a.cp.AddUtf8(rest[0]); err != nil {
+			return value, nil, err
+		}
+		rest = rest[2:]
+		for len(rest) > 0 && rest[0] != "}" {
+			var pair class.ElementValuePair
+			if pair, rest, err = a.elementValuePair(rest); err != nil {
+				return value, nil, err
+			}
+			nested.ElementValuePairs = append(nested.ElementValuePairs, pair)
+		}
+		if len(rest) == 0 {
+			return value, nil, fmt.Errorf("missing } after annotation %s", nested.Type)
+		}
+		value.Tag, value.AnnotationValue = '@', nested
+		return value, rest[1:], nil
+	case "[":
+		value.Tag, value.ArrayValue = '[', []class.ElementValue{}
+		for len(rest) > 0 && rest[0] != "]" {
+			var element class.ElementValue
+			if element, rest, err = a.elementValue(rest); err != nil {
+				return value, nil, err
+			}
+			value.ArrayValue = append(value.ArrayValue, element)
+		}
+		if len(rest) == 0 {
+			return value, nil, fmt.Errorf("missing ] after array")
+		}
+		return value, rest[1:], nil
+	}
+
+	if tag, ok := elementTypes[token]; ok {
+		if len(rest) == 0 {
+			return value, nil, fmt.Errorf("expected an integer after %s", token)
+		}
+		n, err := parseInt(rest[0], -1<<31, 1<<31-1)
+		if err != nil {
+			return value, nil, err
+		}
+		value.Tag = tag
+		value.ConstValueIndex, err = a.cp.AddInteger(int32(n))
+		return value, rest[1:], err
+	}
+	if isString(token) {
+		s, err := unquote(token)
+		if err != nil {
+			return value, nil, err
+		}
+		value.Tag = 's'
+		value.ConstValueIndex, err = a.cp.AddUtf8(s)
+		return value, rest, err
+	}
+
+	tag, number, err := parseNumber(token, class.TagInteger, class.TagDouble)
+	if err != nil {
+		return value, nil, fmt.Errorf("invalid element value %q", token)
+	}
+	switch tag {
+	case class.TagInteger:
+		value.Tag = 'I'
+		value.ConstValueIndex, err = a.cp.AddInteger(int32(number.(int64)))
+	case class.TagLong:
+		value.Tag = 'J'
+		value.ConstValueIndex, err = a.cp.AddLong(number.(int64))
+	case class.TagFloat:
+		value.Tag = 'F'
+		value.ConstValueIndex, err = a.cp.AddFloat(float32(number.(float64)))
+	default:
+		value.Tag = 'D'
+		value.ConstValueIndex, err = a.cp.AddDouble(number.(float64))
+	}
+	return value, rest, err
+}
+
+// endAnnotation adds the open annotation to the annotations attribute of its
+// kind, adding the attribute if it is the first.
+func (a *assembler) endAnnotation() error {
+	open := a.annotation
+	a.annotation = nil
+
+	visibility := "Invisible"
+	if open.visible {
+		visibility = "Visible"
+	}
+	if open.parameter < 0 {
+		name := "Runtime" + visibility + "Annotations"
+		value, err := a.memberAttributeValue(open.attributes, name, func() interface{} {
+			if open.visible {
+				return &class.RuntimeVisibleAnnotationsAttribute{}
+			}
+			return &class.RuntimeInvisibleAnnotationsAttribute{}
+		})
+		if err != nil {
+			return err
+		}
+		switch value := value.(type) {
+		case *class.RuntimeVisibleAnnotationsAttribute:
+			value.Annotations = append(value.Annotations, open.annotation)
+		case *class.RuntimeInvisibleAnnotationsAttribute:
+			value.Annotations = append(value.Annotations, open.annotation)
+		}
+		return nil
+	}
+
+	name := "Runtime" + visibility + "ParameterAnnotations"
+	value, err := a.memberAttributeValue(open.attributes, name, func() interface{} {
+		parameters := make([][]class.Annotation, open.parameters)
+		if open.visible {
+			return &class.RuntimeVisibleParameterAnnotationsAttribute{ParameterAnnotations: parameters}
+		}
+		return &class.RuntimeInvisibleParameterAnnotationsAttribute{ParameterAnnotations: parameters}
+	})
+	if err != nil {
+		return err
+	}
+	switch value := value.(type) {
+	case *class.RuntimeVisibleParameterAnnotationsAttribute:
+		value.ParameterAnnotations[open.parameter] = append(value.ParameterAnnotations[open.parameter], open.annotation)
+	case *class.RuntimeInvisibleParameterAnnotationsAttribute:
+		value.ParameterAnnotations[open.parameter] = append(value.ParameterAnnotations[open.parameter], open.annotation)
+	}
+	return nil
+}
+
+// memberAttributeValue returns the value of the attribute with the given name
+// that an earlier directive added to attributes, or adds one with the value
+// newValue returns.
+func (a *assembler) memberAttributeValue(attributes *[]class.Attribute, name string, newValue func() interface{}) (interface{}, error) {
+	for i := range *attributes {
+		// Attributes written from their bytes are left as they are
+		if attr := &(*attributes)[i]; attr.Name == name && attr.Value != nil && attr.Info == nil {
+			return attr.Value, nil
+		}
+	}
+	attr, err := a.newAttribute(name, newValue())
+	if err != nil {
+		return nil, err
+	}
+	*attributes = append(*attributes, attr)
+	return attr.Value, nil
+}
+
+// elementValueText returns an element value in the form parsed by
+// elementValue, or false if it cannot be written in that form.
+func (d *disassembler) elementValueText(value *class.ElementValue) (string, bool) {
+	index := value.ConstValueIndex
+	switch value.Tag {
+	case 'B', 'C', 'S', 'Z':
+		v, err := d.cp.IntegerConstant(index)
+		if err != nil {
+			return "", false
+		}
+		for word, tag := range elementTypes {
+			if tag == value.Tag {
+				return word + " " + strconv.Itoa(int(v)), true
+			}
+		}
+	case 'I', 'J', 'F', 'D':
+		if d.cp.Get(index).Tag != elementConstantTags[value.Tag] {
+			return "", false
+		}
+		return d.constant(index), true
+	case 's':
+		s, err := d.cp.Utf8(index)
+		return quote(s), err == nil
+	case 'e':
+		desc, err := d.cp.Utf8(value.TypeNameIndex)
+		if err != nil || !isDescriptorWord(desc) {
+			return "", false
+		}
+		name, err := d.cp.Utf8(value.ConstNameIndex)
+		if err != nil || !isWord(name) {
+			return "", false
+		}
+		return "enum " + desc + " " + name, true
+	case 'c':
+		desc, err := d.cp.Utf8(value.ClassInfoIndex)
+		if err != nil || desc != "V" && !isDescriptorWord(desc) {
+			return "", false
+		}
+		return "class " + desc, true
+	case '@':
+		text, ok := d.annotationText(value.AnnotationValue)
+		if !ok {
+			return "", false
+		}
+		return "annotation " + text, true
+	case '[':
+		text := "["
+		for i := range value.ArrayValue {
+			element, ok := d.elementValueText(&value.ArrayValue[i])
+			if !ok {
+				return "", false
+			}
+			text += " " + element
+		}
+		return text + " ]", true
+	}
+	return "", false
+}
+
+// annotationText returns a nested annotation as "<descriptor> { <name> = <value> ... }".
+func (d *disassembler) annotationText(annotation *class.Annotation) (string, bool) {
+	desc, pairs, ok := d.annotationParts(annotation)
+	if !ok {
+		return "", false
+	}
+	text := desc + " {"
+	for _, pair := range pairs {
+		text += " " + pair
+	}
+	return text + " }", true
+}
+
+// annotationParts returns the descriptor of an annotation and its elements
+// as "<name> = <value>", or false if they cannot be written.
+func (d *disassembler) annotationParts(annotation *class.Annotation) (string, []string, bool) {
+	desc, err := d.cp.Utf8(annotation.TypeIndex)
+	if err != nil || !isDescriptorWord(desc) {
+		return "", nil, false
+	}
+	var pairs []string
+	for i := range annotation.ElementValuePairs {
+		pair := &annotation.ElementValuePairs[i]
+		name, err := d.cp.Utf8(pair.ElementNameIndex)
+		if err != nil || !isWord(name) {
+			return "", nil, false
+		}
+		value, ok := d.elementValueText(&pair.Value)
+		if !ok {
+			return "", nil, false
+		}
+		pairs = append(pairs, name+" = "+value)
+	}
+	return desc, pairs, true
+}
+
+// annotationsText returns the .annotation blocks for the annotations of a
+// member or, when parameter is at least zero, of one of its parameters.
+func (d *disassembler) annotationsText(indent, visibility string, parameter int, annotations []class.Annotation) ([]string, bool) {
+	target := visibility
+	if parameter >= 0 {
+		target += " parameter " + strconv.Itoa(parameter)
+	}
+	var lines []string
+	for i := range annotations {
+		desc, pairs, ok := d.annotationParts(&annotations[i])
+		if !ok {
+			return nil, false
+		}
+		lines = append(lines, fmt.Sprintf("%s.annotation %s %s", indent, target, desc))
+		for _, pair := range pairs {
+			lines = append(lines, indent+"    "+pair)
+		}
+		lines = append(lines, indent+".end annotation")
+	}
+	return lines, true
+}
+
+// annotations writes the annotations of an attribute as .annotation
+// directives, or the attribute from its bytes if they cannot be written as
+// directives. parameters is the number of parameters of the method that a
+// parameter annotations attribute belongs to.
+func (d *disassembler) annotations(indent string, attr *class.Attribute, parameters int) {
+	var lines []string
+	ok := true
+	switch value := d.decode(attr).(type) {
+	case *class.RuntimeVisibleAnnotationsAttribute:
+		lines, ok = d.annotationsText(indent, "visible", -1, value.Annotations)
+	case *class.RuntimeInvisibleAnnotationsAttribute:
+		lines, ok = d.annotationsText(indent, "invisible", -1, value.Annotations)
+	case *class.RuntimeVisibleParameterAnnotationsAttribute:
+		lines, ok = d.parameterAnnotationsText(indent, "visible", value.ParameterAnnotations, parameters)
+	case *class.RuntimeInvisibleParameterAnnotationsAttribute:
+		lines, ok = d.parameterAnnotationsText(indent, "invisible", value.ParameterAnnotations, parameters)
+	}
+	// An attribute with no annotations would not be written at all
+	if !ok || len(lines) == 0 {
+		d.raw(indent, ".attribute", attr)
+		return
+	}
+	for _, line := range lines {
+		d.printf("%s", line)
+	}
+}
+
+// parameterAnnotationsText returns the .annotation blocks of a parameter
+// annotations attribute. Attributes that do not give every parameter of
+// the method, as javac writes for some constructors, cannot be written as
+// directives.
+func (d *disassembler) parameterAnnotationsText(indent, visibility string, parameterAnnotations [][]class.Annotation, parameters int) ([]string, bool) {
+	if len(parameterAnnotations) != parameters {
+		return nil, false
+	}
+	var lines []string
+	for parameter, annotations := range parameterAnnotations {
+		text, ok := d.annotationsText(indent, visibility, parameter, annotations)
+		if !ok {
+			return nil, false
+		}
+		lines = append(lines, text...)
+	}
+	return lines, true
+}
+
+// isWord reports whether s reads back as a single name token.
+func isWord(s string) bool {
+	tokens, err := tokenize(s)
+	if err != nil || len(tokens) != 1 || tokens[0] != s || isString(s) {
+		return false
+	}
+	switch s {
+	case "=", "{", "}", "[", "]":
+		return false
+	}
+	return true
+}
+
+func isDescriptorWord(s string) bool {
+	_, err := fieldDescriptor(s)
+	return err == nil && isWord(s)
+}
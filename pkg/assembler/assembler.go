@@ -0,0 +1,617 @@
+// Package assembler assembles class files from a Jasmin-style text format,
+// for writing classes by hand, such as test cases javac would never emit.
+// Disassemble writes a class back in the same format.
+//
+// A file declares one class. Directives start with a dot and instructions are
+// written by mnemonic, one per line; a token starting with ';' begins a
+// comment. For example:
+//
+//	.class public super Hello
+//	.super java/lang/Object
+//
+//	.method public static main([Ljava/lang/String;)V
+//	    getstatic java/lang/System/out Ljava/io/PrintStream;
+//	    ldc "Hello"
+//	    invokevirtual java/io/PrintStream/println(Ljava/lang/String;)V
+//	    return
+//	.end method
+//
+// The class directives are:
+//
+//	.version <major> [<minor>]         the class file version, 49.0 by default
+//	.source <file>                     SourceFile
+//	.class <flags> <name>
+//	.super <name>                      java/lang/Object by default
+//	.implements <name>
+//	.innerclass <flags> <inner> [of <outer>] [as <name>]
+//	.enclosing <class> [<name(descriptor)>]
+//	.nesthost <class>
+//	.nestmember <class>
+//	.permittedsubclass <class>
+//	.bootstrap <method handle> <argument>...
+//
+// .signature "<signature>", .deprecated, .annotation and .attribute <name> [<hex>]
+// apply to the class, or to the field or method they follow. An annotation
+// is written over several lines, one per element:
+//
+//	.annotation visible|invisible [parameter <n>] <descriptor>
+//	<name> = <value>
+//	...
+//	.end annotation
+//
+// where parameter annotates a parameter of the method and the forms of the
+// values are described at elementValue. .attribute adds an attribute from
+// its raw bytes; constant pool indexes in it are kept as is, unless it is
+// preceded by lines of the form
+//
+//	.constant <index> <constant>
+//
+// which declare what each index stands for, numbered as in a constant pool
+// that holds only them. The indexes of a standard attribute are then
+// renumbered into the class; see poolConstant for the forms of constants.
+//
+// Fields and methods are declared with:
+//
+//	.field <flags> <name> <descriptor> [= <constant>]
+//	.method <flags> <name(descriptor)>
+//	...
+//	.end method
+//
+// Within a method, a token ending in ':' defines a label, and branches,
+// switches and the directives below refer to labels by name:
+//
+//	.limit stack <n>, .limit locals <n>  computed from the code when omitted
+//	.throws <class>
+//	.catch <class>|all from <label> to <label> using <label>
+//	.line <n>                            the source line of the next instruction
+//	.var <slot> is <name> <descriptor> [signature "<signature>"] from <label> to <label>
+//	.frame <frame>                       a stack map frame at the next instruction
+//	.codeattribute <name> [<hex>]        a raw attribute of the Code attribute
+//
+// Frames are not computed: from version 51 on, every branch target and
+// exception handler must have a .frame.
+//
+// Operands are written symbolically: classes by internal name, members as
+// owner/name descriptor for fields and owner/name(descriptor) for methods,
+// preceded by "interface" for interface methods, and constants as described
+// at constant. A tableswitch is followed by one target label per line and a
+// lookupswitch by "<key>: <label>" lines, each ending with "default: <label>".
+// An instruction can be preceded by "wide"; instructions that need it for
+// their operands are widened automatically.
+package assembler
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"lava-vm/pkg/class"
+	"strings"
+)
+
+// DefaultMajorVersion is the class file version used when there is no
+// .version directive. Java 5 classes need no stack map frames and may use
+// jsr and ret.
+const DefaultMajorVersion = 49
+
+// framesMajorVersion is the first class file version, Java 7, in which every
+// branch target and exception handler needs a stack map frame.
+const framesMajorVersion = 51
+
+// Assemble reads assembly from r and returns the class file it describes.
+// Errors give the line they were found on.
+func Assemble(r io.Reader) ([]byte, error) {
+	a := &assembler{cp: class.NewConstantPool(), field: -1}
+	a.class.Magic = 0xCAFEBABE
+	a.class.MajorVersion = DefaultMajorVersion
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		a.line++
+		tokens, err := tokenize(scanner.Text())
+		if err == nil && len(tokens) > 0 {
+			err = a.statement(tokens)
+		}
+		if err != nil {
+			var lineErr *lineError
+			if errors.As(err, &lineErr) {
+				return nil, err
+			}
+			return nil, &lineError{a.line, err}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := a.finish(); err != nil {
+		return nil, err
+	}
+	return a.class.Bytes()
+}
+
+// lineError is an error found on a given line of the source. Errors found
+// when a method ends give the line of the label or directive at fault.
+type lineError struct {
+	line int
+	err  error
+}
+
+func (e *lineError) Error() string { return fmt.Sprintf("line %d: %v", e.line, e.err) }
+
+func (e *lineError) Unwrap() error { return e.err }
+
+// assembler holds the class being assembled.
+type assembler struct {
+	cp    *class.ConstantPool
+	class class.Class
+	line  int
+
+	name     string
+	hasSuper bool
+	// field is the position in class.Fields of the field that directives
+	// apply to, or -1 when they apply to the class.
+	field int
+	// method is the method being assembled, between .method and .end method.
+	method *method
+
+	// constants holds the entries declared by .constant for the next
+	// .attribute or .codeattribute, or nil if there are none.
+	constants *class.ConstantPool
+	// annotation is the annotation being assembled, between .annotation and
+	// .end annotation.
+	annotation *openAnnotation
+
+	innerClasses        []class.InnerClass
+	nestMembers         []uint16
+	permittedSubclasses []uint16
+	bootstrapMethods    []class.BootstrapMethod
+}
+
+func (a *assembler) statement(tokens []string) error {
+	if a.annotation != nil {
+		return a.annotationStatement(tokens)
+	}
+	switch tokens[0] {
+	case ".constant":
+		return a.constantDirective(tokens[1:])
+	case ".attribute", ".codeattribute":
+	default:
+		if a.constants != nil {
+			return fmt.Errorf(".constant must be followed by .attribute or .codeattribute")
+		}
+	}
+	if a.method != nil {
+		return a.method.statement(tokens)
+	}
+
+	directive, args := tokens[0], tokens[1:]
+	if directive != ".class" && directive != ".version" && directive != ".source" && a.name == "" {
+		return fmt.Errorf("%s before .class", directive)
+	}
+	switch directive {
+	case ".version":
+		if len(args) < 1 || len(args) > 2 {
+			return fmt.Errorf("usage: .version <major> [<minor>]")
+		}
+		major, err := parseUint16(args[0])
+		if err != nil {
+			return err
+		}
+		a.class.MajorVersion = major
+		if len(args) == 2 {
+			minor, err := parseUint16(args[1])
+			if err != nil {
+				return err
+			}
+			a.class.MinorVersion = minor
+		}
+		return nil
+	case ".class":
+		if a.name != "" {
+			return fmt.Errorf("a file declares a single class")
+		}
+		words, rest := classFlags.splitFlags(args)
+		if len(rest) != 1 {
+			return fmt.Errorf("usage: .class <flags> <name>")
+		}
+		flags, err := classFlags.parse(words)
+		if err != nil {
+			return err
+		}
+		a.name = rest[0]
+		a.class.AccessFlags = class.ClassAccessFlags(flags)
+		a.class.ThisClass, err = a.cp.AddClass(a.name)
+		return err
+	case ".super":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: .super <name>")
+		}
+		index, err := a.cp.AddClass(args[0])
+		a.class.SuperClass, a.hasSuper = index, true
+		return err
+	case ".implements":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: .implements <name>")
+		}
+		index, err := a.cp.AddClass(args[0])
+		a.class.Interfaces = append(a.class.Interfaces, index)
+		return err
+	case ".field":
+		return a.fieldDirective(args)
+	case ".method":
+		return a.methodDirective(args)
+	case ".signature", ".deprecated", ".attribute":
+		return a.memberAttribute(a.attributes(), directive, args)
+	case ".annotation":
+		return a.beginAnnotation(a.attributes(), args, -1)
+	}
+
+	if a.field >= 0 {
+		return fmt.Errorf("%s applies to the class and must come before the fields and methods", directive)
+	}
+	return a.classAttribute(directive, args)
+}
+
+// attributes returns the attributes that .signature, .deprecated and
+// .attribute add to.
+func (a *assembler) attributes() *[]class.Attribute {
+	if a.field >= 0 {
+		return &a.class.Fields[a.field].Attributes
+	}
+	return &a.class.Attributes
+}
+
+// newAttribute returns an attribute with a typed value that the class writer encodes.
+func (a *assembler) newAttribute(name string, value interface{}) (class.Attribute, error) {
+	index, err := a.cp.AddUtf8(name)
+	return class.Attribute{AttributeNameIndex: index, Name: name, Value: value}, err
+}
+
+// rawAttribute returns an attribute holding the bytes of a hex string. When
+// .constant directives precede it, the constant pool indexes in the bytes
+// refer to the entries they declare and are renumbered into the class.
+func (a *assembler) rawAttribute(args []string) (class.Attribute, error) {
+	constants := a.constants
+	a.constants = nil
+	if len(args) < 1 || len(args) > 2 {
+		return class.Attribute{}, fmt.Errorf("usage: .attribute <name> [<hex>]")
+	}
+	var info []byte
+	if len(args) == 2 {
+		var err error
+		if info, err = parseHex(args[1]); err != nil {
+			return class.Attribute{}, err
+		}
+	}
+	if constants != nil {
+		return a.cp.ImportAttribute(&class.Attribute{Name: args[0], Info: info}, constants)
+	}
+	attr, err := a.newAttribute(args[0], nil)
+	attr.Info = info
+	attr.AttributeLength = uint32(len(info))
+	return attr, err
+}
+
+// constantDirective handles .constant <index> <constant>, which declares the
+// entry that index stands for in the next raw attribute. The entries are
+// added to a pool of their own, so they must be declared in the order of
+// their indexes and after the entries they refer to.
+func (a *assembler) constantDirective(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: .constant <index> <constant>")
+	}
+	want, err := parseUint16(args[0])
+	if err != nil {
+		return err
+	}
+	if a.constants == nil {
+		a.constants = class.NewConstantPool()
+	}
+	// The constant syntax adds to a.cp, so point it at the declared pool
+	// while the constant is parsed.
+	cp := a.cp
+	a.cp = a.constants
+	index, rest, err := a.poolConstant(args[1:])
+	a.cp = cp
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("unexpected %q after the constant", rest[0])
+	}
+	if index != want {
+		return fmt.Errorf("constant %d is declared as %d; declare each constant once, in order, after those it refers to", index, want)
+	}
+	return nil
+}
+
+// memberAttribute handles the directives that apply to a class, field or method.
+func (a *assembler) memberAttribute(attrs *[]class.Attribute, directive string, args []string) error {
+	var attr class.Attribute
+	var err error
+	switch directive {
+	case ".signature":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: .signature \"<signature>\"")
+		}
+		signature, err := unquote(args[0])
+		if err != nil {
+			return err
+		}
+		index, err := a.cp.AddUtf8(signature)
+		if err != nil {
+			return err
+		}
+		attr, err = a.newAttribute("Signature", &class.SignatureAttribute{SignatureIndex: index})
+	case ".deprecated":
+		if len(args) != 0 {
+			return fmt.Errorf("usage: .deprecated")
+		}
+		attr, err = a.newAttribute("Deprecated", &class.DeprecatedAttribute{})
+	default:
+		attr, err = a.rawAttribute(args)
+	}
+	if err != nil {
+		return err
+	}
+	*attrs = append(*attrs, attr)
+	return nil
+}
+
+func (a *assembler) classAttribute(directive string, args []string) error {
+	switch directive {
+	case ".source":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: .source <file>")
+		}
+		index, err := a.cp.AddUtf8(args[0])
+		if err != nil {
+			return err
+		}
+		attr, err := a.newAttribute("SourceFile", &class.SourceFileAttribute{SourceFileIndex: index})
+		a.class.Attributes = append(a.class.Attributes, attr)
+		return err
+	case ".innerclass":
+		return a.innerClass(args)
+	case ".enclosing":
+		if len(args) < 1 || len(args) > 2 {
+			return fmt.Errorf("usage: .enclosing <class> [<name(descriptor)>]")
+		}
+		enclosing := &class.EnclosingMethodAttribute{}
+		var err error
+		if enclosing.ClassIndex, err = a.cp.AddClass(args[0]); err != nil {
+			return err
+		}
+		if len(args) == 2 {
+			name, desc, err := nameAndDescriptor(args[1])
+			if err != nil {
+				return err
+			}
+			if enclosing.MethodIndex, err = a.cp.AddNameAndType(name, desc); err != nil {
+				return err
+			}
+		}
+		attr, err := a.newAttribute("EnclosingMethod", enclosing)
+		a.class.Attributes = append(a.class.Attributes, attr)
+		return err
+	case ".nesthost":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: .nesthost <class>")
+		}
+		index, err := a.cp.AddClass(args[0])
+		if err != nil {
+			return err
+		}
+		attr, err := a.newAttribute("NestHost", &class.NestHostAttribute{HostClassIndex: index})
+		a.class.Attributes = append(a.class.Attributes, attr)
+		return err
+	case ".nestmember", ".permittedsubclass":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: %s <class>", directive)
+		}
+		index, err := a.cp.AddClass(args[0])
+		if directive == ".nestmember" {
+			a.nestMembers = append(a.nestMembers, index)
+		} else {
+			a.permittedSubclasses = append(a.permittedSubclasses, index)
+		}
+		return err
+	case ".bootstrap":
+		if len(args) == 0 || args[0] != "methodhandle" {
+			return fmt.Errorf("usage: .bootstrap methodhandle <kind> <method> <argument>...")
+		}
+		handle, rest, err := a.constant(args, class.TagInteger, class.TagDouble)
+		if err != nil {
+			return err
+		}
+		method := class.BootstrapMethod{BootstrapMethodRef: handle, BootstrapArguments: []uint16{}}
+		for len(rest) > 0 {
+			var arg uint16
+			if arg, rest, err = a.constant(rest, class.TagInteger, class.TagDouble); err != nil {
+				return err
+			}
+			method.BootstrapArguments = append(method.BootstrapArguments, arg)
+		}
+		a.bootstrapMethods = append(a.bootstrapMethods, method)
+		return nil
+	}
+	return fmt.Errorf("unknown directive %s", directive)
+}
+
+// innerClass handles .innerclass <flags> <inner> [of <outer>] [as <name>].
+func (a *assembler) innerClass(args []string) error {
+	words, rest := innerClassFlags.splitFlags(args)
+	flags, err := innerClassFlags.parse(words)
+	if err != nil {
+		return err
+	}
+	if len(rest) == 0 {
+		return fmt.Errorf("usage: .innerclass <flags> <inner> [of <outer>] [as <name>]")
+	}
+	inner := class.InnerClass{InnerClassAccessFlags: class.InnerClassAccessFlags(flags)}
+	if inner.InnerClassInfoIndex, err = a.cp.AddClass(rest[0]); err != nil {
+		return err
+	}
+	for rest = rest[1:]; len(rest) > 0; rest = rest[2:] {
+		if len(rest) < 2 {
+			return fmt.Errorf("expected a name after %s", rest[0])
+		}
+		switch rest[0] {
+		case "of":
+			inner.OuterClassInfoIndex, err = a.cp.AddClass(rest[1])
+		case "as":
+			inner.InnerNameIndex, err = a.cp.AddUtf8(rest[1])
+		default:
+			return fmt.Errorf("unexpected %q, expected of or as", rest[0])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	a.innerClasses = append(a.innerClasses, inner)
+	return nil
+}
+
+// fieldDirective handles .field <flags> <name> <descriptor> [= <constant>].
+func (a *assembler) fieldDirective(args []string) error {
+	words, rest := fieldFlags.splitFlags(args)
+	flags, err := fieldFlags.parse(words)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 2 && (len(rest) < 4 || rest[2] != "=") {
+		return fmt.Errorf("usage: .field <flags> <name> <descriptor> [= <constant>]")
+	}
+	desc, err := fieldDescriptor(rest[1])
+	if err != nil {
+		return err
+	}
+	field := class.Field{AccessFlags: class.FieldAccessFlags(flags)}
+	if field.NameIndex, err = a.cp.AddUtf8(rest[0]); err != nil {
+		return err
+	}
+	if field.DescriptorIndex, err = a.cp.AddUtf8(desc); err != nil {
+		return err
+	}
+
+	if len(rest) > 2 {
+		// The descriptor decides the type of an unsuffixed number
+		intTag, floatTag := class.TagInteger, class.TagFloat
+		switch desc {
+		case "J":
+			intTag = class.TagLong
+		case "D":
+			floatTag = class.TagDouble
+		}
+		index, extra, err := a.constant(rest[3:], intTag, floatTag)
+		if err != nil {
+			return err
+		}
+		if len(extra) > 0 {
+			return fmt.Errorf("unexpected %q after the constant value", extra[0])
+		}
+		attr, err := a.newAttribute("ConstantValue", &class.ConstantValueAttribute{ConstantValueIndex: index})
+		if err != nil {
+			return err
+		}
+		field.Attributes = append(field.Attributes, attr)
+	}
+
+	a.class.Fields = append(a.class.Fields, field)
+	a.field = len(a.class.Fields) - 1
+	return nil
+}
+
+// methodDirective handles .method <flags> <name(descriptor)>, which starts a method.
+func (a *assembler) methodDirective(args []string) error {
+	words, rest := methodFlags.splitFlags(args)
+	flags, err := methodFlags.parse(words)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return fmt.Errorf("usage: .method <flags> <name(descriptor)>")
+	}
+	name, desc, err := nameAndDescriptor(rest[0])
+	if err != nil {
+		return err
+	}
+	a.field = -1
+	a.method, err = newMethod(a, class.MethodAccessFlags(flags), name, desc)
+	return err
+}
+
+// endMethod adds the method being assembled to the class.
+func (a *assembler) endMethod() error {
+	m := a.method
+	a.method = nil
+	method, err := m.finish()
+	if err != nil {
+		return err
+	}
+	a.class.Methods = append(a.class.Methods, method)
+	return nil
+}
+
+// finish adds what is only known at the end of the file to the class.
+func (a *assembler) finish() error {
+	if a.method != nil {
+		return &lineError{a.line, errors.New("missing .end method")}
+	}
+	if a.constants != nil {
+		return &lineError{a.line, errors.New(".constant must be followed by .attribute or .codeattribute")}
+	}
+	if a.annotation != nil {
+		return &lineError{a.line, errors.New("missing .end annotation")}
+	}
+	if a.name == "" {
+		return fmt.Errorf("missing .class directive")
+	}
+	if !a.hasSuper && a.name != "java/lang/Object" && !a.class.AccessFlags.IsModule() {
+		index, err := a.cp.AddClass("java/lang/Object")
+		if err != nil {
+			return err
+		}
+		a.class.SuperClass = index
+	}
+
+	var attrs []class.Attribute
+	add := func(name string, value interface{}) error {
+		attr, err := a.newAttribute(name, value)
+		attrs = append(attrs, attr)
+		return err
+	}
+	if len(a.innerClasses) > 0 {
+		if err := add("InnerClasses", &class.InnerClassesAttribute{Classes: a.innerClasses}); err != nil {
+			return err
+		}
+	}
+	if len(a.nestMembers) > 0 {
+		if err := add("NestMembers", &class.NestMembersAttribute{Classes: a.nestMembers}); err != nil {
+			return err
+		}
+	}
+	if len(a.permittedSubclasses) > 0 {
+		if err := add("PermittedSubclasses", &class.PermittedSubclassesAttribute{Classes: a.permittedSubclasses}); err != nil {
+			return err
+		}
+	}
+	if len(a.bootstrapMethods) > 0 {
+		if err := add("BootstrapMethods", &class.BootstrapMethodsAttribute{BootstrapMethods: a.bootstrapMethods}); err != nil {
+			return err
+		}
+	}
+	a.class.Attributes = append(a.class.Attributes, attrs...)
+	a.class.ConstantPool = *a.cp
+	return nil
+}
+
+func parseHex(token string) ([]byte, error) {
+	data, err := hex.DecodeString(strings.TrimPrefix(token, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex %q", token)
+	}
+	return data, nil
+}
@@ -0,0 +1,217 @@
+package assembler
+
+import (
+	"bytes"
+	"flag"
+	"lava-vm/pkg/class"
+	"lava-vm/pkg/javap"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the .golden files in testdata")
+
+// assemble assembles source and parses and checks the class it gives.
+func assemble(t *testing.T, source []byte) *class.Class {
+	t.Helper()
+	data, err := Assemble(bytes.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := class.ParseBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := class.Check(c); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func disassemble(t *testing.T, c *class.Class) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Disassemble(&buf, c); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+var constantIndex = regexp.MustCompile(`#\d+ *`)
+
+// structure returns the javap -v listing of a class without its constant
+// pool and with the constant pool indexes, and the padding after them, left
+// out, so that classes that only number their constants differently compare
+// equal.
+func structure(t *testing.T, c *class.Class) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := javap.Disassemble(&buf, c, javap.Options{Verbose: true}); err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	inPool := false
+	for _, line := range strings.Split(buf.String(), "\n") {
+		switch {
+		case line == "Constant pool:":
+			inPool = true
+		case line == "{":
+			inPool = false
+		}
+		if !inPool {
+			lines = append(lines, constantIndex.ReplaceAllString(line, "# "))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// TestFixtures assembles each testdata/*.j file and compares its disassembly,
+// which shows the computed limits, the pc of every label and any wide
+// prefixes, with the .golden file of the same name. The disassembly must then
+// assemble to a class with the same structure.
+func TestFixtures(t *testing.T) {
+	paths, err := filepath.Glob("testdata/*.j")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".j")
+		t.Run(name, func(t *testing.T) {
+			source, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := assemble(t, source)
+			got := disassemble(t, c)
+
+			golden := strings.TrimSuffix(path, ".j") + ".golden"
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("disassembly differs from %s:\n%s", golden, got)
+			}
+
+			if reassembled := assemble(t, got); structure(t, reassembled) != structure(t, c) {
+				t.Errorf("reassembled class differs:\n%s\nwant:\n%s", structure(t, reassembled), structure(t, c))
+			}
+		})
+	}
+}
+
+func TestDisassembleRoundTrip(t *testing.T) {
+	c, err := class.Parse("../../tst/Test.class")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reassembled := assemble(t, disassemble(t, c))
+	if got, want := structure(t, reassembled), structure(t, c); got != want {
+		t.Errorf("reassembled class differs:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestAssembleErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "constant declared out of order",
+			source: ".class A\n.constant 2 utf8 \"x\"\n.attribute RuntimeVisibleAnnotations 0000\n",
+			want:   "line 2: constant 1 is declared as 2",
+		},
+		{
+			name:   "constant not followed by an attribute",
+			source: ".class A\n.constant 1 utf8 \"x\"\n.super B\n",
+			want:   "line 3: .constant must be followed by .attribute",
+		},
+		{
+			name:   "attribute index not declared",
+			source: ".class A\n.constant 1 utf8 \"LA;\"\n.attribute RuntimeVisibleAnnotations 000100020000\n",
+			want:   "line 3: RuntimeVisibleAnnotations attribute: invalid constant pool index: 2",
+		},
+		{
+			name:   "stack underflow",
+			source: ".class A\n.method static f()V\n    pop\n    return\n.end method\n",
+			want:   "line 3: pop at pc 0 needs 1 stack slots",
+		},
+		{
+			name:   "branch target without a frame",
+			source: ".version 52\n.class A\n.method static f(I)V\n    iload_0\n    ifeq Done\nDone:\n    return\n.end method\n",
+			want:   "line 5: Done at pc 4 needs a stack map frame in class file version 52; add a .frame before it, or use .version 50 or earlier",
+		},
+		{
+			name: "handler without a frame",
+			source: ".version 51\n.class A\n.method static f()V\n    .catch all from Start to End using Handler\n" +
+				"Start:\n    return\nEnd:\nHandler:\n    athrow\n.end method\n",
+			want: "line 4: Handler at pc 1 needs a stack map frame in class file version 51",
+		},
+		{
+			name:   "annotation not ended",
+			source: ".class A\n.annotation visible LA;\n    value = 1\n.method static f()V\n",
+			want:   "line 4: missing .end annotation before .method",
+		},
+		{
+			name:   "annotation of a missing parameter",
+			source: ".class A\n.method static f(I)V\n    .annotation visible parameter 1 LA;\n",
+			want:   "line 3: parameter 1: the method has 1 parameters",
+		},
+		{
+			name:   "parameter annotation of a class",
+			source: ".class A\n.annotation visible parameter 0 LA;\n",
+			want:   "line 2: parameter annotations are only allowed in a method",
+		},
+		{
+			name:   "unterminated array element",
+			source: ".class A\n.annotation visible LA;\n    values = [ 1 2\n",
+			want:   "line 3: element values: missing ] after array",
+		},
+		{
+			name:   "annotation at the end of the file",
+			source: ".class A\n.annotation visible LA;\n",
+			want:   "line 2: missing .end annotation",
+		},
+		{
+			name:   "undefined label",
+			source: ".class A\n.method static f()V\n    goto Nowhere\n.end method\n",
+			want:   "line 3: undefined label Nowhere",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Assemble(strings.NewReader(test.source))
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("Assemble() = %v, want an error containing %q", err, test.want)
+			}
+		})
+	}
+}
+
+// TestDisassembleRawAnnotations checks that annotations that .annotation
+// cannot express are written from their bytes: here a parameter annotations
+// attribute that covers one of the two parameters, as javac writes for the
+// constructors of some inner classes.
+func TestDisassembleRawAnnotations(t *testing.T) {
+	source := ".class A\n.method f(II)V\n" +
+		"    .constant 1 utf8 \"LB;\"\n" +
+		"    .attribute RuntimeInvisibleParameterAnnotations 01000100010000\n" +
+		"    return\n.end method\n"
+	c := assemble(t, []byte(source))
+	got := disassemble(t, c)
+	if !bytes.Contains(got, []byte(".attribute RuntimeInvisibleParameterAnnotations")) || bytes.Contains(got, []byte(".annotation")) {
+		t.Errorf("disassembly does not keep the attribute raw:\n%s", got)
+	}
+	if reassembled := assemble(t, got); structure(t, reassembled) != structure(t, c) {
+		t.Errorf("reassembled class differs:\n%s\nwant:\n%s", structure(t, reassembled), structure(t, c))
+	}
+}
@@ -0,0 +1,972 @@
+package assembler
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"lava-vm/pkg/bytecode"
+	"lava-vm/pkg/class"
+	"lava-vm/pkg/descriptor"
+	"math"
+	"sort"
+	"strings"
+)
+
+// method holds a method between .method and .end method. Instructions are
+// encoded as they are read; branch offsets are filled in at the end, once
+// every label is defined.
+type method struct {
+	a          *assembler
+	flags      class.MethodAccessFlags
+	nameIndex  uint16
+	descIndex  uint16
+	descriptor descriptor.MethodDescriptor
+	attributes []class.Attribute
+	exceptions []uint16
+
+	code    []byte
+	hasCode bool
+	labels  map[string]int
+	// sourceLines holds the line each instruction is written on by pc, for
+	// the errors found once the method ends.
+	sourceLines map[int]int
+	fixups      []fixup
+	// open is the switch whose cases are being read, if any.
+	open *switchTable
+
+	catches   []catch
+	lines     []class.LineNumberTableEntry
+	variables []variable
+	frames    []frame
+	codeAttrs []class.Attribute
+
+	maxStack, maxLocals       uint16
+	hasMaxStack, hasMaxLocals bool
+}
+
+// labelRef is a use of a label, with the line it is used on for errors.
+type labelRef struct {
+	name string
+	line int
+}
+
+// fixup is a branch offset to fill in. The offset is written at position at
+// in the code, size bytes long, relative to the instruction at pc.
+type fixup struct {
+	pc, at, size int
+	target       labelRef
+}
+
+type catch struct {
+	start, end, handler labelRef
+	catchType           uint16
+}
+
+type variable struct {
+	slot                           uint16
+	nameIndex, descIndex, sigIndex uint16
+	size                           int
+	from, to                       labelRef
+}
+
+type frame struct {
+	pc     int
+	kind   string
+	chop   int
+	locals []verificationType
+	stack  []verificationType
+	line   int
+}
+
+// verificationType is a stack map type. For an uninitialized type the
+// offset of the new instruction is given by a label.
+type verificationType struct {
+	info class.VerificationTypeInfo
+	new  labelRef
+}
+
+type switchTable struct {
+	pc      int
+	opcode  bytecode.Opcode
+	low     int32
+	high    *int32
+	keys    []int32
+	targets []labelRef
+}
+
+func newMethod(a *assembler, flags class.MethodAccessFlags, name, desc string) (*method, error) {
+	md, err := descriptor.ParseMethod(desc)
+	if err != nil {
+		return nil, err
+	}
+	m := &method{a: a, flags: flags, descriptor: md, labels: make(map[string]int), sourceLines: make(map[int]int)}
+	if m.nameIndex, err = a.cp.AddUtf8(name); err != nil {
+		return nil, err
+	}
+	if m.descIndex, err = a.cp.AddUtf8(desc); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *method) ref(name string) labelRef {
+	return labelRef{name: name, line: m.a.line}
+}
+
+func (m *method) statement(tokens []string) error {
+	if m.open != nil {
+		return m.switchCase(tokens)
+	}
+	for len(tokens) > 0 && isLabel(tokens[0]) {
+		name := strings.TrimSuffix(tokens[0], ":")
+		if _, ok := m.labels[name]; ok {
+			return fmt.Errorf("label %s is already defined", name)
+		}
+		m.labels[name] = len(m.code)
+		tokens = tokens[1:]
+	}
+	if len(tokens) == 0 {
+		return nil
+	}
+	if strings.HasPrefix(tokens[0], ".") {
+		return m.directive(tokens[0], tokens[1:])
+	}
+	return m.instruction(tokens)
+}
+
+func (m *method) directive(directive string, args []string) error {
+	a := m.a
+	switch directive {
+	case ".end":
+		if len(args) != 1 || args[0] != "method" {
+			return fmt.Errorf("usage: .end method")
+		}
+		return a.endMethod()
+	case ".limit":
+		if len(args) != 2 || (args[0] != "stack" && args[0] != "locals") {
+			return fmt.Errorf("usage: .limit stack|locals <n>")
+		}
+		n, err := parseUint16(args[1])
+		if err != nil {
+			return err
+		}
+		if args[0] == "stack" {
+			m.maxStack, m.hasMaxStack = n, true
+		} else {
+			m.maxLocals, m.hasMaxLocals = n, true
+		}
+		return nil
+	case ".throws":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: .throws <class>")
+		}
+		index, err := a.cp.AddClass(args[0])
+		m.exceptions = append(m.exceptions, index)
+		return err
+	case ".catch":
+		if len(args) != 7 || args[1] != "from" || args[3] != "to" || args[5] != "using" {
+			return fmt.Errorf("usage: .catch <class>|all from <label> to <label> using <label>")
+		}
+		c := catch{start: m.ref(args[2]), end: m.ref(args[4]), handler: m.ref(args[6])}
+		if args[0] != "all" {
+			var err error
+			if c.catchType, err = a.cp.AddClass(args[0]); err != nil {
+				return err
+			}
+		}
+		m.catches = append(m.catches, c)
+		return nil
+	case ".line":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: .line <n>")
+		}
+		line, err := parseUint16(args[0])
+		m.lines = append(m.lines, class.LineNumberTableEntry{StartPc: uint16(len(m.code)), LineNumber: line})
+		return err
+	case ".var":
+		return m.variable(args)
+	case ".frame":
+		return m.frame(args)
+	case ".codeattribute":
+		attr, err := a.rawAttribute(args)
+		m.codeAttrs = append(m.codeAttrs, attr)
+		return err
+	case ".signature", ".deprecated", ".attribute":
+		return a.memberAttribute(&m.attributes, directive, args)
+	case ".annotation":
+		return a.beginAnnotation(&m.attributes, args, len(m.descriptor.Params))
+	}
+	return fmt.Errorf("unknown directive %s in a method", directive)
+}
+
+// variable handles .var <slot> is <name> <descriptor> [signature "<signature>"] from <label> to <label>.
+func (m *method) variable(args []string) error {
+	const usage = `usage: .var <slot> is <name> <descriptor> [signature "<signature>"] from <label> to <label>`
+	if len(args) != 8 && len(args) != 10 || args[1] != "is" {
+		return fmt.Errorf(usage)
+	}
+	slot, err := parseUint16(args[0])
+	if err != nil {
+		return err
+	}
+	fieldType, err := descriptor.ParseField(args[3])
+	if err != nil {
+		return err
+	}
+	v := variable{slot: slot, size: fieldType.Slots()}
+	if v.nameIndex, err = m.a.cp.AddUtf8(args[2]); err != nil {
+		return err
+	}
+	if v.descIndex, err = m.a.cp.AddUtf8(args[3]); err != nil {
+		return err
+	}
+	rest := args[4:]
+	if len(rest) == 6 {
+		if rest[0] != "signature" {
+			return fmt.Errorf(usage)
+		}
+		signature, err := unquote(rest[1])
+		if err != nil {
+			return err
+		}
+		if v.sigIndex, err = m.a.cp.AddUtf8(signature); err != nil {
+			return err
+		}
+		rest = rest[2:]
+	}
+	if rest[0] != "from" || rest[2] != "to" {
+		return fmt.Errorf(usage)
+	}
+	v.from, v.to = m.ref(rest[1]), m.ref(rest[3])
+	m.variables = append(m.variables, v)
+	return nil
+}
+
+// frame handles .frame, one of:
+//
+//	.frame same
+//	.frame same_locals_1_stack_item <type>
+//	.frame chop <n>
+//	.frame append <type>...
+//	.frame full locals <type>... stack <type>...
+//
+// where a type is top, int, float, long, double, null, uninitialized_this,
+// class <name> or uninitialized <label of the new instruction>.
+func (m *method) frame(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: .frame <kind> ...")
+	}
+	f := frame{pc: len(m.code), kind: args[0], line: m.a.line}
+	var err error
+	var rest []string
+	switch f.kind {
+	case "same":
+		rest = args[1:]
+	case "same_locals_1_stack_item":
+		if f.stack, rest, err = m.verificationTypes(args[1:], ""); err == nil && len(f.stack) != 1 {
+			err = fmt.Errorf("same_locals_1_stack_item takes one stack type")
+		}
+	case "chop":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: .frame chop <n>")
+		}
+		var n int64
+		n, err = parseInt(args[1], 1, 3)
+		f.chop = int(n)
+	case "append":
+		if f.locals, rest, err = m.verificationTypes(args[1:], ""); err == nil && (len(f.locals) < 1 || len(f.locals) > 3) {
+			err = fmt.Errorf("append takes one to three local types")
+		}
+	case "full":
+		if len(args) < 2 || args[1] != "locals" {
+			return fmt.Errorf("usage: .frame full locals <type>... stack <type>...")
+		}
+		if f.locals, rest, err = m.verificationTypes(args[2:], "stack"); err != nil {
+			return err
+		}
+		if len(rest) == 0 {
+			return fmt.Errorf("usage: .frame full locals <type>... stack <type>...")
+		}
+		f.stack, rest, err = m.verificationTypes(rest[1:], "")
+	default:
+		return fmt.Errorf("unknown frame kind %q", f.kind)
+	}
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("unexpected %q", rest[0])
+	}
+	m.frames = append(m.frames, f)
+	return nil
+}
+
+var verificationTags = map[string]uint8{
+	"top":                class.ItemTop,
+	"int":                class.ItemInteger,
+	"float":              class.ItemFloat,
+	"double":             class.ItemDouble,
+	"long":               class.ItemLong,
+	"null":               class.ItemNull,
+	"uninitialized_this": class.ItemUninitializedThis,
+}
+
+// verificationTypes reads types up to the end of tokens or the word stop.
+func (m *method) verificationTypes(tokens []string, stop string) ([]verificationType, []string, error) {
+	var types []verificationType
+	for len(tokens) > 0 && tokens[0] != stop {
+		switch word := tokens[0]; word {
+		case "class", "uninitialized":
+			if len(tokens) < 2 {
+				return nil, nil, fmt.Errorf("expected a name after %s", word)
+			}
+			t := verificationType{}
+			if word == "class" {
+				index, err := m.a.cp.AddClass(tokens[1])
+				if err != nil {
+					return nil, nil, err
+				}
+				t.info = class.VerificationTypeInfo{Tag: class.ItemObject, CpoolIndex: index}
+			} else {
+				t.info.Tag = class.ItemUninitialized
+				t.new = m.ref(tokens[1])
+			}
+			types = append(types, t)
+			tokens = tokens[2:]
+		default:
+			tag, ok := verificationTags[word]
+			if !ok {
+				return nil, nil, fmt.Errorf("unknown verification type %q", word)
+			}
+			types = append(types, verificationType{info: class.VerificationTypeInfo{Tag: tag}})
+			tokens = tokens[1:]
+		}
+	}
+	return types, tokens, nil
+}
+
+func (m *method) u1(v uint8) {
+	m.code = append(m.code, v)
+}
+
+func (m *method) u2(v uint16) {
+	m.code = binary.BigEndian.AppendUint16(m.code, v)
+}
+
+func (m *method) u4(v uint32) {
+	m.code = binary.BigEndian.AppendUint32(m.code, v)
+}
+
+// branch writes a branch offset of size bytes to be filled in with the
+// offset of label from the instruction at pc.
+func (m *method) branch(pc, size int, label string) {
+	m.fixups = append(m.fixups, fixup{pc: pc, at: len(m.code), size: size, target: m.ref(label)})
+	m.code = append(m.code, make([]byte, size)...)
+}
+
+func operands(op bytecode.Opcode, args []string, n int, usage string) error {
+	if len(args) != n {
+		return fmt.Errorf("usage: %s %s", op, usage)
+	}
+	return nil
+}
+
+func (m *method) instruction(tokens []string) error {
+	wide := tokens[0] == "wide"
+	if wide {
+		tokens = tokens[1:]
+		if len(tokens) == 0 {
+			return fmt.Errorf("expected an instruction after wide")
+		}
+	}
+	op, ok := bytecode.Lookup(tokens[0])
+	if !ok || !op.IsValid() || op == bytecode.Wide {
+		return fmt.Errorf("unknown instruction %q", tokens[0])
+	}
+	args := tokens[1:]
+	cp := m.a.cp
+	pc := len(m.code)
+	m.hasCode = true
+	m.sourceLines[pc] = m.a.line
+
+	if wide {
+		switch op {
+		case bytecode.Iload, bytecode.Lload, bytecode.Fload, bytecode.Dload, bytecode.Aload,
+			bytecode.Istore, bytecode.Lstore, bytecode.Fstore, bytecode.Dstore, bytecode.Astore,
+			bytecode.Ret, bytecode.Iinc:
+		default:
+			return fmt.Errorf("%s cannot be modified by wide", op)
+		}
+	}
+
+	switch op {
+	case bytecode.Bipush, bytecode.Sipush:
+		if err := operands(op, args, 1, "<value>"); err != nil {
+			return err
+		}
+		if op == bytecode.Bipush {
+			v, err := parseInt(args[0], math.MinInt8, math.MaxInt8)
+			if err != nil {
+				return err
+			}
+			m.u1(uint8(op))
+			m.u1(uint8(v))
+		} else {
+			v, err := parseInt(args[0], math.MinInt16, math.MaxInt16)
+			if err != nil {
+				return err
+			}
+			m.u1(uint8(op))
+			m.u2(uint16(v))
+		}
+
+	case bytecode.Ldc, bytecode.LdcW, bytecode.Ldc2W:
+		intTag, floatTag := class.TagInteger, class.TagFloat
+		if op == bytecode.Ldc2W {
+			intTag, floatTag = class.TagLong, class.TagDouble
+		}
+		index, rest, err := m.a.constant(args, intTag, floatTag)
+		if err != nil {
+			return err
+		}
+		if len(rest) > 0 {
+			return fmt.Errorf("unexpected %q after the constant", rest[0])
+		}
+		tag := cp.Get(index).Tag
+		if isWide := tag == class.TagLong || tag == class.TagDouble; isWide != (op == bytecode.Ldc2W) {
+			return fmt.Errorf("%s cannot load a %s constant", op, class.TagName(tag))
+		}
+		if op == bytecode.Ldc && index > math.MaxUint8 {
+			op = bytecode.LdcW
+		}
+		m.u1(uint8(op))
+		if op == bytecode.Ldc {
+			m.u1(uint8(index))
+		} else {
+			m.u2(index)
+		}
+
+	case bytecode.Iload, bytecode.Lload, bytecode.Fload, bytecode.Dload, bytecode.Aload,
+		bytecode.Istore, bytecode.Lstore, bytecode.Fstore, bytecode.Dstore, bytecode.Astore, bytecode.Ret:
+		if err := operands(op, args, 1, "<local>"); err != nil {
+			return err
+		}
+		local, err := parseUint16(args[0])
+		if err != nil {
+			return err
+		}
+		if wide || local > math.MaxUint8 {
+			m.u1(uint8(bytecode.Wide))
+			m.u1(uint8(op))
+			m.u2(local)
+		} else {
+			m.u1(uint8(op))
+			m.u1(uint8(local))
+		}
+
+	case bytecode.Iinc:
+		if err := operands(op, args, 2, "<local> <increment>"); err != nil {
+			return err
+		}
+		local, err := parseUint16(args[0])
+		if err != nil {
+			return err
+		}
+		increment, err := parseInt(args[1], math.MinInt16, math.MaxInt16)
+		if err != nil {
+			return err
+		}
+		if wide || local > math.MaxUint8 || increment < math.MinInt8 || increment > math.MaxInt8 {
+			m.u1(uint8(bytecode.Wide))
+			m.u1(uint8(op))
+			m.u2(local)
+			m.u2(uint16(increment))
+		} else {
+			m.u1(uint8(op))
+			m.u1(uint8(local))
+			m.u1(uint8(increment))
+		}
+
+	case bytecode.Getstatic, bytecode.Putstatic, bytecode.Getfield, bytecode.Putfield,
+		bytecode.Invokevirtual, bytecode.Invokespecial, bytecode.Invokestatic:
+		isField := op <= bytecode.Putfield
+		index, rest, err := m.a.memberRef(args, isField)
+		if err != nil {
+			return err
+		}
+		if len(rest) > 0 {
+			return fmt.Errorf("unexpected %q after the reference", rest[0])
+		}
+		m.u1(uint8(op))
+		m.u2(index)
+
+	case bytecode.Invokeinterface:
+		if len(args) > 0 && args[0] == "interface" {
+			args = args[1:]
+		}
+		index, rest, err := m.a.memberRef(append([]string{"interface"}, args...), false)
+		if err != nil {
+			return err
+		}
+		_, _, desc, _ := cp.InterfaceMethodRef(index)
+		md, _ := descriptor.ParseMethod(desc)
+		count := int64(md.ArgSlots() + 1)
+		if len(rest) == 1 {
+			if count, err = parseInt(rest[0], 1, math.MaxUint8); err != nil {
+				return err
+			}
+		} else if len(rest) > 1 {
+			return fmt.Errorf("usage: invokeinterface <owner/name(descriptor)> [<count>]")
+		}
+		m.u1(uint8(op))
+		m.u2(index)
+		m.u1(uint8(count))
+		m.u1(0)
+
+	case bytecode.Invokedynamic:
+		if err := operands(op, args, 2, "<bootstrap method> <name(descriptor)>"); err != nil {
+			return err
+		}
+		bootstrap, err := parseUint16(args[0])
+		if err != nil {
+			return err
+		}
+		name, desc, err := nameAndDescriptor(args[1])
+		if err != nil {
+			return err
+		}
+		index, err := cp.AddInvokeDynamic(bootstrap, name, desc)
+		if err != nil {
+			return err
+		}
+		m.u1(uint8(op))
+		m.u2(index)
+		m.u2(0)
+
+	case bytecode.New, bytecode.Anewarray, bytecode.Checkcast, bytecode.Instanceof:
+		if err := operands(op, args, 1, "<class>"); err != nil {
+			return err
+		}
+		index, err := cp.AddClass(args[0])
+		if err != nil {
+			return err
+		}
+		m.u1(uint8(op))
+		m.u2(index)
+
+	case bytecode.Multianewarray:
+		if err := operands(op, args, 2, "<array class> <dimensions>"); err != nil {
+			return err
+		}
+		index, err := cp.AddClass(args[0])
+		if err != nil {
+			return err
+		}
+		dimensions, err := parseInt(args[1], 1, math.MaxUint8)
+		if err != nil {
+			return err
+		}
+		m.u1(uint8(op))
+		m.u2(index)
+		m.u1(uint8(dimensions))
+
+	case bytecode.Newarray:
+		if err := operands(op, args, 1, "<element type>"); err != nil {
+			return err
+		}
+		atype := int32(0)
+		for t := int32(bytecode.TBoolean); t <= bytecode.TLong; t++ {
+			if bytecode.ArrayTypeName(t) == args[0] {
+				atype = t
+			}
+		}
+		if atype == 0 {
+			return fmt.Errorf("unknown array element type %q", args[0])
+		}
+		m.u1(uint8(op))
+		m.u1(uint8(atype))
+
+	case bytecode.Tableswitch, bytecode.Lookupswitch:
+		table := &switchTable{pc: pc, opcode: op}
+		if op == bytecode.Tableswitch {
+			if len(args) < 1 || len(args) > 2 {
+				return fmt.Errorf("usage: tableswitch <low> [<high>]")
+			}
+			low, err := parseInt(args[0], math.MinInt32, math.MaxInt32)
+			if err != nil {
+				return err
+			}
+			table.low = int32(low)
+			if len(args) == 2 {
+				high, err := parseInt(args[1], low, math.MaxInt32)
+				if err != nil {
+					return err
+				}
+				table.high = new(int32)
+				*table.high = int32(high)
+			}
+		} else if err := operands(op, args, 0, ""); err != nil {
+			return err
+		}
+		m.u1(uint8(op))
+		for len(m.code)%4 != 0 {
+			m.u1(0)
+		}
+		m.open = table
+
+	default:
+		if op.IsBranch() {
+			if err := operands(op, args, 1, "<label>"); err != nil {
+				return err
+			}
+			size := 2
+			if op == bytecode.GotoW || op == bytecode.JsrW {
+				size = 4
+			}
+			m.u1(uint8(op))
+			m.branch(pc, size, args[0])
+			break
+		}
+		if err := operands(op, args, 0, ""); err != nil {
+			return err
+		}
+		m.u1(uint8(op))
+	}
+	return nil
+}
+
+// switchCase reads a line of the cases of a switch: a target label of a
+// tableswitch, a "<key>: <label>" pair of a lookupswitch, or the
+// "default: <label>" that ends both.
+func (m *method) switchCase(tokens []string) error {
+	table := m.open
+	if tokens[0] == "default:" {
+		if len(tokens) != 2 {
+			return fmt.Errorf("usage: default: <label>")
+		}
+		m.open = nil
+		return m.endSwitch(table, tokens[1])
+	}
+
+	if table.opcode == bytecode.Tableswitch {
+		if len(tokens) != 1 || isLabel(tokens[0]) {
+			return fmt.Errorf("expected a target label or default: <label> in tableswitch")
+		}
+		table.targets = append(table.targets, m.ref(tokens[0]))
+		return nil
+	}
+
+	if len(tokens) != 2 || !isLabel(tokens[0]) {
+		return fmt.Errorf("expected <key>: <label> or default: <label> in lookupswitch")
+	}
+	key, err := parseInt(strings.TrimSuffix(tokens[0], ":"), math.MinInt32, math.MaxInt32)
+	if err != nil {
+		return err
+	}
+	for _, k := range table.keys {
+		if k == int32(key) {
+			return fmt.Errorf("duplicate key %d in lookupswitch", key)
+		}
+	}
+	table.keys = append(table.keys, int32(key))
+	table.targets = append(table.targets, m.ref(tokens[1]))
+	return nil
+}
+
+func (m *method) endSwitch(table *switchTable, defaultLabel string) error {
+	m.branch(table.pc, 4, defaultLabel)
+	if table.opcode == bytecode.Tableswitch {
+		if len(table.targets) == 0 {
+			return fmt.Errorf("tableswitch has no targets")
+		}
+		high := int64(table.low) + int64(len(table.targets)) - 1
+		if table.high != nil && int64(*table.high) != high {
+			return fmt.Errorf("tableswitch from %d to %d needs %d targets, found %d", table.low, *table.high, int64(*table.high)-int64(table.low)+1, len(table.targets))
+		}
+		if high > math.MaxInt32 {
+			return fmt.Errorf("tableswitch has too many targets")
+		}
+		m.u4(uint32(table.low))
+		m.u4(uint32(int32(high)))
+		for _, target := range table.targets {
+			m.fixups = append(m.fixups, fixup{pc: table.pc, at: len(m.code), size: 4, target: target})
+			m.u4(0)
+		}
+		return nil
+	}
+
+	// Lookupswitch keys must be sorted
+	order := make([]int, len(table.keys))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return table.keys[order[i]] < table.keys[order[j]] })
+	m.u4(uint32(len(order)))
+	for _, i := range order {
+		m.u4(uint32(table.keys[i]))
+		m.fixups = append(m.fixups, fixup{pc: table.pc, at: len(m.code), size: 4, target: table.targets[i]})
+		m.u4(0)
+	}
+	return nil
+}
+
+// label returns the pc of a label.
+func (m *method) label(ref labelRef) (int, error) {
+	pc, ok := m.labels[ref.name]
+	if !ok {
+		return 0, &lineError{ref.line, fmt.Errorf("undefined label %s", ref.name)}
+	}
+	return pc, nil
+}
+
+// finish resolves the labels of the method and builds its attributes.
+func (m *method) finish() (class.Method, error) {
+	a := m.a
+	result := class.Method{AccessFlags: m.flags, NameIndex: m.nameIndex, DescriptorIndex: m.descIndex}
+	if !m.hasCode {
+		if len(m.catches) > 0 || len(m.lines) > 0 || len(m.variables) > 0 || len(m.frames) > 0 || len(m.codeAttrs) > 0 {
+			return result, fmt.Errorf("method has code directives but no instructions")
+		}
+	} else {
+		code, err := m.buildCode()
+		if err != nil {
+			return result, err
+		}
+		attr, err := a.newAttribute("Code", code)
+		if err != nil {
+			return result, err
+		}
+		result.Attributes = append(result.Attributes, attr)
+	}
+
+	if len(m.exceptions) > 0 {
+		attr, err := a.newAttribute("Exceptions", &class.ExceptionsAttribute{ExceptionIndexTable: m.exceptions})
+		if err != nil {
+			return result, err
+		}
+		result.Attributes = append(result.Attributes, attr)
+	}
+	result.Attributes = append(result.Attributes, m.attributes...)
+	return result, nil
+}
+
+func (m *method) buildCode() (*class.Code, error) {
+	if len(m.code) > math.MaxUint16 {
+		return nil, fmt.Errorf("code is %d bytes, longer than the maximum of %d", len(m.code), math.MaxUint16)
+	}
+	for _, f := range m.fixups {
+		target, err := m.label(f.target)
+		if err != nil {
+			return nil, err
+		}
+		offset := target - f.pc
+		if f.size == 2 {
+			if offset < math.MinInt16 || offset > math.MaxInt16 {
+				return nil, &lineError{f.target.line, fmt.Errorf("%s is too far away for a 16-bit branch offset", f.target.name)}
+			}
+			binary.BigEndian.PutUint16(m.code[f.at:], uint16(offset))
+		} else {
+			binary.BigEndian.PutUint32(m.code[f.at:], uint32(offset))
+		}
+	}
+
+	code := &class.Code{Bytecode: m.code, CodeLength: uint32(len(m.code))}
+	for _, c := range m.catches {
+		var pcs [3]int
+		for i, ref := range []labelRef{c.start, c.end, c.handler} {
+			pc, err := m.label(ref)
+			if err != nil {
+				return nil, err
+			}
+			pcs[i] = pc
+		}
+		code.ExceptionTable = append(code.ExceptionTable, class.ExceptionTableEntry{
+			StartPc: uint16(pcs[0]), EndPc: uint16(pcs[1]), HandlerPc: uint16(pcs[2]), CatchType: c.catchType,
+		})
+	}
+
+	if err := m.requireFrames(); err != nil {
+		return nil, err
+	}
+	if err := m.debugAttributes(code); err != nil {
+		return nil, err
+	}
+	if len(m.frames) > 0 {
+		table, err := m.stackMapTable()
+		if err != nil {
+			return nil, err
+		}
+		attr, err := m.a.newAttribute("StackMapTable", table)
+		if err != nil {
+			return nil, err
+		}
+		code.Attributes = append(code.Attributes, attr)
+	}
+	code.Attributes = append(code.Attributes, m.codeAttrs...)
+
+	if !m.hasMaxStack || !m.hasMaxLocals {
+		index, err := bytecode.NewIndex(m.code)
+		if err != nil {
+			return nil, err
+		}
+		if !m.hasMaxStack {
+			if m.maxStack, err = maxStack(index, code.ExceptionTable, m.a.cp); err != nil {
+				err = fmt.Errorf("%w; give .limit stack to assemble it anyway", err)
+				var instructionErr *instructionError
+				if errors.As(err, &instructionErr) {
+					return nil, &lineError{m.sourceLines[instructionErr.pc], err}
+				}
+				return nil, err
+			}
+		}
+		if !m.hasMaxLocals {
+			m.maxLocals = m.maxLocalsUsed(index)
+		}
+	}
+	code.MaxStack, code.MaxLocals = m.maxStack, m.maxLocals
+	return code, nil
+}
+
+// requireFrames fails if the class file version needs stack map frames and
+// a branch target or exception handler has none. Frames are not computed,
+// since merging the types of two paths needs the class hierarchy, so they
+// must be given with .frame.
+func (m *method) requireFrames() error {
+	major := m.a.class.MajorVersion
+	if major < framesMajorVersion {
+		return nil
+	}
+	framed := make(map[int]bool, len(m.frames))
+	for _, f := range m.frames {
+		framed[f.pc] = true
+	}
+	targets := make([]labelRef, 0, len(m.fixups)+len(m.catches))
+	for _, f := range m.fixups {
+		targets = append(targets, f.target)
+	}
+	for _, c := range m.catches {
+		targets = append(targets, c.handler)
+	}
+	for _, target := range targets {
+		pc, err := m.label(target)
+		if err != nil {
+			return err
+		}
+		if !framed[pc] {
+			return &lineError{target.line, fmt.Errorf("%s at pc %d needs a stack map frame in class file version %d; add a .frame before it, or use .version %d or earlier",
+				target.name, pc, major, framesMajorVersion-1)}
+		}
+	}
+	return nil
+}
+
+// debugAttributes adds the LineNumberTable, LocalVariableTable and
+// LocalVariableTypeTable attributes.
+func (m *method) debugAttributes(code *class.Code) error {
+	if len(m.lines) > 0 {
+		attr, err := m.a.newAttribute("LineNumberTable", &class.LineNumberTableAttribute{LineNumberTable: m.lines})
+		if err != nil {
+			return err
+		}
+		code.Attributes = append(code.Attributes, attr)
+	}
+	if len(m.variables) == 0 {
+		return nil
+	}
+
+	variables := &class.LocalVariableTableAttribute{}
+	types := &class.LocalVariableTypeTableAttribute{}
+	for _, v := range m.variables {
+		from, err := m.label(v.from)
+		if err != nil {
+			return err
+		}
+		to, err := m.label(v.to)
+		if err != nil {
+			return err
+		}
+		if to < from {
+			return &lineError{v.to.line, errors.New("variable range ends before it starts")}
+		}
+		variables.LocalVariableTable = append(variables.LocalVariableTable, class.LocalVariableTableEntry{
+			StartPc: uint16(from), Length: uint16(to - from), NameIndex: v.nameIndex, DescriptorIndex: v.descIndex, Index: v.slot,
+		})
+		if v.sigIndex != 0 {
+			types.LocalVariableTypeTable = append(types.LocalVariableTypeTable, class.LocalVariableTypeTableEntry{
+				StartPc: uint16(from), Length: uint16(to - from), NameIndex: v.nameIndex, SignatureIndex: v.sigIndex, Index: v.slot,
+			})
+		}
+	}
+	attr, err := m.a.newAttribute("LocalVariableTable", variables)
+	if err != nil {
+		return err
+	}
+	code.Attributes = append(code.Attributes, attr)
+	if len(types.LocalVariableTypeTable) > 0 {
+		attr, err := m.a.newAttribute("LocalVariableTypeTable", types)
+		if err != nil {
+			return err
+		}
+		code.Attributes = append(code.Attributes, attr)
+	}
+	return nil
+}
+
+// stackMapTable encodes the frames, choosing the compact form of each frame
+// that its offset delta allows.
+func (m *method) stackMapTable() (*class.StackMapTableAttribute, error) {
+	table := &class.StackMapTableAttribute{}
+	previous := -1
+	for _, f := range m.frames {
+		if f.pc <= previous {
+			return nil, &lineError{f.line, fmt.Errorf("frame at pc %d does not follow the previous frame", f.pc)}
+		}
+		delta := f.pc - previous - 1
+		previous = f.pc
+		frame := class.StackMapFrame{OffsetDelta: uint16(delta), Offset: f.pc}
+		var err error
+		if frame.Locals, err = m.resolveTypes(f.locals); err != nil {
+			return nil, err
+		}
+		if frame.Stack, err = m.resolveTypes(f.stack); err != nil {
+			return nil, err
+		}
+
+		switch f.kind {
+		case "same":
+			frame.Kind, frame.FrameType = class.FrameSame, uint8(delta)
+			if delta > 63 {
+				frame.Kind, frame.FrameType = class.FrameSameExtended, 251
+			}
+		case "same_locals_1_stack_item":
+			frame.Kind, frame.FrameType = class.FrameSameLocals1StackItem, uint8(64+delta)
+			if delta > 63 {
+				frame.Kind, frame.FrameType = class.FrameSameLocals1StackItemExtended, 247
+			}
+		case "chop":
+			frame.Kind, frame.FrameType = class.FrameChop, uint8(251-f.chop)
+		case "append":
+			frame.Kind, frame.FrameType = class.FrameAppend, uint8(251+len(f.locals))
+		default:
+			frame.Kind, frame.FrameType = class.FrameFull, 255
+		}
+		table.Entries = append(table.Entries, frame)
+	}
+	return table, nil
+}
+
+func (m *method) resolveTypes(types []verificationType) ([]class.VerificationTypeInfo, error) {
+	var infos []class.VerificationTypeInfo
+	for _, t := range types {
+		info := t.info
+		if info.Tag == class.ItemUninitialized {
+			pc, err := m.label(t.new)
+			if err != nil {
+				return nil, err
+			}
+			info.Offset = uint16(pc)
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
@@ -0,0 +1,397 @@
+package assembler
+
+import (
+	"fmt"
+	"lava-vm/pkg/class"
+	"lava-vm/pkg/descriptor"
+	"strconv"
+	"strings"
+)
+
+// flagKeywords maps the flag words of the format to access flag bits. The
+// words are the JVMS names without the ACC_ prefix, in lower case, such as
+// "public" or "varargs".
+type flagKeywords map[string]uint16
+
+// newFlagKeywords builds the flag words of one context from names, which
+// returns the JVMS names of a single flag bit.
+func newFlagKeywords(names func(bit uint16) []string) flagKeywords {
+	keywords := make(flagKeywords)
+	for bit := uint16(1); bit != 0; bit <<= 1 {
+		for _, name := range names(bit) {
+			keywords[flagWord(name)] = bit
+		}
+	}
+	return keywords
+}
+
+func flagWord(name string) string {
+	return strings.ToLower(strings.TrimPrefix(name, "ACC_"))
+}
+
+// flagWords returns the flag words for JVMS flag names, see flagKeywords.
+func flagWords(names []string) []string {
+	words := make([]string, len(names))
+	for i, name := range names {
+		words[i] = flagWord(name)
+	}
+	return words
+}
+
+var (
+	classFlags      = newFlagKeywords(func(bit uint16) []string { return class.ClassAccessFlags(bit).Names() })
+	fieldFlags      = newFlagKeywords(func(bit uint16) []string { return class.FieldAccessFlags(bit).Names() })
+	methodFlags     = newFlagKeywords(func(bit uint16) []string { return class.MethodAccessFlags(bit).Names() })
+	innerClassFlags = newFlagKeywords(func(bit uint16) []string { return class.InnerClassAccessFlags(bit).Names() })
+)
+
+// parse returns the flags named by words.
+func (k flagKeywords) parse(words []string) (uint16, error) {
+	var flags uint16
+	for _, word := range words {
+		bit, ok := k[word]
+		if !ok {
+			return 0, fmt.Errorf("unknown access flag %q", word)
+		}
+		flags |= bit
+	}
+	return flags, nil
+}
+
+// splitFlags splits tokens into the leading flag words and the rest.
+func (k flagKeywords) splitFlags(tokens []string) ([]string, []string) {
+	i := 0
+	for i < len(tokens) {
+		if _, ok := k[tokens[i]]; !ok {
+			break
+		}
+		i++
+	}
+	return tokens[:i], tokens[i:]
+}
+
+// methodRef splits a method reference such as "java/lang/Object/<init>()V"
+// into the owner, name and descriptor.
+func methodRef(token string) (owner, name, desc string, err error) {
+	paren := strings.IndexByte(token, '(')
+	if paren < 0 {
+		return "", "", "", fmt.Errorf("method reference %q has no descriptor", token)
+	}
+	desc = token[paren:]
+	if _, err := descriptor.ParseMethod(desc); err != nil {
+		return "", "", "", err
+	}
+	owner, name, err = memberName(token[:paren])
+	return owner, name, desc, err
+}
+
+// memberName splits "owner/name" at the last slash.
+func memberName(token string) (owner, name string, err error) {
+	slash := strings.LastIndexByte(token, '/')
+	if slash <= 0 || slash == len(token)-1 {
+		return "", "", fmt.Errorf("%q is not of the form owner/name", token)
+	}
+	return token[:slash], token[slash+1:], nil
+}
+
+// nameAndDescriptor splits "name(desc)" into the name and the method descriptor.
+func nameAndDescriptor(token string) (name, desc string, err error) {
+	paren := strings.IndexByte(token, '(')
+	if paren <= 0 {
+		return "", "", fmt.Errorf("%q is not of the form name(descriptor)", token)
+	}
+	if _, err := descriptor.ParseMethod(token[paren:]); err != nil {
+		return "", "", err
+	}
+	return token[:paren], token[paren:], nil
+}
+
+func fieldDescriptor(token string) (string, error) {
+	if _, err := descriptor.ParseField(token); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// referenceKinds are the words for method handle kinds, which are named after
+// the instruction the handle behaves like.
+var referenceKinds = map[string]class.ReferenceKind{
+	"getfield":         class.RefGetField,
+	"getstatic":        class.RefGetStatic,
+	"putfield":         class.RefPutField,
+	"putstatic":        class.RefPutStatic,
+	"invokevirtual":    class.RefInvokeVirtual,
+	"invokestatic":     class.RefInvokeStatic,
+	"invokespecial":    class.RefInvokeSpecial,
+	"newinvokespecial": class.RefNewInvokeSpecial,
+	"invokeinterface":  class.RefInvokeInterface,
+}
+
+// memberRef adds the field or method reference at the start of tokens and
+// returns its index and the remaining tokens. Fields are written as
+// "owner/name descriptor" and methods as "owner/name(descriptor)"; a method
+// preceded by "interface" is added as an InterfaceMethodref.
+func (a *assembler) memberRef(tokens []string, field bool) (uint16, []string, error) {
+	if field {
+		if len(tokens) < 2 {
+			return 0, nil, fmt.Errorf("expected a field reference: owner/name descriptor")
+		}
+		owner, name, err := memberName(tokens[0])
+		if err != nil {
+			return 0, nil, err
+		}
+		desc, err := fieldDescriptor(tokens[1])
+		if err != nil {
+			return 0, nil, err
+		}
+		index, err := a.cp.AddFieldRef(owner, name, desc)
+		return index, tokens[2:], err
+	}
+
+	isInterface := len(tokens) > 0 && tokens[0] == "interface"
+	if isInterface {
+		tokens = tokens[1:]
+	}
+	if len(tokens) == 0 {
+		return 0, nil, fmt.Errorf("expected a method reference: owner/name(descriptor)")
+	}
+	owner, name, desc, err := methodRef(tokens[0])
+	if err != nil {
+		return 0, nil, err
+	}
+	var index uint16
+	if isInterface {
+		index, err = a.cp.AddInterfaceMethodRef(owner, name, desc)
+	} else {
+		index, err = a.cp.AddMethodRef(owner, name, desc)
+	}
+	return index, tokens[1:], err
+}
+
+// constant adds the loadable constant at the start of tokens and returns its
+// index and the remaining tokens. The forms are:
+//
+//	123, -1, 0x7f           int, or the given intTag for unsuffixed integers
+//	123L                    long
+//	1.5f, NaNf              float
+//	1.5d, -Infd             double, or the given floatTag for unsuffixed numbers
+//	"text"                  String
+//	class java/lang/String  Class
+//	methodtype (I)V         MethodType
+//	methodhandle invokestatic java/lang/Math/abs(I)I
+//	methodhandle getfield Point/x I
+//	dynamic 0 name I        Dynamic, with its bootstrap method index
+func (a *assembler) constant(tokens []string, intTag, floatTag uint8) (uint16, []string, error) {
+	if len(tokens) == 0 {
+		return 0, nil, fmt.Errorf("expected a constant")
+	}
+	token, rest := tokens[0], tokens[1:]
+
+	switch token {
+	case "class":
+		if len(rest) == 0 {
+			return 0, nil, fmt.Errorf("expected a class name after class")
+		}
+		index, err := a.cp.AddClass(rest[0])
+		return index, rest[1:], err
+	case "methodtype":
+		if len(rest) == 0 {
+			return 0, nil, fmt.Errorf("expected a method descriptor after methodtype")
+		}
+		if _, err := descriptor.ParseMethod(rest[0]); err != nil {
+			return 0, nil, err
+		}
+		index, err := a.cp.AddMethodType(rest[0])
+		return index, rest[1:], err
+	case "methodhandle":
+		return a.methodHandle(rest)
+	case "dynamic":
+		if len(rest) < 3 {
+			return 0, nil, fmt.Errorf("expected a bootstrap method index, name and descriptor after dynamic")
+		}
+		bootstrap, err := parseUint16(rest[0])
+		if err != nil {
+			return 0, nil, err
+		}
+		desc, err := fieldDescriptor(rest[2])
+		if err != nil {
+			return 0, nil, err
+		}
+		index, err := a.cp.AddDynamic(bootstrap, rest[1], desc)
+		return index, rest[3:], err
+	}
+
+	if isString(token) {
+		s, err := unquote(token)
+		if err != nil {
+			return 0, nil, err
+		}
+		index, err := a.cp.AddString(s)
+		return index, rest, err
+	}
+
+	tag, value, err := parseNumber(token, intTag, floatTag)
+	if err != nil {
+		return 0, nil, err
+	}
+	var index uint16
+	switch tag {
+	case class.TagInteger:
+		index, err = a.cp.AddInteger(int32(value.(int64)))
+	case class.TagLong:
+		index, err = a.cp.AddLong(value.(int64))
+	case class.TagFloat:
+		index, err = a.cp.AddFloat(float32(value.(float64)))
+	default:
+		index, err = a.cp.AddDouble(value.(float64))
+	}
+	return index, rest, err
+}
+
+// poolConstant adds the constant pool entry at the start of tokens and returns
+// its index and the remaining tokens. Besides the forms of constant, which
+// are read with the int and double tags, the forms are:
+//
+//	utf8 "text"                          Utf8
+//	field java/lang/System/out Ljava/io/PrintStream;
+//	method java/lang/Object/<init>()V
+//	interface java/lang/Runnable/run()V  InterfaceMethodref
+//	nameandtype name descriptor          NameAndType
+//	invokedynamic 0 name descriptor      InvokeDynamic, with its bootstrap method index
+//	module java.base                     Module
+//	package java/lang                    Package
+func (a *assembler) poolConstant(tokens []string) (uint16, []string, error) {
+	if len(tokens) == 0 {
+		return 0, nil, fmt.Errorf("expected a constant")
+	}
+	token, rest := tokens[0], tokens[1:]
+	switch token {
+	case "utf8", "module", "package":
+		if len(rest) == 0 {
+			return 0, nil, fmt.Errorf("expected a name after %s", token)
+		}
+		var index uint16
+		var err error
+		switch token {
+		case "utf8":
+			var s string
+			if s, err = unquote(rest[0]); err == nil {
+				index, err = a.cp.AddUtf8(s)
+			}
+		case "module":
+			index, err = a.cp.AddModule(rest[0])
+		default:
+			index, err = a.cp.AddPackage(rest[0])
+		}
+		return index, rest[1:], err
+	case "field":
+		return a.memberRef(rest, true)
+	case "method":
+		return a.memberRef(rest, false)
+	case "interface":
+		return a.memberRef(tokens, false)
+	case "nameandtype":
+		if len(rest) < 2 {
+			return 0, nil, fmt.Errorf("expected a name and descriptor after nameandtype")
+		}
+		if _, err := descriptor.ParseMethod(rest[1]); err != nil {
+			if _, err := fieldDescriptor(rest[1]); err != nil {
+				return 0, nil, err
+			}
+		}
+		index, err := a.cp.AddNameAndType(rest[0], rest[1])
+		return index, rest[2:], err
+	case "invokedynamic":
+		if len(rest) < 3 {
+			return 0, nil, fmt.Errorf("expected a bootstrap method index, name and descriptor after invokedynamic")
+		}
+		bootstrap, err := parseUint16(rest[0])
+		if err != nil {
+			return 0, nil, err
+		}
+		if _, err := descriptor.ParseMethod(rest[2]); err != nil {
+			return 0, nil, err
+		}
+		index, err := a.cp.AddInvokeDynamic(bootstrap, rest[1], rest[2])
+		return index, rest[3:], err
+	}
+	return a.constant(tokens, class.TagInteger, class.TagDouble)
+}
+
+func (a *assembler) methodHandle(tokens []string) (uint16, []string, error) {
+	if len(tokens) == 0 {
+		return 0, nil, fmt.Errorf("expected a reference kind after methodhandle")
+	}
+	kind, ok := referenceKinds[tokens[0]]
+	if !ok {
+		return 0, nil, fmt.Errorf("unknown method handle kind %q", tokens[0])
+	}
+	field := kind <= class.RefPutStatic
+	ref, rest, err := a.memberRef(tokens[1:], field)
+	if err != nil {
+		return 0, nil, err
+	}
+	index, err := a.cp.AddMethodHandle(kind, ref)
+	return index, rest, err
+}
+
+// parseNumber parses a numeric constant, see constant. The value is an int64
+// for Integer and Long and a float64 for Float and Double.
+func parseNumber(token string, intTag, floatTag uint8) (uint8, interface{}, error) {
+	digits := strings.TrimLeft(token, "+-")
+	isHex := strings.HasPrefix(digits, "0x") || strings.HasPrefix(digits, "0X")
+	if !isHex && len(token) > 1 {
+		switch token[len(token)-1] {
+		case 'L', 'l':
+			v, err := strconv.ParseInt(token[:len(token)-1], 0, 64)
+			if err != nil {
+				return 0, nil, fmt.Errorf("invalid long %q", token)
+			}
+			return class.TagLong, v, nil
+		case 'F', 'f':
+			v, err := strconv.ParseFloat(token[:len(token)-1], 32)
+			if err != nil {
+				return 0, nil, fmt.Errorf("invalid float %q", token)
+			}
+			return class.TagFloat, v, nil
+		case 'D', 'd':
+			v, err := strconv.ParseFloat(token[:len(token)-1], 64)
+			if err != nil {
+				return 0, nil, fmt.Errorf("invalid double %q", token)
+			}
+			return class.TagDouble, v, nil
+		}
+	}
+
+	if v, err := strconv.ParseInt(token, 0, 64); err == nil {
+		if intTag == class.TagInteger && (v < -1<<31 || v > 1<<31-1) {
+			return 0, nil, fmt.Errorf("int %s is out of range", token)
+		}
+		return intTag, v, nil
+	}
+	bitSize := 64
+	if floatTag == class.TagFloat {
+		bitSize = 32
+	}
+	if v, err := strconv.ParseFloat(token, bitSize); err == nil && !isHex {
+		return floatTag, v, nil
+	}
+	return 0, nil, fmt.Errorf("invalid constant %q", token)
+}
+
+// parseInt parses an integer operand that must lie in [min, max].
+func parseInt(token string, min, max int64) (int64, error) {
+	v, err := strconv.ParseInt(token, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid integer %q", token)
+	}
+	if v < min || v > max {
+		return 0, fmt.Errorf("%d is out of range [%d, %d]", v, min, max)
+	}
+	return v, nil
+}
+
+func parseUint16(token string) (uint16, error) {
+	v, err := parseInt(token, 0, 1<<16-1)
+	return uint16(v), err
+}
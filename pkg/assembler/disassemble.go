@@ -0,0 +1,521 @@
+package assembler
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"lava-vm/pkg/bytecode"
+	"lava-vm/pkg/class"
+	"lava-vm/pkg/descriptor"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Disassemble writes c to w as assembly that Assemble accepts, so that a
+// class can be disassembled, edited and assembled again. Attributes without
+// a directive are written as .attribute or .codeattribute with their raw
+// bytes. The constant pool entries that the standard ones refer to are
+// declared with .constant, while unknown attributes keep the indexes of c.
+func Disassemble(w io.Writer, c *class.Class) error {
+	d := &disassembler{w: bufio.NewWriter(w), class: c, cp: &c.ConstantPool}
+	if err := d.classFile(); err != nil {
+		return err
+	}
+	return d.w.Flush()
+}
+
+type disassembler struct {
+	w     *bufio.Writer
+	class *class.Class
+	cp    *class.ConstantPool
+	err   error
+}
+
+func (d *disassembler) printf(format string, args ...interface{}) {
+	fmt.Fprintf(d.w, format, args...)
+	d.w.WriteByte('\n')
+}
+
+// check keeps the first error from resolving constants, so that the
+// formatting code can use their values directly.
+func (d *disassembler) check(s string, err error) string {
+	if err != nil && d.err == nil {
+		d.err = err
+	}
+	return s
+}
+
+func (d *disassembler) className(index uint16) string {
+	return d.check(d.cp.ClassRef(index))
+}
+
+func (d *disassembler) utf8(index uint16) string {
+	return d.check(d.cp.Utf8(index))
+}
+
+func flags(names []string) string {
+	words := flagWords(names)
+	if len(words) == 0 {
+		return ""
+	}
+	return strings.Join(words, " ") + " "
+}
+
+func (d *disassembler) classFile() error {
+	c := d.class
+	d.printf(".version %d %d", c.MajorVersion, c.MinorVersion)
+	d.printf(".class %s%s", flags(c.AccessFlags.Names()), d.className(c.ThisClass))
+	if c.SuperClass != 0 {
+		d.printf(".super %s", d.className(c.SuperClass))
+	}
+	for _, index := range c.Interfaces {
+		d.printf(".implements %s", d.className(index))
+	}
+	for i := range c.Attributes {
+		d.classAttribute(&c.Attributes[i])
+	}
+
+	for i := range c.Fields {
+		field := &c.Fields[i]
+		d.printf("")
+		line := fmt.Sprintf(".field %s%s %s", flags(field.AccessFlags.Names()), field.Name(), field.Descriptor())
+		for j := range field.Attributes {
+			attr := &field.Attributes[j]
+			if value, ok := d.decode(attr).(*class.ConstantValueAttribute); ok {
+				line += " = " + d.constant(value.ConstantValueIndex)
+			}
+		}
+		d.printf("%s", line)
+		for j := range field.Attributes {
+			if attr := &field.Attributes[j]; attr.Name != "ConstantValue" {
+				d.memberAttribute("    ", attr)
+			}
+		}
+	}
+
+	for i := range c.Methods {
+		d.printf("")
+		if err := d.method(&c.Methods[i]); err != nil {
+			return err
+		}
+	}
+	return d.err
+}
+
+// decode returns the typed value of an attribute, or nil if it has none or
+// cannot be decoded, in which case it is written from its raw bytes.
+func (d *disassembler) decode(attr *class.Attribute) interface{} {
+	value, err := attr.Decode()
+	if err != nil {
+		return nil
+	}
+	return value
+}
+
+// raw writes an attribute that has no directive from its bytes. The constant
+// pool indexes in a typed attribute are renumbered into a pool of its own,
+// whose entries are declared with .constant before it, so that they can be
+// renumbered again into the reassembled class. Other attributes keep their
+// indexes.
+func (d *disassembler) raw(indent, directive string, attr *class.Attribute) {
+	info := attr.Info
+	if d.decode(attr) != nil {
+		pool := class.NewConstantPool()
+		imported, err := pool.ImportAttribute(attr, d.cp)
+		if err != nil {
+			d.check("", err)
+			return
+		}
+		info = imported.Info
+		if pool.Len() == 2 {
+			// The name is the only entry, so there are no indexes to renumber
+			d.printf("%s%s %s %s", indent, directive, attr.Name, hex.EncodeToString(info))
+			return
+		}
+
+		cp := d.cp
+		d.cp = pool
+		for i := 1; i < pool.Len(); i++ {
+			if pool.Get(uint16(i)).Value != nil {
+				d.printf("%s.constant %d %s", indent, i, d.poolConstant(uint16(i)))
+			}
+		}
+		d.cp = cp
+	}
+	d.printf("%s%s %s %s", indent, directive, attr.Name, hex.EncodeToString(info))
+}
+
+// poolConstant returns any constant pool entry in the form parsed by poolConstant.
+func (d *disassembler) poolConstant(index uint16) string {
+	switch v := d.cp.Get(index).Value.(type) {
+	case *class.ConstantUtf8Value:
+		return "utf8 " + quote(v.String())
+	case *class.ConstantFieldRefValue:
+		return "field " + d.memberRef(index)
+	case *class.ConstantMethodRefValue:
+		return "method " + d.memberRef(index)
+	case *class.ConstantInterfaceMethodRefValue:
+		return d.memberRef(index)
+	case *class.ConstantNameAndTypeDescriptorValue:
+		return "nameandtype " + d.utf8(v.NameIndex) + " " + d.utf8(v.DescriptorIndex)
+	case *class.ConstantInvokeDynamicValue:
+		name, desc, err := d.cp.NameAndType(v.NameAndTypeIndex)
+		d.check("", err)
+		return fmt.Sprintf("invokedynamic %d %s %s", v.BootstrapMethodAttrIndex, name, desc)
+	case *class.ConstantModuleValue:
+		return "module " + d.utf8(v.NameIndex)
+	case *class.ConstantPackageValue:
+		return "package " + d.utf8(v.NameIndex)
+	}
+	return d.constant(index)
+}
+
+// memberAttribute writes the attributes every class, field and method can have.
+func (d *disassembler) memberAttribute(indent string, attr *class.Attribute) {
+	switch value := d.decode(attr).(type) {
+	case *class.SignatureAttribute:
+		d.printf("%s.signature %s", indent, quote(d.utf8(value.SignatureIndex)))
+	case *class.DeprecatedAttribute:
+		d.printf("%s.deprecated", indent)
+	case *class.RuntimeVisibleAnnotationsAttribute, *class.RuntimeInvisibleAnnotationsAttribute:
+		d.annotations(indent, attr, -1)
+	default:
+		d.raw(indent, ".attribute", attr)
+	}
+}
+
+func (d *disassembler) classAttribute(attr *class.Attribute) {
+	switch value := d.decode(attr).(type) {
+	case *class.SourceFileAttribute:
+		d.printf(".source %s", d.utf8(value.SourceFileIndex))
+	case *class.InnerClassesAttribute:
+		for _, inner := range value.Classes {
+			line := fmt.Sprintf(".innerclass %s%s", flags(inner.InnerClassAccessFlags.Names()), d.className(inner.InnerClassInfoIndex))
+			if inner.OuterClassInfoIndex != 0 {
+				line += " of " + d.className(inner.OuterClassInfoIndex)
+			}
+			if inner.InnerNameIndex != 0 {
+				line += " as " + d.utf8(inner.InnerNameIndex)
+			}
+			d.printf("%s", line)
+		}
+	case *class.EnclosingMethodAttribute:
+		if value.MethodIndex == 0 {
+			d.printf(".enclosing %s", d.className(value.ClassIndex))
+		} else {
+			name, desc, err := d.cp.NameAndType(value.MethodIndex)
+			d.check("", err)
+			d.printf(".enclosing %s %s%s", d.className(value.ClassIndex), name, desc)
+		}
+	case *class.NestHostAttribute:
+		d.printf(".nesthost %s", d.className(value.HostClassIndex))
+	case *class.NestMembersAttribute:
+		for _, index := range value.Classes {
+			d.printf(".nestmember %s", d.className(index))
+		}
+	case *class.PermittedSubclassesAttribute:
+		for _, index := range value.Classes {
+			d.printf(".permittedsubclass %s", d.className(index))
+		}
+	case *class.BootstrapMethodsAttribute:
+		for _, method := range value.BootstrapMethods {
+			args := []string{d.constant(method.BootstrapMethodRef)}
+			for _, arg := range method.BootstrapArguments {
+				args = append(args, d.constant(arg))
+			}
+			d.printf(".bootstrap %s", strings.Join(args, " "))
+		}
+	default:
+		d.memberAttribute("", attr)
+	}
+}
+
+// constant returns a loadable constant in the form parsed by constant.
+func (d *disassembler) constant(index uint16) string {
+	entry := d.cp.Get(index)
+	switch v := entry.Value.(type) {
+	case *class.ConstantIntegerValue:
+		return strconv.Itoa(int(v.Value))
+	case *class.ConstantFloatValue:
+		return strconv.FormatFloat(float64(v.Value), 'g', -1, 32) + "f"
+	case *class.ConstantLongValue:
+		return strconv.FormatInt(v.Value, 10) + "L"
+	case *class.ConstantDoubleValue:
+		return strconv.FormatFloat(v.Value, 'g', -1, 64) + "d"
+	case *class.ConstantStringRefValue:
+		return quote(d.utf8(v.Index))
+	case *class.ConstantClassRefValue:
+		return "class " + d.utf8(v.Index)
+	case *class.ConstantMethodTypeValue:
+		return "methodtype " + d.utf8(v.DescriptorIndex)
+	case *class.ConstantMethodHandleValue:
+		kind := ""
+		for word, k := range referenceKinds {
+			if k == v.ReferenceKind {
+				kind = word
+			}
+		}
+		return "methodhandle " + kind + " " + d.memberRef(v.ReferenceIndex)
+	case *class.ConstantDynamicValue:
+		name, desc, err := d.cp.NameAndType(v.NameAndTypeIndex)
+		d.check("", err)
+		return fmt.Sprintf("dynamic %d %s %s", v.BootstrapMethodAttrIndex, name, desc)
+	}
+	d.check("", fmt.Errorf("constant %d is not loadable", index))
+	return ""
+}
+
+// memberRef returns a field or method reference in the form parsed by memberRef.
+func (d *disassembler) memberRef(index uint16) string {
+	switch d.cp.Get(index).Tag {
+	case class.TagFieldRef:
+		owner, name, desc, err := d.cp.FieldRef(index)
+		d.check("", err)
+		return owner + "/" + name + " " + desc
+	case class.TagInterfaceMethodRef:
+		owner, name, desc, err := d.cp.InterfaceMethodRef(index)
+		d.check("", err)
+		return "interface " + owner + "/" + name + desc
+	default:
+		owner, name, desc, err := d.cp.MethodRef(index)
+		d.check("", err)
+		return owner + "/" + name + desc
+	}
+}
+
+func (d *disassembler) method(method *class.Method) error {
+	d.printf(".method %s%s%s", flags(method.AccessFlags.Names()), method.Name(), method.Descriptor())
+	var code *class.Code
+	for i := range method.Attributes {
+		attr := &method.Attributes[i]
+		switch value := d.decode(attr).(type) {
+		case *class.Code:
+			code = value
+		case *class.ExceptionsAttribute:
+			for _, index := range value.ExceptionIndexTable {
+				d.printf("    .throws %s", d.className(index))
+			}
+		case *class.RuntimeVisibleParameterAnnotationsAttribute, *class.RuntimeInvisibleParameterAnnotationsAttribute:
+			parameters := -1
+			if md, err := descriptor.ParseMethod(method.Descriptor()); err == nil {
+				parameters = len(md.Params)
+			}
+			d.annotations("    ", attr, parameters)
+		default:
+			d.memberAttribute("    ", attr)
+		}
+	}
+	if code != nil {
+		if err := d.code(code); err != nil {
+			return fmt.Errorf("method %s%s: %w", method.Name(), method.Descriptor(), err)
+		}
+	}
+	d.printf(".end method")
+	return nil
+}
+
+func label(pc int) string {
+	return "L" + strconv.Itoa(pc)
+}
+
+// code writes a method body. Every pc that something refers to gets a label
+// named after it, such as L12.
+func (d *disassembler) code(code *class.Code) error {
+	index, err := code.Instructions()
+	if err != nil {
+		return err
+	}
+
+	labels := make(map[int]bool)
+	for _, instruction := range index.Instructions {
+		for _, target := range instruction.Targets() {
+			labels[target] = true
+		}
+	}
+
+	d.printf("    .limit stack %d", code.MaxStack)
+	d.printf("    .limit locals %d", code.MaxLocals)
+	for _, entry := range code.ExceptionTable {
+		catchType := "all"
+		if entry.CatchType != 0 {
+			catchType = d.className(entry.CatchType)
+		}
+		d.printf("    .catch %s from %s to %s using %s", catchType, label(int(entry.StartPc)), label(int(entry.EndPc)), label(int(entry.HandlerPc)))
+		labels[int(entry.StartPc)], labels[int(entry.EndPc)], labels[int(entry.HandlerPc)] = true, true, true
+	}
+
+	lines := make(map[int][]uint16)
+	frames := make(map[int]string)
+	for i := range code.Attributes {
+		attr := &code.Attributes[i]
+		switch value := d.decode(attr).(type) {
+		case *class.LineNumberTableAttribute:
+			for _, entry := range value.LineNumberTable {
+				lines[int(entry.StartPc)] = append(lines[int(entry.StartPc)], entry.LineNumber)
+			}
+		case *class.LocalVariableTableAttribute:
+			d.variables(code, value, labels)
+		case *class.LocalVariableTypeTableAttribute:
+			// Written with the LocalVariableTable entries
+		case *class.StackMapTableAttribute:
+			for _, frame := range value.Entries {
+				frames[frame.Offset] = d.frame(&frame, labels)
+			}
+		default:
+			d.raw("    ", ".codeattribute", attr)
+		}
+	}
+
+	for _, instruction := range index.Instructions {
+		pc := instruction.Offset
+		if labels[pc] {
+			d.printf("%s:", label(pc))
+		}
+		for _, line := range lines[pc] {
+			d.printf("    .line %d", line)
+		}
+		if frame, ok := frames[pc]; ok {
+			d.printf("    .frame %s", frame)
+		}
+		d.instruction(instruction)
+	}
+	if end := index.Len(); labels[end] {
+		d.printf("%s:", label(end))
+	}
+	return d.err
+}
+
+// variables writes the LocalVariableTable as .var directives, adding the
+// signatures from the LocalVariableTypeTable.
+func (d *disassembler) variables(code *class.Code, table *class.LocalVariableTableAttribute, labels map[int]bool) {
+	type key struct{ start, length, slot, name uint16 }
+	signatures := make(map[key]uint16)
+	for i := range code.Attributes {
+		if types, ok := d.decode(&code.Attributes[i]).(*class.LocalVariableTypeTableAttribute); ok {
+			for _, entry := range types.LocalVariableTypeTable {
+				signatures[key{entry.StartPc, entry.Length, entry.Index, entry.NameIndex}] = entry.SignatureIndex
+			}
+		}
+	}
+
+	for _, entry := range table.LocalVariableTable {
+		start, end := int(entry.StartPc), int(entry.StartPc)+int(entry.Length)
+		labels[start], labels[end] = true, true
+		signature := ""
+		if index, ok := signatures[key{entry.StartPc, entry.Length, entry.Index, entry.NameIndex}]; ok {
+			signature = " signature " + quote(d.utf8(index))
+		}
+		d.printf("    .var %d is %s %s%s from %s to %s", entry.Index, d.utf8(entry.NameIndex), d.utf8(entry.DescriptorIndex), signature, label(start), label(end))
+	}
+}
+
+// frame returns the operands of a .frame directive.
+func (d *disassembler) frame(frame *class.StackMapFrame, labels map[int]bool) string {
+	switch frame.Kind {
+	case class.FrameSame, class.FrameSameExtended:
+		return "same"
+	case class.FrameSameLocals1StackItem, class.FrameSameLocals1StackItemExtended:
+		return "same_locals_1_stack_item " + d.verificationTypes(frame.Stack, labels)
+	case class.FrameChop:
+		return fmt.Sprintf("chop %d", frame.ChopCount())
+	case class.FrameAppend:
+		return "append " + d.verificationTypes(frame.Locals, labels)
+	}
+	return strings.TrimRight("full locals "+d.verificationTypes(frame.Locals, labels)+" stack "+d.verificationTypes(frame.Stack, labels), " ")
+}
+
+func (d *disassembler) verificationTypes(types []class.VerificationTypeInfo, labels map[int]bool) string {
+	words := make([]string, len(types))
+	for i, t := range types {
+		switch t.Tag {
+		case class.ItemObject:
+			words[i] = "class " + d.className(t.CpoolIndex)
+		case class.ItemUninitialized:
+			words[i] = "uninitialized " + label(int(t.Offset))
+			labels[int(t.Offset)] = true
+		default:
+			for word, tag := range verificationTags {
+				if tag == t.Tag {
+					words[i] = word
+				}
+			}
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+func (d *disassembler) instruction(instruction bytecode.Instruction) {
+	mnemonic := instruction.Mnemonic()
+	if instruction.Wide {
+		mnemonic = "wide " + mnemonic
+	}
+
+	if table := instruction.Switch; table != nil {
+		if instruction.Opcode == bytecode.Tableswitch {
+			d.printf("    %s %d %d", mnemonic, table.Low, table.High)
+			for _, target := range table.Targets {
+				d.printf("        %s", label(target))
+			}
+		} else {
+			d.printf("    %s", mnemonic)
+			// Keys are written in order so that reassembly gives the same table
+			order := make([]int, len(table.Keys))
+			for i := range order {
+				order[i] = i
+			}
+			sort.SliceStable(order, func(i, j int) bool { return table.Keys[order[i]] < table.Keys[order[j]] })
+			for _, i := range order {
+				d.printf("        %d: %s", table.Keys[i], label(table.Targets[i]))
+			}
+		}
+		d.printf("        default: %s", label(table.DefaultTarget))
+		return
+	}
+
+	if operands := d.operands(instruction); operands != "" {
+		d.printf("    %s %s", mnemonic, operands)
+	} else {
+		d.printf("    %s", mnemonic)
+	}
+}
+
+func (d *disassembler) operands(instruction bytecode.Instruction) string {
+	index := instruction.ConstantIndex
+	switch op := instruction.Opcode; op {
+	case bytecode.Ldc, bytecode.LdcW, bytecode.Ldc2W:
+		return d.constant(index)
+	case bytecode.Getstatic, bytecode.Putstatic, bytecode.Getfield, bytecode.Putfield,
+		bytecode.Invokevirtual, bytecode.Invokespecial, bytecode.Invokestatic:
+		return d.memberRef(index)
+	case bytecode.Invokeinterface:
+		return fmt.Sprintf("%s %d", strings.TrimPrefix(d.memberRef(index), "interface "), instruction.Value)
+	case bytecode.Invokedynamic:
+		value, ok := d.cp.Get(index).Value.(*class.ConstantInvokeDynamicValue)
+		if !ok {
+			d.check("", fmt.Errorf("constant %d is not an InvokeDynamic", index))
+			return ""
+		}
+		name, desc, err := d.cp.NameAndType(value.NameAndTypeIndex)
+		d.check("", err)
+		return fmt.Sprintf("%d %s%s", value.BootstrapMethodAttrIndex, name, desc)
+	case bytecode.New, bytecode.Anewarray, bytecode.Checkcast, bytecode.Instanceof:
+		return d.className(index)
+	case bytecode.Multianewarray:
+		return fmt.Sprintf("%s %d", d.className(index), instruction.Value)
+	case bytecode.Newarray:
+		return bytecode.ArrayTypeName(instruction.Value)
+	case bytecode.Bipush, bytecode.Sipush:
+		return strconv.Itoa(int(instruction.Value))
+	case bytecode.Iinc:
+		return fmt.Sprintf("%d %d", instruction.LocalIndex, instruction.Value)
+	case bytecode.Iload, bytecode.Lload, bytecode.Fload, bytecode.Dload, bytecode.Aload,
+		bytecode.Istore, bytecode.Lstore, bytecode.Fstore, bytecode.Dstore, bytecode.Astore, bytecode.Ret:
+		return strconv.Itoa(int(instruction.LocalIndex))
+	}
+	if instruction.Opcode.IsBranch() {
+		return label(instruction.Target)
+	}
+	return ""
+}
@@ -0,0 +1,71 @@
+package assembler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// tokenize splits a line of assembly into tokens separated by whitespace. A
+// double-quoted string is a single token and keeps its quotes, so that it can
+// be told apart from a name. A token that starts with ';' begins a comment;
+// a ';' inside a token, as in a descriptor, does not.
+func tokenize(text string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(text); {
+		switch c := text[i]; {
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == ';':
+			return tokens, nil
+		case c == '"':
+			end := i + 1
+			for ; end < len(text) && text[end] != '"'; end++ {
+				if text[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(text) {
+				return nil, fmt.Errorf("unterminated string %s", text[i:])
+			}
+			tokens = append(tokens, text[i:end+1])
+			i = end + 1
+		default:
+			end := i
+			for end < len(text) && text[end] != ' ' && text[end] != '\t' && text[end] != '\r' {
+				end++
+			}
+			tokens = append(tokens, text[i:end])
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+// isString reports whether token is a quoted string.
+func isString(token string) bool {
+	return len(token) >= 2 && token[0] == '"'
+}
+
+// unquote returns the contents of a quoted string token, with the escapes
+// of Go string literals, which include those of Java.
+func unquote(token string) (string, error) {
+	if !isString(token) {
+		return "", fmt.Errorf("expected a quoted string, found %q", token)
+	}
+	s, err := strconv.Unquote(token)
+	if err != nil {
+		return "", fmt.Errorf("invalid string %s", token)
+	}
+	return s, nil
+}
+
+// quote returns s as a string token that unquote accepts.
+func quote(s string) string {
+	return strconv.Quote(s)
+}
+
+// isLabel reports whether token defines a label, e.g. "Loop:".
+func isLabel(token string) bool {
+	return len(token) > 1 && strings.HasSuffix(token, ":") && !isString(token)
+}
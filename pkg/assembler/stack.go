@@ -0,0 +1,250 @@
+package assembler
+
+import (
+	"fmt"
+	"lava-vm/pkg/bytecode"
+	"lava-vm/pkg/class"
+	"lava-vm/pkg/descriptor"
+)
+
+// stackEffects holds the operand stack slots popped and pushed by the
+// instructions whose effect does not depend on their operands.
+var stackEffects = map[bytecode.Opcode][2]int{
+	bytecode.Nop: {0, 0}, bytecode.AconstNull: {0, 1},
+	bytecode.IconstM1: {0, 1}, bytecode.Iconst0: {0, 1}, bytecode.Iconst1: {0, 1}, bytecode.Iconst2: {0, 1},
+	bytecode.Iconst3: {0, 1}, bytecode.Iconst4: {0, 1}, bytecode.Iconst5: {0, 1},
+	bytecode.Lconst0: {0, 2}, bytecode.Lconst1: {0, 2},
+	bytecode.Fconst0: {0, 1}, bytecode.Fconst1: {0, 1}, bytecode.Fconst2: {0, 1},
+	bytecode.Dconst0: {0, 2}, bytecode.Dconst1: {0, 2},
+	bytecode.Bipush: {0, 1}, bytecode.Sipush: {0, 1},
+	bytecode.Ldc: {0, 1}, bytecode.LdcW: {0, 1}, bytecode.Ldc2W: {0, 2},
+
+	bytecode.Iload: {0, 1}, bytecode.Lload: {0, 2}, bytecode.Fload: {0, 1}, bytecode.Dload: {0, 2}, bytecode.Aload: {0, 1},
+	bytecode.Iload0: {0, 1}, bytecode.Iload1: {0, 1}, bytecode.Iload2: {0, 1}, bytecode.Iload3: {0, 1},
+	bytecode.Lload0: {0, 2}, bytecode.Lload1: {0, 2}, bytecode.Lload2: {0, 2}, bytecode.Lload3: {0, 2},
+	bytecode.Fload0: {0, 1}, bytecode.Fload1: {0, 1}, bytecode.Fload2: {0, 1}, bytecode.Fload3: {0, 1},
+	bytecode.Dload0: {0, 2}, bytecode.Dload1: {0, 2}, bytecode.Dload2: {0, 2}, bytecode.Dload3: {0, 2},
+	bytecode.Aload0: {0, 1}, bytecode.Aload1: {0, 1}, bytecode.Aload2: {0, 1}, bytecode.Aload3: {0, 1},
+	bytecode.Iaload: {2, 1}, bytecode.Laload: {2, 2}, bytecode.Faload: {2, 1}, bytecode.Daload: {2, 2},
+	bytecode.Aaload: {2, 1}, bytecode.Baload: {2, 1}, bytecode.Caload: {2, 1}, bytecode.Saload: {2, 1},
+
+	bytecode.Istore: {1, 0}, bytecode.Lstore: {2, 0}, bytecode.Fstore: {1, 0}, bytecode.Dstore: {2, 0}, bytecode.Astore: {1, 0},
+	bytecode.Istore0: {1, 0}, bytecode.Istore1: {1, 0}, bytecode.Istore2: {1, 0}, bytecode.Istore3: {1, 0},
+	bytecode.Lstore0: {2, 0}, bytecode.Lstore1: {2, 0}, bytecode.Lstore2: {2, 0}, bytecode.Lstore3: {2, 0},
+	bytecode.Fstore0: {1, 0}, bytecode.Fstore1: {1, 0}, bytecode.Fstore2: {1, 0}, bytecode.Fstore3: {1, 0},
+	bytecode.Dstore0: {2, 0}, bytecode.Dstore1: {2, 0}, bytecode.Dstore2: {2, 0}, bytecode.Dstore3: {2, 0},
+	bytecode.Astore0: {1, 0}, bytecode.Astore1: {1, 0}, bytecode.Astore2: {1, 0}, bytecode.Astore3: {1, 0},
+	bytecode.Iastore: {3, 0}, bytecode.Lastore: {4, 0}, bytecode.Fastore: {3, 0}, bytecode.Dastore: {4, 0},
+	bytecode.Aastore: {3, 0}, bytecode.Bastore: {3, 0}, bytecode.Castore: {3, 0}, bytecode.Sastore: {3, 0},
+
+	bytecode.Pop: {1, 0}, bytecode.Pop2: {2, 0}, bytecode.Dup: {1, 2}, bytecode.DupX1: {2, 3}, bytecode.DupX2: {3, 4},
+	bytecode.Dup2: {2, 4}, bytecode.Dup2X1: {3, 5}, bytecode.Dup2X2: {4, 6}, bytecode.Swap: {2, 2},
+
+	bytecode.Iadd: {2, 1}, bytecode.Ladd: {4, 2}, bytecode.Fadd: {2, 1}, bytecode.Dadd: {4, 2},
+	bytecode.Isub: {2, 1}, bytecode.Lsub: {4, 2}, bytecode.Fsub: {2, 1}, bytecode.Dsub: {4, 2},
+	bytecode.Imul: {2, 1}, bytecode.Lmul: {4, 2}, bytecode.Fmul: {2, 1}, bytecode.Dmul: {4, 2},
+	bytecode.Idiv: {2, 1}, bytecode.Ldiv: {4, 2}, bytecode.Fdiv: {2, 1}, bytecode.Ddiv: {4, 2},
+	bytecode.Irem: {2, 1}, bytecode.Lrem: {4, 2}, bytecode.Frem: {2, 1}, bytecode.Drem: {4, 2},
+	bytecode.Ineg: {1, 1}, bytecode.Lneg: {2, 2}, bytecode.Fneg: {1, 1}, bytecode.Dneg: {2, 2},
+	bytecode.Ishl: {2, 1}, bytecode.Lshl: {3, 2}, bytecode.Ishr: {2, 1}, bytecode.Lshr: {3, 2},
+	bytecode.Iushr: {2, 1}, bytecode.Lushr: {3, 2},
+	bytecode.Iand: {2, 1}, bytecode.Land: {4, 2}, bytecode.Ior: {2, 1}, bytecode.Lor: {4, 2},
+	bytecode.Ixor: {2, 1}, bytecode.Lxor: {4, 2},
+	bytecode.Iinc: {0, 0},
+
+	bytecode.I2l: {1, 2}, bytecode.I2f: {1, 1}, bytecode.I2d: {1, 2},
+	bytecode.L2i: {2, 1}, bytecode.L2f: {2, 1}, bytecode.L2d: {2, 2},
+	bytecode.F2i: {1, 1}, bytecode.F2l: {1, 2}, bytecode.F2d: {1, 2},
+	bytecode.D2i: {2, 1}, bytecode.D2l: {2, 2}, bytecode.D2f: {2, 1},
+	bytecode.I2b: {1, 1}, bytecode.I2c: {1, 1}, bytecode.I2s: {1, 1},
+	bytecode.Lcmp: {4, 1}, bytecode.Fcmpl: {2, 1}, bytecode.Fcmpg: {2, 1}, bytecode.Dcmpl: {4, 1}, bytecode.Dcmpg: {4, 1},
+
+	bytecode.Ifeq: {1, 0}, bytecode.Ifne: {1, 0}, bytecode.Iflt: {1, 0},
+	bytecode.Ifge: {1, 0}, bytecode.Ifgt: {1, 0}, bytecode.Ifle: {1, 0},
+	bytecode.IfIcmpeq: {2, 0}, bytecode.IfIcmpne: {2, 0}, bytecode.IfIcmplt: {2, 0},
+	bytecode.IfIcmpge: {2, 0}, bytecode.IfIcmpgt: {2, 0}, bytecode.IfIcmple: {2, 0},
+	bytecode.IfAcmpeq: {2, 0}, bytecode.IfAcmpne: {2, 0},
+	bytecode.Goto: {0, 0}, bytecode.Jsr: {0, 1}, bytecode.Ret: {0, 0},
+	bytecode.Tableswitch: {1, 0}, bytecode.Lookupswitch: {1, 0},
+	bytecode.Ireturn: {1, 0}, bytecode.Lreturn: {2, 0}, bytecode.Freturn: {1, 0},
+	bytecode.Dreturn: {2, 0}, bytecode.Areturn: {1, 0}, bytecode.Return: {0, 0},
+
+	bytecode.New: {0, 1}, bytecode.Newarray: {1, 1}, bytecode.Anewarray: {1, 1}, bytecode.Arraylength: {1, 1},
+	bytecode.Athrow: {1, 0}, bytecode.Checkcast: {1, 1}, bytecode.Instanceof: {1, 1},
+	bytecode.Monitorenter: {1, 0}, bytecode.Monitorexit: {1, 0},
+	bytecode.Ifnull: {1, 0}, bytecode.Ifnonnull: {1, 0}, bytecode.GotoW: {0, 0}, bytecode.JsrW: {0, 1},
+}
+
+// stackEffect returns the operand stack slots an instruction pops and pushes.
+func stackEffect(instruction *bytecode.Instruction, cp *class.ConstantPool) (pop, push int, err error) {
+	index := instruction.ConstantIndex
+	switch op := instruction.Opcode; op {
+	case bytecode.Getstatic, bytecode.Putstatic, bytecode.Getfield, bytecode.Putfield:
+		_, _, desc, err := cp.FieldRef(index)
+		if err != nil {
+			return 0, 0, err
+		}
+		fieldType, err := descriptor.ParseField(desc)
+		if err != nil {
+			return 0, 0, err
+		}
+		size := fieldType.Slots()
+		switch op {
+		case bytecode.Getstatic:
+			return 0, size, nil
+		case bytecode.Putstatic:
+			return size, 0, nil
+		case bytecode.Getfield:
+			return 1, size, nil
+		default:
+			return 1 + size, 0, nil
+		}
+
+	case bytecode.Invokevirtual, bytecode.Invokespecial, bytecode.Invokestatic, bytecode.Invokeinterface, bytecode.Invokedynamic:
+		var desc string
+		switch entry := cp.Get(index); entry.Tag {
+		case class.TagMethodRef:
+			_, _, desc, err = cp.MethodRef(index)
+		case class.TagInterfaceMethodRef:
+			_, _, desc, err = cp.InterfaceMethodRef(index)
+		case class.TagInvokeDynamic:
+			value := entry.Value.(*class.ConstantInvokeDynamicValue)
+			_, desc, err = cp.NameAndType(value.NameAndTypeIndex)
+		default:
+			err = fmt.Errorf("constant %d is not a method reference", index)
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		md, err := descriptor.ParseMethod(desc)
+		if err != nil {
+			return 0, 0, err
+		}
+		pop = md.ArgSlots()
+		if op != bytecode.Invokestatic && op != bytecode.Invokedynamic {
+			pop++
+		}
+		return pop, md.Return.Slots(), nil
+
+	case bytecode.Multianewarray:
+		return int(instruction.Value), 1, nil
+	}
+
+	effect, ok := stackEffects[instruction.Opcode]
+	if !ok {
+		return 0, 0, fmt.Errorf("unknown stack effect of %s", instruction.Opcode)
+	}
+	return effect[0], effect[1], nil
+}
+
+// instructionError is an error in the instruction at pc, which the method
+// turns into an error on the line the instruction is written on.
+type instructionError struct {
+	pc  int
+	err error
+}
+
+func (e *instructionError) Error() string { return e.err.Error() }
+
+func (e *instructionError) Unwrap() error { return e.err }
+
+// maxStack returns the deepest the operand stack gets on any path through the
+// code. Exception handlers start with the exception on the stack, and a
+// subroutine called by jsr returns to the instruction after the jsr with the
+// stack as it was before the call.
+func maxStack(index *bytecode.Index, handlers []class.ExceptionTableEntry, cp *class.ConstantPool) (uint16, error) {
+	depths := make([]int, len(index.Instructions))
+	for i := range depths {
+		depths[i] = -1
+	}
+	var work []int
+	max := 0
+	visit := func(pc, depth int) {
+		if position, ok := index.Position(pc); ok && depths[position] < 0 {
+			depths[position] = depth
+			work = append(work, position)
+			if depth > max {
+				max = depth
+			}
+		}
+	}
+
+	visit(0, 0)
+	for _, handler := range handlers {
+		visit(int(handler.HandlerPc), 1)
+	}
+	for len(work) > 0 {
+		position := work[len(work)-1]
+		work = work[:len(work)-1]
+		instruction := &index.Instructions[position]
+		depth := depths[position]
+
+		pop, push, err := stackEffect(instruction, cp)
+		if err != nil {
+			return 0, &instructionError{instruction.Offset, fmt.Errorf("%s at pc %d: %w", instruction.Opcode, instruction.Offset, err)}
+		}
+		if depth < pop {
+			return 0, &instructionError{instruction.Offset,
+				fmt.Errorf("%s at pc %d needs %d stack slots but at most %d are in use", instruction.Opcode, instruction.Offset, pop, depth)}
+		}
+		after := depth - pop + push
+		if after > max {
+			max = after
+		}
+
+		for _, target := range instruction.Targets() {
+			visit(target, after)
+		}
+		next := instruction.Offset + instruction.Length
+		switch {
+		case instruction.Opcode == bytecode.Jsr || instruction.Opcode == bytecode.JsrW:
+			visit(next, depth)
+		case instruction.FallsThrough():
+			visit(next, after)
+		}
+	}
+	if max > 1<<16-1 {
+		return 0, fmt.Errorf("the operand stack needs %d slots, more than the maximum of %d", max, 1<<16-1)
+	}
+	return uint16(max), nil
+}
+
+// maxLocalsUsed returns the local variable slots used by the arguments,
+// the instructions and the variables declared with .var.
+func (m *method) maxLocalsUsed(index *bytecode.Index) uint16 {
+	max := m.descriptor.ArgSlots()
+	if !m.flags.IsStatic() {
+		max++
+	}
+	use := func(slot, size int) {
+		if slot+size > max {
+			max = slot + size
+		}
+	}
+
+	for i := range index.Instructions {
+		instruction := &index.Instructions[i]
+		switch op := instruction.Opcode; {
+		case op >= bytecode.Iload && op <= bytecode.Aload3, op >= bytecode.Istore && op <= bytecode.Astore3:
+			size := 1
+			switch op {
+			case bytecode.Lload, bytecode.Dload, bytecode.Lstore, bytecode.Dstore,
+				bytecode.Lload0, bytecode.Lload1, bytecode.Lload2, bytecode.Lload3,
+				bytecode.Dload0, bytecode.Dload1, bytecode.Dload2, bytecode.Dload3,
+				bytecode.Lstore0, bytecode.Lstore1, bytecode.Lstore2, bytecode.Lstore3,
+				bytecode.Dstore0, bytecode.Dstore1, bytecode.Dstore2, bytecode.Dstore3:
+				size = 2
+			}
+			use(int(instruction.LocalIndex), size)
+		case op == bytecode.Iinc, op == bytecode.Ret:
+			use(int(instruction.LocalIndex), 1)
+		}
+	}
+	for _, v := range m.variables {
+		use(int(v.slot), v.size)
+	}
+	if max > 1<<16-1 {
+		max = 1<<16 - 1
+	}
+	return uint16(max)
+}
@@ -0,0 +1,87 @@
+package bytecode
+
+import "fmt"
+
+// Index holds the decoded instructions of a method body and finds them by pc.
+type Index struct {
+	// Instructions are in increasing order of Offset.
+	Instructions []Instruction
+	// positions holds the position in Instructions of the instruction that
+	// starts at each pc, or -1 for a pc inside an instruction.
+	positions  []int32
+	codeLength int
+}
+
+// NewIndex decodes code and indexes its instructions by pc. It fails if a
+// branch or switch jumps outside the code or into the middle of an instruction.
+func NewIndex(code []byte) (*Index, error) {
+	instructions, err := Decode(code)
+	if err != nil {
+		return nil, err
+	}
+
+	index := &Index{
+		Instructions: instructions,
+		positions:    make([]int32, len(code)),
+		codeLength:   len(code),
+	}
+	for pc := range index.positions {
+		index.positions[pc] = -1
+	}
+	for i, instruction := range instructions {
+		index.positions[instruction.Offset] = int32(i)
+	}
+
+	for _, instruction := range instructions {
+		for _, target := range instruction.Targets() {
+			if target < 0 || target >= len(code) {
+				return nil, fmt.Errorf("%s at pc %d jumps to %d, outside the code", instruction.Mnemonic(), instruction.Offset, target)
+			}
+			if !index.IsBoundary(target) {
+				return nil, fmt.Errorf("%s at pc %d jumps to %d, which is not the start of an instruction", instruction.Mnemonic(), instruction.Offset, target)
+			}
+		}
+	}
+	return index, nil
+}
+
+// Len returns the length of the indexed code in bytes.
+func (x *Index) Len() int {
+	return x.codeLength
+}
+
+// Position returns the position in Instructions of the instruction that
+// starts at pc, or false if no instruction starts there.
+func (x *Index) Position(pc int) (int, bool) {
+	if pc < 0 || pc >= len(x.positions) || x.positions[pc] < 0 {
+		return 0, false
+	}
+	return int(x.positions[pc]), true
+}
+
+// At returns the instruction that starts at pc, or false if no instruction
+// starts there.
+func (x *Index) At(pc int) (*Instruction, bool) {
+	position, ok := x.Position(pc)
+	if !ok {
+		return nil, false
+	}
+	return &x.Instructions[position], true
+}
+
+// IsBoundary reports whether an instruction starts at pc.
+func (x *Index) IsBoundary(pc int) bool {
+	_, ok := x.Position(pc)
+	return ok
+}
+
+// Containing returns the instruction that covers pc, which may start before it.
+func (x *Index) Containing(pc int) (*Instruction, bool) {
+	if pc < 0 || pc >= len(x.positions) {
+		return nil, false
+	}
+	for x.positions[pc] < 0 {
+		pc--
+	}
+	return &x.Instructions[x.positions[pc]], true
+}
@@ -0,0 +1,89 @@
+package bytecode
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewIndex(t *testing.T) {
+	code := []byte{
+		byte(Iload0),
+		byte(Ifeq), 0x00, 0x05, // 1: to 6
+		byte(Iconst1),
+		byte(Ireturn),
+		byte(Iconst0),
+		byte(Ireturn),
+	}
+	index, err := NewIndex(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index.Len() != len(code) || len(index.Instructions) != 6 {
+		t.Fatalf("NewIndex() has %d instructions over %d bytes, want 6 over %d", len(index.Instructions), index.Len(), len(code))
+	}
+
+	branch, ok := index.At(1)
+	if !ok || branch.Opcode != Ifeq || branch.Offset != 1 || branch.Length != 3 {
+		t.Fatalf("At(1) = %+v, %v, want ifeq of length 3", branch, ok)
+	}
+	if targets := branch.Targets(); !reflect.DeepEqual(targets, []int{6}) {
+		t.Errorf("Targets() = %v, want [6]", targets)
+	}
+	if position, ok := index.Position(6); !ok || position != 4 {
+		t.Errorf("Position(6) = %d, %v, want 4", position, ok)
+	}
+
+	for _, pc := range []int{2, 3, -1, len(code)} {
+		if index.IsBoundary(pc) {
+			t.Errorf("IsBoundary(%d) = true", pc)
+		}
+		if _, ok := index.At(pc); ok {
+			t.Errorf("At(%d) found an instruction", pc)
+		}
+	}
+	if containing, ok := index.Containing(3); !ok || containing.Offset != 1 {
+		t.Errorf("Containing(3) = %+v, %v, want the ifeq at 1", containing, ok)
+	}
+	if _, ok := index.Containing(len(code)); ok {
+		t.Errorf("Containing(%d) found an instruction past the end", len(code))
+	}
+}
+
+func TestNewIndexErrors(t *testing.T) {
+	// withReturn ends a switch at 0 with a return, at 24 after a tableswitch
+	// over two keys and at 20 after a lookupswitch with one pair
+	withReturn := func(code []byte) []byte { return append(code, byte(Return)) }
+	tests := []struct {
+		name string
+		code []byte
+		want string
+	}{
+		{"branch into its own operand", []byte{byte(Goto), 0x00, 0x01, byte(Return)},
+			"goto at pc 0 jumps to 1, which is not the start of an instruction"},
+		{"branch before the code", []byte{byte(Nop), byte(Ifnull), 0xff, 0xfe, byte(Return)},
+			"ifnull at pc 1 jumps to -1, outside the code"},
+		{"branch to the end of the code", []byte{byte(Goto), 0x00, 0x03},
+			"goto at pc 0 jumps to 3, outside the code"},
+		{"goto_w into the middle", []byte{byte(GotoW), 0x00, 0x00, 0x00, 0x02, byte(Return)},
+			"goto_w at pc 0 jumps to 2"},
+		{"switch default into its padding", withReturn(switchAt(0, Tableswitch, 2, 0, 1, 24, 24)),
+			"tableswitch at pc 0 jumps to 2, which is not the start of an instruction"},
+		{"switch case outside", withReturn(switchAt(0, Lookupswitch, 20, 1, 7, 100)),
+			"lookupswitch at pc 0 jumps to 100, outside the code"},
+		{"undecodable", []byte{byte(Sipush), 0x01}, "truncated sipush"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewIndex(test.code)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("NewIndex() = %v, want an error containing %q", err, test.want)
+			}
+		})
+	}
+
+	// The same switch with every target on the return is valid
+	if _, err := NewIndex(withReturn(switchAt(0, Tableswitch, 24, 0, 1, 24, 24))); err != nil {
+		t.Errorf("NewIndex() = %v for a valid tableswitch", err)
+	}
+}
@@ -0,0 +1,322 @@
+package bytecode
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Instruction is a decoded instruction. Which of the typed operand fields is
+// set depends on the opcode; the others are zero.
+type Instruction struct {
+	// Offset is the pc of the instruction, its position in the code.
+	Offset int
+	// Length is the length of the instruction in bytes, including the opcode
+	// and, for a wide instruction, the wide prefix.
+	Length int
+	Opcode Opcode
+	// Wide is set when the instruction is modified by a wide prefix. Opcode
+	// is then the modified opcode, such as Iload or Iinc, rather than Wide.
+	Wide bool
+	// Operands holds the raw bytes that follow the opcode, or that follow the
+	// modified opcode of a wide instruction. For a switch this includes the
+	// alignment padding.
+	Operands []byte
+
+	// ConstantIndex is the constant pool index of ldc, ldc_w, ldc2_w, the
+	// field and invoke instructions, new, anewarray, checkcast, instanceof
+	// and multianewarray.
+	ConstantIndex uint16
+	// LocalIndex is the local variable of the load and store instructions,
+	// iinc and ret. For the short forms such as iload_2 it is the implied index.
+	LocalIndex uint16
+	// Value is the immediate value of bipush and sipush, the increment of
+	// iinc, the array type of newarray, the count of invokeinterface and the
+	// dimensions of multianewarray.
+	Value int32
+	// BranchOffset is the signed offset of a branch from the instruction's pc,
+	// and Target is the pc it jumps to.
+	BranchOffset int32
+	Target       int
+	// Switch is the jump table of tableswitch and lookupswitch.
+	Switch *SwitchTable
+}
+
+// SwitchTable holds the operands of tableswitch and lookupswitch. Offsets[i]
+// is the branch offset taken when the key is Keys[i], and Targets[i] the pc it
+// jumps to. For tableswitch the keys are Low through High; lookupswitch keys
+// are sorted in increasing order.
+type SwitchTable struct {
+	Default       int32
+	DefaultTarget int
+	Low           int32
+	High          int32
+	Keys          []int32
+	Offsets       []int32
+	Targets       []int
+}
+
+// Mnemonic returns the mnemonic of the instruction, see Opcode.String.
+func (i Instruction) Mnemonic() string {
+	return i.Opcode.String()
+}
+
+// Decode decodes every instruction in code.
+func Decode(code []byte) ([]Instruction, error) {
+	var instructions []Instruction
+	for pc := 0; pc < len(code); {
+		instruction, length, err := DecodeAt(code, pc)
+		if err != nil {
+			return nil, err
+		}
+		instructions = append(instructions, instruction)
+		pc += length
+	}
+	return instructions, nil
+}
+
+// Length returns the length in bytes of the instruction at pc, including the
+// opcode, without decoding its operands.
+func Length(code []byte, pc int) (int, error) {
+	if pc < 0 || pc >= len(code) {
+		return 0, fmt.Errorf("pc %d is outside the code", pc)
+	}
+	op := Opcode(code[pc])
+	info := opcodes[op]
+	length := 0
+	switch info.format {
+	case tableSwitch, lookupSwitch:
+		// Operands start at the next multiple of four after the opcode,
+		// followed by default and low and high, or default and npairs
+		start := (pc + 4) &^ 3
+		header := 12
+		if op == Lookupswitch {
+			header = 8
+		}
+		if start+header > len(code) {
+			return 0, fmt.Errorf("truncated %s at pc %d", op, pc)
+		}
+		if op == Tableswitch {
+			low := int32(binary.BigEndian.Uint32(code[start+4:]))
+			high := int32(binary.BigEndian.Uint32(code[start+8:]))
+			if low > high {
+				return 0, fmt.Errorf("tableswitch at pc %d has low %d greater than high %d", pc, low, high)
+			}
+			length = start + 12 + 4*int(int64(high)-int64(low)+1) - pc
+		} else {
+			pairs := int32(binary.BigEndian.Uint32(code[start+4:]))
+			if pairs < 0 {
+				return 0, fmt.Errorf("lookupswitch at pc %d has negative pair count %d", pc, pairs)
+			}
+			length = start + 8 + 8*int(pairs) - pc
+		}
+	case widePrefix:
+		if pc+1 >= len(code) {
+			return 0, fmt.Errorf("truncated wide at pc %d", pc)
+		}
+		switch modified := Opcode(code[pc+1]); opcodes[modified].format {
+		case iincOperands:
+			length = 6
+		case localIndex:
+			length = 4
+		default:
+			return 0, fmt.Errorf("wide at pc %d modifies %s", pc, modified)
+		}
+	case reserved:
+		return 0, fmt.Errorf("reserved opcode %s at pc %d", op, pc)
+	default:
+		if info.mnemonic == "" {
+			return 0, fmt.Errorf("invalid %s at pc %d", op, pc)
+		}
+		length = 1 + operandLengths[info.format]
+	}
+	if pc+length > len(code) {
+		return 0, fmt.Errorf("truncated %s at pc %d", op, pc)
+	}
+	return length, nil
+}
+
+// DecodeAt decodes the instruction at pc, returning it with its length in
+// bytes. The position matters for the alignment of switch operands.
+func DecodeAt(code []byte, pc int) (Instruction, int, error) {
+	length, err := Length(code, pc)
+	if err != nil {
+		return Instruction{}, 0, err
+	}
+
+	instruction := Instruction{Offset: pc, Length: length, Opcode: Opcode(code[pc]), Operands: code[pc+1 : pc+length]}
+	operands := instruction.Operands
+	switch opcodes[instruction.Opcode].format {
+	case byteValue:
+		instruction.Value = int32(int8(operands[0]))
+	case shortValue:
+		instruction.Value = int32(int16(binary.BigEndian.Uint16(operands)))
+	case constantIndex8:
+		instruction.ConstantIndex = uint16(operands[0])
+	case constantIndex16:
+		instruction.ConstantIndex = binary.BigEndian.Uint16(operands)
+	case localIndex:
+		instruction.LocalIndex = uint16(operands[0])
+	case iincOperands:
+		instruction.LocalIndex = uint16(operands[0])
+		instruction.Value = int32(int8(operands[1]))
+	case branch16:
+		instruction.BranchOffset = int32(int16(binary.BigEndian.Uint16(operands)))
+		instruction.Target = pc + int(instruction.BranchOffset)
+	case branch32:
+		instruction.BranchOffset = int32(binary.BigEndian.Uint32(operands))
+		instruction.Target = pc + int(instruction.BranchOffset)
+	case arrayType:
+		instruction.Value = int32(operands[0])
+		if ArrayTypeName(instruction.Value) == "" {
+			return Instruction{}, 0, fmt.Errorf("newarray at pc %d has invalid array type %d", pc, instruction.Value)
+		}
+	case interfaceCall:
+		instruction.ConstantIndex = binary.BigEndian.Uint16(operands)
+		instruction.Value = int32(operands[2])
+		if operands[2] == 0 || operands[3] != 0 {
+			return Instruction{}, 0, fmt.Errorf("invokeinterface at pc %d has count %d and trailing byte %d", pc, operands[2], operands[3])
+		}
+	case dynamicCall:
+		instruction.ConstantIndex = binary.BigEndian.Uint16(operands)
+		if operands[2] != 0 || operands[3] != 0 {
+			return Instruction{}, 0, fmt.Errorf("invokedynamic at pc %d has non-zero trailing bytes", pc)
+		}
+	case multiArray:
+		instruction.ConstantIndex = binary.BigEndian.Uint16(operands)
+		instruction.Value = int32(operands[2])
+		if operands[2] == 0 {
+			return Instruction{}, 0, fmt.Errorf("multianewarray at pc %d has zero dimensions", pc)
+		}
+	case tableSwitch, lookupSwitch:
+		instruction.Switch = decodeSwitch(instruction.Opcode, code, pc)
+		if instruction.Opcode == Lookupswitch {
+			for i := 1; i < len(instruction.Switch.Keys); i++ {
+				if instruction.Switch.Keys[i-1] >= instruction.Switch.Keys[i] {
+					return Instruction{}, 0, fmt.Errorf("lookupswitch at pc %d has keys out of order", pc)
+				}
+			}
+		}
+	case widePrefix:
+		instruction.Opcode = Opcode(operands[0])
+		instruction.Wide = true
+		instruction.Operands = operands[1:]
+		instruction.LocalIndex = binary.BigEndian.Uint16(operands[1:])
+		if instruction.Opcode == Iinc {
+			instruction.Value = int32(int16(binary.BigEndian.Uint16(operands[3:])))
+		}
+	case noOperands:
+		instruction.LocalIndex = impliedLocalIndex(instruction.Opcode)
+	}
+	return instruction, length, nil
+}
+
+// decodeSwitch decodes the operands of a switch whose length has been checked.
+func decodeSwitch(op Opcode, code []byte, pc int) *SwitchTable {
+	start := (pc + 4) &^ 3
+	s4 := func(offset int) int32 {
+		return int32(binary.BigEndian.Uint32(code[offset:]))
+	}
+
+	table := &SwitchTable{Default: s4(start)}
+	table.DefaultTarget = pc + int(table.Default)
+	if op == Tableswitch {
+		table.Low, table.High = s4(start+4), s4(start+8)
+		count := int(int64(table.High) - int64(table.Low) + 1)
+		table.Keys = make([]int32, count)
+		table.Offsets = make([]int32, count)
+		table.Targets = make([]int, count)
+		for i := range table.Offsets {
+			table.Keys[i] = table.Low + int32(i)
+			table.Offsets[i] = s4(start + 12 + 4*i)
+			table.Targets[i] = pc + int(table.Offsets[i])
+		}
+		return table
+	}
+
+	count := int(s4(start + 4))
+	table.Keys = make([]int32, count)
+	table.Offsets = make([]int32, count)
+	table.Targets = make([]int, count)
+	for i := range table.Offsets {
+		table.Keys[i] = s4(start + 8 + 8*i)
+		table.Offsets[i] = s4(start + 12 + 8*i)
+		table.Targets[i] = pc + int(table.Offsets[i])
+	}
+	return table
+}
+
+// impliedLocalIndex returns the local variable index implied by the short
+// forms of the load and store instructions, such as 2 for iload_2.
+func impliedLocalIndex(op Opcode) uint16 {
+	switch {
+	case op >= Iload0 && op <= Aload3:
+		return uint16(op-Iload0) % 4
+	case op >= Istore0 && op <= Astore3:
+		return uint16(op-Istore0) % 4
+	}
+	return 0
+}
+
+// Targets returns the pcs the instruction may jump to: the target of a
+// branch, or the targets of a switch followed by its default. Execution may
+// also continue with the next instruction, see FallsThrough.
+func (i Instruction) Targets() []int {
+	if i.Switch != nil {
+		return append(append([]int(nil), i.Switch.Targets...), i.Switch.DefaultTarget)
+	}
+	if i.Opcode.IsBranch() {
+		return []int{i.Target}
+	}
+	return nil
+}
+
+// FallsThrough reports whether execution may continue with the instruction
+// that follows. It is false for goto, the switches, the returns, athrow and
+// ret. The jsr instructions fall through, as the subroutine returns to the
+// instruction after them.
+func (i Instruction) FallsThrough() bool {
+	switch i.Opcode {
+	case Goto, GotoW, Tableswitch, Lookupswitch, Ret, Athrow,
+		Ireturn, Lreturn, Freturn, Dreturn, Areturn, Return:
+		return false
+	}
+	return true
+}
+
+// String returns the instruction in assembly form, such as "bipush 10",
+// "invokevirtual #12" or "goto 8". Branches show their target pc.
+func (i Instruction) String() string {
+	var builder strings.Builder
+	if i.Wide {
+		builder.WriteString("wide ")
+	}
+	builder.WriteString(i.Mnemonic())
+
+	switch opcodes[i.Opcode].format {
+	case byteValue, shortValue:
+		fmt.Fprintf(&builder, " %d", i.Value)
+	case constantIndex8, constantIndex16, dynamicCall:
+		fmt.Fprintf(&builder, " #%d", i.ConstantIndex)
+	case localIndex:
+		fmt.Fprintf(&builder, " %d", i.LocalIndex)
+	case iincOperands:
+		fmt.Fprintf(&builder, " %d, %d", i.LocalIndex, i.Value)
+	case branch16, branch32:
+		fmt.Fprintf(&builder, " %d", i.Target)
+	case arrayType:
+		fmt.Fprintf(&builder, " %s", ArrayTypeName(i.Value))
+	case interfaceCall, multiArray:
+		fmt.Fprintf(&builder, " #%d, %d", i.ConstantIndex, i.Value)
+	case tableSwitch, lookupSwitch:
+		if i.Switch == nil {
+			break
+		}
+		builder.WriteString(" {")
+		for j, key := range i.Switch.Keys {
+			fmt.Fprintf(&builder, " %d: %d;", key, i.Switch.Targets[j])
+		}
+		fmt.Fprintf(&builder, " default: %d }", i.Switch.DefaultTarget)
+	}
+	return builder.String()
+}
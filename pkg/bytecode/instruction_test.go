@@ -0,0 +1,169 @@
+package bytecode
+
+import (
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// switchAt returns code with a switch opcode at pc, preceded by nops, followed
+// by the padding that aligns its operands and then the given 32-bit operands.
+func switchAt(pc int, op Opcode, operands ...int32) []byte {
+	code := make([]byte, pc, pc+16+4*len(operands))
+	code = append(code, byte(op))
+	for len(code)%4 != 0 {
+		code = append(code, 0)
+	}
+	for _, operand := range operands {
+		code = binary.BigEndian.AppendUint32(code, uint32(operand))
+	}
+	return code
+}
+
+func TestSwitchAlignment(t *testing.T) {
+	for pc := 0; pc < 4; pc++ {
+		padding := 3 - pc%4
+
+		code := switchAt(pc, Tableswitch, 40, 1, 2, 20, 30)
+		instruction, length, err := DecodeAt(code, pc)
+		if err != nil {
+			t.Fatalf("tableswitch at pc %d: %v", pc, err)
+		}
+		if want := 1 + padding + 20; length != want || instruction.Length != want {
+			t.Errorf("tableswitch at pc %d: length %d, want %d", pc, length, want)
+		}
+		want := &SwitchTable{
+			Default: 40, DefaultTarget: pc + 40, Low: 1, High: 2,
+			Keys: []int32{1, 2}, Offsets: []int32{20, 30}, Targets: []int{pc + 20, pc + 30},
+		}
+		if !reflect.DeepEqual(instruction.Switch, want) {
+			t.Errorf("tableswitch at pc %d: switch %+v, want %+v", pc, instruction.Switch, want)
+		}
+
+		code = switchAt(pc, Lookupswitch, 40, 2, -5, 20, 100, 30)
+		instruction, length, err = DecodeAt(code, pc)
+		if err != nil {
+			t.Fatalf("lookupswitch at pc %d: %v", pc, err)
+		}
+		if want := 1 + padding + 24; length != want {
+			t.Errorf("lookupswitch at pc %d: length %d, want %d", pc, length, want)
+		}
+		want = &SwitchTable{
+			Default: 40, DefaultTarget: pc + 40,
+			Keys: []int32{-5, 100}, Offsets: []int32{20, 30}, Targets: []int{pc + 20, pc + 30},
+		}
+		if !reflect.DeepEqual(instruction.Switch, want) {
+			t.Errorf("lookupswitch at pc %d: switch %+v, want %+v", pc, instruction.Switch, want)
+		}
+		if n, err := Length(code, pc); err != nil || n != length {
+			t.Errorf("Length of lookupswitch at pc %d = %d, %v, want %d", pc, n, err, length)
+		}
+	}
+}
+
+func TestDecodeAt(t *testing.T) {
+	tests := []struct {
+		name string
+		code []byte
+		want Instruction
+	}{
+		{
+			name: "wide iload",
+			code: []byte{byte(Wide), byte(Iload), 0x01, 0x02},
+			want: Instruction{Length: 4, Opcode: Iload, Wide: true, LocalIndex: 258},
+		},
+		{
+			name: "wide iinc",
+			code: []byte{byte(Wide), byte(Iinc), 0x01, 0x2c, 0xff, 0xfe},
+			want: Instruction{Length: 6, Opcode: Iinc, Wide: true, LocalIndex: 300, Value: -2},
+		},
+		{
+			name: "wide ret",
+			code: []byte{byte(Wide), byte(Ret), 0x00, 0x05},
+			want: Instruction{Length: 4, Opcode: Ret, Wide: true, LocalIndex: 5},
+		},
+		{
+			name: "invokeinterface",
+			code: []byte{byte(Invokeinterface), 0x00, 0x05, 0x02, 0x00},
+			want: Instruction{Length: 5, Opcode: Invokeinterface, ConstantIndex: 5, Value: 2},
+		},
+		{
+			name: "invokedynamic",
+			code: []byte{byte(Invokedynamic), 0x00, 0x07, 0x00, 0x00},
+			want: Instruction{Length: 5, Opcode: Invokedynamic, ConstantIndex: 7},
+		},
+		{
+			name: "lookupswitch with no pairs",
+			code: switchAt(0, Lookupswitch, 8, 0),
+			want: Instruction{Length: 12, Opcode: Lookupswitch, Switch: &SwitchTable{
+				Default: 8, DefaultTarget: 8, Keys: []int32{}, Offsets: []int32{}, Targets: []int{},
+			}},
+		},
+		{
+			name: "goto backwards",
+			code: []byte{byte(Goto), 0xff, 0xfd},
+			want: Instruction{Length: 3, Opcode: Goto, BranchOffset: -3, Target: -3},
+		},
+		{
+			name: "iload_2",
+			code: []byte{byte(Iload2)},
+			want: Instruction{Length: 1, Opcode: Iload2, LocalIndex: 2},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			length, err := Length(test.code, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if length != test.want.Length {
+				t.Errorf("Length() = %d, want %d", length, test.want.Length)
+			}
+			instruction, _, err := DecodeAt(test.code, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			instruction.Operands = nil
+			if !reflect.DeepEqual(instruction, test.want) {
+				t.Errorf("DecodeAt() = %+v, want %+v", instruction, test.want)
+			}
+		})
+	}
+}
+
+func TestDecodeAtErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		code []byte
+		want string
+	}{
+		{"tableswitch low greater than high", switchAt(0, Tableswitch, 8, 2, 1), "low 2 greater than high 1"},
+		{"lookupswitch negative pair count", switchAt(0, Lookupswitch, 8, -1), "negative pair count"},
+		{"lookupswitch keys out of order", switchAt(0, Lookupswitch, 8, 2, 5, 8, 3, 8), "keys out of order"},
+		{"lookupswitch duplicate keys", switchAt(0, Lookupswitch, 8, 2, 5, 8, 5, 8), "keys out of order"},
+		{"tableswitch truncated header", switchAt(0, Tableswitch, 8, 0), "truncated tableswitch"},
+		{"tableswitch truncated offsets", switchAt(0, Tableswitch, 8, 0, 3, 8), "truncated tableswitch"},
+		{"lookupswitch truncated pairs", switchAt(0, Lookupswitch, 8, 2, 5, 8), "truncated lookupswitch"},
+		{"sipush truncated", []byte{byte(Sipush), 0x01}, "truncated sipush"},
+		{"wide truncated", []byte{byte(Wide)}, "truncated wide"},
+		{"wide iinc truncated", []byte{byte(Wide), byte(Iinc), 0x00, 0x01, 0x00}, "truncated wide"},
+		{"wide goto", []byte{byte(Wide), byte(Goto), 0x00, 0x00}, "modifies goto"},
+		{"invokeinterface zero count", []byte{byte(Invokeinterface), 0x00, 0x05, 0x00, 0x00}, "count 0"},
+		{"invokeinterface trailing byte", []byte{byte(Invokeinterface), 0x00, 0x05, 0x01, 0x01}, "trailing byte 1"},
+		{"invokedynamic trailing bytes", []byte{byte(Invokedynamic), 0x00, 0x07, 0x00, 0x01}, "non-zero trailing bytes"},
+		{"newarray invalid type", []byte{byte(Newarray), 0x02}, "invalid array type 2"},
+		{"undefined opcode", []byte{0xcb}, "invalid"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := DecodeAt(test.code, 0)
+			if err == nil {
+				t.Fatalf("DecodeAt() succeeded, want an error containing %q", test.want)
+			}
+			if !strings.Contains(err.Error(), test.want) {
+				t.Errorf("DecodeAt() error %q, want it to contain %q", err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,546 @@
+// Package bytecode decodes the instructions of JVM method bodies.
+// See https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-6.html
+package bytecode
+
+import "fmt"
+
+// Opcode is the first byte of an instruction.
+type Opcode byte
+
+const (
+	Nop             Opcode = 0x00
+	AconstNull      Opcode = 0x01
+	IconstM1        Opcode = 0x02
+	Iconst0         Opcode = 0x03
+	Iconst1         Opcode = 0x04
+	Iconst2         Opcode = 0x05
+	Iconst3         Opcode = 0x06
+	Iconst4         Opcode = 0x07
+	Iconst5         Opcode = 0x08
+	Lconst0         Opcode = 0x09
+	Lconst1         Opcode = 0x0a
+	Fconst0         Opcode = 0x0b
+	Fconst1         Opcode = 0x0c
+	Fconst2         Opcode = 0x0d
+	Dconst0         Opcode = 0x0e
+	Dconst1         Opcode = 0x0f
+	Bipush          Opcode = 0x10
+	Sipush          Opcode = 0x11
+	Ldc             Opcode = 0x12
+	LdcW            Opcode = 0x13
+	Ldc2W           Opcode = 0x14
+	Iload           Opcode = 0x15
+	Lload           Opcode = 0x16
+	Fload           Opcode = 0x17
+	Dload           Opcode = 0x18
+	Aload           Opcode = 0x19
+	Iload0          Opcode = 0x1a
+	Iload1          Opcode = 0x1b
+	Iload2          Opcode = 0x1c
+	Iload3          Opcode = 0x1d
+	Lload0          Opcode = 0x1e
+	Lload1          Opcode = 0x1f
+	Lload2          Opcode = 0x20
+	Lload3          Opcode = 0x21
+	Fload0          Opcode = 0x22
+	Fload1          Opcode = 0x23
+	Fload2          Opcode = 0x24
+	Fload3          Opcode = 0x25
+	Dload0          Opcode = 0x26
+	Dload1          Opcode = 0x27
+	Dload2          Opcode = 0x28
+	Dload3          Opcode = 0x29
+	Aload0          Opcode = 0x2a
+	Aload1          Opcode = 0x2b
+	Aload2          Opcode = 0x2c
+	Aload3          Opcode = 0x2d
+	Iaload          Opcode = 0x2e
+	Laload          Opcode = 0x2f
+	Faload          Opcode = 0x30
+	Daload          Opcode = 0x31
+	Aaload          Opcode = 0x32
+	Baload          Opcode = 0x33
+	Caload          Opcode = 0x34
+	Saload          Opcode = 0x35
+	Istore          Opcode = 0x36
+	Lstore          Opcode = 0x37
+	Fstore          Opcode = 0x38
+	Dstore          Opcode = 0x39
+	Astore          Opcode = 0x3a
+	Istore0         Opcode = 0x3b
+	Istore1         Opcode = 0x3c
+	Istore2         Opcode = 0x3d
+	Istore3         Opcode = 0x3e
+	Lstore0         Opcode = 0x3f
+	Lstore1         Opcode = 0x40
+	Lstore2         Opcode = 0x41
+	Lstore3         Opcode = 0x42
+	Fstore0         Opcode = 0x43
+	Fstore1         Opcode = 0x44
+	Fstore2         Opcode = 0x45
+	Fstore3         Opcode = 0x46
+	Dstore0         Opcode = 0x47
+	Dstore1         Opcode = 0x48
+	Dstore2         Opcode = 0x49
+	Dstore3         Opcode = 0x4a
+	Astore0         Opcode = 0x4b
+	Astore1         Opcode = 0x4c
+	Astore2         Opcode = 0x4d
+	Astore3         Opcode = 0x4e
+	Iastore         Opcode = 0x4f
+	Lastore         Opcode = 0x50
+	Fastore         Opcode = 0x51
+	Dastore         Opcode = 0x52
+	Aastore         Opcode = 0x53
+	Bastore         Opcode = 0x54
+	Castore         Opcode = 0x55
+	Sastore         Opcode = 0x56
+	Pop             Opcode = 0x57
+	Pop2            Opcode = 0x58
+	Dup             Opcode = 0x59
+	DupX1           Opcode = 0x5a
+	DupX2           Opcode = 0x5b
+	Dup2            Opcode = 0x5c
+	Dup2X1          Opcode = 0x5d
+	Dup2X2          Opcode = 0x5e
+	Swap            Opcode = 0x5f
+	Iadd            Opcode = 0x60
+	Ladd            Opcode = 0x61
+	Fadd            Opcode = 0x62
+	Dadd            Opcode = 0x63
+	Isub            Opcode = 0x64
+	Lsub            Opcode = 0x65
+	Fsub            Opcode = 0x66
+	Dsub            Opcode = 0x67
+	Imul            Opcode = 0x68
+	Lmul            Opcode = 0x69
+	Fmul            Opcode = 0x6a
+	Dmul            Opcode = 0x6b
+	Idiv            Opcode = 0x6c
+	Ldiv            Opcode = 0x6d
+	Fdiv            Opcode = 0x6e
+	Ddiv            Opcode = 0x6f
+	Irem            Opcode = 0x70
+	Lrem            Opcode = 0x71
+	Frem            Opcode = 0x72
+	Drem            Opcode = 0x73
+	Ineg            Opcode = 0x74
+	Lneg            Opcode = 0x75
+	Fneg            Opcode = 0x76
+	Dneg            Opcode = 0x77
+	Ishl            Opcode = 0x78
+	Lshl            Opcode = 0x79
+	Ishr            Opcode = 0x7a
+	Lshr            Opcode = 0x7b
+	Iushr           Opcode = 0x7c
+	Lushr           Opcode = 0x7d
+	Iand            Opcode = 0x7e
+	Land            Opcode = 0x7f
+	Ior             Opcode = 0x80
+	Lor             Opcode = 0x81
+	Ixor            Opcode = 0x82
+	Lxor            Opcode = 0x83
+	Iinc            Opcode = 0x84
+	I2l             Opcode = 0x85
+	I2f             Opcode = 0x86
+	I2d             Opcode = 0x87
+	L2i             Opcode = 0x88
+	L2f             Opcode = 0x89
+	L2d             Opcode = 0x8a
+	F2i             Opcode = 0x8b
+	F2l             Opcode = 0x8c
+	F2d             Opcode = 0x8d
+	D2i             Opcode = 0x8e
+	D2l             Opcode = 0x8f
+	D2f             Opcode = 0x90
+	I2b             Opcode = 0x91
+	I2c             Opcode = 0x92
+	I2s             Opcode = 0x93
+	Lcmp            Opcode = 0x94
+	Fcmpl           Opcode = 0x95
+	Fcmpg           Opcode = 0x96
+	Dcmpl           Opcode = 0x97
+	Dcmpg           Opcode = 0x98
+	Ifeq            Opcode = 0x99
+	Ifne            Opcode = 0x9a
+	Iflt            Opcode = 0x9b
+	Ifge            Opcode = 0x9c
+	Ifgt            Opcode = 0x9d
+	Ifle            Opcode = 0x9e
+	IfIcmpeq        Opcode = 0x9f
+	IfIcmpne        Opcode = 0xa0
+	IfIcmplt        Opcode = 0xa1
+	IfIcmpge        Opcode = 0xa2
+	IfIcmpgt        Opcode = 0xa3
+	IfIcmple        Opcode = 0xa4
+	IfAcmpeq        Opcode = 0xa5
+	IfAcmpne        Opcode = 0xa6
+	Goto            Opcode = 0xa7
+	Jsr             Opcode = 0xa8
+	Ret             Opcode = 0xa9
+	Tableswitch     Opcode = 0xaa
+	Lookupswitch    Opcode = 0xab
+	Ireturn         Opcode = 0xac
+	Lreturn         Opcode = 0xad
+	Freturn         Opcode = 0xae
+	Dreturn         Opcode = 0xaf
+	Areturn         Opcode = 0xb0
+	Return          Opcode = 0xb1
+	Getstatic       Opcode = 0xb2
+	Putstatic       Opcode = 0xb3
+	Getfield        Opcode = 0xb4
+	Putfield        Opcode = 0xb5
+	Invokevirtual   Opcode = 0xb6
+	Invokespecial   Opcode = 0xb7
+	Invokestatic    Opcode = 0xb8
+	Invokeinterface Opcode = 0xb9
+	Invokedynamic   Opcode = 0xba
+	New             Opcode = 0xbb
+	Newarray        Opcode = 0xbc
+	Anewarray       Opcode = 0xbd
+	Arraylength     Opcode = 0xbe
+	Athrow          Opcode = 0xbf
+	Checkcast       Opcode = 0xc0
+	Instanceof      Opcode = 0xc1
+	Monitorenter    Opcode = 0xc2
+	Monitorexit     Opcode = 0xc3
+	Wide            Opcode = 0xc4
+	Multianewarray  Opcode = 0xc5
+	Ifnull          Opcode = 0xc6
+	Ifnonnull       Opcode = 0xc7
+	GotoW           Opcode = 0xc8
+	JsrW            Opcode = 0xc9
+
+	// The reserved opcodes are for debuggers and implementations and must not
+	// appear in a class file.
+	Breakpoint Opcode = 0xca
+	Impdep1    Opcode = 0xfe
+	Impdep2    Opcode = 0xff
+)
+
+// operandFormat describes the operands that follow an opcode.
+type operandFormat uint8
+
+const (
+	noOperands      operandFormat = iota
+	byteValue                     // bipush: s1 value
+	shortValue                    // sipush: s2 value
+	constantIndex8                // ldc: u1 constant pool index
+	constantIndex16               // u2 constant pool index
+	localIndex                    // u1 local variable index, u2 after wide
+	iincOperands                  // u1 local variable index and s1 increment, u2 and s2 after wide
+	branch16                      // s2 branch offset
+	branch32                      // s4 branch offset
+	arrayType                     // newarray: u1 array type
+	interfaceCall                 // invokeinterface: u2 index, u1 count and a zero byte
+	dynamicCall                   // invokedynamic: u2 index and two zero bytes
+	multiArray                    // multianewarray: u2 index and u1 dimensions
+	tableSwitch                   // padding, s4 default, low and high, and high-low+1 s4 offsets
+	lookupSwitch                  // padding, s4 default and npairs, and npairs s4 key and offset pairs
+	widePrefix                    // wide: the modified opcode and its widened operands
+	reserved                      // not allowed in a class file
+)
+
+// operandLengths holds the length of the fixed-size operand formats.
+var operandLengths = [...]int{
+	byteValue:       1,
+	shortValue:      2,
+	constantIndex8:  1,
+	constantIndex16: 2,
+	localIndex:      1,
+	iincOperands:    2,
+	branch16:        2,
+	branch32:        4,
+	arrayType:       1,
+	interfaceCall:   4,
+	dynamicCall:     4,
+	multiArray:      3,
+	reserved:        0,
+}
+
+type opcodeInfo struct {
+	mnemonic string
+	format   operandFormat
+}
+
+// opcodes holds the mnemonic and operand format of every opcode, see JVMS 6.5.
+// Opcodes missing from the table are undefined.
+var opcodes = [256]opcodeInfo{
+	Nop:             {"nop", noOperands},
+	AconstNull:      {"aconst_null", noOperands},
+	IconstM1:        {"iconst_m1", noOperands},
+	Iconst0:         {"iconst_0", noOperands},
+	Iconst1:         {"iconst_1", noOperands},
+	Iconst2:         {"iconst_2", noOperands},
+	Iconst3:         {"iconst_3", noOperands},
+	Iconst4:         {"iconst_4", noOperands},
+	Iconst5:         {"iconst_5", noOperands},
+	Lconst0:         {"lconst_0", noOperands},
+	Lconst1:         {"lconst_1", noOperands},
+	Fconst0:         {"fconst_0", noOperands},
+	Fconst1:         {"fconst_1", noOperands},
+	Fconst2:         {"fconst_2", noOperands},
+	Dconst0:         {"dconst_0", noOperands},
+	Dconst1:         {"dconst_1", noOperands},
+	Bipush:          {"bipush", byteValue},
+	Sipush:          {"sipush", shortValue},
+	Ldc:             {"ldc", constantIndex8},
+	LdcW:            {"ldc_w", constantIndex16},
+	Ldc2W:           {"ldc2_w", constantIndex16},
+	Iload:           {"iload", localIndex},
+	Lload:           {"lload", localIndex},
+	Fload:           {"fload", localIndex},
+	Dload:           {"dload", localIndex},
+	Aload:           {"aload", localIndex},
+	Iload0:          {"iload_0", noOperands},
+	Iload1:          {"iload_1", noOperands},
+	Iload2:          {"iload_2", noOperands},
+	Iload3:          {"iload_3", noOperands},
+	Lload0:          {"lload_0", noOperands},
+	Lload1:          {"lload_1", noOperands},
+	Lload2:          {"lload_2", noOperands},
+	Lload3:          {"lload_3", noOperands},
+	Fload0:          {"fload_0", noOperands},
+	Fload1:          {"fload_1", noOperands},
+	Fload2:          {"fload_2", noOperands},
+	Fload3:          {"fload_3", noOperands},
+	Dload0:          {"dload_0", noOperands},
+	Dload1:          {"dload_1", noOperands},
+	Dload2:          {"dload_2", noOperands},
+	Dload3:          {"dload_3", noOperands},
+	Aload0:          {"aload_0", noOperands},
+	Aload1:          {"aload_1", noOperands},
+	Aload2:          {"aload_2", noOperands},
+	Aload3:          {"aload_3", noOperands},
+	Iaload:          {"iaload", noOperands},
+	Laload:          {"laload", noOperands},
+	Faload:          {"faload", noOperands},
+	Daload:          {"daload", noOperands},
+	Aaload:          {"aaload", noOperands},
+	Baload:          {"baload", noOperands},
+	Caload:          {"caload", noOperands},
+	Saload:          {"saload", noOperands},
+	Istore:          {"istore", localIndex},
+	Lstore:          {"lstore", localIndex},
+	Fstore:          {"fstore", localIndex},
+	Dstore:          {"dstore", localIndex},
+	Astore:          {"astore", localIndex},
+	Istore0:         {"istore_0", noOperands},
+	Istore1:         {"istore_1", noOperands},
+	Istore2:         {"istore_2", noOperands},
+	Istore3:         {"istore_3", noOperands},
+	Lstore0:         {"lstore_0", noOperands},
+	Lstore1:         {"lstore_1", noOperands},
+	Lstore2:         {"lstore_2", noOperands},
+	Lstore3:         {"lstore_3", noOperands},
+	Fstore0:         {"fstore_0", noOperands},
+	Fstore1:         {"fstore_1", noOperands},
+	Fstore2:         {"fstore_2", noOperands},
+	Fstore3:         {"fstore_3", noOperands},
+	Dstore0:         {"dstore_0", noOperands},
+	Dstore1:         {"dstore_1", noOperands},
+	Dstore2:         {"dstore_2", noOperands},
+	Dstore3:         {"dstore_3", noOperands},
+	Astore0:         {"astore_0", noOperands},
+	Astore1:         {"astore_1", noOperands},
+	Astore2:         {"astore_2", noOperands},
+	Astore3:         {"astore_3", noOperands},
+	Iastore:         {"iastore", noOperands},
+	Lastore:         {"lastore", noOperands},
+	Fastore:         {"fastore", noOperands},
+	Dastore:         {"dastore", noOperands},
+	Aastore:         {"aastore", noOperands},
+	Bastore:         {"bastore", noOperands},
+	Castore:         {"castore", noOperands},
+	Sastore:         {"sastore", noOperands},
+	Pop:             {"pop", noOperands},
+	Pop2:            {"pop2", noOperands},
+	Dup:             {"dup", noOperands},
+	DupX1:           {"dup_x1", noOperands},
+	DupX2:           {"dup_x2", noOperands},
+	Dup2:            {"dup2", noOperands},
+	Dup2X1:          {"dup2_x1", noOperands},
+	Dup2X2:          {"dup2_x2", noOperands},
+	Swap:            {"swap", noOperands},
+	Iadd:            {"iadd", noOperands},
+	Ladd:            {"ladd", noOperands},
+	Fadd:            {"fadd", noOperands},
+	Dadd:            {"dadd", noOperands},
+	Isub:            {"isub", noOperands},
+	Lsub:            {"lsub", noOperands},
+	Fsub:            {"fsub", noOperands},
+	Dsub:            {"dsub", noOperands},
+	Imul:            {"imul", noOperands},
+	Lmul:            {"lmul", noOperands},
+	Fmul:            {"fmul", noOperands},
+	Dmul:            {"dmul", noOperands},
+	Idiv:            {"idiv", noOperands},
+	Ldiv:            {"ldiv", noOperands},
+	Fdiv:            {"fdiv", noOperands},
+	Ddiv:            {"ddiv", noOperands},
+	Irem:            {"irem", noOperands},
+	Lrem:            {"lrem", noOperands},
+	Frem:            {"frem", noOperands},
+	Drem:            {"drem", noOperands},
+	Ineg:            {"ineg", noOperands},
+	Lneg:            {"lneg", noOperands},
+	Fneg:            {"fneg", noOperands},
+	Dneg:            {"dneg", noOperands},
+	Ishl:            {"ishl", noOperands},
+	Lshl:            {"lshl", noOperands},
+	Ishr:            {"ishr", noOperands},
+	Lshr:            {"lshr", noOperands},
+	Iushr:           {"iushr", noOperands},
+	Lushr:           {"lushr", noOperands},
+	Iand:            {"iand", noOperands},
+	Land:            {"land", noOperands},
+	Ior:             {"ior", noOperands},
+	Lor:             {"lor", noOperands},
+	Ixor:            {"ixor", noOperands},
+	Lxor:            {"lxor", noOperands},
+	Iinc:            {"iinc", iincOperands},
+	I2l:             {"i2l", noOperands},
+	I2f:             {"i2f", noOperands},
+	I2d:             {"i2d", noOperands},
+	L2i:             {"l2i", noOperands},
+	L2f:             {"l2f", noOperands},
+	L2d:             {"l2d", noOperands},
+	F2i:             {"f2i", noOperands},
+	F2l:             {"f2l", noOperands},
+	F2d:             {"f2d", noOperands},
+	D2i:             {"d2i", noOperands},
+	D2l:             {"d2l", noOperands},
+	D2f:             {"d2f", noOperands},
+	I2b:             {"i2b", noOperands},
+	I2c:             {"i2c", noOperands},
+	I2s:             {"i2s", noOperands},
+	Lcmp:            {"lcmp", noOperands},
+	Fcmpl:           {"fcmpl", noOperands},
+	Fcmpg:           {"fcmpg", noOperands},
+	Dcmpl:           {"dcmpl", noOperands},
+	Dcmpg:           {"dcmpg", noOperands},
+	Ifeq:            {"ifeq", branch16},
+	Ifne:            {"ifne", branch16},
+	Iflt:            {"iflt", branch16},
+	Ifge:            {"ifge", branch16},
+	Ifgt:            {"ifgt", branch16},
+	Ifle:            {"ifle", branch16},
+	IfIcmpeq:        {"if_icmpeq", branch16},
+	IfIcmpne:        {"if_icmpne", branch16},
+	IfIcmplt:        {"if_icmplt", branch16},
+	IfIcmpge:        {"if_icmpge", branch16},
+	IfIcmpgt:        {"if_icmpgt", branch16},
+	IfIcmple:        {"if_icmple", branch16},
+	IfAcmpeq:        {"if_acmpeq", branch16},
+	IfAcmpne:        {"if_acmpne", branch16},
+	Goto:            {"goto", branch16},
+	Jsr:             {"jsr", branch16},
+	Ret:             {"ret", localIndex},
+	Tableswitch:     {"tableswitch", tableSwitch},
+	Lookupswitch:    {"lookupswitch", lookupSwitch},
+	Ireturn:         {"ireturn", noOperands},
+	Lreturn:         {"lreturn", noOperands},
+	Freturn:         {"freturn", noOperands},
+	Dreturn:         {"dreturn", noOperands},
+	Areturn:         {"areturn", noOperands},
+	Return:          {"return", noOperands},
+	Getstatic:       {"getstatic", constantIndex16},
+	Putstatic:       {"putstatic", constantIndex16},
+	Getfield:        {"getfield", constantIndex16},
+	Putfield:        {"putfield", constantIndex16},
+	Invokevirtual:   {"invokevirtual", constantIndex16},
+	Invokespecial:   {"invokespecial", constantIndex16},
+	Invokestatic:    {"invokestatic", constantIndex16},
+	Invokeinterface: {"invokeinterface", interfaceCall},
+	Invokedynamic:   {"invokedynamic", dynamicCall},
+	New:             {"new", constantIndex16},
+	Newarray:        {"newarray", arrayType},
+	Anewarray:       {"anewarray", constantIndex16},
+	Arraylength:     {"arraylength", noOperands},
+	Athrow:          {"athrow", noOperands},
+	Checkcast:       {"checkcast", constantIndex16},
+	Instanceof:      {"instanceof", constantIndex16},
+	Monitorenter:    {"monitorenter", noOperands},
+	Monitorexit:     {"monitorexit", noOperands},
+	Wide:            {"wide", widePrefix},
+	Multianewarray:  {"multianewarray", multiArray},
+	Ifnull:          {"ifnull", branch16},
+	Ifnonnull:       {"ifnonnull", branch16},
+	GotoW:           {"goto_w", branch32},
+	JsrW:            {"jsr_w", branch32},
+	Breakpoint:      {"breakpoint", reserved},
+	Impdep1:         {"impdep1", reserved},
+	Impdep2:         {"impdep2", reserved},
+}
+
+// String returns the mnemonic of the opcode, such as "invokevirtual".
+func (op Opcode) String() string {
+	if mnemonic := opcodes[op].mnemonic; mnemonic != "" {
+		return mnemonic
+	}
+	return fmt.Sprintf("opcode 0x%02X", byte(op))
+}
+
+// IsValid reports whether the opcode may appear in a class file.
+func (op Opcode) IsValid() bool {
+	info := opcodes[op]
+	return info.mnemonic != "" && info.format != reserved
+}
+
+// IsBranch reports whether the instruction jumps to a branch offset: the
+// conditional branches, goto, jsr, and their wide forms. Switches are not
+// included.
+func (op Opcode) IsBranch() bool {
+	format := opcodes[op].format
+	return format == branch16 || format == branch32
+}
+
+// IsSwitch reports whether the opcode is tableswitch or lookupswitch.
+func (op Opcode) IsSwitch() bool {
+	return op == Tableswitch || op == Lookupswitch
+}
+
+// Lookup returns the opcode with the given mnemonic.
+func Lookup(mnemonic string) (Opcode, bool) {
+	op, ok := mnemonics[mnemonic]
+	return op, ok
+}
+
+var mnemonics = func() map[string]Opcode {
+	m := make(map[string]Opcode)
+	for i, info := range opcodes {
+		if info.mnemonic != "" {
+			m[info.mnemonic] = Opcode(i)
+		}
+	}
+	return m
+}()
+
+// Array types are the operand of newarray, see JVMS table 6.5.newarray-A.
+const (
+	TBoolean = 4
+	TChar    = 5
+	TFloat   = 6
+	TDouble  = 7
+	TByte    = 8
+	TShort   = 9
+	TInt     = 10
+	TLong    = 11
+)
+
+var arrayTypeNames = map[int32]string{
+	TBoolean: "boolean",
+	TChar:    "char",
+	TFloat:   "float",
+	TDouble:  "double",
+	TByte:    "byte",
+	TShort:   "short",
+	TInt:     "int",
+	TLong:    "long",
+}
+
+// ArrayTypeName returns the element type name of a newarray array type, such
+// as "int", or an empty string if the type is invalid.
+func ArrayTypeName(atype int32) string {
+	return arrayTypeNames[atype]
+}
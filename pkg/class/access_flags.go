@@ -0,0 +1,540 @@
+package class
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// The same access flag bit means different things depending on where it
+// appears, e.g. 0x0020 is ACC_SUPER on a class but ACC_SYNCHRONIZED on a
+// method, so each context has its own flag set type.
+
+// flagInfo describes one access flag bit. Keyword is the Java source modifier
+// for the flag, or empty for flags that have no source form.
+type flagInfo struct {
+	bit     uint16
+	name    string
+	keyword string
+}
+
+// flagNames returns the ACC_ names of the bits set in flags, in table order.
+func flagNames(flags uint16, table []flagInfo) []string {
+	var names []string
+	for _, info := range table {
+		if flags&info.bit != 0 {
+			names = append(names, info.name)
+		}
+	}
+	return names
+}
+
+// flagKeywords returns the Java source modifiers of the bits set in flags,
+// in table order.
+func flagKeywords(flags uint16, table []flagInfo) string {
+	var keywords []string
+	for _, info := range table {
+		if flags&info.bit != 0 && info.keyword != "" {
+			keywords = append(keywords, info.keyword)
+		}
+	}
+	return strings.Join(keywords, " ")
+}
+
+// countSet returns the number of the given bits that are set in flags.
+func countSet(flags uint16, bits ...uint16) int {
+	count := 0
+	for _, bit := range bits {
+		if flags&bit != 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// ClassAccessFlags are the access_flags of a ClassFile, see JVMS table 4.1-B.
+type ClassAccessFlags uint16
+
+const (
+	ClassPublic     ClassAccessFlags = 0x0001
+	ClassFinal      ClassAccessFlags = 0x0010
+	ClassSuper      ClassAccessFlags = 0x0020
+	ClassInterface  ClassAccessFlags = 0x0200
+	ClassAbstract   ClassAccessFlags = 0x0400
+	ClassSynthetic  ClassAccessFlags = 0x1000
+	ClassAnnotation ClassAccessFlags = 0x2000
+	ClassEnum       ClassAccessFlags = 0x4000
+	ClassModule     ClassAccessFlags = 0x8000
+)
+
+// Interfaces are implicitly abstract, so "abstract" is left to the caller.
+var classFlagTable = []flagInfo{
+	{0x0001, "ACC_PUBLIC", "public"},
+	{0x0010, "ACC_FINAL", "final"},
+	{0x0020, "ACC_SUPER", ""},
+	{0x0200, "ACC_INTERFACE", ""},
+	{0x0400, "ACC_ABSTRACT", "abstract"},
+	{0x1000, "ACC_SYNTHETIC", ""},
+	{0x2000, "ACC_ANNOTATION", ""},
+	{0x4000, "ACC_ENUM", ""},
+	{0x8000, "ACC_MODULE", ""},
+}
+
+func (f ClassAccessFlags) Has(flag ClassAccessFlags) bool { return f&flag == flag }
+func (f ClassAccessFlags) IsPublic() bool                 { return f.Has(ClassPublic) }
+func (f ClassAccessFlags) IsFinal() bool                  { return f.Has(ClassFinal) }
+func (f ClassAccessFlags) IsSuper() bool                  { return f.Has(ClassSuper) }
+func (f ClassAccessFlags) IsInterface() bool              { return f.Has(ClassInterface) }
+func (f ClassAccessFlags) IsAbstract() bool               { return f.Has(ClassAbstract) }
+func (f ClassAccessFlags) IsSynthetic() bool              { return f.Has(ClassSynthetic) }
+func (f ClassAccessFlags) IsAnnotation() bool             { return f.Has(ClassAnnotation) }
+func (f ClassAccessFlags) IsEnum() bool                   { return f.Has(ClassEnum) }
+func (f ClassAccessFlags) IsModule() bool                 { return f.Has(ClassModule) }
+
+// Names returns the JVMS names of the set flags, e.g. [ACC_PUBLIC ACC_SUPER].
+func (f ClassAccessFlags) Names() []string {
+	return flagNames(uint16(f), classFlagTable)
+}
+
+// String returns the flags as Java source modifiers, e.g. "public final".
+// Interfaces are implicitly abstract, so abstract is omitted for them.
+func (f ClassAccessFlags) String() string {
+	if f.IsInterface() {
+		f &^= ClassAbstract
+	}
+	return flagKeywords(uint16(f), classFlagTable)
+}
+
+// Keyword returns the Java keyword that declares a class with these flags:
+// "class", "interface", "@interface", "enum" or "module".
+func (f ClassAccessFlags) Keyword() string {
+	switch {
+	case f.IsModule():
+		return "module"
+	case f.IsAnnotation():
+		return "@interface"
+	case f.IsInterface():
+		return "interface"
+	case f.IsEnum():
+		return "enum"
+	default:
+		return "class"
+	}
+}
+
+// Validate checks the flags against the rules of JVMS 4.1 for a class file
+// with the given major version, returning every violation found.
+func (f ClassAccessFlags) Validate(majorVersion uint16) error {
+	var errs []error
+	if f.IsModule() {
+		if majorVersion < 53 {
+			errs = append(errs, fmt.Errorf("ACC_MODULE requires class file version 53 or later"))
+		}
+		if f != ClassModule {
+			errs = append(errs, fmt.Errorf("ACC_MODULE is set with other flags %v", (f&^ClassModule).Names()))
+		}
+		return errors.Join(errs...)
+	}
+
+	if f.IsInterface() {
+		if !f.IsAbstract() {
+			errs = append(errs, fmt.Errorf("interface is not ACC_ABSTRACT"))
+		}
+		for _, flag := range []ClassAccessFlags{ClassFinal, ClassSuper, ClassEnum} {
+			if f.Has(flag) {
+				errs = append(errs, fmt.Errorf("interface has %s set", flag.Names()[0]))
+			}
+		}
+	} else {
+		if f.IsAnnotation() {
+			errs = append(errs, fmt.Errorf("ACC_ANNOTATION is set on a class that is not an interface"))
+		}
+		if f.IsFinal() && f.IsAbstract() {
+			errs = append(errs, fmt.Errorf("class is both ACC_FINAL and ACC_ABSTRACT"))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// FieldAccessFlags are the access_flags of a field_info, see JVMS table 4.5-A.
+type FieldAccessFlags uint16
+
+const (
+	FieldPublic    FieldAccessFlags = 0x0001
+	FieldPrivate   FieldAccessFlags = 0x0002
+	FieldProtected FieldAccessFlags = 0x0004
+	FieldStatic    FieldAccessFlags = 0x0008
+	FieldFinal     FieldAccessFlags = 0x0010
+	FieldVolatile  FieldAccessFlags = 0x0040
+	FieldTransient FieldAccessFlags = 0x0080
+	FieldSynthetic FieldAccessFlags = 0x1000
+	FieldEnum      FieldAccessFlags = 0x4000
+)
+
+var fieldFlagTable = []flagInfo{
+	{0x0001, "ACC_PUBLIC", "public"},
+	{0x0002, "ACC_PRIVATE", "private"},
+	{0x0004, "ACC_PROTECTED", "protected"},
+	{0x0008, "ACC_STATIC", "static"},
+	{0x0010, "ACC_FINAL", "final"},
+	{0x0040, "ACC_VOLATILE", "volatile"},
+	{0x0080, "ACC_TRANSIENT", "transient"},
+	{0x1000, "ACC_SYNTHETIC", ""},
+	{0x4000, "ACC_ENUM", ""},
+}
+
+func (f FieldAccessFlags) Has(flag FieldAccessFlags) bool { return f&flag == flag }
+func (f FieldAccessFlags) IsPublic() bool                 { return f.Has(FieldPublic) }
+func (f FieldAccessFlags) IsPrivate() bool                { return f.Has(FieldPrivate) }
+func (f FieldAccessFlags) IsProtected() bool              { return f.Has(FieldProtected) }
+func (f FieldAccessFlags) IsStatic() bool                 { return f.Has(FieldStatic) }
+func (f FieldAccessFlags) IsFinal() bool                  { return f.Has(FieldFinal) }
+func (f FieldAccessFlags) IsVolatile() bool               { return f.Has(FieldVolatile) }
+func (f FieldAccessFlags) IsTransient() bool              { return f.Has(FieldTransient) }
+func (f FieldAccessFlags) IsSynthetic() bool              { return f.Has(FieldSynthetic) }
+func (f FieldAccessFlags) IsEnum() bool                   { return f.Has(FieldEnum) }
+
+// Names returns the JVMS names of the set flags, e.g. [ACC_PRIVATE ACC_FINAL].
+func (f FieldAccessFlags) Names() []string {
+	return flagNames(uint16(f), fieldFlagTable)
+}
+
+// String returns the flags as Java source modifiers, e.g. "public static final".
+func (f FieldAccessFlags) String() string {
+	return flagKeywords(uint16(f), fieldFlagTable)
+}
+
+// Validate checks the flags against the rules of JVMS 4.5 for a field declared
+// in a class, or in an interface if inInterface is set, returning every
+// violation found.
+func (f FieldAccessFlags) Validate(inInterface bool) error {
+	var errs []error
+	if countSet(uint16(f), uint16(FieldPublic), uint16(FieldPrivate), uint16(FieldProtected)) > 1 {
+		errs = append(errs, fmt.Errorf("field has more than one of ACC_PUBLIC, ACC_PRIVATE and ACC_PROTECTED"))
+	}
+	if f.IsFinal() && f.IsVolatile() {
+		errs = append(errs, fmt.Errorf("field is both ACC_FINAL and ACC_VOLATILE"))
+	}
+	if inInterface {
+		if !f.Has(FieldPublic | FieldStatic | FieldFinal) {
+			errs = append(errs, fmt.Errorf("interface field is not ACC_PUBLIC, ACC_STATIC and ACC_FINAL"))
+		}
+		if extra := f &^ (FieldPublic | FieldStatic | FieldFinal | FieldSynthetic); extra != 0 {
+			errs = append(errs, fmt.Errorf("interface field has %v set", extra.Names()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// MethodAccessFlags are the access_flags of a method_info, see JVMS table 4.6-A.
+type MethodAccessFlags uint16
+
+const (
+	MethodPublic       MethodAccessFlags = 0x0001
+	MethodPrivate      MethodAccessFlags = 0x0002
+	MethodProtected    MethodAccessFlags = 0x0004
+	MethodStatic       MethodAccessFlags = 0x0008
+	MethodFinal        MethodAccessFlags = 0x0010
+	MethodSynchronized MethodAccessFlags = 0x0020
+	MethodBridge       MethodAccessFlags = 0x0040
+	MethodVarargs      MethodAccessFlags = 0x0080
+	MethodNative       MethodAccessFlags = 0x0100
+	MethodAbstract     MethodAccessFlags = 0x0400
+	MethodStrict       MethodAccessFlags = 0x0800
+	MethodSynthetic    MethodAccessFlags = 0x1000
+)
+
+var methodFlagTable = []flagInfo{
+	{0x0001, "ACC_PUBLIC", "public"},
+	{0x0002, "ACC_PRIVATE", "private"},
+	{0x0004, "ACC_PROTECTED", "protected"},
+	{0x0400, "ACC_ABSTRACT", "abstract"},
+	{0x0008, "ACC_STATIC", "static"},
+	{0x0010, "ACC_FINAL", "final"},
+	{0x0020, "ACC_SYNCHRONIZED", "synchronized"},
+	{0x0040, "ACC_BRIDGE", ""},
+	{0x0080, "ACC_VARARGS", ""},
+	{0x0100, "ACC_NATIVE", "native"},
+	{0x0800, "ACC_STRICT", "strictfp"},
+	{0x1000, "ACC_SYNTHETIC", ""},
+}
+
+func (f MethodAccessFlags) Has(flag MethodAccessFlags) bool { return f&flag == flag }
+func (f MethodAccessFlags) IsPublic() bool                  { return f.Has(MethodPublic) }
+func (f MethodAccessFlags) IsPrivate() bool                 { return f.Has(MethodPrivate) }
+func (f MethodAccessFlags) IsProtected() bool               { return f.Has(MethodProtected) }
+func (f MethodAccessFlags) IsStatic() bool                  { return f.Has(MethodStatic) }
+func (f MethodAccessFlags) IsFinal() bool                   { return f.Has(MethodFinal) }
+func (f MethodAccessFlags) IsSynchronized() bool            { return f.Has(MethodSynchronized) }
+func (f MethodAccessFlags) IsBridge() bool                  { return f.Has(MethodBridge) }
+func (f MethodAccessFlags) IsVarargs() bool                 { return f.Has(MethodVarargs) }
+func (f MethodAccessFlags) IsNative() bool                  { return f.Has(MethodNative) }
+func (f MethodAccessFlags) IsAbstract() bool                { return f.Has(MethodAbstract) }
+func (f MethodAccessFlags) IsStrict() bool                  { return f.Has(MethodStrict) }
+func (f MethodAccessFlags) IsSynthetic() bool               { return f.Has(MethodSynthetic) }
+
+// Names returns the JVMS names of the set flags, e.g. [ACC_PUBLIC ACC_STATIC].
+func (f MethodAccessFlags) Names() []string {
+	return flagNames(uint16(f), methodFlagTable)
+}
+
+// String returns the flags as Java source modifiers, e.g. "public static synchronized".
+func (f MethodAccessFlags) String() string {
+	return flagKeywords(uint16(f), methodFlagTable)
+}
+
+// Validate checks the flags against the rules of JVMS 4.6 for the named
+// method of a class, or of an interface if inInterface is set, in a class file
+// with the given major version. It returns every violation found.
+func (f MethodAccessFlags) Validate(name string, inInterface bool, majorVersion uint16) error {
+	var errs []error
+	if name == "<clinit>" {
+		// Class initializers ignore all flags but ACC_STATIC, which is required from version 51
+		if majorVersion >= 51 && !f.IsStatic() {
+			errs = append(errs, fmt.Errorf("<clinit> is not ACC_STATIC"))
+		}
+		return errors.Join(errs...)
+	}
+
+	if countSet(uint16(f), uint16(MethodPublic), uint16(MethodPrivate), uint16(MethodProtected)) > 1 {
+		errs = append(errs, fmt.Errorf("method has more than one of ACC_PUBLIC, ACC_PRIVATE and ACC_PROTECTED"))
+	}
+
+	if inInterface {
+		if majorVersion < 52 {
+			if !f.Has(MethodPublic | MethodAbstract) {
+				errs = append(errs, fmt.Errorf("interface method is not ACC_PUBLIC and ACC_ABSTRACT"))
+			}
+		} else if countSet(uint16(f), uint16(MethodPublic), uint16(MethodPrivate)) != 1 {
+			errs = append(errs, fmt.Errorf("interface method does not have exactly one of ACC_PUBLIC and ACC_PRIVATE"))
+		}
+		for _, flag := range []MethodAccessFlags{MethodProtected, MethodFinal, MethodSynchronized, MethodNative} {
+			if f.Has(flag) {
+				errs = append(errs, fmt.Errorf("interface method has %s set", flag.Names()[0]))
+			}
+		}
+	}
+
+	if f.IsAbstract() {
+		forbidden := []MethodAccessFlags{MethodPrivate, MethodStatic, MethodFinal, MethodSynchronized, MethodNative}
+		if majorVersion >= 46 && majorVersion < 61 {
+			forbidden = append(forbidden, MethodStrict)
+		}
+		for _, flag := range forbidden {
+			if f.Has(flag) {
+				errs = append(errs, fmt.Errorf("abstract method has %s set", flag.Names()[0]))
+			}
+		}
+	}
+
+	if name == "<init>" {
+		allowed := MethodPublic | MethodPrivate | MethodProtected | MethodVarargs | MethodStrict | MethodSynthetic
+		if extra := f &^ allowed; extra != 0 {
+			errs = append(errs, fmt.Errorf("<init> has %v set", extra.Names()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// InnerClassAccessFlags are the inner_class_access_flags of an InnerClasses
+// entry, see JVMS table 4.7.6-A.
+type InnerClassAccessFlags uint16
+
+const (
+	InnerClassPublic     InnerClassAccessFlags = 0x0001
+	InnerClassPrivate    InnerClassAccessFlags = 0x0002
+	InnerClassProtected  InnerClassAccessFlags = 0x0004
+	InnerClassStatic     InnerClassAccessFlags = 0x0008
+	InnerClassFinal      InnerClassAccessFlags = 0x0010
+	InnerClassInterface  InnerClassAccessFlags = 0x0200
+	InnerClassAbstract   InnerClassAccessFlags = 0x0400
+	InnerClassSynthetic  InnerClassAccessFlags = 0x1000
+	InnerClassAnnotation InnerClassAccessFlags = 0x2000
+	InnerClassEnum       InnerClassAccessFlags = 0x4000
+)
+
+var innerClassFlagTable = []flagInfo{
+	{0x0001, "ACC_PUBLIC", "public"},
+	{0x0002, "ACC_PRIVATE", "private"},
+	{0x0004, "ACC_PROTECTED", "protected"},
+	{0x0008, "ACC_STATIC", "static"},
+	{0x0010, "ACC_FINAL", "final"},
+	{0x0200, "ACC_INTERFACE", ""},
+	{0x0400, "ACC_ABSTRACT", "abstract"},
+	{0x1000, "ACC_SYNTHETIC", ""},
+	{0x2000, "ACC_ANNOTATION", ""},
+	{0x4000, "ACC_ENUM", ""},
+}
+
+func (f InnerClassAccessFlags) Has(flag InnerClassAccessFlags) bool { return f&flag == flag }
+func (f InnerClassAccessFlags) IsPublic() bool                      { return f.Has(InnerClassPublic) }
+func (f InnerClassAccessFlags) IsPrivate() bool                     { return f.Has(InnerClassPrivate) }
+func (f InnerClassAccessFlags) IsProtected() bool                   { return f.Has(InnerClassProtected) }
+func (f InnerClassAccessFlags) IsStatic() bool                      { return f.Has(InnerClassStatic) }
+func (f InnerClassAccessFlags) IsFinal() bool                       { return f.Has(InnerClassFinal) }
+func (f InnerClassAccessFlags) IsInterface() bool                   { return f.Has(InnerClassInterface) }
+func (f InnerClassAccessFlags) IsAbstract() bool                    { return f.Has(InnerClassAbstract) }
+func (f InnerClassAccessFlags) IsSynthetic() bool                   { return f.Has(InnerClassSynthetic) }
+func (f InnerClassAccessFlags) IsAnnotation() bool                  { return f.Has(InnerClassAnnotation) }
+func (f InnerClassAccessFlags) IsEnum() bool                        { return f.Has(InnerClassEnum) }
+
+// Names returns the JVMS names of the set flags.
+func (f InnerClassAccessFlags) Names() []string {
+	return flagNames(uint16(f), innerClassFlagTable)
+}
+
+// String returns the flags as Java source modifiers, e.g. "public static".
+// Interfaces are implicitly abstract, so abstract is omitted for them.
+func (f InnerClassAccessFlags) String() string {
+	if f.IsInterface() {
+		f &^= InnerClassAbstract
+	}
+	return flagKeywords(uint16(f), innerClassFlagTable)
+}
+
+// Validate checks the flags of a nested class against the rules JVMS 4.1 gives
+// for classes, returning every violation found.
+func (f InnerClassAccessFlags) Validate() error {
+	var errs []error
+	if countSet(uint16(f), uint16(InnerClassPublic), uint16(InnerClassPrivate), uint16(InnerClassProtected)) > 1 {
+		errs = append(errs, fmt.Errorf("nested class has more than one of ACC_PUBLIC, ACC_PRIVATE and ACC_PROTECTED"))
+	}
+	if f.IsInterface() {
+		if !f.IsAbstract() {
+			errs = append(errs, fmt.Errorf("nested interface is not ACC_ABSTRACT"))
+		}
+		if f.IsFinal() || f.IsEnum() {
+			errs = append(errs, fmt.Errorf("nested interface has ACC_FINAL or ACC_ENUM set"))
+		}
+	} else {
+		if f.IsAnnotation() {
+			errs = append(errs, fmt.Errorf("ACC_ANNOTATION is set on a nested class that is not an interface"))
+		}
+		if f.IsFinal() && f.IsAbstract() {
+			errs = append(errs, fmt.Errorf("nested class is both ACC_FINAL and ACC_ABSTRACT"))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ModuleAccessFlags are the module_flags of a Module attribute.
+type ModuleAccessFlags uint16
+
+const (
+	ModuleOpen      ModuleAccessFlags = 0x0020
+	ModuleSynthetic ModuleAccessFlags = 0x1000
+	ModuleMandated  ModuleAccessFlags = 0x8000
+)
+
+var moduleFlagTable = []flagInfo{
+	{0x0020, "ACC_OPEN", "open"},
+	{0x1000, "ACC_SYNTHETIC", ""},
+	{0x8000, "ACC_MANDATED", ""},
+}
+
+func (f ModuleAccessFlags) Has(flag ModuleAccessFlags) bool { return f&flag == flag }
+func (f ModuleAccessFlags) IsOpen() bool                    { return f.Has(ModuleOpen) }
+func (f ModuleAccessFlags) IsSynthetic() bool               { return f.Has(ModuleSynthetic) }
+func (f ModuleAccessFlags) IsMandated() bool                { return f.Has(ModuleMandated) }
+
+// Names returns the JVMS names of the set flags.
+func (f ModuleAccessFlags) Names() []string {
+	return flagNames(uint16(f), moduleFlagTable)
+}
+
+// String returns the flags as Java source modifiers, i.e. "open" or "".
+func (f ModuleAccessFlags) String() string {
+	return flagKeywords(uint16(f), moduleFlagTable)
+}
+
+// ModuleRequiresAccessFlags are the requires_flags of a requires directive.
+type ModuleRequiresAccessFlags uint16
+
+const (
+	RequiresTransitive  ModuleRequiresAccessFlags = 0x0020
+	RequiresStaticPhase ModuleRequiresAccessFlags = 0x0040
+	RequiresSynthetic   ModuleRequiresAccessFlags = 0x1000
+	RequiresMandated    ModuleRequiresAccessFlags = 0x8000
+)
+
+var moduleRequiresFlagTable = []flagInfo{
+	{0x0020, "ACC_TRANSITIVE", "transitive"},
+	{0x0040, "ACC_STATIC_PHASE", "static"},
+	{0x1000, "ACC_SYNTHETIC", ""},
+	{0x8000, "ACC_MANDATED", ""},
+}
+
+func (f ModuleRequiresAccessFlags) Has(flag ModuleRequiresAccessFlags) bool { return f&flag == flag }
+func (f ModuleRequiresAccessFlags) IsTransitive() bool                      { return f.Has(RequiresTransitive) }
+func (f ModuleRequiresAccessFlags) IsStaticPhase() bool                     { return f.Has(RequiresStaticPhase) }
+func (f ModuleRequiresAccessFlags) IsSynthetic() bool                       { return f.Has(RequiresSynthetic) }
+func (f ModuleRequiresAccessFlags) IsMandated() bool                        { return f.Has(RequiresMandated) }
+
+// Names returns the JVMS names of the set flags.
+func (f ModuleRequiresAccessFlags) Names() []string {
+	return flagNames(uint16(f), moduleRequiresFlagTable)
+}
+
+// String returns the flags as Java source modifiers, e.g. "transitive static".
+func (f ModuleRequiresAccessFlags) String() string {
+	return flagKeywords(uint16(f), moduleRequiresFlagTable)
+}
+
+// ModuleExportsAccessFlags are the exports_flags and opens_flags of exports
+// and opens directives.
+type ModuleExportsAccessFlags uint16
+
+const (
+	ExportsSynthetic ModuleExportsAccessFlags = 0x1000
+	ExportsMandated  ModuleExportsAccessFlags = 0x8000
+)
+
+var moduleExportsFlagTable = []flagInfo{
+	{0x1000, "ACC_SYNTHETIC", ""},
+	{0x8000, "ACC_MANDATED", ""},
+}
+
+func (f ModuleExportsAccessFlags) Has(flag ModuleExportsAccessFlags) bool { return f&flag == flag }
+func (f ModuleExportsAccessFlags) IsSynthetic() bool                      { return f.Has(ExportsSynthetic) }
+func (f ModuleExportsAccessFlags) IsMandated() bool                       { return f.Has(ExportsMandated) }
+
+// Names returns the JVMS names of the set flags.
+func (f ModuleExportsAccessFlags) Names() []string {
+	return flagNames(uint16(f), moduleExportsFlagTable)
+}
+
+// String returns the flags as Java source modifiers, which is always empty
+// since exports and opens flags have no source form.
+func (f ModuleExportsAccessFlags) String() string {
+	return flagKeywords(uint16(f), moduleExportsFlagTable)
+}
+
+// MethodParameterAccessFlags are the access_flags of a MethodParameters entry.
+type MethodParameterAccessFlags uint16
+
+const (
+	ParameterFinal     MethodParameterAccessFlags = 0x0010
+	ParameterSynthetic MethodParameterAccessFlags = 0x1000
+	ParameterMandated  MethodParameterAccessFlags = 0x8000
+)
+
+var methodParameterFlagTable = []flagInfo{
+	{0x0010, "ACC_FINAL", "final"},
+	{0x1000, "ACC_SYNTHETIC", ""},
+	{0x8000, "ACC_MANDATED", ""},
+}
+
+func (f MethodParameterAccessFlags) Has(flag MethodParameterAccessFlags) bool { return f&flag == flag }
+func (f MethodParameterAccessFlags) IsFinal() bool                            { return f.Has(ParameterFinal) }
+func (f MethodParameterAccessFlags) IsSynthetic() bool                        { return f.Has(ParameterSynthetic) }
+func (f MethodParameterAccessFlags) IsMandated() bool                         { return f.Has(ParameterMandated) }
+
+// Names returns the JVMS names of the set flags.
+func (f MethodParameterAccessFlags) Names() []string {
+	return flagNames(uint16(f), methodParameterFlagTable)
+}
+
+// String returns the flags as Java source modifiers, i.e. "final" or "".
+func (f MethodParameterAccessFlags) String() string {
+	return flagKeywords(uint16(f), methodParameterFlagTable)
+}
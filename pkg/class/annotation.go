@@ -0,0 +1,750 @@
+package class
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// Annotation is a single annotation of a class, field, method, parameter,
+// record component or type use.
+// See https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.16
+type Annotation struct {
+	TypeIndex uint16
+	// Type is the field descriptor of the annotation interface, e.g. "Lorg/junit/Test;"
+	Type              string
+	ElementValuePairs []ElementValuePair
+}
+
+// Element returns the value of the named element, or nil if the annotation
+// does not set it explicitly.
+func (a *Annotation) Element(name string) *ElementValue {
+	for i := range a.ElementValuePairs {
+		if a.ElementValuePairs[i].ElementName == name {
+			return &a.ElementValuePairs[i].Value
+		}
+	}
+	return nil
+}
+
+type ElementValuePair struct {
+	ElementNameIndex uint16
+	ElementName      string
+	Value            ElementValue
+}
+
+// ElementValue is the value of an annotation element. Tag determines which of
+// the remaining fields is set:
+//
+//	B C D F I J S Z s  ConstValueIndex
+//	e                  TypeNameIndex and ConstNameIndex
+//	c                  ClassInfoIndex
+//	@                  AnnotationValue
+//	[                  ArrayValue
+type ElementValue struct {
+	Tag             byte
+	ConstValueIndex uint16
+	TypeNameIndex   uint16
+	ConstNameIndex  uint16
+	ClassInfoIndex  uint16
+	AnnotationValue *Annotation
+	ArrayValue      []ElementValue
+}
+
+func readAnnotation(reader *bytes.Reader, cp *ConstantPool, annotation *Annotation) error {
+	if err := binary.Read(reader, binary.BigEndian, &annotation.TypeIndex); err != nil {
+		return err
+	}
+	annotation.Type = cp.GetConstantName(annotation.TypeIndex)
+
+	var count uint16
+	if err := binary.Read(reader, binary.BigEndian, &count); err != nil {
+		return err
+	}
+	// A pair is a name index and an element value of at least three bytes
+	if err := checkCount(reader, uint32(count), 5); err != nil {
+		return err
+	}
+	annotation.ElementValuePairs = make([]ElementValuePair, count)
+	for i := range annotation.ElementValuePairs {
+		pair := &annotation.ElementValuePairs[i]
+		if err := binary.Read(reader, binary.BigEndian, &pair.ElementNameIndex); err != nil {
+			return err
+		}
+		pair.ElementName = cp.GetConstantName(pair.ElementNameIndex)
+		if err := readElementValue(reader, cp, &pair.Value); err != nil {
+			return fmt.Errorf("reading element %q: %w", pair.ElementName, err)
+		}
+	}
+	return nil
+}
+
+func readElementValue(reader *bytes.Reader, cp *ConstantPool, value *ElementValue) error {
+	if err := binary.Read(reader, binary.BigEndian, &value.Tag); err != nil {
+		return err
+	}
+
+	switch value.Tag {
+	case 'B', 'C', 'D', 'F', 'I', 'J', 'S', 'Z', 's':
+		return binary.Read(reader, binary.BigEndian, &value.ConstValueIndex)
+	case 'e':
+		if err := binary.Read(reader, binary.BigEndian, &value.TypeNameIndex); err != nil {
+			return err
+		}
+		return binary.Read(reader, binary.BigEndian, &value.ConstNameIndex)
+	case 'c':
+		return binary.Read(reader, binary.BigEndian, &value.ClassInfoIndex)
+	case '@':
+		value.AnnotationValue = &Annotation{}
+		return readAnnotation(reader, cp, value.AnnotationValue)
+	case '[':
+		var count uint16
+		if err := binary.Read(reader, binary.BigEndian, &count); err != nil {
+			return err
+		}
+		if err := checkCount(reader, uint32(count), 3); err != nil {
+			return err
+		}
+		value.ArrayValue = make([]ElementValue, count)
+		for i := range value.ArrayValue {
+			if err := readElementValue(reader, cp, &value.ArrayValue[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid element value tag %q", value.Tag)
+	}
+}
+
+func readAnnotations(reader *bytes.Reader, cp *ConstantPool) ([]Annotation, error) {
+	var count uint16
+	if err := binary.Read(reader, binary.BigEndian, &count); err != nil {
+		return nil, err
+	}
+	if err := checkCount(reader, uint32(count), 4); err != nil {
+		return nil, err
+	}
+	annotations := make([]Annotation, count)
+	for i := range annotations {
+		if err := readAnnotation(reader, cp, &annotations[i]); err != nil {
+			return nil, fmt.Errorf("reading annotation %d: %w", i, err)
+		}
+	}
+	return annotations, nil
+}
+
+func writeAnnotation(w *bytes.Buffer, annotation *Annotation) error {
+	put(w, annotation.TypeIndex)
+	if err := putCount(w, len(annotation.ElementValuePairs)); err != nil {
+		return err
+	}
+	for i := range annotation.ElementValuePairs {
+		pair := &annotation.ElementValuePairs[i]
+		put(w, pair.ElementNameIndex)
+		if err := writeElementValue(w, &pair.Value); err != nil {
+			return fmt.Errorf("writing element %q: %w", pair.ElementName, err)
+		}
+	}
+	return nil
+}
+
+func writeElementValue(w *bytes.Buffer, value *ElementValue) error {
+	put(w, value.Tag)
+	switch value.Tag {
+	case 'B', 'C', 'D', 'F', 'I', 'J', 'S', 'Z', 's':
+		put(w, value.ConstValueIndex)
+	case 'e':
+		put(w, value.TypeNameIndex)
+		put(w, value.ConstNameIndex)
+	case 'c':
+		put(w, value.ClassInfoIndex)
+	case '@':
+		if value.AnnotationValue == nil {
+			return fmt.Errorf("annotation element value has no annotation")
+		}
+		return writeAnnotation(w, value.AnnotationValue)
+	case '[':
+		if err := putCount(w, len(value.ArrayValue)); err != nil {
+			return err
+		}
+		for i := range value.ArrayValue {
+			if err := writeElementValue(w, &value.ArrayValue[i]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("invalid element value tag %q", value.Tag)
+	}
+	return nil
+}
+
+func writeAnnotations(w *bytes.Buffer, annotations []Annotation) error {
+	if err := putCount(w, len(annotations)); err != nil {
+		return err
+	}
+	for i := range annotations {
+		if err := writeAnnotation(w, &annotations[i]); err != nil {
+			return fmt.Errorf("writing annotation %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// RuntimeVisibleAnnotationsAttribute holds the annotations that are visible
+// through reflection.
+// See https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.16
+type RuntimeVisibleAnnotationsAttribute struct {
+	Annotations []Annotation
+}
+
+func decodeRuntimeVisibleAnnotationsAttribute(info []byte, cp *ConstantPool) (interface{}, error) {
+	reader := bytes.NewReader(info)
+	annotations, err := readAnnotations(reader, cp)
+	if err != nil {
+		return nil, err
+	}
+	return &RuntimeVisibleAnnotationsAttribute{Annotations: annotations}, checkFullyRead(reader)
+}
+
+func (a *RuntimeVisibleAnnotationsAttribute) encode(w *bytes.Buffer) error {
+	return writeAnnotations(w, a.Annotations)
+}
+
+// RuntimeInvisibleAnnotationsAttribute holds the annotations that are retained
+// in the class file but not visible through reflection.
+// See https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.17
+type RuntimeInvisibleAnnotationsAttribute struct {
+	Annotations []Annotation
+}
+
+func decodeRuntimeInvisibleAnnotationsAttribute(info []byte, cp *ConstantPool) (interface{}, error) {
+	reader := bytes.NewReader(info)
+	annotations, err := readAnnotations(reader, cp)
+	if err != nil {
+		return nil, err
+	}
+	return &RuntimeInvisibleAnnotationsAttribute{Annotations: annotations}, checkFullyRead(reader)
+}
+
+func (a *RuntimeInvisibleAnnotationsAttribute) encode(w *bytes.Buffer) error {
+	return writeAnnotations(w, a.Annotations)
+}
+
+func readParameterAnnotations(reader *bytes.Reader, cp *ConstantPool) ([][]Annotation, error) {
+	var numParameters uint8
+	if err := binary.Read(reader, binary.BigEndian, &numParameters); err != nil {
+		return nil, err
+	}
+	if err := checkCount(reader, uint32(numParameters), 2); err != nil {
+		return nil, err
+	}
+	parameters := make([][]Annotation, numParameters)
+	for i := range parameters {
+		annotations, err := readAnnotations(reader, cp)
+		if err != nil {
+			return nil, fmt.Errorf("reading parameter %d: %w", i, err)
+		}
+		parameters[i] = annotations
+	}
+	return parameters, nil
+}
+
+func writeParameterAnnotations(w *bytes.Buffer, parameters [][]Annotation) error {
+	if err := putCount8(w, len(parameters)); err != nil {
+		return err
+	}
+	for i, annotations := range parameters {
+		if err := writeAnnotations(w, annotations); err != nil {
+			return fmt.Errorf("writing parameter %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// RuntimeVisibleParameterAnnotationsAttribute holds the visible annotations of
+// each formal parameter of a method.
+// See https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.18
+type RuntimeVisibleParameterAnnotationsAttribute struct {
+	ParameterAnnotations [][]Annotation
+}
+
+func decodeRuntimeVisibleParameterAnnotationsAttribute(info []byte, cp *ConstantPool) (interface{}, error) {
+	reader := bytes.NewReader(info)
+	parameters, err := readParameterAnnotations(reader, cp)
+	if err != nil {
+		return nil, err
+	}
+	return &RuntimeVisibleParameterAnnotationsAttribute{ParameterAnnotations: parameters}, checkFullyRead(reader)
+}
+
+func (a *RuntimeVisibleParameterAnnotationsAttribute) encode(w *bytes.Buffer) error {
+	return writeParameterAnnotations(w, a.ParameterAnnotations)
+}
+
+// RuntimeInvisibleParameterAnnotationsAttribute holds the invisible
+// annotations of each formal parameter of a method.
+// See https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.19
+type RuntimeInvisibleParameterAnnotationsAttribute struct {
+	ParameterAnnotations [][]Annotation
+}
+
+func decodeRuntimeInvisibleParameterAnnotationsAttribute(info []byte, cp *ConstantPool) (interface{}, error) {
+	reader := bytes.NewReader(info)
+	parameters, err := readParameterAnnotations(reader, cp)
+	if err != nil {
+		return nil, err
+	}
+	return &RuntimeInvisibleParameterAnnotationsAttribute{ParameterAnnotations: parameters}, checkFullyRead(reader)
+}
+
+func (a *RuntimeInvisibleParameterAnnotationsAttribute) encode(w *bytes.Buffer) error {
+	return writeParameterAnnotations(w, a.ParameterAnnotations)
+}
+
+// Type annotation target types, see JVMS table 4.7.20-A and 4.7.20-B
+const (
+	TargetClassTypeParameter           uint8 = 0x00
+	TargetMethodTypeParameter          uint8 = 0x01
+	TargetClassExtends                 uint8 = 0x10
+	TargetClassTypeParameterBound      uint8 = 0x11
+	TargetMethodTypeParameterBound     uint8 = 0x12
+	TargetField                        uint8 = 0x13
+	TargetMethodReturn                 uint8 = 0x14
+	TargetMethodReceiver               uint8 = 0x15
+	TargetMethodFormalParameter        uint8 = 0x16
+	TargetThrows                       uint8 = 0x17
+	TargetLocalVariable                uint8 = 0x40
+	TargetResourceVariable             uint8 = 0x41
+	TargetExceptionParameter           uint8 = 0x42
+	TargetInstanceOf                   uint8 = 0x43
+	TargetNew                          uint8 = 0x44
+	TargetConstructorReference         uint8 = 0x45
+	TargetMethodReference              uint8 = 0x46
+	TargetCast                         uint8 = 0x47
+	TargetConstructorInvocationTypeArg uint8 = 0x48
+	TargetMethodInvocationTypeArg      uint8 = 0x49
+	TargetConstructorReferenceTypeArg  uint8 = 0x4A
+	TargetMethodReferenceTypeArg       uint8 = 0x4B
+)
+
+type LocalVarTargetEntry struct {
+	StartPc uint16
+	Length  uint16
+	Index   uint16
+}
+
+// TypeAnnotationTarget identifies the annotated type use. The fields that are
+// set depend on the target type of the annotation.
+type TypeAnnotationTarget struct {
+	// type_parameter_target and type_parameter_bound_target
+	TypeParameterIndex uint8
+	// supertype_target
+	SupertypeIndex uint16
+	// type_parameter_bound_target
+	BoundIndex uint8
+	// formal_parameter_target
+	FormalParameterIndex uint8
+	// throws_target
+	ThrowsTypeIndex uint16
+	// localvar_target
+	LocalVarTable []LocalVarTargetEntry
+	// catch_target
+	ExceptionTableIndex uint16
+	// offset_target and type_argument_target
+	Offset uint16
+	// type_argument_target
+	TypeArgumentIndex uint8
+}
+
+// TypePathEntry is one step into a nested, array, wildcard or parameterized type.
+type TypePathEntry struct {
+	TypePathKind      uint8
+	TypeArgumentIndex uint8
+}
+
+// TypeAnnotation is an annotation on a use of a type.
+// See https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.20
+type TypeAnnotation struct {
+	TargetType uint8
+	TargetInfo TypeAnnotationTarget
+	TargetPath []TypePathEntry
+	Annotation
+}
+
+func readTypeAnnotation(reader *bytes.Reader, cp *ConstantPool, annotation *TypeAnnotation) error {
+	if err := binary.Read(reader, binary.BigEndian, &annotation.TargetType); err != nil {
+		return err
+	}
+
+	target := &annotation.TargetInfo
+	var err error
+	switch annotation.TargetType {
+	case TargetClassTypeParameter, TargetMethodTypeParameter:
+		err = binary.Read(reader, binary.BigEndian, &target.TypeParameterIndex)
+	case TargetClassExtends:
+		err = binary.Read(reader, binary.BigEndian, &target.SupertypeIndex)
+	case TargetClassTypeParameterBound, TargetMethodTypeParameterBound:
+		if err = binary.Read(reader, binary.BigEndian, &target.TypeParameterIndex); err == nil {
+			err = binary.Read(reader, binary.BigEndian, &target.BoundIndex)
+		}
+	case TargetField, TargetMethodReturn, TargetMethodReceiver:
+	case TargetMethodFormalParameter:
+		err = binary.Read(reader, binary.BigEndian, &target.FormalParameterIndex)
+	case TargetThrows:
+		err = binary.Read(reader, binary.BigEndian, &target.ThrowsTypeIndex)
+	case TargetLocalVariable, TargetResourceVariable:
+		var length uint16
+		if err = binary.Read(reader, binary.BigEndian, &length); err == nil {
+			if err = checkCount(reader, uint32(length), 6); err == nil {
+				target.LocalVarTable = make([]LocalVarTargetEntry, length)
+				err = binary.Read(reader, binary.BigEndian, target.LocalVarTable)
+			}
+		}
+	case TargetExceptionParameter:
+		err = binary.Read(reader, binary.BigEndian, &target.ExceptionTableIndex)
+	case TargetInstanceOf, TargetNew, TargetConstructorReference, TargetMethodReference:
+		err = binary.Read(reader, binary.BigEndian, &target.Offset)
+	case TargetCast, TargetConstructorInvocationTypeArg, TargetMethodInvocationTypeArg,
+		TargetConstructorReferenceTypeArg, TargetMethodReferenceTypeArg:
+		if err = binary.Read(reader, binary.BigEndian, &target.Offset); err == nil {
+			err = binary.Read(reader, binary.BigEndian, &target.TypeArgumentIndex)
+		}
+	default:
+		return fmt.Errorf("invalid type annotation target type 0x%02X", annotation.TargetType)
+	}
+	if err != nil {
+		return err
+	}
+
+	var pathLength uint8
+	if err := binary.Read(reader, binary.BigEndian, &pathLength); err != nil {
+		return err
+	}
+	if err := checkCount(reader, uint32(pathLength), 2); err != nil {
+		return err
+	}
+	annotation.TargetPath = make([]TypePathEntry, pathLength)
+	if err := binary.Read(reader, binary.BigEndian, annotation.TargetPath); err != nil {
+		return err
+	}
+
+	return readAnnotation(reader, cp, &annotation.Annotation)
+}
+
+func readTypeAnnotations(reader *bytes.Reader, cp *ConstantPool) ([]TypeAnnotation, error) {
+	var count uint16
+	if err := binary.Read(reader, binary.BigEndian, &count); err != nil {
+		return nil, err
+	}
+	// A target type, a path length and an annotation with no elements
+	if err := checkCount(reader, uint32(count), 6); err != nil {
+		return nil, err
+	}
+	annotations := make([]TypeAnnotation, count)
+	for i := range annotations {
+		if err := readTypeAnnotation(reader, cp, &annotations[i]); err != nil {
+			return nil, fmt.Errorf("reading type annotation %d: %w", i, err)
+		}
+	}
+	return annotations, nil
+}
+
+func writeTypeAnnotation(w *bytes.Buffer, annotation *TypeAnnotation) error {
+	put(w, annotation.TargetType)
+
+	target := &annotation.TargetInfo
+	switch annotation.TargetType {
+	case TargetClassTypeParameter, TargetMethodTypeParameter:
+		put(w, target.TypeParameterIndex)
+	case TargetClassExtends:
+		put(w, target.SupertypeIndex)
+	case TargetClassTypeParameterBound, TargetMethodTypeParameterBound:
+		put(w, target.TypeParameterIndex)
+		put(w, target.BoundIndex)
+	case TargetField, TargetMethodReturn, TargetMethodReceiver:
+	case TargetMethodFormalParameter:
+		put(w, target.FormalParameterIndex)
+	case TargetThrows:
+		put(w, target.ThrowsTypeIndex)
+	case TargetLocalVariable, TargetResourceVariable:
+		if err := putCount(w, len(target.LocalVarTable)); err != nil {
+			return err
+		}
+		put(w, target.LocalVarTable)
+	case TargetExceptionParameter:
+		put(w, target.ExceptionTableIndex)
+	case TargetInstanceOf, TargetNew, TargetConstructorReference, TargetMethodReference:
+		put(w, target.Offset)
+	case TargetCast, TargetConstructorInvocationTypeArg, TargetMethodInvocationTypeArg,
+		TargetConstructorReferenceTypeArg, TargetMethodReferenceTypeArg:
+		put(w, target.Offset)
+		put(w, target.TypeArgumentIndex)
+	default:
+		return fmt.Errorf("invalid type annotation target type 0x%02X", annotation.TargetType)
+	}
+
+	if err := putCount8(w, len(annotation.TargetPath)); err != nil {
+		return err
+	}
+	put(w, annotation.TargetPath)
+
+	return writeAnnotation(w, &annotation.Annotation)
+}
+
+func writeTypeAnnotations(w *bytes.Buffer, annotations []TypeAnnotation) error {
+	if err := putCount(w, len(annotations)); err != nil {
+		return err
+	}
+	for i := range annotations {
+		if err := writeTypeAnnotation(w, &annotations[i]); err != nil {
+			return fmt.Errorf("writing type annotation %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// RuntimeVisibleTypeAnnotationsAttribute holds the visible annotations on
+// types used in a declaration or, inside Code, in an expression.
+// See https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.20
+type RuntimeVisibleTypeAnnotationsAttribute struct {
+	Annotations []TypeAnnotation
+}
+
+func decodeRuntimeVisibleTypeAnnotationsAttribute(info []byte, cp *ConstantPool) (interface{}, error) {
+	reader := bytes.NewReader(info)
+	annotations, err := readTypeAnnotations(reader, cp)
+	if err != nil {
+		return nil, err
+	}
+	return &RuntimeVisibleTypeAnnotationsAttribute{Annotations: annotations}, checkFullyRead(reader)
+}
+
+func (a *RuntimeVisibleTypeAnnotationsAttribute) encode(w *bytes.Buffer) error {
+	return writeTypeAnnotations(w, a.Annotations)
+}
+
+// RuntimeInvisibleTypeAnnotationsAttribute holds the invisible annotations on
+// types used in a declaration or, inside Code, in an expression.
+// See https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.21
+type RuntimeInvisibleTypeAnnotationsAttribute struct {
+	Annotations []TypeAnnotation
+}
+
+func decodeRuntimeInvisibleTypeAnnotationsAttribute(info []byte, cp *ConstantPool) (interface{}, error) {
+	reader := bytes.NewReader(info)
+	annotations, err := readTypeAnnotations(reader, cp)
+	if err != nil {
+		return nil, err
+	}
+	return &RuntimeInvisibleTypeAnnotationsAttribute{Annotations: annotations}, checkFullyRead(reader)
+}
+
+func (a *RuntimeInvisibleTypeAnnotationsAttribute) encode(w *bytes.Buffer) error {
+	return writeTypeAnnotations(w, a.Annotations)
+}
+
+// AnnotationDefaultAttribute holds the default value of an annotation
+// interface element.
+// See https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.22
+type AnnotationDefaultAttribute struct {
+	DefaultValue ElementValue
+}
+
+func decodeAnnotationDefaultAttribute(info []byte, cp *ConstantPool) (interface{}, error) {
+	reader := bytes.NewReader(info)
+	attr := &AnnotationDefaultAttribute{}
+	if err := readElementValue(reader, cp, &attr.DefaultValue); err != nil {
+		return nil, err
+	}
+	return attr, checkFullyRead(reader)
+}
+
+func (a *AnnotationDefaultAttribute) encode(w *bytes.Buffer) error {
+	return writeElementValue(w, &a.DefaultValue)
+}
+
+// annotations returns the visible and then the invisible annotations found in attributes.
+func annotations(attributes []Attribute) ([]Annotation, error) {
+	var result []Annotation
+	visible, err := attributeValue(attributes, "RuntimeVisibleAnnotations")
+	if err != nil {
+		return nil, err
+	}
+	if attr, ok := visible.(*RuntimeVisibleAnnotationsAttribute); ok {
+		result = append(result, attr.Annotations...)
+	}
+	invisible, err := attributeValue(attributes, "RuntimeInvisibleAnnotations")
+	if err != nil {
+		return nil, err
+	}
+	if attr, ok := invisible.(*RuntimeInvisibleAnnotationsAttribute); ok {
+		result = append(result, attr.Annotations...)
+	}
+	return result, nil
+}
+
+// findAnnotation returns the annotation with the given type descriptor, or nil.
+func findAnnotation(attributes []Attribute, descriptor string) (*Annotation, error) {
+	all, err := annotations(attributes)
+	if err != nil {
+		return nil, err
+	}
+	for _, annotation := range all {
+		if annotation.Type == descriptor {
+			annotation := annotation
+			return &annotation, nil
+		}
+	}
+	return nil, nil
+}
+
+// hasAnnotation reports whether attributes hold an annotation with the given type descriptor.
+func hasAnnotation(attributes []Attribute, descriptor string) (bool, error) {
+	annotation, err := findAnnotation(attributes, descriptor)
+	return annotation != nil, err
+}
+
+// typeAnnotations returns the visible and then the invisible type annotations found in attributes.
+func typeAnnotations(attributes []Attribute) ([]TypeAnnotation, error) {
+	var result []TypeAnnotation
+	visible, err := attributeValue(attributes, "RuntimeVisibleTypeAnnotations")
+	if err != nil {
+		return nil, err
+	}
+	if attr, ok := visible.(*RuntimeVisibleTypeAnnotationsAttribute); ok {
+		result = append(result, attr.Annotations...)
+	}
+	invisible, err := attributeValue(attributes, "RuntimeInvisibleTypeAnnotations")
+	if err != nil {
+		return nil, err
+	}
+	if attr, ok := invisible.(*RuntimeInvisibleTypeAnnotationsAttribute); ok {
+		result = append(result, attr.Annotations...)
+	}
+	return result, nil
+}
+
+// Annotations returns the visible and invisible annotations of the class.
+func (c *Class) Annotations() ([]Annotation, error) {
+	return annotations(c.Attributes)
+}
+
+// Annotation returns the annotation of the class with the given type
+// descriptor, e.g. "Ljava/lang/FunctionalInterface;", or nil.
+func (c *Class) Annotation(descriptor string) (*Annotation, error) {
+	return findAnnotation(c.Attributes, descriptor)
+}
+
+// HasAnnotation reports whether the class is annotated with the given type descriptor.
+func (c *Class) HasAnnotation(descriptor string) (bool, error) {
+	return hasAnnotation(c.Attributes, descriptor)
+}
+
+// TypeAnnotations returns the visible and invisible type annotations of the class.
+func (c *Class) TypeAnnotations() ([]TypeAnnotation, error) {
+	return typeAnnotations(c.Attributes)
+}
+
+// Annotations returns the visible and invisible annotations of the field.
+func (f *Field) Annotations() ([]Annotation, error) {
+	return annotations(f.Attributes)
+}
+
+// Annotation returns the annotation of the field with the given type descriptor, or nil.
+func (f *Field) Annotation(descriptor string) (*Annotation, error) {
+	return findAnnotation(f.Attributes, descriptor)
+}
+
+// HasAnnotation reports whether the field is annotated with the given type descriptor.
+func (f *Field) HasAnnotation(descriptor string) (bool, error) {
+	return hasAnnotation(f.Attributes, descriptor)
+}
+
+// TypeAnnotations returns the visible and invisible type annotations of the field.
+func (f *Field) TypeAnnotations() ([]TypeAnnotation, error) {
+	return typeAnnotations(f.Attributes)
+}
+
+// Annotations returns the visible and invisible annotations of the method.
+func (m *Method) Annotations() ([]Annotation, error) {
+	return annotations(m.Attributes)
+}
+
+// Annotation returns the annotation of the method with the given type
+// descriptor, e.g. "Lorg/junit/Test;", or nil.
+func (m *Method) Annotation(descriptor string) (*Annotation, error) {
+	return findAnnotation(m.Attributes, descriptor)
+}
+
+// HasAnnotation reports whether the method is annotated with the given type descriptor.
+func (m *Method) HasAnnotation(descriptor string) (bool, error) {
+	return hasAnnotation(m.Attributes, descriptor)
+}
+
+// TypeAnnotations returns the visible and invisible type annotations of the
+// method declaration. Type annotations inside the method body belong to its Code.
+func (m *Method) TypeAnnotations() ([]TypeAnnotation, error) {
+	return typeAnnotations(m.Attributes)
+}
+
+// ParameterAnnotations returns the visible and invisible annotations of each
+// formal parameter, indexed by parameter. Note that javac may omit synthetic
+// and implicit parameters, so the index does not always match the descriptor.
+func (m *Method) ParameterAnnotations() ([][]Annotation, error) {
+	var result [][]Annotation
+	merge := func(parameters [][]Annotation) {
+		for i, annotations := range parameters {
+			if i >= len(result) {
+				result = append(result, make([][]Annotation, i-len(result)+1)...)
+			}
+			result[i] = append(result[i], annotations...)
+		}
+	}
+	visible, err := attributeValue(m.Attributes, "RuntimeVisibleParameterAnnotations")
+	if err != nil {
+		return nil, err
+	}
+	if attr, ok := visible.(*RuntimeVisibleParameterAnnotationsAttribute); ok {
+		merge(attr.ParameterAnnotations)
+	}
+	invisible, err := attributeValue(m.Attributes, "RuntimeInvisibleParameterAnnotations")
+	if err != nil {
+		return nil, err
+	}
+	if attr, ok := invisible.(*RuntimeInvisibleParameterAnnotationsAttribute); ok {
+		merge(attr.ParameterAnnotations)
+	}
+	return result, nil
+}
+
+// HasParameterAnnotation reports whether the parameter at index is annotated
+// with the given type descriptor.
+func (m *Method) HasParameterAnnotation(index int, descriptor string) (bool, error) {
+	parameters, err := m.ParameterAnnotations()
+	if err != nil || index < 0 || index >= len(parameters) {
+		return false, err
+	}
+	for _, annotation := range parameters[index] {
+		if annotation.Type == descriptor {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// AnnotationDefault returns the default value of an annotation interface
+// element, or nil if the method has none.
+func (m *Method) AnnotationDefault() (*ElementValue, error) {
+	value, err := attributeValue(m.Attributes, "AnnotationDefault")
+	if attr, ok := value.(*AnnotationDefaultAttribute); ok {
+		return &attr.DefaultValue, nil
+	}
+	return nil, err
+}
+
+// TypeAnnotations returns the visible and invisible type annotations found in
+// the method body, such as those on casts and local variables.
+func (c *Code) TypeAnnotations() ([]TypeAnnotation, error) {
+	return typeAnnotations(c.Attributes)
+}
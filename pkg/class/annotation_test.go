@@ -0,0 +1,143 @@
+package class
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// info encodes values, each a uint8 or uint16, as attribute info.
+func info(values ...interface{}) []byte {
+	var buf bytes.Buffer
+	for _, v := range values {
+		put(&buf, v)
+	}
+	return buf.Bytes()
+}
+
+func TestAnnotationElementValues(t *testing.T) {
+	c := parseTestClass(t)
+	cp := &c.ConstantPool
+	typeA, typeB, typeC := utf8Index(t, c, "LA;"), utf8Index(t, c, "LB;"), utf8Index(t, c, "LC;")
+	nameI, nameE, nameC, nameN, nameA := utf8Index(t, c, "i"), utf8Index(t, c, "e"),
+		utf8Index(t, c, "c"), utf8Index(t, c, "n"), utf8Index(t, c, "a")
+	policy, runtime := utf8Index(t, c, "Ljava/lang/annotation/RetentionPolicy;"), utf8Index(t, c, "RUNTIME")
+	stringType, x, y := utf8Index(t, c, "Ljava/lang/String;"), utf8Index(t, c, "x"), utf8Index(t, c, "y")
+	answer, err := cp.AddInteger(42)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// @A(i = 42, e = RUNTIME, c = String.class, n = @B, a = {"x", "y"})
+	addClassAttribute(t, c, "RuntimeVisibleAnnotations", info(uint16(1),
+		typeA, uint16(5),
+		nameI, uint8('I'), answer,
+		nameE, uint8('e'), policy, runtime,
+		nameC, uint8('c'), stringType,
+		nameN, uint8('@'), typeB, uint16(0),
+		nameA, uint8('['), uint16(2), uint8('s'), x, uint8('s'), y))
+	addClassAttribute(t, c, "RuntimeInvisibleAnnotations", info(uint16(1), typeC, uint16(0)))
+
+	want := Annotation{TypeIndex: typeA, Type: "LA;", ElementValuePairs: []ElementValuePair{
+		{nameI, "i", ElementValue{Tag: 'I', ConstValueIndex: answer}},
+		{nameE, "e", ElementValue{Tag: 'e', TypeNameIndex: policy, ConstNameIndex: runtime}},
+		{nameC, "c", ElementValue{Tag: 'c', ClassInfoIndex: stringType}},
+		{nameN, "n", ElementValue{Tag: '@', AnnotationValue: &Annotation{
+			TypeIndex: typeB, Type: "LB;", ElementValuePairs: []ElementValuePair{}}}},
+		{nameA, "a", ElementValue{Tag: '[', ArrayValue: []ElementValue{
+			{Tag: 's', ConstValueIndex: x}, {Tag: 's', ConstValueIndex: y}}}},
+	}}
+
+	annotations, err := c.Annotations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(annotations) != 2 || annotations[1].Type != "LC;" {
+		t.Fatalf("Annotations() = %+v, want LA; and then the invisible LC;", annotations)
+	}
+	if !reflect.DeepEqual(annotations[0], want) {
+		t.Errorf("Annotations()[0] = %+v, want %+v", annotations[0], want)
+	}
+
+	a, err := c.Annotation("LA;")
+	if err != nil || a == nil {
+		t.Fatalf("Annotation(LA;) = %v, %v", a, err)
+	}
+	if e := a.Element("e"); e == nil || cp.GetConstantName(e.ConstNameIndex) != "RUNTIME" {
+		t.Errorf("Element(e) = %+v, want the enum constant RUNTIME", e)
+	}
+	if e := a.Element("missing"); e != nil {
+		t.Errorf("Element(missing) = %+v, want nil", e)
+	}
+
+	for _, test := range []struct {
+		descriptor string
+		want       bool
+	}{
+		{"LA;", true},
+		{"LC;", true},
+		{"LB;", false}, // nested annotations are element values, not annotations of the class
+		{"A", false},
+	} {
+		if got, err := c.HasAnnotation(test.descriptor); err != nil || got != test.want {
+			t.Errorf("HasAnnotation(%q) = %v, %v, want %v", test.descriptor, got, err, test.want)
+		}
+	}
+}
+
+func TestMethodParameterAnnotations(t *testing.T) {
+	c := parseTestClass(t)
+	m := c.FindMethod("main", "")
+	typeA, typeB := utf8Index(t, c, "LA;"), utf8Index(t, c, "LB;")
+	// Visible @A on parameter 0; invisible @B on parameter 1, which javac may
+	// record for synthetic parameters that the descriptor does not show
+	addAttribute(t, &c.ConstantPool, &m.Attributes, "RuntimeVisibleParameterAnnotations",
+		info(uint8(1), uint16(1), typeA, uint16(0)))
+	addAttribute(t, &c.ConstantPool, &m.Attributes, "RuntimeInvisibleParameterAnnotations",
+		info(uint8(2), uint16(0), uint16(1), typeB, uint16(0)))
+
+	parameters, err := m.ParameterAnnotations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parameters) != 2 || len(parameters[0]) != 1 || len(parameters[1]) != 1 {
+		t.Fatalf("ParameterAnnotations() = %+v, want one annotation on each of two parameters", parameters)
+	}
+	tests := []struct {
+		index      int
+		descriptor string
+		want       bool
+	}{
+		{0, "LA;", true},
+		{0, "LB;", false},
+		{1, "LB;", true},
+		{2, "LA;", false},
+		{-1, "LA;", false},
+	}
+	for _, test := range tests {
+		if got, err := m.HasParameterAnnotation(test.index, test.descriptor); err != nil || got != test.want {
+			t.Errorf("HasParameterAnnotation(%d, %q) = %v, %v, want %v", test.index, test.descriptor, got, err, test.want)
+		}
+	}
+}
+
+func TestAnnotationDefault(t *testing.T) {
+	c := parseTestClass(t)
+	m := c.FindMethod("foo", "()Z")
+	if value, err := m.AnnotationDefault(); err != nil || value != nil {
+		t.Errorf("AnnotationDefault() = %v, %v without the attribute, want nil", value, err)
+	}
+
+	yes, err := c.ConstantPool.AddInteger(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addAttribute(t, &c.ConstantPool, &m.Attributes, "AnnotationDefault", info(uint8('Z'), yes))
+	value, err := m.AnnotationDefault()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (ElementValue{Tag: 'Z', ConstValueIndex: yes}); value == nil || !reflect.DeepEqual(*value, want) {
+		t.Errorf("AnnotationDefault() = %+v, want %+v", value, want)
+	}
+}
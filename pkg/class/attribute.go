@@ -1,39 +1,258 @@
 package class
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
-	"os"
+	"io"
 	"strings"
+	"sync"
 )
 
 type Attribute struct {
 	AttributeNameIndex uint16
 	AttributeLength    uint32
 	Info               []byte
+	// Name is the attribute name resolved from the constant pool
+	Name string
+	// Value is the typed attribute produced by the decoder registered for Name,
+	// or nil when no decoder is registered. Info is kept either way. When the
+	// class was parsed with WithLazyAttributes, Value stays nil until the
+	// first call to Decode.
+	Value interface{}
+	// Offset is the position of Info in the class file. It is only recorded
+	// by lazy parsing and is zero otherwise.
+	Offset int
+	// pending holds the constant pool while a lazily parsed attribute has yet
+	// to be decoded.
+	pending *ConstantPool
 }
 
-// Read an Attribute from the given file
-func readAttribute(file *os.File, attribute *Attribute) error {
-	if err := binary.Read(file, binary.BigEndian, &attribute.AttributeNameIndex); err != nil {
+// AttributeDecoder decodes the info bytes of an attribute into a typed value.
+// The constant pool of the class being parsed is passed so that decoders can
+// resolve names and nested attributes.
+type AttributeDecoder func(info []byte, cp *ConstantPool) (interface{}, error)
+
+var (
+	attributeDecodersMu sync.RWMutex
+	attributeDecoders   map[string]AttributeDecoder
+)
+
+// The standard decoders are installed in init rather than in the variable
+// declaration because decoders such as Code decode nested attributes, which
+// would otherwise be an initialization cycle.
+func init() {
+	attributeDecoders = map[string]AttributeDecoder{
+		"AnnotationDefault":                    decodeAnnotationDefaultAttribute,
+		"BootstrapMethods":                     decodeBootstrapMethodsAttribute,
+		"Code":                                 decodeCodeAttribute,
+		"ConstantValue":                        decodeConstantValueAttribute,
+		"Deprecated":                           decodeDeprecatedAttribute,
+		"EnclosingMethod":                      decodeEnclosingMethodAttribute,
+		"Exceptions":                           decodeExceptionsAttribute,
+		"InnerClasses":                         decodeInnerClassesAttribute,
+		"LineNumberTable":                      decodeLineNumberTableAttribute,
+		"LocalVariableTable":                   decodeLocalVariableTableAttribute,
+		"LocalVariableTypeTable":               decodeLocalVariableTypeTableAttribute,
+		"MethodParameters":                     decodeMethodParametersAttribute,
+		"Module":                               decodeModuleAttribute,
+		"ModuleMainClass":                      decodeModuleMainClassAttribute,
+		"ModulePackages":                       decodeModulePackagesAttribute,
+		"NestHost":                             decodeNestHostAttribute,
+		"NestMembers":                          decodeNestMembersAttribute,
+		"PermittedSubclasses":                  decodePermittedSubclassesAttribute,
+		"Record":                               decodeRecordAttribute,
+		"RuntimeInvisibleAnnotations":          decodeRuntimeInvisibleAnnotationsAttribute,
+		"RuntimeInvisibleParameterAnnotations": decodeRuntimeInvisibleParameterAnnotationsAttribute,
+		"RuntimeInvisibleTypeAnnotations":      decodeRuntimeInvisibleTypeAnnotationsAttribute,
+		"RuntimeVisibleAnnotations":            decodeRuntimeVisibleAnnotationsAttribute,
+		"RuntimeVisibleParameterAnnotations":   decodeRuntimeVisibleParameterAnnotationsAttribute,
+		"RuntimeVisibleTypeAnnotations":        decodeRuntimeVisibleTypeAnnotationsAttribute,
+		"Signature":                            decodeSignatureAttribute,
+		"SourceDebugExtension":                 decodeSourceDebugExtensionAttribute,
+		"SourceFile":                           decodeSourceFileAttribute,
+		"StackMapTable":                        decodeStackMapTableAttribute,
+		"Synthetic":                            decodeSyntheticAttribute,
+	}
+}
+
+// RegisterAttribute registers the decoder used for attributes with the given
+// name, replacing any decoder already registered for it. Decoders for the
+// standard attributes are registered by this package; embedders can register
+// decoders for their own attributes before parsing.
+func RegisterAttribute(name string, decoder AttributeDecoder) {
+	attributeDecodersMu.Lock()
+	defer attributeDecodersMu.Unlock()
+	attributeDecoders[name] = decoder
+}
+
+func lookupAttributeDecoder(name string) AttributeDecoder {
+	attributeDecodersMu.RLock()
+	defer attributeDecodersMu.RUnlock()
+	return attributeDecoders[name]
+}
+
+// resolveName resolves the attribute name from the constant pool.
+func (a *Attribute) resolveName(cp *ConstantPool) error {
+	a.Name = cp.GetConstantName(a.AttributeNameIndex)
+	if a.Name == "" {
+		return fmt.Errorf("attribute name index %d is not a UTF-8 constant", a.AttributeNameIndex)
+	}
+	return nil
+}
+
+// decode resolves the attribute name and runs the registered decoder, if any.
+func (a *Attribute) decode(cp *ConstantPool) error {
+	if err := a.resolveName(cp); err != nil {
+		return err
+	}
+	return a.decodeValue(cp)
+}
+
+// Decode returns the typed value of the attribute, see Value. Attributes of a
+// lazily parsed class are decoded on the first call, which makes Decode, and
+// the accessors that use it, unsafe to call from several goroutines at once
+// on such a class.
+func (a *Attribute) Decode() (interface{}, error) {
+	if a.pending != nil {
+		if err := a.decodeValue(a.pending); err != nil {
+			return nil, err
+		}
+		a.pending = nil
+	}
+	return a.Value, nil
+}
+
+// decodeValue runs the decoder registered for the attribute name, if any.
+// Standard attributes that are newer than the class file are not decoded.
+func (a *Attribute) decodeValue(cp *ConstantPool) error {
+	if minVersion, ok := attributeMinMajorVersions[a.Name]; ok && cp.majorVersion != 0 && cp.majorVersion < minVersion {
+		return nil
+	}
+	decoder := lookupAttributeDecoder(a.Name)
+	if decoder == nil {
+		return nil
+	}
+
+	value, err := decoder(a.Info, cp)
+	if err != nil {
+		return fmt.Errorf("decoding %s attribute: %w", a.Name, err)
+	}
+	a.Value = value
+	return nil
+}
+
+// findAttribute returns the first attribute with the given name, or nil.
+func findAttribute(attributes []Attribute, name string) *Attribute {
+	for i := range attributes {
+		if attributes[i].Name == name {
+			return &attributes[i]
+		}
+	}
+	return nil
+}
+
+// attributeValue returns the decoded value of the first attribute with the
+// given name, or nil if there is no such attribute. An attribute that fails
+// to decode is reported as an error rather than treated as absent.
+func attributeValue(attributes []Attribute, name string) (interface{}, error) {
+	attr := findAttribute(attributes, name)
+	if attr == nil {
+		return nil, nil
+	}
+	value, err := attr.Decode()
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s attribute: %w", name, err)
+	}
+	return value, nil
+}
+
+// Read an Attribute from the given reader
+func readAttribute(r io.Reader, attribute *Attribute, cp *ConstantPool) error {
+	if err := binary.Read(r, binary.BigEndian, &attribute.AttributeNameIndex); err != nil {
 		return fmt.Errorf("reading attribute name index: %w", err)
 	}
 
-	if err := binary.Read(file, binary.BigEndian, &attribute.AttributeLength); err != nil {
+	if err := binary.Read(r, binary.BigEndian, &attribute.AttributeLength); err != nil {
 		return fmt.Errorf("reading attribute length: %w", err)
 	}
 
-	attribute.Info = make([]byte, attribute.AttributeLength)
-	if err := binary.Read(file, binary.BigEndian, &attribute.Info); err != nil {
+	info, err := readBytes(r, attribute.AttributeLength)
+	if err != nil {
 		return fmt.Errorf("reading attribute info: %w", err)
 	}
+	attribute.Info = info
 
-	return nil
+	return attribute.decode(cp)
+}
+
+// readBytes reads n bytes from r. The length comes from the class file, so it
+// is checked against what a bytes.Reader has left, and other readers fill a
+// buffer that grows as data arrives; either way a truncated file cannot force
+// a large allocation.
+func readBytes(r io.Reader, n uint32) ([]byte, error) {
+	if sized, ok := r.(interface{ Len() int }); ok {
+		if uint64(n) > uint64(sized.Len()) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		b := make([]byte, n)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		return b, nil
+	}
+
+	var buf bytes.Buffer
+	copied, err := io.CopyN(&buf, r, int64(n))
+	if err == io.EOF && copied < int64(n) {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// attributeReader reads one attribute; readAttribute and lazyAttributeReader
+// are the two implementations.
+type attributeReader func(r io.Reader, attribute *Attribute, cp *ConstantPool) error
+
+// lazyAttributeReader returns an attributeReader for a class held in data and
+// read through r. Info is sliced from data instead of copied and decoding is
+// deferred until Decode is called.
+func lazyAttributeReader(data []byte, r *bytes.Reader) attributeReader {
+	return func(_ io.Reader, attribute *Attribute, cp *ConstantPool) error {
+		if err := binary.Read(r, binary.BigEndian, &attribute.AttributeNameIndex); err != nil {
+			return fmt.Errorf("reading attribute name index: %w", err)
+		}
+
+		if err := binary.Read(r, binary.BigEndian, &attribute.AttributeLength); err != nil {
+			return fmt.Errorf("reading attribute length: %w", err)
+		}
+
+		offset := len(data) - r.Len()
+		if uint64(attribute.AttributeLength) > uint64(r.Len()) {
+			return fmt.Errorf("reading attribute info: %w", io.ErrUnexpectedEOF)
+		}
+		end := offset + int(attribute.AttributeLength)
+		// Limit the capacity so that appending to Info cannot overwrite data
+		attribute.Info = data[offset:end:end]
+		attribute.Offset = offset
+		if _, err := r.Seek(int64(attribute.AttributeLength), io.SeekCurrent); err != nil {
+			return fmt.Errorf("reading attribute info: %w", err)
+		}
+
+		if err := attribute.resolveName(cp); err != nil {
+			return err
+		}
+		attribute.pending = cp
+		return nil
+	}
 }
 
 func (a Attribute) String() string {
 	var builder strings.Builder
-	fmt.Fprintf(&builder, "Attribute Name Index: %d\n", a.AttributeNameIndex)
+	fmt.Fprintf(&builder, "Attribute Name Index: %d (%s)\n", a.AttributeNameIndex, a.Name)
 	fmt.Fprintf(&builder, "Attribute Length: %d\n", a.AttributeLength)
 	fmt.Fprintf(&builder, "Info: %x\n", a.Info)
 	return builder.String()
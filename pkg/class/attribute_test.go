@@ -0,0 +1,135 @@
+package class
+
+import (
+	"strings"
+	"testing"
+)
+
+// addCorruptAttribute adds an attribute ahead of the others whose one byte of
+// info is too short for any of the attributes under test, leaving it to be
+// decoded lazily.
+func addCorruptAttribute(t *testing.T, c *Class, attributes *[]Attribute, name string) {
+	t.Helper()
+	nameIndex, err := c.ConstantPool.AddUtf8(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrupt := Attribute{
+		AttributeNameIndex: nameIndex,
+		AttributeLength:    1,
+		Info:               []byte{0xFF},
+		Name:               name,
+		pending:            &c.ConstantPool,
+	}
+	*attributes = append([]Attribute{corrupt}, *attributes...)
+}
+
+func TestAccessorsReportDecodeErrors(t *testing.T) {
+	tests := []struct {
+		attribute string
+		call      func(c *Class) error
+	}{
+		{"NestHost", func(c *Class) error {
+			_, err := c.NestHost()
+			return err
+		}},
+		{"NestMembers", func(c *Class) error {
+			_, err := c.NestMembers()
+			return err
+		}},
+		{"PermittedSubclasses", func(c *Class) error {
+			_, err := c.PermittedSubclasses()
+			return err
+		}},
+		{"InnerClasses", func(c *Class) error {
+			_, err := c.InnerClassEntries()
+			return err
+		}},
+		{"EnclosingMethod", func(c *Class) error {
+			_, err := c.EnclosingMethodRef()
+			return err
+		}},
+		{"SourceFile", func(c *Class) error {
+			_, err := c.SourceFile()
+			return err
+		}},
+		{"Signature", func(c *Class) error {
+			_, err := c.GenericSignature()
+			return err
+		}},
+		{"BootstrapMethods", func(c *Class) error {
+			_, err := c.BootstrapMethods()
+			return err
+		}},
+		{"Record", func(c *Class) error {
+			_, err := c.RecordComponents()
+			return err
+		}},
+		{"RuntimeVisibleAnnotations", func(c *Class) error {
+			_, err := c.HasAnnotation("Ljava/lang/Deprecated;")
+			return err
+		}},
+		{"RuntimeInvisibleTypeAnnotations", func(c *Class) error {
+			_, err := c.TypeAnnotations()
+			return err
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.attribute, func(t *testing.T) {
+			c := parseTestClass(t)
+			addCorruptAttribute(t, c, &c.Attributes, test.attribute)
+			err := test.call(c)
+			want := "decoding " + test.attribute + " attribute"
+			if err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("err = %v, want an error containing %q", err, want)
+			}
+		})
+	}
+}
+
+func TestMethodAccessorsReportDecodeErrors(t *testing.T) {
+	tests := []struct {
+		attribute string
+		call      func(m *Method) error
+	}{
+		{"Exceptions", func(m *Method) error {
+			_, err := m.Exceptions()
+			return err
+		}},
+		{"MethodParameters", func(m *Method) error {
+			_, err := m.MethodParameters()
+			return err
+		}},
+		{"RuntimeInvisibleParameterAnnotations", func(m *Method) error {
+			_, err := m.HasParameterAnnotation(0, "Ljava/lang/Deprecated;")
+			return err
+		}},
+		{"AnnotationDefault", func(m *Method) error {
+			_, err := m.AnnotationDefault()
+			return err
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.attribute, func(t *testing.T) {
+			c := parseTestClass(t)
+			m := &c.Methods[0]
+			addCorruptAttribute(t, c, &m.Attributes, test.attribute)
+			err := test.call(m)
+			want := "decoding " + test.attribute + " attribute"
+			if err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("err = %v, want an error containing %q", err, want)
+			}
+		})
+	}
+}
+
+func TestNestHostCorruptIsNotSelf(t *testing.T) {
+	c := parseTestClass(t)
+	addCorruptAttribute(t, c, &c.Attributes, "NestHost")
+	if host, err := c.NestHost(); err == nil {
+		t.Errorf("NestHost() = %q, want an error for the corrupt attribute", host)
+	}
+	if err := Check(c); err == nil || !strings.Contains(err.Error(), "NestHost") {
+		t.Errorf("Check() = %v, want an error naming NestHost", err)
+	}
+}
@@ -0,0 +1,240 @@
+package class
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// ConstantValueAttribute holds the value of a constant field.
+// See https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.2
+type ConstantValueAttribute struct {
+	ConstantValueIndex uint16
+}
+
+func decodeConstantValueAttribute(info []byte, cp *ConstantPool) (interface{}, error) {
+	reader := bytes.NewReader(info)
+	attr := &ConstantValueAttribute{}
+	if err := binary.Read(reader, binary.BigEndian, &attr.ConstantValueIndex); err != nil {
+		return nil, err
+	}
+	return attr, checkFullyRead(reader)
+}
+
+func (a *ConstantValueAttribute) encode(w *bytes.Buffer) error {
+	put(w, a.ConstantValueIndex)
+	return nil
+}
+
+// ExceptionsAttribute lists the checked exceptions a method may throw. Each
+// entry is an index to a Class constant.
+// See https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.5
+type ExceptionsAttribute struct {
+	ExceptionIndexTable []uint16
+}
+
+func decodeExceptionsAttribute(info []byte, cp *ConstantPool) (interface{}, error) {
+	reader := bytes.NewReader(info)
+	attr := &ExceptionsAttribute{}
+	table, err := readUint16Table(reader)
+	if err != nil {
+		return nil, err
+	}
+	attr.ExceptionIndexTable = table
+	return attr, checkFullyRead(reader)
+}
+
+func (a *ExceptionsAttribute) encode(w *bytes.Buffer) error {
+	return putUint16Table(w, a.ExceptionIndexTable)
+}
+
+// SourceFileAttribute names the source file a class was compiled from.
+// See https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.10
+type SourceFileAttribute struct {
+	SourceFileIndex uint16
+}
+
+func decodeSourceFileAttribute(info []byte, cp *ConstantPool) (interface{}, error) {
+	reader := bytes.NewReader(info)
+	attr := &SourceFileAttribute{}
+	if err := binary.Read(reader, binary.BigEndian, &attr.SourceFileIndex); err != nil {
+		return nil, err
+	}
+	return attr, checkFullyRead(reader)
+}
+
+func (a *SourceFileAttribute) encode(w *bytes.Buffer) error {
+	put(w, a.SourceFileIndex)
+	return nil
+}
+
+// SourceDebugExtensionAttribute holds extended debugging information, such as
+// the SMAP of a JSP page. The contents are modified UTF-8 but not
+// length-limited, so they are kept as raw bytes.
+// See https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.11
+type SourceDebugExtensionAttribute struct {
+	DebugExtension []byte
+}
+
+func decodeSourceDebugExtensionAttribute(info []byte, cp *ConstantPool) (interface{}, error) {
+	return &SourceDebugExtensionAttribute{DebugExtension: info}, nil
+}
+
+func (a *SourceDebugExtensionAttribute) encode(w *bytes.Buffer) error {
+	w.Write(a.DebugExtension)
+	return nil
+}
+
+// SignatureAttribute holds the generic signature of a class, field, method or
+// record component. Signature is resolved from the constant pool when the
+// attribute is decoded; see the signature package for parsing it.
+// See https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.9
+type SignatureAttribute struct {
+	SignatureIndex uint16
+	Signature      string
+}
+
+func decodeSignatureAttribute(info []byte, cp *ConstantPool) (interface{}, error) {
+	reader := bytes.NewReader(info)
+	attr := &SignatureAttribute{}
+	if err := binary.Read(reader, binary.BigEndian, &attr.SignatureIndex); err != nil {
+		return nil, err
+	}
+	signature, err := cp.utf8(attr.SignatureIndex)
+	if err != nil {
+		return nil, err
+	}
+	attr.Signature = signature
+	return attr, checkFullyRead(reader)
+}
+
+func (a *SignatureAttribute) encode(w *bytes.Buffer) error {
+	put(w, a.SignatureIndex)
+	return nil
+}
+
+// DeprecatedAttribute marks a class, field or method as deprecated.
+// See https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.15
+type DeprecatedAttribute struct{}
+
+func decodeDeprecatedAttribute(info []byte, cp *ConstantPool) (interface{}, error) {
+	if len(info) != 0 {
+		return nil, fmt.Errorf("expected empty attribute, got %d bytes", len(info))
+	}
+	return &DeprecatedAttribute{}, nil
+}
+
+func (a *DeprecatedAttribute) encode(w *bytes.Buffer) error {
+	return nil
+}
+
+// SyntheticAttribute marks a class, field or method that does not appear in
+// the source code.
+// See https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.8
+type SyntheticAttribute struct{}
+
+func decodeSyntheticAttribute(info []byte, cp *ConstantPool) (interface{}, error) {
+	if len(info) != 0 {
+		return nil, fmt.Errorf("expected empty attribute, got %d bytes", len(info))
+	}
+	return &SyntheticAttribute{}, nil
+}
+
+func (a *SyntheticAttribute) encode(w *bytes.Buffer) error {
+	return nil
+}
+
+// EnclosingMethodAttribute ties a local or anonymous class to the class and,
+// if any, the method that declares it. MethodIndex is zero when the class is
+// not enclosed by a method, e.g. when declared in an initializer.
+// See https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.7
+type EnclosingMethodAttribute struct {
+	ClassIndex  uint16
+	MethodIndex uint16
+}
+
+func decodeEnclosingMethodAttribute(info []byte, cp *ConstantPool) (interface{}, error) {
+	reader := bytes.NewReader(info)
+	attr := &EnclosingMethodAttribute{}
+	if err := binary.Read(reader, binary.BigEndian, attr); err != nil {
+		return nil, err
+	}
+	return attr, checkFullyRead(reader)
+}
+
+func (a *EnclosingMethodAttribute) encode(w *bytes.Buffer) error {
+	put(w, a)
+	return nil
+}
+
+type MethodParameter struct {
+	NameIndex   uint16
+	AccessFlags MethodParameterAccessFlags
+}
+
+// MethodParametersAttribute holds the names and flags of a method's formal
+// parameters. A NameIndex of zero means the parameter has no name.
+// See https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.24
+type MethodParametersAttribute struct {
+	Parameters []MethodParameter
+}
+
+func decodeMethodParametersAttribute(info []byte, cp *ConstantPool) (interface{}, error) {
+	reader := bytes.NewReader(info)
+	var count uint8
+	if err := binary.Read(reader, binary.BigEndian, &count); err != nil {
+		return nil, err
+	}
+	if err := checkCount(reader, uint32(count), 4); err != nil {
+		return nil, err
+	}
+	attr := &MethodParametersAttribute{Parameters: make([]MethodParameter, count)}
+	if err := binary.Read(reader, binary.BigEndian, attr.Parameters); err != nil {
+		return nil, err
+	}
+	return attr, checkFullyRead(reader)
+}
+
+func (a *MethodParametersAttribute) encode(w *bytes.Buffer) error {
+	if err := putCount8(w, len(a.Parameters)); err != nil {
+		return err
+	}
+	put(w, a.Parameters)
+	return nil
+}
+
+// readUint16Table reads a u2 count followed by that many u2 values.
+func readUint16Table(reader *bytes.Reader) ([]uint16, error) {
+	var count uint16
+	if err := binary.Read(reader, binary.BigEndian, &count); err != nil {
+		return nil, err
+	}
+	if err := checkCount(reader, uint32(count), 2); err != nil {
+		return nil, err
+	}
+	table := make([]uint16, count)
+	if err := binary.Read(reader, binary.BigEndian, table); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+// checkCount returns an error if fewer bytes are left in reader than count
+// items of at least minSize bytes each would take. Counts come from the class
+// file, so decoders check them before allocating to keep a corrupt count from
+// forcing an allocation far larger than the attribute.
+func checkCount(reader *bytes.Reader, count uint32, minSize int) error {
+	if uint64(count)*uint64(minSize) > uint64(reader.Len()) {
+		return fmt.Errorf("count %d does not fit in the %d bytes left: %w", count, reader.Len(), io.ErrUnexpectedEOF)
+	}
+	return nil
+}
+
+// checkFullyRead returns an error if the attribute has bytes left over after decoding.
+func checkFullyRead(reader *bytes.Reader) error {
+	if reader.Len() != 0 {
+		return fmt.Errorf("%d unexpected trailing bytes", reader.Len())
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package class
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// BootstrapMethod is a single entry of the BootstrapMethods attribute.
+// BootstrapMethodRef points to a MethodHandle constant and each argument
+// points to a loadable constant.
+type BootstrapMethod struct {
+	BootstrapMethodRef uint16
+	BootstrapArguments []uint16
+}
+
+// BootstrapMethodsAttribute holds the bootstrap method specifiers referenced
+// by the InvokeDynamic and Dynamic constants of a class.
+// See https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.23
+type BootstrapMethodsAttribute struct {
+	BootstrapMethods []BootstrapMethod
+}
+
+func decodeBootstrapMethodsAttribute(info []byte, cp *ConstantPool) (interface{}, error) {
+	reader := bytes.NewReader(info)
+	var count uint16
+	if err := binary.Read(reader, binary.BigEndian, &count); err != nil {
+		return nil, err
+	}
+	if err := checkCount(reader, uint32(count), 4); err != nil {
+		return nil, err
+	}
+	attr := &BootstrapMethodsAttribute{BootstrapMethods: make([]BootstrapMethod, count)}
+	for i := range attr.BootstrapMethods {
+		method := &attr.BootstrapMethods[i]
+		if err := binary.Read(reader, binary.BigEndian, &method.BootstrapMethodRef); err != nil {
+			return nil, err
+		}
+		arguments, err := readUint16Table(reader)
+		if err != nil {
+			return nil, fmt.Errorf("reading bootstrap method %d: %w", i, err)
+		}
+		method.BootstrapArguments = arguments
+	}
+	return attr, checkFullyRead(reader)
+}
+
+func (a *BootstrapMethodsAttribute) encode(w *bytes.Buffer) error {
+	if err := putCount(w, len(a.BootstrapMethods)); err != nil {
+		return err
+	}
+	for i, method := range a.BootstrapMethods {
+		put(w, method.BootstrapMethodRef)
+		if err := putUint16Table(w, method.BootstrapArguments); err != nil {
+			return fmt.Errorf("writing bootstrap method %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// BootstrapMethods returns the BootstrapMethods attribute of the class, or nil.
+func (c *Class) BootstrapMethods() (*BootstrapMethodsAttribute, error) {
+	value, err := attributeValue(c.Attributes, "BootstrapMethods")
+	attr, _ := value.(*BootstrapMethodsAttribute)
+	return attr, err
+}
+
+// MethodHandle is a MethodHandle constant with its reference resolved.
+type MethodHandle struct {
+	ReferenceKind ReferenceKind
+	Owner         string
+	Name          string
+	Descriptor    string
+	// IsInterface is set when the handle refers to an InterfaceMethodref
+	IsInterface bool
+}
+
+func (h *MethodHandle) String() string {
+	return fmt.Sprintf("%s %s.%s:%s", h.ReferenceKind, h.Owner, h.Name, h.Descriptor)
+}
+
+// BootstrapArgument is a static argument passed to a bootstrap method. Value
+// depends on Tag:
+//
+//	Integer, Float, Long, Double  int32, float32, int64, float64
+//	String                        string holding the string value
+//	Class                         string holding the internal class name
+//	MethodType                    string holding the method descriptor
+//	MethodHandle                  *MethodHandle
+//	Dynamic                       *CallSite
+type BootstrapArgument struct {
+	Index uint16
+	Tag   uint8
+	Value interface{}
+}
+
+// CallSite is the metadata of an InvokeDynamic or Dynamic constant: the
+// bootstrap method that links it, the bootstrap method's static arguments and
+// the name and descriptor of the call site or constant.
+type CallSite struct {
+	// Index is the constant pool index of the InvokeDynamic or Dynamic constant
+	Index uint16
+	Tag   uint8
+	// BootstrapMethodAttrIndex indexes the BootstrapMethods attribute
+	BootstrapMethodAttrIndex uint16
+	BootstrapMethod          *MethodHandle
+	Arguments                []BootstrapArgument
+	Name                     string
+	// Descriptor is a method descriptor for InvokeDynamic constants and a
+	// field descriptor for Dynamic constants.
+	Descriptor string
+}
+
+// ResolveCallSite resolves the InvokeDynamic or Dynamic constant at index, as
+// referenced by an invokedynamic instruction or an ldc of a dynamic constant.
+func (c *Class) ResolveCallSite(index uint16) (*CallSite, error) {
+	return c.resolveCallSite(index, map[uint16]bool{})
+}
+
+func (c *Class) resolveCallSite(index uint16, resolving map[uint16]bool) (*CallSite, error) {
+	if resolving[index] {
+		return nil, fmt.Errorf("dynamic constant %d refers to itself through its bootstrap arguments", index)
+	}
+	resolving[index] = true
+	defer delete(resolving, index)
+
+	entry, err := c.ConstantPool.entry(index)
+	if err != nil {
+		return nil, err
+	}
+
+	site := &CallSite{Index: index, Tag: entry.Tag}
+	var nameAndTypeIndex uint16
+	switch value := entry.Value.(type) {
+	case *ConstantInvokeDynamicValue:
+		site.BootstrapMethodAttrIndex, nameAndTypeIndex = value.BootstrapMethodAttrIndex, value.NameAndTypeIndex
+	case *ConstantDynamicValue:
+		site.BootstrapMethodAttrIndex, nameAndTypeIndex = value.BootstrapMethodAttrIndex, value.NameAndTypeIndex
+	default:
+		return nil, fmt.Errorf("constant pool index %d is a %s, expected InvokeDynamic or Dynamic", index, TagName(entry.Tag))
+	}
+
+	if site.Name, site.Descriptor, err = c.ConstantPool.nameAndType(nameAndTypeIndex); err != nil {
+		return nil, err
+	}
+
+	table, err := c.BootstrapMethods()
+	if err != nil {
+		return nil, err
+	}
+	if table == nil {
+		return nil, fmt.Errorf("class has no BootstrapMethods attribute")
+	}
+	if int(site.BootstrapMethodAttrIndex) >= len(table.BootstrapMethods) {
+		return nil, fmt.Errorf("bootstrap method index %d out of range, class has %d",
+			site.BootstrapMethodAttrIndex, len(table.BootstrapMethods))
+	}
+	bootstrap := table.BootstrapMethods[site.BootstrapMethodAttrIndex]
+
+	if site.BootstrapMethod, err = c.resolveMethodHandle(bootstrap.BootstrapMethodRef); err != nil {
+		return nil, fmt.Errorf("resolving bootstrap method: %w", err)
+	}
+
+	site.Arguments = make([]BootstrapArgument, len(bootstrap.BootstrapArguments))
+	for i, argIndex := range bootstrap.BootstrapArguments {
+		if site.Arguments[i], err = c.resolveBootstrapArgument(argIndex, resolving); err != nil {
+			return nil, fmt.Errorf("resolving bootstrap argument %d: %w", i, err)
+		}
+	}
+	return site, nil
+}
+
+// resolveMethodHandle resolves the MethodHandle constant at index.
+func (c *Class) resolveMethodHandle(index uint16) (*MethodHandle, error) {
+	entry, err := c.ConstantPool.entryWithTag(index, TagMethodHandle)
+	if err != nil {
+		return nil, err
+	}
+	value := entry.Value.(*ConstantMethodHandleValue)
+
+	handle := &MethodHandle{ReferenceKind: value.ReferenceKind}
+	if handle.Owner, handle.Name, handle.Descriptor, err = c.ConstantPool.memberRef(value.ReferenceIndex); err != nil {
+		return nil, err
+	}
+	handle.IsInterface = c.ConstantPool.entries[value.ReferenceIndex].Tag == TagInterfaceMethodRef
+	return handle, nil
+}
+
+func (c *Class) resolveBootstrapArgument(index uint16, resolving map[uint16]bool) (BootstrapArgument, error) {
+	entry, err := c.ConstantPool.entry(index)
+	if err != nil {
+		return BootstrapArgument{}, err
+	}
+
+	argument := BootstrapArgument{Index: index, Tag: entry.Tag}
+	switch value := entry.Value.(type) {
+	case *ConstantIntegerValue:
+		argument.Value = value.Value
+	case *ConstantFloatValue:
+		argument.Value = value.Value
+	case *ConstantLongValue:
+		argument.Value = value.Value
+	case *ConstantDoubleValue:
+		argument.Value = value.Value
+	case *ConstantStringRefValue:
+		argument.Value, err = c.ConstantPool.utf8(value.Index)
+	case *ConstantClassRefValue:
+		argument.Value, err = c.ConstantPool.utf8(value.Index)
+	case *ConstantMethodTypeValue:
+		argument.Value, err = c.ConstantPool.utf8(value.DescriptorIndex)
+	case *ConstantMethodHandleValue:
+		argument.Value, err = c.resolveMethodHandle(index)
+	case *ConstantDynamicValue:
+		argument.Value, err = c.resolveCallSite(index, resolving)
+	default:
+		return BootstrapArgument{}, fmt.Errorf("constant pool index %d is a %s, which is not loadable", index, TagName(entry.Tag))
+	}
+	if err != nil {
+		return BootstrapArgument{}, err
+	}
+	return argument, nil
+}
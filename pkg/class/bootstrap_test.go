@@ -0,0 +1,111 @@
+package class
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// addBootstrapMethods builds the constants of a lambda call site and of three
+// dynamic constants, and a BootstrapMethods attribute linking them:
+//
+//	0  metafactory(()V, 7)        used by the InvokeDynamic run and Dynamic inner
+//	1  metafactory(Dynamic self)  used by the Dynamic self, which refers to itself
+//	2  metafactory(Dynamic inner) used by the Dynamic outer
+func addBootstrapMethods(t *testing.T, c *Class) (indy, self, outer uint16) {
+	t.Helper()
+	cp := &c.ConstantPool
+	must := func(index uint16, err error) uint16 {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return index
+	}
+	factory := must(cp.AddMethodRef("java/lang/invoke/LambdaMetafactory", "metafactory", "()Ljava/lang/invoke/CallSite;"))
+	handle := must(cp.AddMethodHandle(RefInvokeStatic, factory))
+	methodType := must(cp.AddMethodType("()V"))
+	seven := must(cp.AddInteger(7))
+	indy = must(cp.AddInvokeDynamic(0, "run", "()Ljava/lang/Runnable;"))
+	self = must(cp.AddDynamic(1, "self", "I"))
+	inner := must(cp.AddDynamic(0, "inner", "J"))
+	outer = must(cp.AddDynamic(2, "outer", "Ljava/lang/Object;"))
+	addClassAttribute(t, c, "BootstrapMethods", u2(3,
+		handle, 2, methodType, seven,
+		handle, 1, self,
+		handle, 1, inner))
+	return indy, self, outer
+}
+
+func TestResolveCallSite(t *testing.T) {
+	c := parseTestClass(t)
+	indy, _, outer := addBootstrapMethods(t, c)
+	metafactory := &MethodHandle{
+		ReferenceKind: RefInvokeStatic,
+		Owner:         "java/lang/invoke/LambdaMetafactory",
+		Name:          "metafactory",
+		Descriptor:    "()Ljava/lang/invoke/CallSite;",
+	}
+
+	site, err := c.ResolveCallSite(indy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if site.Tag != TagInvokeDynamic || site.Name != "run" || site.Descriptor != "()Ljava/lang/Runnable;" {
+		t.Errorf("ResolveCallSite() = %s %s%s, want InvokeDynamic run()Ljava/lang/Runnable;", TagName(site.Tag), site.Name, site.Descriptor)
+	}
+	if !reflect.DeepEqual(site.BootstrapMethod, metafactory) {
+		t.Errorf("BootstrapMethod = %v, want %v", site.BootstrapMethod, metafactory)
+	}
+	if len(site.Arguments) != 2 || site.Arguments[0].Value != "()V" || site.Arguments[1].Value != int32(7) {
+		t.Errorf("Arguments = %+v, want the method type ()V and the int 7", site.Arguments)
+	}
+
+	site, err = c.ResolveCallSite(outer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(site.Arguments) != 1 {
+		t.Fatalf("Arguments = %+v, want the Dynamic inner", site.Arguments)
+	}
+	inner, ok := site.Arguments[0].Value.(*CallSite)
+	if !ok || inner.Tag != TagDynamic || inner.Name != "inner" || inner.Descriptor != "J" {
+		t.Errorf("Arguments[0] = %+v, want the resolved Dynamic inner:J", site.Arguments[0].Value)
+	}
+}
+
+func TestResolveCallSiteErrors(t *testing.T) {
+	c := parseTestClass(t)
+	_, self, _ := addBootstrapMethods(t, c)
+	outOfRange, err := c.ConstantPool.AddInvokeDynamic(3, "missing", "()V")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		index uint16
+		want  string
+	}{
+		{"cycle", self, "refers to itself"},
+		{"bootstrap index out of range", outOfRange, "bootstrap method index 3 out of range"},
+		{"not a dynamic constant", 7, "is a String, expected InvokeDynamic or Dynamic"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := c.ResolveCallSite(test.index)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("ResolveCallSite(%d) = %v, want an error containing %q", test.index, err, test.want)
+			}
+		})
+	}
+
+	c = parseTestClass(t)
+	index, err := c.ConstantPool.AddInvokeDynamic(0, "run", "()V")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ResolveCallSite(index); err == nil || !strings.Contains(err.Error(), "no BootstrapMethods") {
+		t.Errorf("ResolveCallSite() = %v without BootstrapMethods, want an error", err)
+	}
+}
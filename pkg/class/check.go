@@ -0,0 +1,845 @@
+package class
+
+import (
+	"bytes"
+	"fmt"
+	"lava-vm/pkg/descriptor"
+	"strings"
+)
+
+// Violation is a single format error found by Check. Location names the part
+// of the class file at fault, e.g. "constant pool #12" or
+// "method main([Ljava/lang/String;)V attribute Code".
+type Violation struct {
+	Location string
+	Msg      string
+}
+
+func (v Violation) String() string {
+	return v.Location + ": " + v.Msg
+}
+
+// CheckError is returned by Check and lists every violation found.
+type CheckError struct {
+	Violations []Violation
+}
+
+func (e *CheckError) Error() string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "class file has %d format error(s):", len(e.Violations))
+	for _, violation := range e.Violations {
+		builder.WriteString("\n\t")
+		builder.WriteString(violation.String())
+	}
+	return builder.String()
+}
+
+// Check performs the format checks of JVMS 4.8 on a parsed class: constant
+// pool indexes are in range and point at entries of the expected kind, names
+// and descriptors are well formed, members are unique, access flags are legal
+// and features are not used before the class file version that introduced
+// them. It returns nil if the class passes, or a *CheckError listing every
+// violation otherwise.
+//
+// A parsed class cannot show whether its class file had extra bytes after its
+// end, which JVMS 4.8 also forbids; the parse functions reject such files, and
+// CheckBytes reports them together with every other violation.
+// See https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.8
+func Check(c *Class) error {
+	k := &checker{class: c, cp: &c.ConstantPool}
+	return k.check()
+}
+
+// CheckBytes parses the class file held in data and checks it as Check does,
+// also reporting any bytes left over after the end of the class file. Errors
+// that stop the class file from being parsed at all are returned as they are
+// rather than as a *CheckError.
+func CheckBytes(data []byte, opts ...ParseOption) error {
+	var options parseOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	reader := bytes.NewReader(data)
+	c, err := parse(reader, readAttribute, options)
+	if err != nil {
+		return err
+	}
+	k := &checker{class: c, cp: &c.ConstantPool}
+	if err := checkEnd(reader); err != nil {
+		k.addErr("class file", err)
+	}
+	return k.check()
+}
+
+// check runs every check on the class, returning a *CheckError if any
+// violation has been found.
+func (k *checker) check() error {
+	c := k.class
+	k.checkHeader()
+	k.checkConstantPool()
+	k.checkClass()
+	for i := range c.Fields {
+		k.checkField(i, &c.Fields[i])
+	}
+	for i := range c.Methods {
+		k.checkMethod(i, &c.Methods[i])
+	}
+	k.checkAttributes("class", c.Attributes)
+
+	if len(k.violations) > 0 {
+		return &CheckError{Violations: k.violations}
+	}
+	return nil
+}
+
+type checker struct {
+	class      *Class
+	cp         *ConstantPool
+	violations []Violation
+}
+
+func (k *checker) addf(location, format string, args ...interface{}) {
+	k.violations = append(k.violations, Violation{Location: location, Msg: fmt.Sprintf(format, args...)})
+}
+
+// addErr records err, splitting errors joined with errors.Join into one
+// violation each.
+func (k *checker) addErr(location string, err error) {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, err := range joined.Unwrap() {
+			k.addErr(location, err)
+		}
+		return
+	}
+	k.addf(location, "%v", err)
+}
+
+// ref checks that index points at a usable entry with one of the given tags.
+func (k *checker) ref(location, what string, index uint16, tags ...uint8) (ConstantPoolEntry, bool) {
+	entry, err := k.cp.entry(index)
+	if err != nil {
+		k.addf(location, "%s: %v", what, err)
+		return entry, false
+	}
+	for _, tag := range tags {
+		if entry.Tag == tag {
+			return entry, true
+		}
+	}
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = TagName(tag)
+	}
+	k.addf(location, "%s: constant pool index %d is a %s, expected %s", what, index, TagName(entry.Tag), strings.Join(names, " or "))
+	return entry, false
+}
+
+// optionalRef is ref for indexes that may be zero.
+func (k *checker) optionalRef(location, what string, index uint16, tags ...uint8) {
+	if index != 0 {
+		k.ref(location, what, index, tags...)
+	}
+}
+
+// utf8 checks that index points at a Utf8 entry and returns its string.
+func (k *checker) utf8(location, what string, index uint16) (string, bool) {
+	entry, ok := k.ref(location, what, index, TagUtf8)
+	if !ok {
+		return "", false
+	}
+	return entry.Value.(*ConstantUtf8Value).String(), true
+}
+
+func (k *checker) checkHeader() {
+	if k.class.Magic != 0xCAFEBABE {
+		k.addf("header", "invalid magic number 0x%08X", k.class.Magic)
+	}
+	if err := checkVersion(k.class.MajorVersion, k.class.MinorVersion, true); err != nil {
+		k.addf("header", "%v", err)
+	}
+}
+
+func (k *checker) checkConstantPool() {
+	entries := k.cp.entries
+	major := k.class.MajorVersion
+	for i := 1; i < len(entries); i++ {
+		entry := entries[i]
+		location := fmt.Sprintf("constant pool #%d", i)
+		if entry.Value == nil {
+			k.addf(location, "missing entry")
+			continue
+		}
+		if minVersion, ok := tagMinMajorVersions[entry.Tag]; ok && major < minVersion {
+			k.addf(location, "%s constants require class file version %d or later, got %d", TagName(entry.Tag), minVersion, major)
+		}
+
+		switch value := entry.Value.(type) {
+		case *ConstantClassRefValue:
+			if name, ok := k.utf8(location, "name", value.Index); ok {
+				k.checkClassName(location, name, true)
+			}
+		case *ConstantStringRefValue:
+			k.utf8(location, "string", value.Index)
+		case *ConstantFieldRefValue:
+			k.checkMemberRef(location, entry.Tag, value.ClassIndex, value.NameAndTypeIndex)
+		case *ConstantMethodRefValue:
+			k.checkMemberRef(location, entry.Tag, value.ClassIndex, value.NameAndTypeIndex)
+		case *ConstantInterfaceMethodRefValue:
+			k.checkMemberRef(location, entry.Tag, value.ClassIndex, value.NameAndTypeIndex)
+		case *ConstantNameAndTypeDescriptorValue:
+			k.checkNameAndType(location, value)
+		case *ConstantMethodHandleValue:
+			k.checkMethodHandle(location, value)
+		case *ConstantMethodTypeValue:
+			if desc, ok := k.utf8(location, "descriptor", value.DescriptorIndex); ok {
+				k.checkMethodDescriptor(location, desc)
+			}
+		case *ConstantDynamicValue:
+			k.checkDynamic(location, value.BootstrapMethodAttrIndex, value.NameAndTypeIndex, false)
+		case *ConstantInvokeDynamicValue:
+			k.checkDynamic(location, value.BootstrapMethodAttrIndex, value.NameAndTypeIndex, true)
+		case *ConstantModuleValue:
+			k.checkModuleConstant(location, value.NameIndex)
+		case *ConstantPackageValue:
+			k.checkModuleConstant(location, value.NameIndex)
+		}
+
+		if entry.Tag == TagLong || entry.Tag == TagDouble {
+			if i+1 >= len(entries) {
+				k.addf(location, "%s is the last entry but takes two slots", TagName(entry.Tag))
+			}
+			i++
+		}
+	}
+}
+
+func (k *checker) checkMemberRef(location string, tag uint8, classIndex, nameAndTypeIndex uint16) {
+	k.ref(location, "class", classIndex, TagClass)
+	name, desc, err := k.cp.nameAndType(nameAndTypeIndex)
+	if err != nil {
+		k.addf(location, "name and type: %v", err)
+		return
+	}
+	if tag == TagFieldRef {
+		if _, err := descriptor.ParseField(desc); err != nil {
+			k.addf(location, "%v", err)
+		}
+		return
+	}
+
+	if strings.HasPrefix(name, "<") {
+		if tag == TagInterfaceMethodRef || name != "<init>" {
+			k.addf(location, "%s cannot refer to %s", TagName(tag), name)
+		} else if !strings.HasSuffix(desc, ")V") {
+			k.addf(location, "<init> must return void, got descriptor %s", desc)
+		}
+	}
+	k.checkMethodDescriptor(location, desc)
+}
+
+func (k *checker) checkNameAndType(location string, value *ConstantNameAndTypeDescriptorValue) {
+	if name, ok := k.utf8(location, "name", value.NameIndex); ok && name != "<init>" && name != "<clinit>" {
+		k.checkUnqualifiedName(location, name, false)
+	}
+	if desc, ok := k.utf8(location, "descriptor", value.DescriptorIndex); ok {
+		if strings.HasPrefix(desc, "(") {
+			k.checkMethodDescriptor(location, desc)
+		} else if _, err := descriptor.ParseField(desc); err != nil {
+			k.addf(location, "%v", err)
+		}
+	}
+}
+
+func (k *checker) checkMethodHandle(location string, value *ConstantMethodHandleValue) {
+	var tags []uint8
+	switch value.ReferenceKind {
+	case RefGetField, RefGetStatic, RefPutField, RefPutStatic:
+		tags = []uint8{TagFieldRef}
+	case RefInvokeVirtual, RefNewInvokeSpecial:
+		tags = []uint8{TagMethodRef}
+	case RefInvokeStatic, RefInvokeSpecial:
+		tags = []uint8{TagMethodRef}
+		if k.class.MajorVersion >= 52 {
+			tags = append(tags, TagInterfaceMethodRef)
+		}
+	case RefInvokeInterface:
+		tags = []uint8{TagInterfaceMethodRef}
+	default:
+		k.addf(location, "invalid reference kind %d", value.ReferenceKind)
+		return
+	}
+	if _, ok := k.ref(location, "reference", value.ReferenceIndex, tags...); !ok {
+		return
+	}
+
+	_, name, _, err := k.cp.memberRef(value.ReferenceIndex)
+	if err != nil {
+		// Reported when the referenced entry itself is checked
+		return
+	}
+	switch {
+	case value.ReferenceKind == RefNewInvokeSpecial && name != "<init>":
+		k.addf(location, "%s must refer to <init>, got %s", value.ReferenceKind, name)
+	case value.ReferenceKind >= RefInvokeVirtual && value.ReferenceKind != RefNewInvokeSpecial &&
+		(name == "<init>" || name == "<clinit>"):
+		k.addf(location, "%s cannot refer to %s", value.ReferenceKind, name)
+	}
+}
+
+func (k *checker) checkDynamic(location string, bootstrapIndex, nameAndTypeIndex uint16, isMethod bool) {
+	bootstrap, err := k.class.BootstrapMethods()
+	switch {
+	case err != nil:
+		// checkAttribute reports the BootstrapMethods attribute that fails to decode
+	case bootstrap == nil:
+		k.addf(location, "class has no BootstrapMethods attribute")
+	case int(bootstrapIndex) >= len(bootstrap.BootstrapMethods):
+		k.addf(location, "bootstrap method index %d is out of range, class has %d", bootstrapIndex, len(bootstrap.BootstrapMethods))
+	}
+
+	_, desc, err := k.cp.nameAndType(nameAndTypeIndex)
+	if err != nil {
+		k.addf(location, "name and type: %v", err)
+		return
+	}
+	if isMethod {
+		k.checkMethodDescriptor(location, desc)
+	} else if _, err := descriptor.ParseField(desc); err != nil {
+		k.addf(location, "%v", err)
+	}
+}
+
+func (k *checker) checkModuleConstant(location string, nameIndex uint16) {
+	if !k.class.AccessFlags.IsModule() {
+		k.addf(location, "Module and Package constants may only appear in a module-info class")
+	}
+	if name, ok := k.utf8(location, "name", nameIndex); ok && name == "" {
+		k.addf(location, "empty name")
+	}
+}
+
+// checkClassName checks a binary class name in internal form. Class constants
+// may also hold array descriptors when allowArray is set.
+func (k *checker) checkClassName(location, name string, allowArray bool) {
+	if strings.HasPrefix(name, "[") {
+		if !allowArray {
+			k.addf(location, "%q is an array type, expected a class", name)
+		} else if _, err := descriptor.ParseField(name); err != nil {
+			k.addf(location, "%v", err)
+		}
+		return
+	}
+	if name == "" {
+		k.addf(location, "empty class name")
+		return
+	}
+	for _, segment := range strings.Split(name, "/") {
+		if !k.checkUnqualifiedName(location, segment, false) {
+			return
+		}
+	}
+}
+
+// checkUnqualifiedName checks a field or method name per JVMS 4.2.2. Method
+// names may not contain '<' or '>' either.
+func (k *checker) checkUnqualifiedName(location, name string, isMethod bool) bool {
+	illegal := ".;[/"
+	if isMethod {
+		illegal += "<>"
+	}
+	if name == "" {
+		k.addf(location, "empty name")
+		return false
+	}
+	if strings.ContainsAny(name, illegal) {
+		k.addf(location, "illegal name %q", name)
+		return false
+	}
+	return true
+}
+
+func (k *checker) checkMethodDescriptor(location, desc string) (descriptor.MethodDescriptor, bool) {
+	method, err := descriptor.ParseMethod(desc)
+	if err != nil {
+		k.addf(location, "%v", err)
+		return method, false
+	}
+	return method, true
+}
+
+func (k *checker) checkClass() {
+	c := k.class
+	if err := c.AccessFlags.Validate(c.MajorVersion); err != nil {
+		k.addErr("class", err)
+	}
+
+	thisName := ""
+	if entry, ok := k.ref("class", "this_class", c.ThisClass, TagClass); ok {
+		thisName, _ = k.cp.utf8(entry.Value.(*ConstantClassRefValue).Index)
+		if strings.HasPrefix(thisName, "[") {
+			k.addf("class", "this_class %q is an array type", thisName)
+		}
+	}
+
+	if c.AccessFlags.IsModule() {
+		if thisName != "" && thisName != "module-info" {
+			k.addf("class", "module class is named %q, expected module-info", thisName)
+		}
+		if c.SuperClass != 0 || len(c.Interfaces) > 0 || len(c.Fields) > 0 || len(c.Methods) > 0 {
+			k.addf("class", "module-info class may not have a superclass, interfaces, fields or methods")
+		}
+		if findAttribute(c.Attributes, "Module") == nil {
+			k.addf("class", "module-info class has no Module attribute")
+		}
+		return
+	}
+
+	if c.SuperClass == 0 {
+		if thisName != "java/lang/Object" {
+			k.addf("class", "super_class is zero but only java/lang/Object has no superclass")
+		}
+	} else if entry, ok := k.ref("class", "super_class", c.SuperClass, TagClass); ok {
+		superName, _ := k.cp.utf8(entry.Value.(*ConstantClassRefValue).Index)
+		switch {
+		case strings.HasPrefix(superName, "["):
+			k.addf("class", "super_class %q is an array type", superName)
+		case c.AccessFlags.IsInterface() && superName != "java/lang/Object":
+			k.addf("class", "interface super_class is %q, expected java/lang/Object", superName)
+		}
+	}
+
+	seen := make(map[uint16]bool)
+	for i, index := range c.Interfaces {
+		location := fmt.Sprintf("interface %d", i)
+		k.ref(location, "interface", index, TagClass)
+		if seen[index] {
+			k.addf(location, "duplicate interface #%d", index)
+		}
+		seen[index] = true
+	}
+}
+
+func (k *checker) checkField(i int, field *Field) {
+	location := fmt.Sprintf("field %d", i)
+	name, nameOK := k.utf8(location, "name", field.NameIndex)
+	desc, descOK := k.utf8(location, "descriptor", field.DescriptorIndex)
+	if nameOK && descOK {
+		location = fmt.Sprintf("field %s:%s", name, desc)
+	}
+
+	if nameOK {
+		k.checkUnqualifiedName(location, name, false)
+	}
+	var fieldType descriptor.FieldType
+	if descOK {
+		var err error
+		if fieldType, err = descriptor.ParseField(desc); err != nil {
+			k.addf(location, "%v", err)
+			descOK = false
+		}
+	}
+	for j := 0; j < i; j++ {
+		other := &k.class.Fields[j]
+		if other.NameIndex == field.NameIndex && other.DescriptorIndex == field.DescriptorIndex && nameOK && descOK {
+			k.addf(location, "duplicate field, also declared as field %d", j)
+			break
+		}
+	}
+
+	if err := field.AccessFlags.Validate(k.class.AccessFlags.IsInterface()); err != nil {
+		k.addErr(location, err)
+	}
+
+	if constant, _ := field.ConstantValue(); constant != nil && descOK && field.AccessFlags.IsStatic() {
+		k.checkConstantValue(location, fieldType, constant.ConstantValueIndex)
+	}
+	k.checkAttributes(location, field.Attributes)
+}
+
+// checkConstantValue checks that a ConstantValue attribute points at a
+// constant of the field's type, per JVMS table 4.7.2-A.
+func (k *checker) checkConstantValue(location string, fieldType descriptor.FieldType, index uint16) {
+	location += " attribute ConstantValue"
+	switch {
+	case fieldType.IsArray():
+		k.addf(location, "array fields cannot have a constant value")
+	case fieldType.Base == descriptor.Long:
+		k.ref(location, "value", index, TagLong)
+	case fieldType.Base == descriptor.Float:
+		k.ref(location, "value", index, TagFloat)
+	case fieldType.Base == descriptor.Double:
+		k.ref(location, "value", index, TagDouble)
+	case fieldType.Base == descriptor.Object:
+		if fieldType.ClassName != "java/lang/String" {
+			k.addf(location, "fields of type %s cannot have a constant value", fieldType.JavaName())
+		} else {
+			k.ref(location, "value", index, TagString)
+		}
+	default:
+		k.ref(location, "value", index, TagInteger)
+	}
+}
+
+func (k *checker) checkMethod(i int, method *Method) {
+	location := fmt.Sprintf("method %d", i)
+	name, nameOK := k.utf8(location, "name", method.NameIndex)
+	desc, descOK := k.utf8(location, "descriptor", method.DescriptorIndex)
+	if nameOK && descOK {
+		location = fmt.Sprintf("method %s%s", name, desc)
+	}
+
+	isInterface := k.class.AccessFlags.IsInterface()
+	if nameOK {
+		switch name {
+		case "<init>":
+			if isInterface {
+				k.addf(location, "interfaces cannot declare <init>")
+			}
+		case "<clinit>":
+		default:
+			k.checkUnqualifiedName(location, name, true)
+		}
+	}
+
+	if descOK {
+		if parsed, ok := k.checkMethodDescriptor(location, desc); ok {
+			slots := parsed.ArgSlots()
+			if !method.AccessFlags.IsStatic() {
+				slots++
+			}
+			if slots > 255 {
+				k.addf(location, "parameters take %d slots, more than the limit of 255", slots)
+			}
+			if (name == "<init>" || name == "<clinit>") && !parsed.ReturnsVoid() {
+				k.addf(location, "%s must return void", name)
+			}
+			if name == "<clinit>" && k.class.MajorVersion >= 51 && len(parsed.Params) > 0 {
+				k.addf(location, "<clinit> cannot take parameters")
+			}
+		} else {
+			descOK = false
+		}
+	}
+	for j := 0; j < i; j++ {
+		other := &k.class.Methods[j]
+		if other.NameIndex == method.NameIndex && other.DescriptorIndex == method.DescriptorIndex && nameOK && descOK {
+			k.addf(location, "duplicate method, also declared as method %d", j)
+			break
+		}
+	}
+
+	if nameOK {
+		if err := method.AccessFlags.Validate(name, isInterface, k.class.MajorVersion); err != nil {
+			k.addErr(location, err)
+		}
+	}
+
+	codeCount := 0
+	for j := range method.Attributes {
+		if method.Attributes[j].Name == "Code" {
+			codeCount++
+		}
+	}
+	hasNoCode := method.AccessFlags.IsAbstract() || method.AccessFlags.IsNative()
+	switch {
+	case hasNoCode && codeCount > 0:
+		k.addf(location, "abstract and native methods cannot have a Code attribute")
+	case !hasNoCode && codeCount == 0:
+		k.addf(location, "missing Code attribute")
+	}
+	k.checkAttributes(location, method.Attributes)
+}
+
+func (k *checker) checkCode(location string, code *Code) {
+	if len(code.Bytecode) == 0 || len(code.Bytecode) > 65535 {
+		k.addf(location, "code length %d is not in the range 1 to 65535", len(code.Bytecode))
+	}
+	index, err := code.Instructions()
+	if err != nil {
+		k.addf(location, "%v", err)
+	}
+	// isBoundary reports whether pc starts an instruction, or, when the code
+	// could not be decoded, just whether it is inside the code
+	isBoundary := func(pc uint16) bool {
+		if index == nil {
+			return int(pc) < len(code.Bytecode)
+		}
+		return index.IsBoundary(int(pc))
+	}
+	for i, entry := range code.ExceptionTable {
+		entryLocation := fmt.Sprintf("%s exception table entry %d", location, i)
+		if entry.StartPc >= entry.EndPc || int(entry.EndPc) > len(code.Bytecode) {
+			k.addf(entryLocation, "invalid range [%d, %d)", entry.StartPc, entry.EndPc)
+		} else if !isBoundary(entry.StartPc) || (int(entry.EndPc) < len(code.Bytecode) && !isBoundary(entry.EndPc)) {
+			k.addf(entryLocation, "range [%d, %d) does not start and end on instructions", entry.StartPc, entry.EndPc)
+		}
+		if !isBoundary(entry.HandlerPc) {
+			k.addf(entryLocation, "handler %d is not the start of an instruction in the code", entry.HandlerPc)
+		}
+		k.optionalRef(entryLocation, "catch type", entry.CatchType, TagClass)
+	}
+	k.checkAttributes(location, code.Attributes)
+}
+
+// uniqueAttributes are the standard attributes that may appear at most once
+// in the same attributes table.
+var uniqueAttributes = map[string]bool{
+	"AnnotationDefault":                    true,
+	"BootstrapMethods":                     true,
+	"Code":                                 true,
+	"ConstantValue":                        true,
+	"EnclosingMethod":                      true,
+	"Exceptions":                           true,
+	"InnerClasses":                         true,
+	"MethodParameters":                     true,
+	"Module":                               true,
+	"ModuleMainClass":                      true,
+	"ModulePackages":                       true,
+	"NestHost":                             true,
+	"NestMembers":                          true,
+	"PermittedSubclasses":                  true,
+	"Record":                               true,
+	"RuntimeInvisibleAnnotations":          true,
+	"RuntimeInvisibleParameterAnnotations": true,
+	"RuntimeInvisibleTypeAnnotations":      true,
+	"RuntimeVisibleAnnotations":            true,
+	"RuntimeVisibleParameterAnnotations":   true,
+	"RuntimeVisibleTypeAnnotations":        true,
+	"Signature":                            true,
+	"SourceDebugExtension":                 true,
+	"SourceFile":                           true,
+	"StackMapTable":                        true,
+}
+
+func (k *checker) checkAttributes(location string, attributes []Attribute) {
+	seen := make(map[string]bool)
+	for i := range attributes {
+		attr := &attributes[i]
+		attrLocation := fmt.Sprintf("%s attribute %d", location, i)
+		if _, ok := k.utf8(attrLocation, "name", attr.AttributeNameIndex); !ok {
+			continue
+		}
+		attrLocation = location + " attribute " + attr.Name
+		if uniqueAttributes[attr.Name] && seen[attr.Name] {
+			k.addf(attrLocation, "attribute appears more than once")
+		}
+		seen[attr.Name] = true
+		k.checkAttribute(attrLocation, attr)
+	}
+}
+
+// checkAttribute checks the constant pool references of the standard attributes.
+func (k *checker) checkAttribute(location string, attr *Attribute) {
+	value, err := attr.Decode()
+	if err != nil {
+		k.addf(location, "%v", err)
+		return
+	}
+	switch value := value.(type) {
+	case *Code:
+		k.checkCode(location, value)
+	case *SourceFileAttribute:
+		k.utf8(location, "source file", value.SourceFileIndex)
+	case *SignatureAttribute:
+		k.utf8(location, "signature", value.SignatureIndex)
+	case *ExceptionsAttribute:
+		for _, index := range value.ExceptionIndexTable {
+			k.ref(location, "exception", index, TagClass)
+		}
+	case *EnclosingMethodAttribute:
+		k.ref(location, "class", value.ClassIndex, TagClass)
+		k.optionalRef(location, "method", value.MethodIndex, TagNameAndType)
+	case *InnerClassesAttribute:
+		for _, inner := range value.Classes {
+			k.ref(location, "inner class", inner.InnerClassInfoIndex, TagClass)
+			k.optionalRef(location, "outer class", inner.OuterClassInfoIndex, TagClass)
+			k.optionalRef(location, "inner name", inner.InnerNameIndex, TagUtf8)
+			if err := inner.InnerClassAccessFlags.Validate(); err != nil {
+				k.addErr(location, err)
+			}
+		}
+	case *NestHostAttribute:
+		k.ref(location, "host class", value.HostClassIndex, TagClass)
+	case *NestMembersAttribute:
+		for _, index := range value.Classes {
+			k.ref(location, "member", index, TagClass)
+		}
+	case *PermittedSubclassesAttribute:
+		for _, index := range value.Classes {
+			k.ref(location, "subclass", index, TagClass)
+		}
+	case *BootstrapMethodsAttribute:
+		for i, method := range value.BootstrapMethods {
+			methodLocation := fmt.Sprintf("%s method %d", location, i)
+			k.ref(methodLocation, "bootstrap method", method.BootstrapMethodRef, TagMethodHandle)
+			for _, index := range method.BootstrapArguments {
+				k.ref(methodLocation, "argument", index, TagInteger, TagFloat, TagLong, TagDouble,
+					TagClass, TagString, TagMethodHandle, TagMethodType, TagDynamic)
+			}
+		}
+	case *LocalVariableTableAttribute:
+		for _, entry := range value.LocalVariableTable {
+			if name, ok := k.utf8(location, "name", entry.NameIndex); ok {
+				k.checkUnqualifiedName(location, name, false)
+			}
+			if desc, ok := k.utf8(location, "descriptor", entry.DescriptorIndex); ok {
+				if _, err := descriptor.ParseField(desc); err != nil {
+					k.addf(location, "%v", err)
+				}
+			}
+		}
+	case *LocalVariableTypeTableAttribute:
+		for _, entry := range value.LocalVariableTypeTable {
+			k.utf8(location, "name", entry.NameIndex)
+			k.utf8(location, "signature", entry.SignatureIndex)
+		}
+	case *MethodParametersAttribute:
+		for _, param := range value.Parameters {
+			k.optionalRef(location, "parameter name", param.NameIndex, TagUtf8)
+		}
+	case *RecordAttribute:
+		for i := range value.Components {
+			component := &value.Components[i]
+			componentLocation := fmt.Sprintf("%s component %s", location, component.Name)
+			if _, err := descriptor.ParseField(component.Descriptor); err != nil {
+				k.addf(componentLocation, "%v", err)
+			}
+			k.checkAttributes(componentLocation, component.Attributes)
+		}
+	case *ModulePackagesAttribute:
+		for _, index := range value.PackageIndex {
+			k.ref(location, "package", index, TagPackage)
+		}
+	case *ModuleMainClassAttribute:
+		k.ref(location, "main class", value.MainClassIndex, TagClass)
+	case *ModuleAttribute:
+		k.checkModule(location, value)
+	case *StackMapTableAttribute:
+		for _, frame := range value.Entries {
+			for _, types := range [][]VerificationTypeInfo{frame.Locals, frame.Stack} {
+				for _, info := range types {
+					if info.Tag == ItemObject {
+						k.ref(location, "object type", info.CpoolIndex, TagClass)
+					}
+				}
+			}
+		}
+	case *RuntimeVisibleAnnotationsAttribute:
+		k.checkAnnotations(location, value.Annotations)
+	case *RuntimeInvisibleAnnotationsAttribute:
+		k.checkAnnotations(location, value.Annotations)
+	case *RuntimeVisibleParameterAnnotationsAttribute:
+		for _, annotations := range value.ParameterAnnotations {
+			k.checkAnnotations(location, annotations)
+		}
+	case *RuntimeInvisibleParameterAnnotationsAttribute:
+		for _, annotations := range value.ParameterAnnotations {
+			k.checkAnnotations(location, annotations)
+		}
+	case *RuntimeVisibleTypeAnnotationsAttribute:
+		for i := range value.Annotations {
+			k.checkAnnotation(location, &value.Annotations[i].Annotation)
+		}
+	case *RuntimeInvisibleTypeAnnotationsAttribute:
+		for i := range value.Annotations {
+			k.checkAnnotation(location, &value.Annotations[i].Annotation)
+		}
+	case *AnnotationDefaultAttribute:
+		k.checkElementValue(location, &value.DefaultValue)
+	}
+}
+
+func (k *checker) checkAnnotations(location string, annotations []Annotation) {
+	for i := range annotations {
+		k.checkAnnotation(location, &annotations[i])
+	}
+}
+
+func (k *checker) checkAnnotation(location string, annotation *Annotation) {
+	k.fieldDescriptor(location, "annotation type", annotation.TypeIndex)
+	for i := range annotation.ElementValuePairs {
+		pair := &annotation.ElementValuePairs[i]
+		k.utf8(location, "element name", pair.ElementNameIndex)
+		k.checkElementValue(location, &pair.Value)
+	}
+}
+
+// elementValueTags are the constant pool tags of the constant element values,
+// per JVMS table 4.7.16.1-A.
+var elementValueTags = map[byte]uint8{
+	'B': TagInteger,
+	'C': TagInteger,
+	'I': TagInteger,
+	'S': TagInteger,
+	'Z': TagInteger,
+	'D': TagDouble,
+	'F': TagFloat,
+	'J': TagLong,
+	's': TagUtf8,
+}
+
+func (k *checker) checkElementValue(location string, value *ElementValue) {
+	switch value.Tag {
+	case 'B', 'C', 'D', 'F', 'I', 'J', 'S', 'Z', 's':
+		k.ref(location, fmt.Sprintf("element value of type %c", value.Tag), value.ConstValueIndex, elementValueTags[value.Tag])
+	case 'e':
+		k.fieldDescriptor(location, "enum type", value.TypeNameIndex)
+		k.utf8(location, "enum constant", value.ConstNameIndex)
+	case 'c':
+		// A return descriptor, so void.class is written as V
+		if desc, ok := k.utf8(location, "class", value.ClassInfoIndex); ok && desc != "V" {
+			if _, err := descriptor.ParseField(desc); err != nil {
+				k.addf(location, "%v", err)
+			}
+		}
+	case '@':
+		if value.AnnotationValue != nil {
+			k.checkAnnotation(location, value.AnnotationValue)
+		}
+	case '[':
+		for i := range value.ArrayValue {
+			k.checkElementValue(location, &value.ArrayValue[i])
+		}
+	}
+}
+
+// fieldDescriptor checks that index points at a Utf8 entry holding a field
+// descriptor.
+func (k *checker) fieldDescriptor(location, what string, index uint16) {
+	if desc, ok := k.utf8(location, what, index); ok {
+		if _, err := descriptor.ParseField(desc); err != nil {
+			k.addf(location, "%s: %v", what, err)
+		}
+	}
+}
+
+func (k *checker) checkModule(location string, module *ModuleAttribute) {
+	k.ref(location, "module name", module.ModuleNameIndex, TagModule)
+	k.optionalRef(location, "module version", module.ModuleVersionIndex, TagUtf8)
+	for _, requires := range module.Requires {
+		k.ref(location, "requires", requires.RequiresIndex, TagModule)
+		k.optionalRef(location, "requires version", requires.RequiresVersionIndex, TagUtf8)
+	}
+	for _, exports := range module.Exports {
+		k.ref(location, "exports", exports.ExportsIndex, TagPackage)
+		for _, index := range exports.ExportsToIndex {
+			k.ref(location, "exports to", index, TagModule)
+		}
+	}
+	for _, opens := range module.Opens {
+		k.ref(location, "opens", opens.OpensIndex, TagPackage)
+		for _, index := range opens.OpensToIndex {
+			k.ref(location, "opens to", index, TagModule)
+		}
+	}
+	for _, index := range module.UsesIndex {
+		k.ref(location, "uses", index, TagClass)
+	}
+	for _, provides := range module.Provides {
+		k.ref(location, "provides", provides.ProvidesIndex, TagClass)
+		for _, index := range provides.ProvidesWithIndex {
+			k.ref(location, "provides with", index, TagClass)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package class
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func parseTestClass(t *testing.T) *Class {
+	t.Helper()
+	c, err := Parse("../../tst/Test.class")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// addClassAttribute adds an attribute with the given info to the class,
+// decoding it as parsing would.
+func addClassAttribute(t *testing.T, c *Class, name string, info []byte) {
+	t.Helper()
+	addAttribute(t, &c.ConstantPool, &c.Attributes, name, info)
+}
+
+// addAttribute adds an attribute with the given info to attributes, such as
+// those of a field, method or Code attribute, decoding it as parsing would.
+func addAttribute(t *testing.T, cp *ConstantPool, attributes *[]Attribute, name string, info []byte) {
+	t.Helper()
+	nameIndex, err := cp.AddUtf8(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attr := Attribute{AttributeNameIndex: nameIndex, AttributeLength: uint32(len(info)), Info: info}
+	if err := attr.decode(cp); err != nil {
+		t.Fatal(err)
+	}
+	*attributes = append(*attributes, attr)
+}
+
+// utf8Index adds s to the constant pool of c if needed and returns its index.
+func utf8Index(t *testing.T, c *Class, s string) uint16 {
+	t.Helper()
+	index, err := c.ConstantPool.AddUtf8(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return index
+}
+
+func u2(values ...uint16) []byte {
+	var buf bytes.Buffer
+	for _, v := range values {
+		put(&buf, v)
+	}
+	return buf.Bytes()
+}
+
+func TestCheckTestClass(t *testing.T) {
+	if err := Check(parseTestClass(t)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckAnnotationRefs(t *testing.T) {
+	tests := []struct {
+		name string
+		// info returns the info of a RuntimeVisibleAnnotations attribute
+		// holding one annotation
+		info func(cp *ConstantPool) []byte
+		want string
+	}{
+		{
+			name: "valid",
+			info: func(cp *ConstantPool) []byte {
+				typeIndex, _ := cp.AddUtf8("Ljava/lang/Deprecated;")
+				name, _ := cp.AddUtf8("since")
+				value, _ := cp.AddUtf8("9")
+				return append(append(u2(1, typeIndex, 1, name), 's'), u2(value)...)
+			},
+		},
+		{
+			name: "type is a Methodref",
+			info: func(cp *ConstantPool) []byte {
+				method, _ := cp.AddMethodRef("Test", "foo", "()Z")
+				return u2(1, method, 0)
+			},
+			want: "annotation type: constant pool index",
+		},
+		{
+			name: "type is not a descriptor",
+			info: func(cp *ConstantPool) []byte {
+				typeIndex, _ := cp.AddUtf8("java/lang/Deprecated")
+				return u2(1, typeIndex, 0)
+			},
+			want: "annotation type:",
+		},
+		{
+			name: "int element is a Utf8",
+			info: func(cp *ConstantPool) []byte {
+				typeIndex, _ := cp.AddUtf8("LA;")
+				name, _ := cp.AddUtf8("value")
+				return append(append(u2(1, typeIndex, 1, name), 'I'), u2(name)...)
+			},
+			want: "element value of type I: constant pool index",
+		},
+		{
+			name: "enum constant name is a Class",
+			info: func(cp *ConstantPool) []byte {
+				typeIndex, _ := cp.AddUtf8("LA;")
+				name, _ := cp.AddUtf8("value")
+				class, _ := cp.AddClass("Test")
+				return append(append(u2(1, typeIndex, 1, name), 'e'), u2(typeIndex, class)...)
+			},
+			want: "enum constant: constant pool index",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := parseTestClass(t)
+			addClassAttribute(t, c, "RuntimeVisibleAnnotations", test.info(&c.ConstantPool))
+			err := Check(c)
+			if test.want == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			var checkErr *CheckError
+			if !errors.As(err, &checkErr) {
+				t.Fatalf("Check() = %v, want a *CheckError", err)
+			}
+			if !strings.Contains(err.Error(), test.want) {
+				t.Errorf("Check() = %v, want a violation containing %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckStackMapObjectRef(t *testing.T) {
+	c := parseTestClass(t)
+	code, err := c.FindMethod("main", "").GetCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nameIndex, err := c.ConstantPool.AddUtf8("StackMapTable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// One full_frame at offset 0 with an Object local pointing at a Utf8
+	info := append(u2(1), 255)
+	info = append(info, u2(0, 1)...)
+	info = append(info, ItemObject)
+	info = append(info, u2(nameIndex, 0)...)
+	attr := Attribute{AttributeNameIndex: nameIndex, AttributeLength: uint32(len(info)), Info: info}
+	if err := attr.decode(&c.ConstantPool); err != nil {
+		t.Fatal(err)
+	}
+	code.Attributes = append(code.Attributes, attr)
+
+	err = Check(c)
+	if err == nil || !strings.Contains(err.Error(), "object type: constant pool index") {
+		t.Errorf("Check() = %v, want an object type violation", err)
+	}
+}
+
+func TestCheckBytes(t *testing.T) {
+	c := parseTestClass(t)
+	valid, err := c.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckBytes(valid); err != nil {
+		t.Fatalf("CheckBytes() = %v for Test.class", err)
+	}
+
+	// this_class pointing at the Utf8 "Yes" and one byte past the end are
+	// both reported
+	c.ThisClass = 8
+	data, err := c.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = CheckBytes(append(data, 0))
+	checkErr, ok := err.(*CheckError)
+	if !ok {
+		t.Fatalf("CheckBytes() = %v, want a *CheckError", err)
+	}
+	want := []Violation{
+		{"class file", "extra bytes after the end of the class file (1)"},
+		{"class", "this_class: constant pool index 8 is a Utf8, expected Class"},
+	}
+	if len(checkErr.Violations) != len(want) {
+		t.Fatalf("CheckBytes() = %v, want %d violations", err, len(want))
+	}
+	for i, violation := range checkErr.Violations {
+		if violation != want[i] {
+			t.Errorf("violation %d = %q, want %q", i, violation, want[i])
+		}
+	}
+
+	if err := CheckBytes(valid[:len(valid)-1]); err == nil {
+		t.Error("CheckBytes() = nil for a truncated class file")
+	} else if _, ok := err.(*CheckError); ok {
+		t.Errorf("CheckBytes() = %v for a truncated class file, want the parse error", err)
+	}
+}
+
+func TestCheckExceptionTable(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry ExceptionTableEntry
+		want  string
+	}{
+		{"valid", ExceptionTableEntry{StartPc: 0, EndPc: 8, HandlerPc: 13}, ""},
+		{"whole code", ExceptionTableEntry{StartPc: 0, EndPc: 14, HandlerPc: 13}, ""},
+		{"start inside new", ExceptionTableEntry{StartPc: 1, EndPc: 8, HandlerPc: 13},
+			"range [1, 8) does not start and end on instructions"},
+		{"end inside invokespecial", ExceptionTableEntry{StartPc: 0, EndPc: 5, HandlerPc: 13},
+			"range [0, 5) does not start and end on instructions"},
+		{"handler inside invokevirtual", ExceptionTableEntry{StartPc: 0, EndPc: 8, HandlerPc: 10},
+			"handler 10 is not the start of an instruction in the code"},
+		{"handler past the end", ExceptionTableEntry{StartPc: 0, EndPc: 8, HandlerPc: 14},
+			"handler 14 is not the start of an instruction in the code"},
+		{"empty range", ExceptionTableEntry{StartPc: 8, EndPc: 8, HandlerPc: 13}, "invalid range [8, 8)"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := parseTestClass(t)
+			code := mainCode(t, c)
+			code.ExceptionTable = []ExceptionTableEntry{test.entry}
+			err := Check(c)
+			if test.want == "" {
+				if err != nil {
+					t.Errorf("Check() = %v, want no violations", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), "exception table entry 0: "+test.want) {
+				t.Errorf("Check() = %v, want a violation containing %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestCodeInstructions(t *testing.T) {
+	c := parseTestClass(t)
+	code := mainCode(t, c)
+	index, err := code.Instructions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index.Len() != len(code.Bytecode) || len(index.Instructions) != 8 {
+		t.Errorf("Instructions() has %d instructions over %d bytes, want 8 over %d", len(index.Instructions), index.Len(), len(code.Bytecode))
+	}
+	if instruction, ok := index.At(9); !ok || instruction.Mnemonic() != "invokevirtual" || instruction.ConstantIndex != 16 {
+		t.Errorf("At(9) = %+v, %v, want invokevirtual #16", instruction, ok)
+	}
+
+	// A goto over the pop into the middle of the invokevirtual before it
+	code.Bytecode = append(code.Bytecode[:12:12], 0xa7, 0xff, 0xfe, 0xb1)
+	if _, err := code.Instructions(); err == nil || !strings.Contains(err.Error(), "goto at pc 12 jumps to 10, which is not the start of an instruction") {
+		t.Errorf("Instructions() = %v, want a jump boundary error", err)
+	}
+	if err := Check(c); err == nil || !strings.Contains(err.Error(), "jumps to 10") {
+		t.Errorf("Check() = %v, want the jump boundary error", err)
+	}
+}
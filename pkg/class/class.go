@@ -1,19 +1,28 @@
 package class
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
+	"lava-vm/pkg/signature"
 	"os"
 	"strings"
 )
 
 type Class struct {
-	Magic             uint32
-	MinorVersion      uint16
-	MajorVersion      uint16
+	Magic        uint32
+	MinorVersion uint16
+	MajorVersion uint16
+	// ConstantPoolCount and the other counts are as read from the class file.
+	// They are not kept up to date as the class is changed: the constant pool
+	// builder, RemoveUnreferencedConstants and the writer use the lengths of
+	// the tables instead, such as ConstantPool.Len.
 	ConstantPoolCount uint16
 	ConstantPool      ConstantPool
-	AccessFlags       uint16
+	AccessFlags       ClassAccessFlags
 	ThisClass         uint16
 	SuperClass        uint16
 	InterfacesCount   uint16
@@ -37,7 +46,125 @@ func (c *Class) GetConstantName(index uint16) (string, error) {
 	return "", fmt.Errorf("index does not point to a UTF-8 constant: %d", index)
 }
 
-func Parse(filename string) (*Class, error) {
+// ThisClassName returns the internal name of the class, e.g. "java/util/ArrayList".
+func (c *Class) ThisClassName() (string, error) {
+	return c.ConstantPool.className(c.ThisClass)
+}
+
+// SuperClassName returns the internal name of the superclass. It returns an
+// empty string for java/lang/Object and module-info, which have none.
+func (c *Class) SuperClassName() (string, error) {
+	if c.SuperClass == 0 {
+		return "", nil
+	}
+	return c.ConstantPool.className(c.SuperClass)
+}
+
+// InterfaceNames returns the internal names of the direct superinterfaces.
+func (c *Class) InterfaceNames() ([]string, error) {
+	return c.ConstantPool.classNames(c.Interfaces)
+}
+
+// FindField returns the field with the given name and descriptor, or nil. An
+// empty descriptor matches any field with the name.
+func (c *Class) FindField(name, descriptor string) *Field {
+	for i := range c.Fields {
+		field := &c.Fields[i]
+		if field.Name() == name && (descriptor == "" || field.Descriptor() == descriptor) {
+			return field
+		}
+	}
+	return nil
+}
+
+// FindMethod returns the method with the given name and descriptor, or nil.
+// An empty descriptor matches the first method with the name.
+func (c *Class) FindMethod(name, descriptor string) *Method {
+	for i := range c.Methods {
+		method := &c.Methods[i]
+		if method.Name() == name && (descriptor == "" || method.Descriptor() == descriptor) {
+			return method
+		}
+	}
+	return nil
+}
+
+// Attribute returns the first class attribute with the given name, or nil.
+func (c *Class) Attribute(name string) *Attribute {
+	return findAttribute(c.Attributes, name)
+}
+
+// SourceFile returns the SourceFile attribute of the class, or nil.
+func (c *Class) SourceFile() (*SourceFileAttribute, error) {
+	value, err := attributeValue(c.Attributes, "SourceFile")
+	attr, _ := value.(*SourceFileAttribute)
+	return attr, err
+}
+
+// Signature returns the Signature attribute of the class, or nil.
+func (c *Class) Signature() (*SignatureAttribute, error) {
+	value, err := attributeValue(c.Attributes, "Signature")
+	attr, _ := value.(*SignatureAttribute)
+	return attr, err
+}
+
+// GenericSignature parses the Signature attribute of the class. It returns nil
+// if the class has no Signature attribute.
+func (c *Class) GenericSignature() (*signature.ClassSignature, error) {
+	attr, err := c.Signature()
+	if attr == nil {
+		return nil, err
+	}
+	return signature.ParseClass(attr.Signature)
+}
+
+// EnclosingMethod returns the EnclosingMethod attribute of the class, or nil.
+func (c *Class) EnclosingMethod() (*EnclosingMethodAttribute, error) {
+	value, err := attributeValue(c.Attributes, "EnclosingMethod")
+	attr, _ := value.(*EnclosingMethodAttribute)
+	return attr, err
+}
+
+// IsDeprecated reports whether the class has a Deprecated attribute.
+func (c *Class) IsDeprecated() bool {
+	return findAttribute(c.Attributes, "Deprecated") != nil
+}
+
+// IsSynthetic reports whether the class has a Synthetic attribute.
+func (c *Class) IsSynthetic() bool {
+	return findAttribute(c.Attributes, "Synthetic") != nil
+}
+
+// ParseOption configures how a class file is parsed.
+type ParseOption func(*parseOptions)
+
+type parseOptions struct {
+	lazyAttributes bool
+	preview        bool
+}
+
+// WithLazyAttributes parses only the skeleton of the class: the header,
+// constant pool, and the fields, methods and attributes with their names,
+// offsets and Info bytes. Attribute bodies, such as Code, are decoded on
+// first access through Attribute.Decode or the accessors that use it, so
+// classes that are only looked up by name or signature never pay for them.
+// Info then shares memory with the class file data rather than copying it.
+func WithLazyAttributes() ParseOption {
+	return func(o *parseOptions) {
+		o.lazyAttributes = true
+	}
+}
+
+// WithPreview accepts class files that depend on the preview features of
+// the latest supported Java release, which are rejected by default.
+func WithPreview() ParseOption {
+	return func(o *parseOptions) {
+		o.preview = true
+	}
+}
+
+// Parse reads and parses the class file with the given filename.
+func Parse(filename string, opts ...ParseOption) (*Class, error) {
 	file, err := os.Open(filename)
 	if err != nil {
 		return nil, fmt.Errorf("opening file: %w", err)
@@ -46,78 +173,157 @@ func Parse(filename string) (*Class, error) {
 		_ = file.Close()
 	}(file)
 
+	reader := bufio.NewReader(file)
+	class, err := ParseReader(reader, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := reader.ReadByte(); err != io.EOF {
+		return nil, errors.New("extra bytes after the end of the class file")
+	}
+	return class, nil
+}
+
+// ParseBytes parses a class file held in memory, such as one read from a JAR
+// or embedded with go:embed. With WithLazyAttributes the class keeps
+// referring to data, which must not be modified afterwards.
+func ParseBytes(data []byte, opts ...ParseOption) (*Class, error) {
+	var options parseOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	reader := bytes.NewReader(data)
+	readAttr := readAttribute
+	if options.lazyAttributes {
+		readAttr = lazyAttributeReader(data, reader)
+	}
+	class, err := parse(reader, readAttr, options)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkEnd(reader); err != nil {
+		return nil, err
+	}
+	return class, nil
+}
+
+// ParseReader parses a class file from the given reader. The reader is
+// consumed up to the end of the class file, or to EOF with WithLazyAttributes,
+// which reads the whole class into memory first. A reader that reports the
+// bytes it has left, such as a bytes.Reader, must have none left over.
+func ParseReader(r io.Reader, opts ...ParseOption) (*Class, error) {
+	var options parseOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.lazyAttributes {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("reading class file: %w", err)
+		}
+		return ParseBytes(data, opts...)
+	}
+	class, err := parse(r, readAttribute, options)
+	if err != nil {
+		return nil, err
+	}
+	if sized, ok := r.(interface{ Len() int }); ok {
+		if err := checkEnd(sized); err != nil {
+			return nil, err
+		}
+	}
+	return class, nil
+}
+
+// checkEnd returns an error if bytes are left in r after the class file,
+// which JVMS 4.8 does not allow.
+func checkEnd(r interface{ Len() int }) error {
+	if n := r.Len(); n != 0 {
+		return fmt.Errorf("extra bytes after the end of the class file (%d)", n)
+	}
+	return nil
+}
+
+// parse parses a class file, reading every attribute with readAttr.
+func parse(r io.Reader, readAttr attributeReader, options parseOptions) (*Class, error) {
+	var err error
 	class := &Class{}
-	if err = binary.Read(file, binary.BigEndian, &class.Magic); err != nil {
+	if err = binary.Read(r, binary.BigEndian, &class.Magic); err != nil {
 		return nil, fmt.Errorf("reading magic number: %w", err)
 	}
 
 	if class.Magic != 0xCAFEBABE {
-		return nil, fmt.Errorf("magic number check: %w", err)
+		return nil, fmt.Errorf("invalid magic number 0x%08X", class.Magic)
 	}
 
-	if err = binary.Read(file, binary.BigEndian, &class.MinorVersion); err != nil {
+	if err = binary.Read(r, binary.BigEndian, &class.MinorVersion); err != nil {
 		return nil, fmt.Errorf("reading minor version: %w", err)
 	}
 
-	// 49 = Java SE 5.0 through 65 = Java SE 21
-	if err = binary.Read(file, binary.BigEndian, &class.MajorVersion); err != nil {
+	if err = binary.Read(r, binary.BigEndian, &class.MajorVersion); err != nil {
 		return nil, fmt.Errorf("reading major version: %w", err)
 	}
 
-	if err := readConstantPool(file, class); err != nil {
+	if err = checkVersion(class.MajorVersion, class.MinorVersion, options.preview); err != nil {
+		return nil, err
+	}
+
+	if err := readConstantPool(r, class); err != nil {
 		return nil, fmt.Errorf("reading constant pool: %w", err)
 	}
 
-	if err = binary.Read(file, binary.BigEndian, &class.AccessFlags); err != nil {
+	if err = binary.Read(r, binary.BigEndian, &class.AccessFlags); err != nil {
 		return nil, fmt.Errorf("reading access flags: %w", err)
 	}
 
-	if err = binary.Read(file, binary.BigEndian, &class.ThisClass); err != nil {
+	if err = binary.Read(r, binary.BigEndian, &class.ThisClass); err != nil {
 		return nil, fmt.Errorf("reading this class: %w", err)
 	}
 
-	if err = binary.Read(file, binary.BigEndian, &class.SuperClass); err != nil {
+	if err = binary.Read(r, binary.BigEndian, &class.SuperClass); err != nil {
 		return nil, fmt.Errorf("reading super class: %w", err)
 	}
 
-	if err = binary.Read(file, binary.BigEndian, &class.InterfacesCount); err != nil {
+	if err = binary.Read(r, binary.BigEndian, &class.InterfacesCount); err != nil {
 		return nil, fmt.Errorf("reading interfaces count: %w", err)
 	}
 
 	class.Interfaces = make([]uint16, class.InterfacesCount)
-	if err = binary.Read(file, binary.BigEndian, &class.Interfaces); err != nil {
+	if err = binary.Read(r, binary.BigEndian, &class.Interfaces); err != nil {
 		return nil, fmt.Errorf("reading interfaces: %w", err)
 	}
 
-	if err = binary.Read(file, binary.BigEndian, &class.FieldsCount); err != nil {
+	if err = binary.Read(r, binary.BigEndian, &class.FieldsCount); err != nil {
 		return nil, fmt.Errorf("reading fields count: %w", err)
 	}
 
 	class.Fields = make([]Field, class.FieldsCount)
 	for i := range class.Fields {
-		if err = readField(file, &class.Fields[i]); err != nil {
+		if err = readField(r, &class.Fields[i], &class.ConstantPool, readAttr); err != nil {
 			return nil, fmt.Errorf("reading field %d: %w", i, err)
 		}
 	}
 
-	if err = binary.Read(file, binary.BigEndian, &class.MethodsCount); err != nil {
+	if err = binary.Read(r, binary.BigEndian, &class.MethodsCount); err != nil {
 		return nil, fmt.Errorf("reading methods count: %w", err)
 	}
 
 	class.Methods = make([]Method, class.MethodsCount)
 	for i := range class.Methods {
-		if err = readMethod(file, &class.Methods[i], &class.ConstantPool); err != nil {
+		if err = readMethod(r, &class.Methods[i], &class.ConstantPool, readAttr); err != nil {
 			return nil, fmt.Errorf("reading method %d: %w", i, err)
 		}
 	}
 
-	if err = binary.Read(file, binary.BigEndian, &class.AttributesCount); err != nil {
+	if err = binary.Read(r, binary.BigEndian, &class.AttributesCount); err != nil {
 		return nil, fmt.Errorf("reading attributes count: %w", err)
 	}
 
 	class.Attributes = make([]Attribute, class.AttributesCount)
 	for i := range class.Attributes {
-		if err = readAttribute(file, &class.Attributes[i]); err != nil {
+		if err = readAttr(r, &class.Attributes[i], &class.ConstantPool); err != nil {
 			return nil, fmt.Errorf("reading attribute %d: %w", i, err)
 		}
 	}
@@ -131,9 +337,9 @@ func (c Class) String() string {
 	fmt.Fprintf(&builder, "Magic: 0x%X\n", c.Magic)
 	fmt.Fprintf(&builder, "Minor Version: %d\n", c.MinorVersion)
 	fmt.Fprintf(&builder, "Major Version: %d\n", c.MajorVersion)
-	fmt.Fprintf(&builder, "Constant Pool Count: %d\n", c.ConstantPoolCount)
+	fmt.Fprintf(&builder, "Constant Pool Count: %d\n", c.ConstantPool.Len())
 
-	for i := uint16(1); i < c.ConstantPoolCount; i++ {
+	for i := 1; i < c.ConstantPool.Len(); i++ {
 		fmt.Fprintf(&builder, "\n\tConstant #%d\n", i)
 		fmt.Fprintf(&builder, "\tTag: %v\n", c.ConstantPool.entries[i].Tag)
 
@@ -145,7 +351,7 @@ func (c Class) String() string {
 		}
 	}
 
-	fmt.Fprintf(&builder, "\nAccess Flags: 0x%04X\n", c.AccessFlags)
+	fmt.Fprintf(&builder, "\nAccess Flags: 0x%04X %s\n", uint16(c.AccessFlags), strings.Join(c.AccessFlags.Names(), ", "))
 	fmt.Fprintf(&builder, "This Class: %d: %s\n", c.ThisClass, c.ConstantPool.entries[c.ThisClass].Value)
 	fmt.Fprintf(&builder, "Super Class: %d\n", c.SuperClass)
 
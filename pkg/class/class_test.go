@@ -0,0 +1,48 @@
+package class
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseExtraBytes(t *testing.T) {
+	data, err := os.ReadFile("../../tst/Test.class")
+	if err != nil {
+		t.Fatal(err)
+	}
+	extra := append(data[:len(data):len(data)], 0)
+	path := filepath.Join(t.TempDir(), "Extra.class")
+	if err := os.WriteFile(path, extra, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		parse func(data []byte) (*Class, error)
+	}{
+		{"ParseBytes", func(data []byte) (*Class, error) { return ParseBytes(data) }},
+		{"ParseBytes lazy", func(data []byte) (*Class, error) { return ParseBytes(data, WithLazyAttributes()) }},
+		{"ParseReader", func(data []byte) (*Class, error) { return ParseReader(bytes.NewReader(data)) }},
+		{"ParseReader lazy", func(data []byte) (*Class, error) {
+			return ParseReader(bytes.NewReader(data), WithLazyAttributes())
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := test.parse(data); err != nil {
+				t.Fatalf("parsing Test.class: %v", err)
+			}
+			_, err := test.parse(extra)
+			if err == nil || !strings.Contains(err.Error(), "extra bytes after the end of the class file (1)") {
+				t.Errorf("parsing Test.class with a byte appended = %v, want an extra bytes error", err)
+			}
+		})
+	}
+
+	if _, err := Parse(path); err == nil || !strings.Contains(err.Error(), "extra bytes") {
+		t.Errorf("Parse() = %v, want an extra bytes error", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"lava-vm/pkg/bytecode"
 	"strings"
 )
 
@@ -26,11 +27,21 @@ type Code struct {
 	ExceptionTable       []ExceptionTableEntry
 	AttributesCount      uint16
 	Attributes           []Attribute
+	constantPool         *ConstantPool
 }
 
-func parseCodeAttribute(attr *Attribute) (*Code, error) {
-	reader := bytes.NewReader(attr.Info)
-	code := &Code{}
+// Instructions decodes the bytecode and indexes the instructions by pc. It
+// fails if the bytecode cannot be decoded or if a branch or switch does not
+// land on the start of an instruction. The result is not cached, since the
+// bytecode may be changed in place.
+func (c *Code) Instructions() (*bytecode.Index, error) {
+	return bytecode.NewIndex(c.Bytecode)
+}
+
+// decodeCodeAttribute decodes the info of a Code attribute.
+func decodeCodeAttribute(info []byte, cp *ConstantPool) (interface{}, error) {
+	reader := bytes.NewReader(info)
+	code := &Code{constantPool: cp}
 
 	if err := binary.Read(reader, binary.BigEndian, &code.MaxStack); err != nil {
 		return nil, err
@@ -41,6 +52,9 @@ func parseCodeAttribute(attr *Attribute) (*Code, error) {
 	if err := binary.Read(reader, binary.BigEndian, &code.CodeLength); err != nil {
 		return nil, err
 	}
+	if err := checkCount(reader, code.CodeLength, 1); err != nil {
+		return nil, err
+	}
 	code.Bytecode = make([]byte, code.CodeLength)
 	if err := binary.Read(reader, binary.BigEndian, &code.Bytecode); err != nil {
 		return nil, err
@@ -48,16 +62,51 @@ func parseCodeAttribute(attr *Attribute) (*Code, error) {
 	if err := binary.Read(reader, binary.BigEndian, &code.ExceptionTableLength); err != nil {
 		return nil, err
 	}
+	if err := checkCount(reader, uint32(code.ExceptionTableLength), 8); err != nil {
+		return nil, err
+	}
 	code.ExceptionTable = make([]ExceptionTableEntry, code.ExceptionTableLength)
 	for i := range code.ExceptionTable {
 		if err := binary.Read(reader, binary.BigEndian, &code.ExceptionTable[i]); err != nil {
 			return nil, err
 		}
 	}
+	if err := binary.Read(reader, binary.BigEndian, &code.AttributesCount); err != nil {
+		return nil, err
+	}
+	if err := checkCount(reader, uint32(code.AttributesCount), 6); err != nil {
+		return nil, err
+	}
+	code.Attributes = make([]Attribute, code.AttributesCount)
+	for i := range code.Attributes {
+		if err := readAttribute(reader, &code.Attributes[i], cp); err != nil {
+			return nil, fmt.Errorf("reading attribute %d: %w", i, err)
+		}
+	}
+	if err := checkFullyRead(reader); err != nil {
+		return nil, err
+	}
 
 	return code, nil
 }
 
+func (c *Code) encode(w *bytes.Buffer) error {
+	put(w, c.MaxStack)
+	put(w, c.MaxLocals)
+	put(w, uint32(len(c.Bytecode)))
+	w.Write(c.Bytecode)
+	if err := putCount(w, len(c.ExceptionTable)); err != nil {
+		return fmt.Errorf("writing exception table: %w", err)
+	}
+	put(w, c.ExceptionTable)
+	return writeAttributes(w, c.Attributes)
+}
+
+// Attribute returns the first attribute of the code with the given name, or nil.
+func (c *Code) Attribute(name string) *Attribute {
+	return findAttribute(c.Attributes, name)
+}
+
 func bytecodeToHex(bytecode []byte) string {
 	hexCodes := make([]string, len(bytecode))
 	for i, code := range bytecode {
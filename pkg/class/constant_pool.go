@@ -3,20 +3,89 @@ package class
 import (
 	"encoding/binary"
 	"fmt"
-	"os"
-	"unicode/utf8"
+	"io"
+	"math"
 )
 
+// Constant pool tags, see JVMS 4.4
+const (
+	TagUtf8               uint8 = 1
+	TagInteger            uint8 = 3
+	TagFloat              uint8 = 4
+	TagLong               uint8 = 5
+	TagDouble             uint8 = 6
+	TagClass              uint8 = 7
+	TagString             uint8 = 8
+	TagFieldRef           uint8 = 9
+	TagMethodRef          uint8 = 10
+	TagInterfaceMethodRef uint8 = 11
+	TagNameAndType        uint8 = 12
+	TagMethodHandle       uint8 = 15
+	TagMethodType         uint8 = 16
+	TagDynamic            uint8 = 17
+	TagInvokeDynamic      uint8 = 18
+	TagModule             uint8 = 19
+	TagPackage            uint8 = 20
+)
+
+var tagNames = map[uint8]string{
+	TagUtf8:               "Utf8",
+	TagInteger:            "Integer",
+	TagFloat:              "Float",
+	TagLong:               "Long",
+	TagDouble:             "Double",
+	TagClass:              "Class",
+	TagString:             "String",
+	TagFieldRef:           "Fieldref",
+	TagMethodRef:          "Methodref",
+	TagInterfaceMethodRef: "InterfaceMethodref",
+	TagNameAndType:        "NameAndType",
+	TagMethodHandle:       "MethodHandle",
+	TagMethodType:         "MethodType",
+	TagDynamic:            "Dynamic",
+	TagInvokeDynamic:      "InvokeDynamic",
+	TagModule:             "Module",
+	TagPackage:            "Package",
+}
+
+// TagName returns the name javap uses for a constant pool tag, e.g. "Methodref".
+func TagName(tag uint8) string {
+	if name, ok := tagNames[tag]; ok {
+		return name
+	}
+	return fmt.Sprintf("Unknown(%d)", tag)
+}
+
 type ConstantPool struct {
 	entries []ConstantPoolEntry
+	// index maps entry contents to indexes for the Add methods. It is built
+	// on first use and dropped whenever entries are renumbered.
+	index map[constantKey]uint16
+	// majorVersion is the version of the class the pool was read from, which
+	// decides the attributes that are recognized. It is zero for a pool built
+	// with NewConstantPool, in which case every attribute is.
+	majorVersion uint16
 }
 
+// Get returns the entry at index. Indexes start at one, as in the class file,
+// so Get agrees with GetConstantName and with the indexes stored in the class.
+// It returns the zero entry for index zero, indexes past the end of the pool
+// and the unusable slot after a long or double.
 func (cp *ConstantPool) Get(index uint16) ConstantPoolEntry {
-	return cp.entries[index-1]
+	if int(index) >= len(cp.entries) {
+		return ConstantPoolEntry{}
+	}
+	return cp.entries[index]
+}
+
+// Len returns the constant_pool_count of the pool, which is one more than the
+// largest valid index.
+func (cp *ConstantPool) Len() int {
+	return len(cp.entries)
 }
 
 func (cp *ConstantPool) GetConstantName(index uint16) string {
-	if index == 0 || index >= uint16(len(cp.entries)) {
+	if cp == nil || index == 0 || index >= uint16(len(cp.entries)) {
 		return ""
 	}
 	entry := cp.entries[index]
@@ -26,6 +95,180 @@ func (cp *ConstantPool) GetConstantName(index uint16) string {
 	return ""
 }
 
+// entry returns the entry at index, checking that the index is in range and
+// does not refer to the unusable slot after a long or double.
+func (cp *ConstantPool) entry(index uint16) (ConstantPoolEntry, error) {
+	if index == 0 || int(index) >= len(cp.entries) {
+		return ConstantPoolEntry{}, fmt.Errorf("invalid constant pool index: %d", index)
+	}
+	entry := cp.entries[index]
+	if entry.Value == nil {
+		return ConstantPoolEntry{}, fmt.Errorf("constant pool index %d is not a usable entry", index)
+	}
+	return entry, nil
+}
+
+// entryWithTag returns the entry at index, checking that it has the expected tag.
+func (cp *ConstantPool) entryWithTag(index uint16, tag uint8) (ConstantPoolEntry, error) {
+	entry, err := cp.entry(index)
+	if err != nil {
+		return entry, err
+	}
+	if entry.Tag != tag {
+		return entry, fmt.Errorf("constant pool index %d is a %s, expected %s", index, TagName(entry.Tag), TagName(tag))
+	}
+	return entry, nil
+}
+
+// utf8 returns the string held by the Utf8 entry at index.
+func (cp *ConstantPool) utf8(index uint16) (string, error) {
+	entry, err := cp.entryWithTag(index, TagUtf8)
+	if err != nil {
+		return "", err
+	}
+	return entry.Value.(*ConstantUtf8Value).String(), nil
+}
+
+// className returns the internal name held by the Class entry at index.
+func (cp *ConstantPool) className(index uint16) (string, error) {
+	entry, err := cp.entryWithTag(index, TagClass)
+	if err != nil {
+		return "", err
+	}
+	return cp.utf8(entry.Value.(*ConstantClassRefValue).Index)
+}
+
+// nameAndType returns the name and descriptor of the NameAndType entry at index.
+func (cp *ConstantPool) nameAndType(index uint16) (string, string, error) {
+	entry, err := cp.entryWithTag(index, TagNameAndType)
+	if err != nil {
+		return "", "", err
+	}
+	value := entry.Value.(*ConstantNameAndTypeDescriptorValue)
+	name, err := cp.utf8(value.NameIndex)
+	if err != nil {
+		return "", "", err
+	}
+	descriptor, err := cp.utf8(value.DescriptorIndex)
+	if err != nil {
+		return "", "", err
+	}
+	return name, descriptor, nil
+}
+
+// memberRef returns the owner class, name and descriptor of the Fieldref,
+// Methodref or InterfaceMethodref entry at index.
+func (cp *ConstantPool) memberRef(index uint16) (owner, name, descriptor string, err error) {
+	entry, err := cp.entry(index)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	var classIndex, nameAndTypeIndex uint16
+	switch value := entry.Value.(type) {
+	case *ConstantFieldRefValue:
+		classIndex, nameAndTypeIndex = value.ClassIndex, value.NameAndTypeIndex
+	case *ConstantMethodRefValue:
+		classIndex, nameAndTypeIndex = value.ClassIndex, value.NameAndTypeIndex
+	case *ConstantInterfaceMethodRefValue:
+		classIndex, nameAndTypeIndex = value.ClassIndex, value.NameAndTypeIndex
+	default:
+		return "", "", "", fmt.Errorf("constant pool index %d is a %s, expected a field or method reference", index, TagName(entry.Tag))
+	}
+
+	if owner, err = cp.className(classIndex); err != nil {
+		return "", "", "", err
+	}
+	if name, descriptor, err = cp.nameAndType(nameAndTypeIndex); err != nil {
+		return "", "", "", err
+	}
+	return owner, name, descriptor, nil
+}
+
+// Utf8 returns the string held by the Utf8 entry at index.
+func (cp *ConstantPool) Utf8(index uint16) (string, error) {
+	return cp.utf8(index)
+}
+
+// ClassRef returns the internal name held by the Class entry at index, e.g.
+// "java/lang/String", or an array descriptor such as "[I".
+func (cp *ConstantPool) ClassRef(index uint16) (string, error) {
+	return cp.className(index)
+}
+
+// NameAndType returns the name and descriptor of the NameAndType entry at index.
+func (cp *ConstantPool) NameAndType(index uint16) (name, descriptor string, err error) {
+	return cp.nameAndType(index)
+}
+
+// FieldRef returns the owner class, name and descriptor of the Fieldref entry at index.
+func (cp *ConstantPool) FieldRef(index uint16) (owner, name, descriptor string, err error) {
+	return cp.memberRefWithTag(index, TagFieldRef)
+}
+
+// MethodRef returns the owner class, name and descriptor of the Methodref entry at index.
+func (cp *ConstantPool) MethodRef(index uint16) (owner, name, descriptor string, err error) {
+	return cp.memberRefWithTag(index, TagMethodRef)
+}
+
+// InterfaceMethodRef returns the owner interface, name and descriptor of the
+// InterfaceMethodref entry at index.
+func (cp *ConstantPool) InterfaceMethodRef(index uint16) (owner, name, descriptor string, err error) {
+	return cp.memberRefWithTag(index, TagInterfaceMethodRef)
+}
+
+func (cp *ConstantPool) memberRefWithTag(index uint16, tag uint8) (owner, name, descriptor string, err error) {
+	if _, err := cp.entryWithTag(index, tag); err != nil {
+		return "", "", "", err
+	}
+	return cp.memberRef(index)
+}
+
+// StringConstant returns the value of the String entry at index.
+func (cp *ConstantPool) StringConstant(index uint16) (string, error) {
+	entry, err := cp.entryWithTag(index, TagString)
+	if err != nil {
+		return "", err
+	}
+	return cp.utf8(entry.Value.(*ConstantStringRefValue).Index)
+}
+
+// IntegerConstant returns the value of the Integer entry at index.
+func (cp *ConstantPool) IntegerConstant(index uint16) (int32, error) {
+	entry, err := cp.entryWithTag(index, TagInteger)
+	if err != nil {
+		return 0, err
+	}
+	return entry.Value.(*ConstantIntegerValue).Value, nil
+}
+
+// FloatConstant returns the value of the Float entry at index.
+func (cp *ConstantPool) FloatConstant(index uint16) (float32, error) {
+	entry, err := cp.entryWithTag(index, TagFloat)
+	if err != nil {
+		return 0, err
+	}
+	return entry.Value.(*ConstantFloatValue).Value, nil
+}
+
+// LongConstant returns the value of the Long entry at index.
+func (cp *ConstantPool) LongConstant(index uint16) (int64, error) {
+	entry, err := cp.entryWithTag(index, TagLong)
+	if err != nil {
+		return 0, err
+	}
+	return entry.Value.(*ConstantLongValue).Value, nil
+}
+
+// DoubleConstant returns the value of the Double entry at index.
+func (cp *ConstantPool) DoubleConstant(index uint16) (float64, error) {
+	entry, err := cp.entryWithTag(index, TagDouble)
+	if err != nil {
+		return 0, err
+	}
+	return entry.Value.(*ConstantDoubleValue).Value, nil
+}
+
 type ConstantPoolEntry struct {
 	// The tag representing the type of constant pool entry
 	Tag uint8
@@ -35,32 +278,56 @@ type ConstantPoolEntry struct {
 
 type ConstantPoolValue interface{}
 
-// ConstantUtf8Value represents a UTF-8 string in a Java class file
+// ConstantUtf8Value represents a string constant in a Java class file. Bytes
+// holds the raw modified UTF-8 encoding, see DecodeModifiedUTF8.
 type ConstantUtf8Value struct {
 	Length uint16
 	Bytes  []byte
 }
 
-// String returns the ConstantUtf8Value as a string.
+// NewConstantUtf8Value returns a ConstantUtf8Value holding s encoded as modified UTF-8.
+func NewConstantUtf8Value(s string) (*ConstantUtf8Value, error) {
+	b := EncodeModifiedUTF8(s)
+	if len(b) > math.MaxUint16 {
+		return nil, fmt.Errorf("encoded string is %d bytes, longer than the maximum of %d", len(b), math.MaxUint16)
+	}
+	return &ConstantUtf8Value{Length: uint16(len(b)), Bytes: b}, nil
+}
+
+// String returns the ConstantUtf8Value decoded as a Go string.
 func (c *ConstantUtf8Value) String() string {
-	return string(c.Bytes)
+	s, err := DecodeModifiedUTF8(c.Bytes)
+	if err != nil {
+		return string(c.Bytes)
+	}
+	return s
 }
 
-// readConstantUtf8Value reads a ConstantUtf8Value from the provided file.
-// It returns the ConstantPoolValue and any error encountered.
-// readConstantUtf8Value reads a ConstantUtf8Value from the provided file.
+// UTF16 returns the ConstantUtf8Value as UTF-16 code units, the representation
+// used by java.lang.String.
+func (c *ConstantUtf8Value) UTF16() []uint16 {
+	units, err := DecodeModifiedUTF8ToUTF16(c.Bytes)
+	if err != nil {
+		return nil
+	}
+	return units
+}
+
+// readConstantUtf8Value reads a ConstantUtf8Value from the provided reader.
 // It returns the ConstantPoolValue and any error encountered.
-func readConstantUtf8Value(file *os.File) (ConstantPoolValue, error) {
+func readConstantUtf8Value(r io.Reader) (ConstantPoolValue, error) {
 	value := ConstantUtf8Value{}
-	if err := binary.Read(file, binary.BigEndian, &value.Length); err != nil {
+	if err := binary.Read(r, binary.BigEndian, &value.Length); err != nil {
 		return nil, err
 	}
 	value.Bytes = make([]byte, value.Length)
-	if err := binary.Read(file, binary.BigEndian, &value.Bytes); err != nil {
+	if err := binary.Read(r, binary.BigEndian, &value.Bytes); err != nil {
 		return nil, err
 	}
-	if !utf8.Valid(value.Bytes) {
-		return nil, fmt.Errorf("invalid UTF-8 sequence")
+	if !isASCII(value.Bytes) {
+		if _, err := DecodeModifiedUTF8ToUTF16(value.Bytes); err != nil {
+			return nil, err
+		}
 	}
 	return &value, nil
 }
@@ -70,11 +337,11 @@ type ConstantIntegerValue struct {
 	Value int32
 }
 
-// readConstantIntegerValue reads a ConstantIntegerValue from the provided file.
+// readConstantIntegerValue reads a ConstantIntegerValue from the provided reader.
 // It returns the ConstantPoolValue and any error encountered.
-func readConstantIntegerValue(file *os.File) (ConstantPoolValue, error) {
+func readConstantIntegerValue(r io.Reader) (ConstantPoolValue, error) {
 	value := ConstantIntegerValue{}
-	if err := binary.Read(file, binary.BigEndian, &value.Value); err != nil {
+	if err := binary.Read(r, binary.BigEndian, &value.Value); err != nil {
 		return nil, err
 	}
 	return &value, nil
@@ -85,11 +352,11 @@ type ConstantFloatValue struct {
 	Value float32
 }
 
-// readConstantFloatValue reads a ConstantFloatValue from the provided file.
+// readConstantFloatValue reads a ConstantFloatValue from the provided reader.
 // It returns the ConstantPoolValue and any error encountered.
-func readConstantFloatValue(file *os.File) (ConstantPoolValue, error) {
+func readConstantFloatValue(r io.Reader) (ConstantPoolValue, error) {
 	value := ConstantFloatValue{}
-	if err := binary.Read(file, binary.BigEndian, &value.Value); err != nil {
+	if err := binary.Read(r, binary.BigEndian, &value.Value); err != nil {
 		return nil, err
 	}
 	return &value, nil
@@ -100,11 +367,11 @@ type ConstantLongValue struct {
 	Value int64
 }
 
-// readConstantLongValue reads a ConstantLongValue from the provided file.
+// readConstantLongValue reads a ConstantLongValue from the provided reader.
 // It returns the ConstantPoolValue and any error encountered.
-func readConstantLongValue(file *os.File) (ConstantPoolValue, error) {
+func readConstantLongValue(r io.Reader) (ConstantPoolValue, error) {
 	value := ConstantLongValue{}
-	if err := binary.Read(file, binary.BigEndian, &value.Value); err != nil {
+	if err := binary.Read(r, binary.BigEndian, &value.Value); err != nil {
 		return nil, err
 	}
 	return &value, nil
@@ -115,11 +382,11 @@ type ConstantDoubleValue struct {
 	Value float64
 }
 
-// readConstantDoubleValue reads a ConstantDoubleValue from the provided file.
+// readConstantDoubleValue reads a ConstantDoubleValue from the provided reader.
 // It returns the ConstantPoolValue and any error encountered.
-func readConstantDoubleValue(file *os.File) (ConstantPoolValue, error) {
+func readConstantDoubleValue(r io.Reader) (ConstantPoolValue, error) {
 	value := ConstantDoubleValue{}
-	if err := binary.Read(file, binary.BigEndian, &value.Value); err != nil {
+	if err := binary.Read(r, binary.BigEndian, &value.Value); err != nil {
 		return nil, err
 	}
 	return &value, nil
@@ -131,11 +398,11 @@ type ConstantClassRefValue struct {
 	Index uint16
 }
 
-// readConstantClassRefValue reads a ConstantClassRefValue from the provided file.
+// readConstantClassRefValue reads a ConstantClassRefValue from the provided reader.
 // It returns the ConstantPoolValue and any error encountered.
-func readConstantClassRefValue(file *os.File) (ConstantPoolValue, error) {
+func readConstantClassRefValue(r io.Reader) (ConstantPoolValue, error) {
 	value := ConstantClassRefValue{}
-	if err := binary.Read(file, binary.BigEndian, &value.Index); err != nil {
+	if err := binary.Read(r, binary.BigEndian, &value.Index); err != nil {
 		return nil, err
 	}
 	return &value, nil
@@ -147,11 +414,11 @@ type ConstantStringRefValue struct {
 	Index uint16
 }
 
-// readConstantStringRefValue reads a ConstantStringRefValue from the provided file.
+// readConstantStringRefValue reads a ConstantStringRefValue from the provided reader.
 // It returns the ConstantPoolValue and any error encountered.
-func readConstantStringRefValue(file *os.File) (ConstantPoolValue, error) {
+func readConstantStringRefValue(r io.Reader) (ConstantPoolValue, error) {
 	value := ConstantStringRefValue{}
-	if err := binary.Read(file, binary.BigEndian, &value.Index); err != nil {
+	if err := binary.Read(r, binary.BigEndian, &value.Index); err != nil {
 		return nil, err
 	}
 	return &value, nil
@@ -166,14 +433,14 @@ type ConstantFieldRefValue struct {
 	NameAndTypeIndex uint16
 }
 
-// readConstantFieldRefValue reads a ConstantFieldRefValue from the provided file.
+// readConstantFieldRefValue reads a ConstantFieldRefValue from the provided reader.
 // It returns the ConstantPoolValue and any error encountered.
-func readConstantFieldRefValue(file *os.File) (ConstantPoolValue, error) {
+func readConstantFieldRefValue(r io.Reader) (ConstantPoolValue, error) {
 	value := ConstantFieldRefValue{}
-	if err := binary.Read(file, binary.BigEndian, &value.ClassIndex); err != nil {
+	if err := binary.Read(r, binary.BigEndian, &value.ClassIndex); err != nil {
 		return nil, err
 	}
-	if err := binary.Read(file, binary.BigEndian, &value.NameAndTypeIndex); err != nil {
+	if err := binary.Read(r, binary.BigEndian, &value.NameAndTypeIndex); err != nil {
 		return nil, err
 	}
 	return &value, nil
@@ -188,14 +455,14 @@ type ConstantMethodRefValue struct {
 	NameAndTypeIndex uint16
 }
 
-// readConstantMethodRefValue reads a ConstantMethodRefValue from the provided file.
+// readConstantMethodRefValue reads a ConstantMethodRefValue from the provided reader.
 // It returns the ConstantPoolValue and any error encountered.
-func readConstantMethodRefValue(file *os.File) (ConstantPoolValue, error) {
+func readConstantMethodRefValue(r io.Reader) (ConstantPoolValue, error) {
 	value := ConstantMethodRefValue{}
-	if err := binary.Read(file, binary.BigEndian, &value.ClassIndex); err != nil {
+	if err := binary.Read(r, binary.BigEndian, &value.ClassIndex); err != nil {
 		return nil, err
 	}
-	if err := binary.Read(file, binary.BigEndian, &value.NameAndTypeIndex); err != nil {
+	if err := binary.Read(r, binary.BigEndian, &value.NameAndTypeIndex); err != nil {
 		return nil, err
 	}
 	return &value, nil
@@ -210,14 +477,14 @@ type ConstantInterfaceMethodRefValue struct {
 	NameAndTypeIndex uint16
 }
 
-// readConstantInterfaceMethodRefValue reads a ConstantInterfaceMethodRefValue from the provided file.
+// readConstantInterfaceMethodRefValue reads a ConstantInterfaceMethodRefValue from the provided reader.
 // It returns the ConstantPoolValue and any error encountered.
-func readConstantInterfaceMethodRefValue(file *os.File) (ConstantPoolValue, error) {
+func readConstantInterfaceMethodRefValue(r io.Reader) (ConstantPoolValue, error) {
 	value := ConstantInterfaceMethodRefValue{}
-	if err := binary.Read(file, binary.BigEndian, &value.ClassIndex); err != nil {
+	if err := binary.Read(r, binary.BigEndian, &value.ClassIndex); err != nil {
 		return nil, err
 	}
-	if err := binary.Read(file, binary.BigEndian, &value.NameAndTypeIndex); err != nil {
+	if err := binary.Read(r, binary.BigEndian, &value.NameAndTypeIndex); err != nil {
 		return nil, err
 	}
 	return &value, nil
@@ -232,48 +499,246 @@ type ConstantNameAndTypeDescriptorValue struct {
 	DescriptorIndex uint16
 }
 
-// readConstantNameAndTypeDescriptorValue reads a ConstantNameAndTypeDescriptorValue from the provided file.
+// readConstantNameAndTypeDescriptorValue reads a ConstantNameAndTypeDescriptorValue from the provided reader.
 // It returns the ConstantPoolValue and any error encountered.
-func readConstantNameAndTypeDescriptorValue(file *os.File) (ConstantPoolValue, error) {
+func readConstantNameAndTypeDescriptorValue(r io.Reader) (ConstantPoolValue, error) {
 	value := ConstantNameAndTypeDescriptorValue{}
-	if err := binary.Read(file, binary.BigEndian, &value.NameIndex); err != nil {
+	if err := binary.Read(r, binary.BigEndian, &value.NameIndex); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(r, binary.BigEndian, &value.DescriptorIndex); err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
+
+// ReferenceKind identifies the kind of a method handle, and so the bytecode
+// behavior of the handle. See JVMS 5.4.3.5.
+type ReferenceKind uint8
+
+const (
+	RefGetField         ReferenceKind = 1
+	RefGetStatic        ReferenceKind = 2
+	RefPutField         ReferenceKind = 3
+	RefPutStatic        ReferenceKind = 4
+	RefInvokeVirtual    ReferenceKind = 5
+	RefInvokeStatic     ReferenceKind = 6
+	RefInvokeSpecial    ReferenceKind = 7
+	RefNewInvokeSpecial ReferenceKind = 8
+	RefInvokeInterface  ReferenceKind = 9
+)
+
+var referenceKindNames = map[ReferenceKind]string{
+	RefGetField:         "REF_getField",
+	RefGetStatic:        "REF_getStatic",
+	RefPutField:         "REF_putField",
+	RefPutStatic:        "REF_putStatic",
+	RefInvokeVirtual:    "REF_invokeVirtual",
+	RefInvokeStatic:     "REF_invokeStatic",
+	RefInvokeSpecial:    "REF_invokeSpecial",
+	RefNewInvokeSpecial: "REF_newInvokeSpecial",
+	RefInvokeInterface:  "REF_invokeInterface",
+}
+
+// String returns the JVMS name of the reference kind, e.g. REF_invokeStatic.
+func (k ReferenceKind) String() string {
+	if name, ok := referenceKindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("REF_unknown(%d)", uint8(k))
+}
+
+// ConstantMethodHandleValue represents a method handle in Java class files.
+// The reference index points to a field, method or interface method reference
+// entry, depending on the reference kind.
+type ConstantMethodHandleValue struct {
+	ReferenceKind  ReferenceKind
+	ReferenceIndex uint16
+}
+
+// String returns the ConstantMethodHandleValue as "<reference kind> #<index>".
+func (c *ConstantMethodHandleValue) String() string {
+	return fmt.Sprintf("%s #%d", c.ReferenceKind, c.ReferenceIndex)
+}
+
+// readConstantMethodHandleValue reads a ConstantMethodHandleValue from the provided reader.
+// It returns the ConstantPoolValue and any error encountered.
+func readConstantMethodHandleValue(r io.Reader) (ConstantPoolValue, error) {
+	value := ConstantMethodHandleValue{}
+	if err := binary.Read(r, binary.BigEndian, &value.ReferenceKind); err != nil {
+		return nil, err
+	}
+	if value.ReferenceKind < RefGetField || value.ReferenceKind > RefInvokeInterface {
+		return nil, fmt.Errorf("invalid method handle reference kind %d", value.ReferenceKind)
+	}
+	if err := binary.Read(r, binary.BigEndian, &value.ReferenceIndex); err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
+
+// ConstantMethodTypeValue represents a method type in Java class files.
+// It contains an index to a UTF-8 entry holding a method descriptor.
+type ConstantMethodTypeValue struct {
+	DescriptorIndex uint16
+}
+
+// String returns the ConstantMethodTypeValue as "#<descriptor index>".
+func (c *ConstantMethodTypeValue) String() string {
+	return fmt.Sprintf("#%d", c.DescriptorIndex)
+}
+
+// readConstantMethodTypeValue reads a ConstantMethodTypeValue from the provided reader.
+// It returns the ConstantPoolValue and any error encountered.
+func readConstantMethodTypeValue(r io.Reader) (ConstantPoolValue, error) {
+	value := ConstantMethodTypeValue{}
+	if err := binary.Read(r, binary.BigEndian, &value.DescriptorIndex); err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
+
+// ConstantDynamicValue represents a dynamically-computed constant in Java class files.
+// The first index points into the bootstrap_methods array of the BootstrapMethods attribute and
+// the second index points to a NameAndType descriptor entry.
+type ConstantDynamicValue struct {
+	BootstrapMethodAttrIndex uint16
+	NameAndTypeIndex         uint16
+}
+
+// String returns the ConstantDynamicValue as "#<bootstrap method>:#<name and type>".
+func (c *ConstantDynamicValue) String() string {
+	return fmt.Sprintf("#%d:#%d", c.BootstrapMethodAttrIndex, c.NameAndTypeIndex)
+}
+
+// readConstantDynamicValue reads a ConstantDynamicValue from the provided reader.
+// It returns the ConstantPoolValue and any error encountered.
+func readConstantDynamicValue(r io.Reader) (ConstantPoolValue, error) {
+	value := ConstantDynamicValue{}
+	if err := binary.Read(r, binary.BigEndian, &value.BootstrapMethodAttrIndex); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(r, binary.BigEndian, &value.NameAndTypeIndex); err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
+
+// ConstantInvokeDynamicValue represents a dynamically-computed call site in Java class files.
+// The first index points into the bootstrap_methods array of the BootstrapMethods attribute and
+// the second index points to a NameAndType descriptor entry.
+type ConstantInvokeDynamicValue struct {
+	BootstrapMethodAttrIndex uint16
+	NameAndTypeIndex         uint16
+}
+
+// String returns the ConstantInvokeDynamicValue as "#<bootstrap method>:#<name and type>".
+func (c *ConstantInvokeDynamicValue) String() string {
+	return fmt.Sprintf("#%d:#%d", c.BootstrapMethodAttrIndex, c.NameAndTypeIndex)
+}
+
+// readConstantInvokeDynamicValue reads a ConstantInvokeDynamicValue from the provided reader.
+// It returns the ConstantPoolValue and any error encountered.
+func readConstantInvokeDynamicValue(r io.Reader) (ConstantPoolValue, error) {
+	value := ConstantInvokeDynamicValue{}
+	if err := binary.Read(r, binary.BigEndian, &value.BootstrapMethodAttrIndex); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(r, binary.BigEndian, &value.NameAndTypeIndex); err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
+
+// ConstantModuleValue represents a module in Java class files.
+// It contains an index to a UTF-8 entry holding the module name.
+type ConstantModuleValue struct {
+	NameIndex uint16
+}
+
+// String returns the ConstantModuleValue as "#<name index>".
+func (c *ConstantModuleValue) String() string {
+	return fmt.Sprintf("#%d", c.NameIndex)
+}
+
+// readConstantModuleValue reads a ConstantModuleValue from the provided reader.
+// It returns the ConstantPoolValue and any error encountered.
+func readConstantModuleValue(r io.Reader) (ConstantPoolValue, error) {
+	value := ConstantModuleValue{}
+	if err := binary.Read(r, binary.BigEndian, &value.NameIndex); err != nil {
 		return nil, err
 	}
-	if err := binary.Read(file, binary.BigEndian, &value.DescriptorIndex); err != nil {
+	return &value, nil
+}
+
+// ConstantPackageValue represents a package exported or opened by a module in Java class files.
+// It contains an index to a UTF-8 entry holding the package name in internal form.
+type ConstantPackageValue struct {
+	NameIndex uint16
+}
+
+// String returns the ConstantPackageValue as "#<name index>".
+func (c *ConstantPackageValue) String() string {
+	return fmt.Sprintf("#%d", c.NameIndex)
+}
+
+// readConstantPackageValue reads a ConstantPackageValue from the provided reader.
+// It returns the ConstantPoolValue and any error encountered.
+func readConstantPackageValue(r io.Reader) (ConstantPoolValue, error) {
+	value := ConstantPackageValue{}
+	if err := binary.Read(r, binary.BigEndian, &value.NameIndex); err != nil {
 		return nil, err
 	}
 	return &value, nil
 }
 
-type valueReader func(file *os.File) (ConstantPoolValue, error)
+type valueReader func(r io.Reader) (ConstantPoolValue, error)
 
 var valueReaders = map[uint8]valueReader{
-	1:  readConstantUtf8Value,
-	3:  readConstantIntegerValue,
-	4:  readConstantFloatValue,
-	5:  readConstantLongValue,
-	6:  readConstantDoubleValue,
-	7:  readConstantClassRefValue,
-	8:  readConstantStringRefValue,
-	9:  readConstantFieldRefValue,
-	10: readConstantMethodRefValue,
-	11: readConstantInterfaceMethodRefValue,
-	12: readConstantNameAndTypeDescriptorValue,
-}
-
-func readConstantPool(file *os.File, class *Class) (err error) {
-	if err := binary.Read(file, binary.BigEndian, &class.ConstantPoolCount); err != nil {
+	TagUtf8:               readConstantUtf8Value,
+	TagInteger:            readConstantIntegerValue,
+	TagFloat:              readConstantFloatValue,
+	TagLong:               readConstantLongValue,
+	TagDouble:             readConstantDoubleValue,
+	TagClass:              readConstantClassRefValue,
+	TagString:             readConstantStringRefValue,
+	TagFieldRef:           readConstantFieldRefValue,
+	TagMethodRef:          readConstantMethodRefValue,
+	TagInterfaceMethodRef: readConstantInterfaceMethodRefValue,
+	TagNameAndType:        readConstantNameAndTypeDescriptorValue,
+	TagMethodHandle:       readConstantMethodHandleValue,
+	TagMethodType:         readConstantMethodTypeValue,
+	TagDynamic:            readConstantDynamicValue,
+	TagInvokeDynamic:      readConstantInvokeDynamicValue,
+	TagModule:             readConstantModuleValue,
+	TagPackage:            readConstantPackageValue,
+}
+
+// tagMinMajorVersions holds the first class file major version in which a
+// constant pool tag may appear. Tags missing from the map are valid in every version.
+// See JVMS table 4.4-B.
+var tagMinMajorVersions = map[uint8]uint16{
+	TagMethodHandle:  51,
+	TagMethodType:    51,
+	TagInvokeDynamic: 51,
+	TagModule:        53,
+	TagPackage:       53,
+	TagDynamic:       55,
+}
+
+func readConstantPool(r io.Reader, class *Class) (err error) {
+	if err := binary.Read(r, binary.BigEndian, &class.ConstantPoolCount); err != nil {
 		return err
 	}
 
 	class.ConstantPool.entries = make([]ConstantPoolEntry, class.ConstantPoolCount)
+	class.ConstantPool.majorVersion = class.MajorVersion
 
 	for i := uint16(1); i < class.ConstantPoolCount; i++ {
 		var tag uint8
-		if err := binary.Read(file, binary.BigEndian, &tag); err != nil {
+		if err := binary.Read(r, binary.BigEndian, &tag); err != nil {
 			return err
 		}
-		fmt.Println("Tag :", tag)
 
 		reader, exists := valueReaders[tag]
 		if !exists {
@@ -281,14 +746,19 @@ func readConstantPool(file *os.File, class *Class) (err error) {
 			return fmt.Errorf("unknown tag %d", tag)
 		}
 
-		value, err := reader(file)
+		if minVersion, ok := tagMinMajorVersions[tag]; ok && class.MajorVersion < minVersion {
+			return fmt.Errorf("tag %d at index %d requires class file version %d or later, got %d",
+				tag, i, minVersion, class.MajorVersion)
+		}
+
+		value, err := reader(r)
 		if err != nil {
 			return err
 		}
 
 		class.ConstantPool.entries[i] = ConstantPoolEntry{Tag: tag, Value: value}
 
-		if tag == 5 || tag == 6 {
+		if tag == TagLong || tag == TagDouble {
 			i++
 			if i < class.ConstantPoolCount {
 				class.ConstantPool.entries[i] = ConstantPoolEntry{}
@@ -0,0 +1,277 @@
+package class
+
+import (
+	"fmt"
+	"math"
+)
+
+// MaxConstantPoolCount is the largest constant_pool_count a class file can
+// hold, so the largest usable index is one less.
+const MaxConstantPoolCount = math.MaxUint16
+
+// The Add methods below append entries to the pool and return their index.
+// An entry equal to one already in the pool is not added again; the index of
+// the existing entry is returned instead. Entries that refer to other entries,
+// such as Class or Methodref, add those as needed.
+
+// NewConstantPool returns an empty constant pool for building a class from scratch.
+func NewConstantPool() *ConstantPool {
+	return &ConstantPool{entries: make([]ConstantPoolEntry, 1)}
+}
+
+// constantKey identifies an entry by content for deduplication. The value
+// structs are comparable, except that Utf8 holds a slice and floating point
+// values need comparing by bits so that NaNs and signed zeros are kept apart.
+type constantKey struct {
+	tag   uint8
+	value interface{}
+}
+
+func keyOf(entry ConstantPoolEntry) constantKey {
+	var value interface{}
+	switch v := entry.Value.(type) {
+	case *ConstantUtf8Value:
+		value = string(v.Bytes)
+	case *ConstantIntegerValue:
+		value = *v
+	case *ConstantFloatValue:
+		value = math.Float32bits(v.Value)
+	case *ConstantLongValue:
+		value = *v
+	case *ConstantDoubleValue:
+		value = math.Float64bits(v.Value)
+	case *ConstantClassRefValue:
+		value = *v
+	case *ConstantStringRefValue:
+		value = *v
+	case *ConstantFieldRefValue:
+		value = *v
+	case *ConstantMethodRefValue:
+		value = *v
+	case *ConstantInterfaceMethodRefValue:
+		value = *v
+	case *ConstantNameAndTypeDescriptorValue:
+		value = *v
+	case *ConstantMethodHandleValue:
+		value = *v
+	case *ConstantMethodTypeValue:
+		value = *v
+	case *ConstantDynamicValue:
+		value = *v
+	case *ConstantInvokeDynamicValue:
+		value = *v
+	case *ConstantModuleValue:
+		value = *v
+	case *ConstantPackageValue:
+		value = *v
+	}
+	return constantKey{tag: entry.Tag, value: value}
+}
+
+// add appends the entry unless an equal one exists, returning its index.
+func (cp *ConstantPool) add(tag uint8, value ConstantPoolValue) (uint16, error) {
+	if len(cp.entries) == 0 {
+		cp.entries = make([]ConstantPoolEntry, 1)
+	}
+	if cp.index == nil {
+		cp.reindex()
+	}
+
+	entry := ConstantPoolEntry{Tag: tag, Value: value}
+	key := keyOf(entry)
+	if index, ok := cp.index[key]; ok {
+		return index, nil
+	}
+
+	slots := 1
+	if tag == TagLong || tag == TagDouble {
+		slots = 2
+	}
+	if len(cp.entries)+slots > MaxConstantPoolCount {
+		return 0, fmt.Errorf("constant pool is full: adding a %s would exceed %d entries", TagName(tag), MaxConstantPoolCount)
+	}
+
+	index := uint16(len(cp.entries))
+	cp.entries = append(cp.entries, entry)
+	if slots == 2 {
+		cp.entries = append(cp.entries, ConstantPoolEntry{})
+	}
+	cp.index[key] = index
+	return index, nil
+}
+
+// reindex rebuilds the deduplication index from the entries. When a parsed
+// pool holds duplicates, the first one is reused.
+func (cp *ConstantPool) reindex() {
+	cp.index = make(map[constantKey]uint16, len(cp.entries))
+	for i := 1; i < len(cp.entries); i++ {
+		entry := cp.entries[i]
+		if entry.Value == nil {
+			continue
+		}
+		key := keyOf(entry)
+		if _, ok := cp.index[key]; !ok {
+			cp.index[key] = uint16(i)
+		}
+	}
+}
+
+// AddUtf8 adds a Utf8 entry holding s.
+func (cp *ConstantPool) AddUtf8(s string) (uint16, error) {
+	value, err := NewConstantUtf8Value(s)
+	if err != nil {
+		return 0, err
+	}
+	return cp.add(TagUtf8, value)
+}
+
+// AddInteger adds an Integer entry.
+func (cp *ConstantPool) AddInteger(v int32) (uint16, error) {
+	return cp.add(TagInteger, &ConstantIntegerValue{Value: v})
+}
+
+// AddFloat adds a Float entry.
+func (cp *ConstantPool) AddFloat(v float32) (uint16, error) {
+	return cp.add(TagFloat, &ConstantFloatValue{Value: v})
+}
+
+// AddLong adds a Long entry, which takes two slots.
+func (cp *ConstantPool) AddLong(v int64) (uint16, error) {
+	return cp.add(TagLong, &ConstantLongValue{Value: v})
+}
+
+// AddDouble adds a Double entry, which takes two slots.
+func (cp *ConstantPool) AddDouble(v float64) (uint16, error) {
+	return cp.add(TagDouble, &ConstantDoubleValue{Value: v})
+}
+
+// AddClass adds a Class entry for the class with the given internal name,
+// e.g. "java/lang/String", or for an array descriptor such as "[I".
+func (cp *ConstantPool) AddClass(name string) (uint16, error) {
+	nameIndex, err := cp.AddUtf8(name)
+	if err != nil {
+		return 0, err
+	}
+	return cp.add(TagClass, &ConstantClassRefValue{Index: nameIndex})
+}
+
+// AddString adds a String entry holding s.
+func (cp *ConstantPool) AddString(s string) (uint16, error) {
+	index, err := cp.AddUtf8(s)
+	if err != nil {
+		return 0, err
+	}
+	return cp.add(TagString, &ConstantStringRefValue{Index: index})
+}
+
+// AddNameAndType adds a NameAndType entry.
+func (cp *ConstantPool) AddNameAndType(name, descriptor string) (uint16, error) {
+	nameIndex, err := cp.AddUtf8(name)
+	if err != nil {
+		return 0, err
+	}
+	descriptorIndex, err := cp.AddUtf8(descriptor)
+	if err != nil {
+		return 0, err
+	}
+	return cp.add(TagNameAndType, &ConstantNameAndTypeDescriptorValue{NameIndex: nameIndex, DescriptorIndex: descriptorIndex})
+}
+
+// memberIndexes adds the Class and NameAndType entries of a member reference.
+func (cp *ConstantPool) memberIndexes(owner, name, descriptor string) (uint16, uint16, error) {
+	classIndex, err := cp.AddClass(owner)
+	if err != nil {
+		return 0, 0, err
+	}
+	nameAndTypeIndex, err := cp.AddNameAndType(name, descriptor)
+	if err != nil {
+		return 0, 0, err
+	}
+	return classIndex, nameAndTypeIndex, nil
+}
+
+// AddFieldRef adds a Fieldref entry for the field of owner with the given
+// name and descriptor.
+func (cp *ConstantPool) AddFieldRef(owner, name, descriptor string) (uint16, error) {
+	classIndex, nameAndTypeIndex, err := cp.memberIndexes(owner, name, descriptor)
+	if err != nil {
+		return 0, err
+	}
+	return cp.add(TagFieldRef, &ConstantFieldRefValue{ClassIndex: classIndex, NameAndTypeIndex: nameAndTypeIndex})
+}
+
+// AddMethodRef adds a Methodref entry for the method of the class owner with
+// the given name and descriptor.
+func (cp *ConstantPool) AddMethodRef(owner, name, descriptor string) (uint16, error) {
+	classIndex, nameAndTypeIndex, err := cp.memberIndexes(owner, name, descriptor)
+	if err != nil {
+		return 0, err
+	}
+	return cp.add(TagMethodRef, &ConstantMethodRefValue{ClassIndex: classIndex, NameAndTypeIndex: nameAndTypeIndex})
+}
+
+// AddInterfaceMethodRef adds an InterfaceMethodref entry for the method of the
+// interface owner with the given name and descriptor.
+func (cp *ConstantPool) AddInterfaceMethodRef(owner, name, descriptor string) (uint16, error) {
+	classIndex, nameAndTypeIndex, err := cp.memberIndexes(owner, name, descriptor)
+	if err != nil {
+		return 0, err
+	}
+	return cp.add(TagInterfaceMethodRef, &ConstantInterfaceMethodRefValue{ClassIndex: classIndex, NameAndTypeIndex: nameAndTypeIndex})
+}
+
+// AddMethodHandle adds a MethodHandle entry. The reference index must point
+// at the Fieldref, Methodref or InterfaceMethodref entry the kind calls for.
+func (cp *ConstantPool) AddMethodHandle(kind ReferenceKind, referenceIndex uint16) (uint16, error) {
+	if kind < RefGetField || kind > RefInvokeInterface {
+		return 0, fmt.Errorf("invalid method handle reference kind %d", kind)
+	}
+	return cp.add(TagMethodHandle, &ConstantMethodHandleValue{ReferenceKind: kind, ReferenceIndex: referenceIndex})
+}
+
+// AddMethodType adds a MethodType entry for the method descriptor.
+func (cp *ConstantPool) AddMethodType(descriptor string) (uint16, error) {
+	index, err := cp.AddUtf8(descriptor)
+	if err != nil {
+		return 0, err
+	}
+	return cp.add(TagMethodType, &ConstantMethodTypeValue{DescriptorIndex: index})
+}
+
+// AddDynamic adds a Dynamic entry whose value is produced by the bootstrap
+// method at bootstrapIndex in the BootstrapMethods attribute.
+func (cp *ConstantPool) AddDynamic(bootstrapIndex uint16, name, descriptor string) (uint16, error) {
+	nameAndTypeIndex, err := cp.AddNameAndType(name, descriptor)
+	if err != nil {
+		return 0, err
+	}
+	return cp.add(TagDynamic, &ConstantDynamicValue{BootstrapMethodAttrIndex: bootstrapIndex, NameAndTypeIndex: nameAndTypeIndex})
+}
+
+// AddInvokeDynamic adds an InvokeDynamic entry whose call site is linked by
+// the bootstrap method at bootstrapIndex in the BootstrapMethods attribute.
+func (cp *ConstantPool) AddInvokeDynamic(bootstrapIndex uint16, name, descriptor string) (uint16, error) {
+	nameAndTypeIndex, err := cp.AddNameAndType(name, descriptor)
+	if err != nil {
+		return 0, err
+	}
+	return cp.add(TagInvokeDynamic, &ConstantInvokeDynamicValue{BootstrapMethodAttrIndex: bootstrapIndex, NameAndTypeIndex: nameAndTypeIndex})
+}
+
+// AddModule adds a Module entry for the named module.
+func (cp *ConstantPool) AddModule(name string) (uint16, error) {
+	index, err := cp.AddUtf8(name)
+	if err != nil {
+		return 0, err
+	}
+	return cp.add(TagModule, &ConstantModuleValue{NameIndex: index})
+}
+
+// AddPackage adds a Package entry for the package with the given internal name.
+func (cp *ConstantPool) AddPackage(name string) (uint16, error) {
+	index, err := cp.AddUtf8(name)
+	if err != nil {
+		return 0, err
+	}
+	return cp.add(TagPackage, &ConstantPackageValue{NameIndex: index})
+}
@@ -0,0 +1,156 @@
+package class
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolvedNames(t *testing.T) {
+	c := parseTestClass(t)
+	if name, err := c.ThisClassName(); err != nil || name != "Test" {
+		t.Errorf("ThisClassName() = %q, %v, want Test", name, err)
+	}
+	if name, err := c.SuperClassName(); err != nil || name != "java/lang/Object" {
+		t.Errorf("SuperClassName() = %q, %v, want java/lang/Object", name, err)
+	}
+	if names, err := c.InterfaceNames(); err != nil || len(names) != 0 {
+		t.Errorf("InterfaceNames() = %v, %v, want none", names, err)
+	}
+	if field := &c.Fields[0]; field.Name() != "foo" || field.Descriptor() != "Ljava/lang/String;" {
+		t.Errorf("field 0 = %s %s, want foo Ljava/lang/String;", field.Name(), field.Descriptor())
+	}
+	if method := &c.Methods[1]; method.Name() != "main" || method.Descriptor() != "([Ljava/lang/String;)V" {
+		t.Errorf("method 1 = %s%s, want main([Ljava/lang/String;)V", method.Name(), method.Descriptor())
+	}
+
+	// Get and GetConstantName both take class file indexes
+	if entry := c.ConstantPool.Get(8); entry.Tag != TagUtf8 || c.ConstantPool.GetConstantName(8) != "Yes" {
+		t.Errorf("Get(8) = %+v and GetConstantName(8) = %q, want the Utf8 Yes", entry, c.ConstantPool.GetConstantName(8))
+	}
+}
+
+func TestFindMember(t *testing.T) {
+	c := parseTestClass(t)
+	// An overload of foo declared ahead of foo()Z
+	overload, err := c.ConstantPool.AddUtf8("(I)V")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Methods = append([]Method{{NameIndex: 13, DescriptorIndex: overload, constantPool: &c.ConstantPool}}, c.Methods...)
+
+	methods := []struct {
+		name, descriptor string
+		want             string
+	}{
+		{"foo", "()Z", "()Z"},
+		{"foo", "(I)V", "(I)V"},
+		// An empty descriptor matches the first method with the name
+		{"foo", "", "(I)V"},
+		{"foo", "()V", ""},
+		{"bar", "", ""},
+		{"<init>", "", "()V"},
+	}
+	for _, test := range methods {
+		method := c.FindMethod(test.name, test.descriptor)
+		got := ""
+		if method != nil {
+			got = method.Descriptor()
+		}
+		if got != test.want {
+			t.Errorf("FindMethod(%q, %q) has descriptor %q, want %q", test.name, test.descriptor, got, test.want)
+		}
+	}
+
+	fields := []struct {
+		name, descriptor string
+		found            bool
+	}{
+		{"foo", "Ljava/lang/String;", true},
+		{"foo", "", true},
+		{"foo", "I", false},
+		{"main", "", false},
+	}
+	for _, test := range fields {
+		if field := c.FindField(test.name, test.descriptor); (field != nil) != test.found {
+			t.Errorf("FindField(%q, %q) = %v, want found %v", test.name, test.descriptor, field, test.found)
+		}
+	}
+}
+
+func TestConstantGetters(t *testing.T) {
+	c := parseTestClass(t)
+	cp := &c.ConstantPool
+	long, err := cp.AddLong(1 << 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	integer, err := cp.AddInteger(-3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	join := func(values ...string) string { return strings.Join(values, " ") }
+	tests := []struct {
+		name string
+		get  func() (string, error)
+		want string
+		err  string
+	}{
+		{"ClassRef", func() (string, error) { return cp.ClassRef(10) }, "Test", ""},
+		{"ClassRef of a String", func() (string, error) { return cp.ClassRef(7) }, "", "constant pool index 7 is a String, expected Class"},
+		{"MethodRef", func() (string, error) {
+			owner, name, desc, err := cp.MethodRef(1)
+			return join(owner, name, desc), err
+		}, "java/lang/Object <init> ()V", ""},
+		{"MethodRef of a Fieldref", func() (string, error) {
+			_, _, _, err := cp.MethodRef(9)
+			return "", err
+		}, "", "constant pool index 9 is a Fieldref, expected Methodref"},
+		{"FieldRef", func() (string, error) {
+			owner, name, desc, err := cp.FieldRef(9)
+			return join(owner, name, desc), err
+		}, "Test foo Ljava/lang/String;", ""},
+		{"InterfaceMethodRef of a Methodref", func() (string, error) {
+			_, _, _, err := cp.InterfaceMethodRef(16)
+			return "", err
+		}, "", "is a Methodref, expected InterfaceMethodref"},
+		{"NameAndType", func() (string, error) {
+			name, desc, err := cp.NameAndType(17)
+			return join(name, desc), err
+		}, "foo ()Z", ""},
+		{"StringConstant", func() (string, error) { return cp.StringConstant(7) }, "Yes", ""},
+		{"StringConstant of a Utf8", func() (string, error) { return cp.StringConstant(8) }, "", "is a Utf8, expected String"},
+		{"Utf8", func() (string, error) { return cp.Utf8(8) }, "Yes", ""},
+		{"index zero", func() (string, error) { return cp.Utf8(0) }, "", "invalid constant pool index: 0"},
+		{"index past the end", func() (string, error) { return cp.Utf8(999) }, "", "invalid constant pool index: 999"},
+		{"slot after a long", func() (string, error) {
+			_, err := cp.LongConstant(long + 1)
+			return "", err
+		}, "", "is not a usable entry"},
+		{"IntegerConstant of a Long", func() (string, error) {
+			_, err := cp.IntegerConstant(long)
+			return "", err
+		}, "", "is a Long, expected Integer"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.get()
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("err = %v, want an error containing %q", err, test.err)
+				}
+				return
+			}
+			if err != nil || got != test.want {
+				t.Errorf("got %q, %v, want %q", got, err, test.want)
+			}
+		})
+	}
+
+	if v, err := cp.LongConstant(long); err != nil || v != 1<<40 {
+		t.Errorf("LongConstant() = %d, %v, want %d", v, err, int64(1<<40))
+	}
+	if v, err := cp.IntegerConstant(integer); err != nil || v != -3 {
+		t.Errorf("IntegerConstant() = %d, %v, want -3", v, err)
+	}
+}
@@ -0,0 +1,685 @@
+package class
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"lava-vm/pkg/bytecode"
+)
+
+// refVisitor is called with a pointer to every constant pool index stored in
+// a class, so that one walk serves both to find the entries in use and to
+// renumber them. Optional indexes are only visited when they are non-zero.
+type refVisitor func(index *uint16) error
+
+// RemoveUnreferencedConstants removes the constant pool entries that nothing
+// in the class refers to, directly or through other entries, and renumbers
+// every remaining index, including those in typed attributes and in bytecode.
+// Entries keep their relative order. It returns the number of entries removed.
+//
+// Attributes without a typed Value, such as unknown attributes, may hold
+// indexes that cannot be found, so the pool is left untouched and an error is
+// returned if the class has any.
+func (c *Class) RemoveUnreferencedConstants() (int, error) {
+	cp := &c.ConstantPool
+	used := make([]bool, len(cp.entries))
+	var worklist []uint16
+	mark := func(index *uint16) error {
+		if int(*index) >= len(used) || cp.entries[*index].Value == nil {
+			return fmt.Errorf("invalid constant pool index: %d", *index)
+		}
+		if !used[*index] {
+			used[*index] = true
+			worklist = append(worklist, *index)
+		}
+		return nil
+	}
+	if err := c.visitConstantRefs(mark); err != nil {
+		return 0, err
+	}
+	for len(worklist) > 0 {
+		index := worklist[len(worklist)-1]
+		worklist = worklist[:len(worklist)-1]
+		if err := visitEntryRefs(&cp.entries[index], mark); err != nil {
+			return 0, fmt.Errorf("constant pool #%d: %w", index, err)
+		}
+	}
+
+	renumbered := make([]uint16, len(cp.entries))
+	entries := make([]ConstantPoolEntry, 1, len(cp.entries))
+	removed := 0
+	for i := 1; i < len(cp.entries); i++ {
+		entry := cp.entries[i]
+		if entry.Value == nil {
+			continue
+		}
+		if !used[i] {
+			removed++
+			continue
+		}
+		renumbered[i] = uint16(len(entries))
+		entries = append(entries, entry)
+		if entry.Tag == TagLong || entry.Tag == TagDouble {
+			entries = append(entries, ConstantPoolEntry{})
+		}
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+
+	// Every index was checked while marking, so renumbering cannot fail
+	// except for an ldc whose operand no longer fits, which removal alone
+	// never causes.
+	renumber := func(index *uint16) error {
+		*index = renumbered[*index]
+		return nil
+	}
+	for i := range entries {
+		if entries[i].Value != nil {
+			entries[i].Value = copyConstantValue(entries[i].Value)
+			if err := visitEntryRefs(&entries[i], renumber); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if err := c.visitConstantRefs(renumber); err != nil {
+		return 0, err
+	}
+
+	// Encode before committing to the new pool, and on failure put the
+	// original indexes back so that the class is left as it was
+	encoded, err := c.encodeAttributeInfo()
+	if err != nil {
+		original := make([]uint16, len(entries))
+		for i, index := range renumbered {
+			if index != 0 {
+				original[index] = uint16(i)
+			}
+		}
+		_ = c.visitConstantRefs(func(index *uint16) error {
+			*index = original[*index]
+			return nil
+		})
+		return 0, err
+	}
+	cp.entries = entries
+	cp.index = nil
+	storeAttributeInfo(encoded)
+	return removed, nil
+}
+
+// ImportAttribute returns a copy of attr, an attribute of a class with the
+// constant pool from, whose name and constant pool indexes refer to equal
+// entries of cp. Entries that cp lacks are added to it together with the
+// entries they refer to. The copy is decoded again from attr.Info, leaving
+// attr unchanged, and its Info is encoded from the renumbered value.
+//
+// Only attributes with a typed Value, including any nested attributes, can
+// be imported, since the indexes held by any other attribute are unknown.
+func (cp *ConstantPool) ImportAttribute(attr *Attribute, from *ConstantPool) (Attribute, error) {
+	imported := Attribute{Name: attr.Name, Info: attr.Info}
+	if err := imported.decodeValue(from); err != nil {
+		return Attribute{}, err
+	}
+	encoder, ok := imported.Value.(attributeEncoder)
+	if !ok {
+		return Attribute{}, fmt.Errorf("%s attribute has no typed value, so its constant pool references are unknown", attr.Name)
+	}
+
+	indexes := make(map[uint16]uint16)
+	importRef := func(index *uint16) error {
+		var err error
+		*index, err = cp.importEntry(from, *index, indexes)
+		return err
+	}
+	if err := visitAttributeValueRefs(&imported, importRef); err != nil {
+		return Attribute{}, fmt.Errorf("%s attribute: %w", attr.Name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := encoder.encode(&buf); err != nil {
+		return Attribute{}, fmt.Errorf("encoding %s attribute: %w", attr.Name, err)
+	}
+	nameIndex, err := cp.AddUtf8(attr.Name)
+	if err != nil {
+		return Attribute{}, err
+	}
+	imported.AttributeNameIndex = nameIndex
+	imported.Info = buf.Bytes()
+	imported.AttributeLength = uint32(len(imported.Info))
+	return imported, nil
+}
+
+// importEntry returns the index in cp of an entry equal to the entry at index
+// in from, adding it and the entries it refers to if needed. indexes holds the
+// entries imported so far, and zero for those being imported, which only an
+// entry that refers to itself finds.
+func (cp *ConstantPool) importEntry(from *ConstantPool, index uint16, indexes map[uint16]uint16) (uint16, error) {
+	if imported, ok := indexes[index]; ok {
+		if imported == 0 {
+			return 0, fmt.Errorf("constant pool index %d refers to itself", index)
+		}
+		return imported, nil
+	}
+	entry, err := from.entry(index)
+	if err != nil {
+		return 0, err
+	}
+	indexes[index] = 0
+	entry.Value = copyConstantValue(entry.Value)
+	err = visitEntryRefs(&entry, func(ref *uint16) error {
+		var err error
+		*ref, err = cp.importEntry(from, *ref, indexes)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	imported, err := cp.add(entry.Tag, entry.Value)
+	if err != nil {
+		return 0, err
+	}
+	indexes[index] = imported
+	return imported, nil
+}
+
+// copyConstantValue returns a shallow copy of a constant so that renumbering
+// does not change values shared with another pool.
+func copyConstantValue(value ConstantPoolValue) ConstantPoolValue {
+	switch v := value.(type) {
+	case *ConstantClassRefValue:
+		copied := *v
+		return &copied
+	case *ConstantStringRefValue:
+		copied := *v
+		return &copied
+	case *ConstantFieldRefValue:
+		copied := *v
+		return &copied
+	case *ConstantMethodRefValue:
+		copied := *v
+		return &copied
+	case *ConstantInterfaceMethodRefValue:
+		copied := *v
+		return &copied
+	case *ConstantNameAndTypeDescriptorValue:
+		copied := *v
+		return &copied
+	case *ConstantMethodHandleValue:
+		copied := *v
+		return &copied
+	case *ConstantMethodTypeValue:
+		copied := *v
+		return &copied
+	case *ConstantDynamicValue:
+		copied := *v
+		return &copied
+	case *ConstantInvokeDynamicValue:
+		copied := *v
+		return &copied
+	case *ConstantModuleValue:
+		copied := *v
+		return &copied
+	case *ConstantPackageValue:
+		copied := *v
+		return &copied
+	default:
+		// Utf8 and numeric constants hold no indexes
+		return value
+	}
+}
+
+// visitEntryRefs visits the indexes a constant pool entry holds to other entries.
+func visitEntryRefs(entry *ConstantPoolEntry, visit refVisitor) error {
+	switch v := entry.Value.(type) {
+	case *ConstantClassRefValue:
+		return visit(&v.Index)
+	case *ConstantStringRefValue:
+		return visit(&v.Index)
+	case *ConstantFieldRefValue:
+		return visitAll(visit, &v.ClassIndex, &v.NameAndTypeIndex)
+	case *ConstantMethodRefValue:
+		return visitAll(visit, &v.ClassIndex, &v.NameAndTypeIndex)
+	case *ConstantInterfaceMethodRefValue:
+		return visitAll(visit, &v.ClassIndex, &v.NameAndTypeIndex)
+	case *ConstantNameAndTypeDescriptorValue:
+		return visitAll(visit, &v.NameIndex, &v.DescriptorIndex)
+	case *ConstantMethodHandleValue:
+		return visit(&v.ReferenceIndex)
+	case *ConstantMethodTypeValue:
+		return visit(&v.DescriptorIndex)
+	case *ConstantDynamicValue:
+		return visit(&v.NameAndTypeIndex)
+	case *ConstantInvokeDynamicValue:
+		return visit(&v.NameAndTypeIndex)
+	case *ConstantModuleValue:
+		return visit(&v.NameIndex)
+	case *ConstantPackageValue:
+		return visit(&v.NameIndex)
+	}
+	return nil
+}
+
+func visitAll(visit refVisitor, indexes ...*uint16) error {
+	for _, index := range indexes {
+		if err := visit(index); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func visitOptional(visit refVisitor, index *uint16) error {
+	if *index == 0 {
+		return nil
+	}
+	return visit(index)
+}
+
+func visitTable(visit refVisitor, table []uint16) error {
+	for i := range table {
+		if err := visit(&table[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// visitConstantRefs visits every constant pool index held by the class
+// outside the constant pool itself.
+func (c *Class) visitConstantRefs(visit refVisitor) error {
+	if err := visit(&c.ThisClass); err != nil {
+		return fmt.Errorf("this class: %w", err)
+	}
+	if err := visitOptional(visit, &c.SuperClass); err != nil {
+		return fmt.Errorf("super class: %w", err)
+	}
+	if err := visitTable(visit, c.Interfaces); err != nil {
+		return fmt.Errorf("interfaces: %w", err)
+	}
+	for i := range c.Fields {
+		field := &c.Fields[i]
+		if err := visitAll(visit, &field.NameIndex, &field.DescriptorIndex); err != nil {
+			return fmt.Errorf("field %d: %w", i, err)
+		}
+		if err := visitAttributeRefs(field.Attributes, visit); err != nil {
+			return fmt.Errorf("field %d: %w", i, err)
+		}
+	}
+	for i := range c.Methods {
+		method := &c.Methods[i]
+		if err := visitAll(visit, &method.NameIndex, &method.DescriptorIndex); err != nil {
+			return fmt.Errorf("method %d: %w", i, err)
+		}
+		if err := visitAttributeRefs(method.Attributes, visit); err != nil {
+			return fmt.Errorf("method %d: %w", i, err)
+		}
+	}
+	return visitAttributeRefs(c.Attributes, visit)
+}
+
+func visitAttributeRefs(attributes []Attribute, visit refVisitor) error {
+	for i := range attributes {
+		attr := &attributes[i]
+		if err := visit(&attr.AttributeNameIndex); err != nil {
+			return fmt.Errorf("attribute %d: %w", i, err)
+		}
+		if err := visitAttributeValueRefs(attr, visit); err != nil {
+			return fmt.Errorf("%s attribute: %w", attr.Name, err)
+		}
+	}
+	return nil
+}
+
+func visitAttributeValueRefs(attr *Attribute, visit refVisitor) error {
+	value, err := attr.Decode()
+	if err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case *DeprecatedAttribute, *SyntheticAttribute, *SourceDebugExtensionAttribute, *LineNumberTableAttribute:
+		return nil
+	case *ConstantValueAttribute:
+		return visit(&v.ConstantValueIndex)
+	case *ExceptionsAttribute:
+		return visitTable(visit, v.ExceptionIndexTable)
+	case *SourceFileAttribute:
+		return visit(&v.SourceFileIndex)
+	case *SignatureAttribute:
+		return visit(&v.SignatureIndex)
+	case *EnclosingMethodAttribute:
+		if err := visit(&v.ClassIndex); err != nil {
+			return err
+		}
+		return visitOptional(visit, &v.MethodIndex)
+	case *MethodParametersAttribute:
+		for i := range v.Parameters {
+			if err := visitOptional(visit, &v.Parameters[i].NameIndex); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *Code:
+		return visitCodeRefs(v, visit)
+	case *LocalVariableTableAttribute:
+		for i := range v.LocalVariableTable {
+			entry := &v.LocalVariableTable[i]
+			if err := visitAll(visit, &entry.NameIndex, &entry.DescriptorIndex); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *LocalVariableTypeTableAttribute:
+		for i := range v.LocalVariableTypeTable {
+			entry := &v.LocalVariableTypeTable[i]
+			if err := visitAll(visit, &entry.NameIndex, &entry.SignatureIndex); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *StackMapTableAttribute:
+		for i := range v.Entries {
+			frame := &v.Entries[i]
+			for _, types := range [][]VerificationTypeInfo{frame.Locals, frame.Stack} {
+				for j := range types {
+					if types[j].Tag == ItemObject {
+						if err := visit(&types[j].CpoolIndex); err != nil {
+							return err
+						}
+					}
+				}
+			}
+		}
+		return nil
+	case *RuntimeVisibleAnnotationsAttribute:
+		return visitAnnotationsRefs(v.Annotations, visit)
+	case *RuntimeInvisibleAnnotationsAttribute:
+		return visitAnnotationsRefs(v.Annotations, visit)
+	case *RuntimeVisibleParameterAnnotationsAttribute:
+		for _, annotations := range v.ParameterAnnotations {
+			if err := visitAnnotationsRefs(annotations, visit); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *RuntimeInvisibleParameterAnnotationsAttribute:
+		for _, annotations := range v.ParameterAnnotations {
+			if err := visitAnnotationsRefs(annotations, visit); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *RuntimeVisibleTypeAnnotationsAttribute:
+		return visitTypeAnnotationsRefs(v.Annotations, visit)
+	case *RuntimeInvisibleTypeAnnotationsAttribute:
+		return visitTypeAnnotationsRefs(v.Annotations, visit)
+	case *AnnotationDefaultAttribute:
+		return visitElementValueRefs(&v.DefaultValue, visit)
+	case *BootstrapMethodsAttribute:
+		for i := range v.BootstrapMethods {
+			method := &v.BootstrapMethods[i]
+			if err := visit(&method.BootstrapMethodRef); err != nil {
+				return err
+			}
+			if err := visitTable(visit, method.BootstrapArguments); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *InnerClassesAttribute:
+		for i := range v.Classes {
+			inner := &v.Classes[i]
+			if err := visit(&inner.InnerClassInfoIndex); err != nil {
+				return err
+			}
+			if err := visitOptional(visit, &inner.OuterClassInfoIndex); err != nil {
+				return err
+			}
+			if err := visitOptional(visit, &inner.InnerNameIndex); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *NestHostAttribute:
+		return visit(&v.HostClassIndex)
+	case *NestMembersAttribute:
+		return visitTable(visit, v.Classes)
+	case *PermittedSubclassesAttribute:
+		return visitTable(visit, v.Classes)
+	case *RecordAttribute:
+		for i := range v.Components {
+			component := &v.Components[i]
+			if err := visitAll(visit, &component.NameIndex, &component.DescriptorIndex); err != nil {
+				return err
+			}
+			if err := visitAttributeRefs(component.Attributes, visit); err != nil {
+				return fmt.Errorf("record component %d: %w", i, err)
+			}
+		}
+		return nil
+	case *ModuleAttribute:
+		return visitModuleRefs(v, visit)
+	case *ModulePackagesAttribute:
+		return visitTable(visit, v.PackageIndex)
+	case *ModuleMainClassAttribute:
+		return visit(&v.MainClassIndex)
+	default:
+		return fmt.Errorf("attribute has no typed value, so its constant pool references are unknown")
+	}
+}
+
+func visitAnnotationsRefs(annotations []Annotation, visit refVisitor) error {
+	for i := range annotations {
+		if err := visitAnnotationRefs(&annotations[i], visit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func visitAnnotationRefs(annotation *Annotation, visit refVisitor) error {
+	if err := visit(&annotation.TypeIndex); err != nil {
+		return err
+	}
+	for i := range annotation.ElementValuePairs {
+		pair := &annotation.ElementValuePairs[i]
+		if err := visit(&pair.ElementNameIndex); err != nil {
+			return err
+		}
+		if err := visitElementValueRefs(&pair.Value, visit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func visitElementValueRefs(value *ElementValue, visit refVisitor) error {
+	switch value.Tag {
+	case 'B', 'C', 'D', 'F', 'I', 'J', 'S', 'Z', 's':
+		return visit(&value.ConstValueIndex)
+	case 'e':
+		return visitAll(visit, &value.TypeNameIndex, &value.ConstNameIndex)
+	case 'c':
+		return visit(&value.ClassInfoIndex)
+	case '@':
+		if value.AnnotationValue != nil {
+			return visitAnnotationRefs(value.AnnotationValue, visit)
+		}
+	case '[':
+		for i := range value.ArrayValue {
+			if err := visitElementValueRefs(&value.ArrayValue[i], visit); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func visitTypeAnnotationsRefs(annotations []TypeAnnotation, visit refVisitor) error {
+	for i := range annotations {
+		if err := visitAnnotationRefs(&annotations[i].Annotation, visit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func visitModuleRefs(module *ModuleAttribute, visit refVisitor) error {
+	if err := visit(&module.ModuleNameIndex); err != nil {
+		return err
+	}
+	if err := visitOptional(visit, &module.ModuleVersionIndex); err != nil {
+		return err
+	}
+	for i := range module.Requires {
+		requires := &module.Requires[i]
+		if err := visit(&requires.RequiresIndex); err != nil {
+			return err
+		}
+		if err := visitOptional(visit, &requires.RequiresVersionIndex); err != nil {
+			return err
+		}
+	}
+	for i := range module.Exports {
+		if err := visit(&module.Exports[i].ExportsIndex); err != nil {
+			return err
+		}
+		if err := visitTable(visit, module.Exports[i].ExportsToIndex); err != nil {
+			return err
+		}
+	}
+	for i := range module.Opens {
+		if err := visit(&module.Opens[i].OpensIndex); err != nil {
+			return err
+		}
+		if err := visitTable(visit, module.Opens[i].OpensToIndex); err != nil {
+			return err
+		}
+	}
+	if err := visitTable(visit, module.UsesIndex); err != nil {
+		return err
+	}
+	for i := range module.Provides {
+		if err := visit(&module.Provides[i].ProvidesIndex); err != nil {
+			return err
+		}
+		if err := visitTable(visit, module.Provides[i].ProvidesWithIndex); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func visitCodeRefs(code *Code, visit refVisitor) error {
+	if err := visitBytecodeRefs(code.Bytecode, visit); err != nil {
+		return err
+	}
+	for i := range code.ExceptionTable {
+		if err := visitOptional(visit, &code.ExceptionTable[i].CatchType); err != nil {
+			return fmt.Errorf("exception table entry %d: %w", i, err)
+		}
+	}
+	return visitAttributeRefs(code.Attributes, visit)
+}
+
+// visitBytecodeRefs visits the constant pool operands of the instructions in
+// bytecode, writing back any index the visitor changes.
+func visitBytecodeRefs(code []byte, visit refVisitor) error {
+	for pc := 0; pc < len(code); {
+		op := bytecode.Opcode(code[pc])
+		length, err := bytecode.Length(code, pc)
+		if err != nil {
+			return err
+		}
+
+		switch op {
+		case bytecode.Ldc:
+			index := uint16(code[pc+1])
+			if err := visit(&index); err != nil {
+				return fmt.Errorf("ldc at pc %d: %w", pc, err)
+			}
+			if index > 0xFF {
+				return fmt.Errorf("ldc at pc %d: index %d does not fit in one byte", pc, index)
+			}
+			code[pc+1] = uint8(index)
+		case bytecode.LdcW, bytecode.Ldc2W,
+			bytecode.Getstatic, bytecode.Putstatic, bytecode.Getfield, bytecode.Putfield,
+			bytecode.Invokevirtual, bytecode.Invokespecial, bytecode.Invokestatic, bytecode.Invokeinterface, bytecode.Invokedynamic,
+			bytecode.New, bytecode.Anewarray, bytecode.Checkcast, bytecode.Instanceof, bytecode.Multianewarray:
+			index := binary.BigEndian.Uint16(code[pc+1:])
+			if err := visit(&index); err != nil {
+				return fmt.Errorf("%s at pc %d: %w", op, pc, err)
+			}
+			binary.BigEndian.PutUint16(code[pc+1:], index)
+		}
+		pc += length
+	}
+	return nil
+}
+
+// attributeInfo is the re-encoded Info of an attribute, held back until every
+// attribute has been encoded.
+type attributeInfo struct {
+	attr *Attribute
+	info []byte
+}
+
+// encodeAttributeInfo re-encodes the Info of every typed attribute in the
+// class from its Value, after the values have been changed in place. The
+// attributes are left untouched so that a failure changes nothing; see
+// storeAttributeInfo.
+func (c *Class) encodeAttributeInfo() ([]attributeInfo, error) {
+	var encoded []attributeInfo
+	var err error
+	for i := range c.Fields {
+		if encoded, err = encodeAttributes(c.Fields[i].Attributes, encoded); err != nil {
+			return nil, fmt.Errorf("field %d: %w", i, err)
+		}
+	}
+	for i := range c.Methods {
+		if encoded, err = encodeAttributes(c.Methods[i].Attributes, encoded); err != nil {
+			return nil, fmt.Errorf("method %d: %w", i, err)
+		}
+	}
+	return encodeAttributes(c.Attributes, encoded)
+}
+
+func encodeAttributes(attributes []Attribute, encoded []attributeInfo) ([]attributeInfo, error) {
+	var err error
+	for i := range attributes {
+		attr := &attributes[i]
+		// Outer attributes encode their nested attributes from Value, so
+		// nested Info can be stored in any order
+		switch v := attr.Value.(type) {
+		case *Code:
+			if encoded, err = encodeAttributes(v.Attributes, encoded); err != nil {
+				return nil, err
+			}
+		case *RecordAttribute:
+			for j := range v.Components {
+				if encoded, err = encodeAttributes(v.Components[j].Attributes, encoded); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		encoder, ok := attr.Value.(attributeEncoder)
+		if !ok {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := encoder.encode(&buf); err != nil {
+			return nil, fmt.Errorf("encoding %s attribute: %w", attr.Name, err)
+		}
+		encoded = append(encoded, attributeInfo{attr: attr, info: buf.Bytes()})
+	}
+	return encoded, nil
+}
+
+// storeAttributeInfo stores the Info returned by encodeAttributeInfo.
+func storeAttributeInfo(encoded []attributeInfo) {
+	for _, e := range encoded {
+		e.attr.Info = e.info
+		e.attr.AttributeLength = uint32(len(e.info))
+	}
+}
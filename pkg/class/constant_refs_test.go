@@ -0,0 +1,123 @@
+package class
+
+import (
+	"bytes"
+	"testing"
+)
+
+// dropLineNumbers removes the LineNumberTable of every method, leaving the
+// Utf8 entry holding its name unreferenced.
+func dropLineNumbers(t *testing.T, c *Class) {
+	t.Helper()
+	for i := range c.Methods {
+		code, err := c.Methods[i].GetCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var kept []Attribute
+		for _, attr := range code.Attributes {
+			if attr.Name != "LineNumberTable" {
+				kept = append(kept, attr)
+			}
+		}
+		code.Attributes = kept
+	}
+}
+
+func TestRemoveUnreferencedConstants(t *testing.T) {
+	c := parseTestClass(t)
+	dropLineNumbers(t, c)
+	if _, err := c.ConstantPool.AddString("unused"); err != nil {
+		t.Fatal(err)
+	}
+	before := c.ConstantPool.Len()
+
+	removed, err := c.RemoveUnreferencedConstants()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// LineNumberTable, and the String added with its Utf8
+	if removed != 3 || c.ConstantPool.Len() != before-3 {
+		t.Errorf("removed %d entries leaving %d, want 3 leaving %d", removed, c.ConstantPool.Len(), before-3)
+	}
+	if err := Check(c); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := c.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reparsed, err := ParseBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := reparsed.FindMethod("main", "").Name(); name != "main" {
+		t.Errorf("main is named %q after renumbering", name)
+	}
+}
+
+func TestRemoveUnreferencedConstantsFailureLeavesClass(t *testing.T) {
+	c := parseTestClass(t)
+	dropLineNumbers(t, c)
+	want, err := c.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An exception table too long to encode makes re-encoding fail after the
+	// indexes have been renumbered
+	code, err := c.FindMethod("foo", "()Z").GetCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := code.ExceptionTable
+	code.ExceptionTable = make([]ExceptionTableEntry, 1<<16)
+	if _, err := c.RemoveUnreferencedConstants(); err == nil {
+		t.Fatal("RemoveUnreferencedConstants succeeded with an exception table that cannot be encoded")
+	}
+	code.ExceptionTable = table
+
+	got, err := c.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("class changed by a failed RemoveUnreferencedConstants:\ngot  %x\nwant %x", got, want)
+	}
+}
+
+func TestImportAttribute(t *testing.T) {
+	c := parseTestClass(t)
+	cp := &c.ConstantPool
+	typeIndex, _ := cp.AddUtf8("Ljava/lang/Deprecated;")
+	name, _ := cp.AddUtf8("since")
+	value, _ := cp.AddUtf8("9")
+	addClassAttribute(t, c, "RuntimeVisibleAnnotations", append(append(u2(1, typeIndex, 1, name), 's'), u2(value)...))
+	attr := &c.Attributes[len(c.Attributes)-1]
+
+	pool := NewConstantPool()
+	imported, err := pool.ImportAttribute(attr, cp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The three Utf8 entries the annotation refers to and the name
+	if pool.Len() != 5 {
+		t.Errorf("imported pool has %d entries, want 5", pool.Len())
+	}
+	if err := imported.decode(pool); err != nil {
+		t.Fatal(err)
+	}
+	annotation := imported.Value.(*RuntimeVisibleAnnotationsAttribute).Annotations[0]
+	if annotation.Type != "Ljava/lang/Deprecated;" || annotation.ElementValuePairs[0].ElementName != "since" {
+		t.Errorf("imported annotation %+v", annotation)
+	}
+	if s, _ := pool.Utf8(annotation.ElementValuePairs[0].Value.ConstValueIndex); s != "9" {
+		t.Errorf("imported element value %q, want \"9\"", s)
+	}
+
+	unknown := Attribute{Name: "Unknown", Info: u2(typeIndex)}
+	if _, err := pool.ImportAttribute(&unknown, cp); err == nil {
+		t.Error("ImportAttribute succeeded for an attribute with no typed value")
+	}
+}
@@ -0,0 +1,192 @@
+package class
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+type LineNumberTableEntry struct {
+	StartPc    uint16
+	LineNumber uint16
+}
+
+// LineNumberTableAttribute maps ranges of bytecode to source line numbers.
+// A Code attribute may carry several of them, in no particular order.
+// See https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.12
+type LineNumberTableAttribute struct {
+	LineNumberTable []LineNumberTableEntry
+}
+
+func decodeLineNumberTableAttribute(info []byte, cp *ConstantPool) (interface{}, error) {
+	reader := bytes.NewReader(info)
+	var length uint16
+	if err := binary.Read(reader, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+	if err := checkCount(reader, uint32(length), 4); err != nil {
+		return nil, err
+	}
+	attr := &LineNumberTableAttribute{LineNumberTable: make([]LineNumberTableEntry, length)}
+	if err := binary.Read(reader, binary.BigEndian, attr.LineNumberTable); err != nil {
+		return nil, err
+	}
+	return attr, checkFullyRead(reader)
+}
+
+func (a *LineNumberTableAttribute) encode(w *bytes.Buffer) error {
+	if err := putCount(w, len(a.LineNumberTable)); err != nil {
+		return err
+	}
+	put(w, a.LineNumberTable)
+	return nil
+}
+
+type LocalVariableTableEntry struct {
+	StartPc         uint16
+	Length          uint16
+	NameIndex       uint16
+	DescriptorIndex uint16
+	Index           uint16
+}
+
+// LocalVariableTableAttribute describes the local variables of a method. Each
+// entry is live for pcs in [StartPc, StartPc+Length) and occupies local slot Index.
+// See https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.13
+type LocalVariableTableAttribute struct {
+	LocalVariableTable []LocalVariableTableEntry
+}
+
+func decodeLocalVariableTableAttribute(info []byte, cp *ConstantPool) (interface{}, error) {
+	reader := bytes.NewReader(info)
+	var length uint16
+	if err := binary.Read(reader, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+	if err := checkCount(reader, uint32(length), 10); err != nil {
+		return nil, err
+	}
+	attr := &LocalVariableTableAttribute{LocalVariableTable: make([]LocalVariableTableEntry, length)}
+	if err := binary.Read(reader, binary.BigEndian, attr.LocalVariableTable); err != nil {
+		return nil, err
+	}
+	return attr, checkFullyRead(reader)
+}
+
+func (a *LocalVariableTableAttribute) encode(w *bytes.Buffer) error {
+	if err := putCount(w, len(a.LocalVariableTable)); err != nil {
+		return err
+	}
+	put(w, a.LocalVariableTable)
+	return nil
+}
+
+type LocalVariableTypeTableEntry struct {
+	StartPc        uint16
+	Length         uint16
+	NameIndex      uint16
+	SignatureIndex uint16
+	Index          uint16
+}
+
+// LocalVariableTypeTableAttribute holds the generic signatures of local
+// variables whose type uses a type variable or parameterized type.
+// See https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.14
+type LocalVariableTypeTableAttribute struct {
+	LocalVariableTypeTable []LocalVariableTypeTableEntry
+}
+
+func decodeLocalVariableTypeTableAttribute(info []byte, cp *ConstantPool) (interface{}, error) {
+	reader := bytes.NewReader(info)
+	var length uint16
+	if err := binary.Read(reader, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+	if err := checkCount(reader, uint32(length), 10); err != nil {
+		return nil, err
+	}
+	attr := &LocalVariableTypeTableAttribute{LocalVariableTypeTable: make([]LocalVariableTypeTableEntry, length)}
+	if err := binary.Read(reader, binary.BigEndian, attr.LocalVariableTypeTable); err != nil {
+		return nil, err
+	}
+	return attr, checkFullyRead(reader)
+}
+
+func (a *LocalVariableTypeTableAttribute) encode(w *bytes.Buffer) error {
+	if err := putCount(w, len(a.LocalVariableTypeTable)); err != nil {
+		return err
+	}
+	put(w, a.LocalVariableTypeTable)
+	return nil
+}
+
+// LineNumber returns the source line of the instruction at pc, using every
+// LineNumberTable attribute of the code. It returns false if no entry covers pc.
+func (c *Code) LineNumber(pc int) (int, bool) {
+	line, start := 0, -1
+	for _, attr := range c.Attributes {
+		table, ok := attr.Value.(*LineNumberTableAttribute)
+		if !ok {
+			continue
+		}
+		for _, entry := range table.LineNumberTable {
+			if int(entry.StartPc) <= pc && int(entry.StartPc) > start {
+				line, start = int(entry.LineNumber), int(entry.StartPc)
+			}
+		}
+	}
+	return line, start >= 0
+}
+
+// LocalVariable is a local variable resolved from the LocalVariableTable and
+// LocalVariableTypeTable attributes. Signature is empty for variables that
+// have no generic type.
+type LocalVariable struct {
+	StartPc    uint16
+	Length     uint16
+	Index      uint16
+	Name       string
+	Descriptor string
+	Signature  string
+}
+
+// LocalVariable returns the local variable held in the given slot at pc, or
+// false if the code has no debug information for it.
+func (c *Code) LocalVariable(pc int, slot uint16) (*LocalVariable, bool) {
+	for _, attr := range c.Attributes {
+		table, ok := attr.Value.(*LocalVariableTableAttribute)
+		if !ok {
+			continue
+		}
+		for _, entry := range table.LocalVariableTable {
+			if entry.Index != slot || pc < int(entry.StartPc) || pc >= int(entry.StartPc)+int(entry.Length) {
+				continue
+			}
+			return &LocalVariable{
+				StartPc:    entry.StartPc,
+				Length:     entry.Length,
+				Index:      entry.Index,
+				Name:       c.constantPool.GetConstantName(entry.NameIndex),
+				Descriptor: c.constantPool.GetConstantName(entry.DescriptorIndex),
+				Signature:  c.localVariableSignature(entry.StartPc, entry.Length, entry.Index),
+			}, true
+		}
+	}
+	return nil, false
+}
+
+// localVariableSignature returns the generic signature of the local variable
+// with the given range and slot, or an empty string.
+func (c *Code) localVariableSignature(startPc, length, index uint16) string {
+	for _, attr := range c.Attributes {
+		table, ok := attr.Value.(*LocalVariableTypeTableAttribute)
+		if !ok {
+			continue
+		}
+		for _, entry := range table.LocalVariableTypeTable {
+			if entry.StartPc == startPc && entry.Length == length && entry.Index == index {
+				return c.constantPool.GetConstantName(entry.SignatureIndex)
+			}
+		}
+	}
+	return ""
+}
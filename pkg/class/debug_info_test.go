@@ -0,0 +1,82 @@
+package class
+
+import (
+	"reflect"
+	"testing"
+)
+
+// mainCode returns the Code attribute of Test.main, whose LineNumberTable maps
+// pc 0 to line 7, pc 8 to line 8 and pc 13 to line 9.
+func mainCode(t *testing.T, c *Class) *Code {
+	t.Helper()
+	code, err := c.FindMethod("main", "([Ljava/lang/String;)V").GetCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func TestLineNumber(t *testing.T) {
+	c := parseTestClass(t)
+	code := mainCode(t, c)
+	// A second table may cover the same code; the closest start wins
+	addAttribute(t, &c.ConstantPool, &code.Attributes, "LineNumberTable", u2(1, 4, 20))
+
+	tests := []struct {
+		pc   int
+		line int
+		ok   bool
+	}{
+		{0, 7, true},
+		{3, 7, true},
+		{4, 20, true},
+		{7, 20, true},
+		{8, 8, true},
+		{13, 9, true},
+	}
+	for _, test := range tests {
+		line, ok := code.LineNumber(test.pc)
+		if line != test.line || ok != test.ok {
+			t.Errorf("LineNumber(%d) = %d, %v, want %d, %v", test.pc, line, ok, test.line, test.ok)
+		}
+	}
+
+	code.Attributes = nil
+	if line, ok := code.LineNumber(0); ok {
+		t.Errorf("LineNumber(0) = %d, true without a LineNumberTable, want false", line)
+	}
+}
+
+func TestLocalVariable(t *testing.T) {
+	c := parseTestClass(t)
+	code := mainCode(t, c)
+	args, stringArray := utf8Index(t, c, "args"), utf8Index(t, c, "[Ljava/lang/String;")
+	test, testType := utf8Index(t, c, "test"), utf8Index(t, c, "LTest;")
+	testSignature := utf8Index(t, c, "LTest<Ljava/lang/String;>;")
+	addAttribute(t, &c.ConstantPool, &code.Attributes, "LocalVariableTable", u2(2,
+		0, 14, args, stringArray, 0,
+		8, 6, test, testType, 1))
+	addAttribute(t, &c.ConstantPool, &code.Attributes, "LocalVariableTypeTable", u2(1,
+		8, 6, test, testSignature, 1))
+
+	tests := []struct {
+		pc   int
+		slot uint16
+		want *LocalVariable
+	}{
+		{0, 0, &LocalVariable{StartPc: 0, Length: 14, Index: 0, Name: "args", Descriptor: "[Ljava/lang/String;"}},
+		{13, 0, &LocalVariable{StartPc: 0, Length: 14, Index: 0, Name: "args", Descriptor: "[Ljava/lang/String;"}},
+		{8, 1, &LocalVariable{StartPc: 8, Length: 6, Index: 1, Name: "test", Descriptor: "LTest;",
+			Signature: "LTest<Ljava/lang/String;>;"}},
+		// Slot 1 is not live before it is stored at pc 7, nor at the end of its range
+		{7, 1, nil},
+		{14, 1, nil},
+		{8, 2, nil},
+	}
+	for _, test := range tests {
+		got, ok := code.LocalVariable(test.pc, test.slot)
+		if ok != (test.want != nil) || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("LocalVariable(%d, %d) = %+v, %v, want %+v", test.pc, test.slot, got, ok, test.want)
+		}
+	}
+}
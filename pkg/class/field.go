@@ -3,39 +3,103 @@ package class
 import (
 	"encoding/binary"
 	"fmt"
-	"os"
+	"io"
+	"lava-vm/pkg/descriptor"
+	"lava-vm/pkg/signature"
 	"strings"
 )
 
 type Field struct {
-	AccessFlags     uint16
+	AccessFlags     FieldAccessFlags
 	NameIndex       uint16
 	DescriptorIndex uint16
 	AttributesCount uint16
 	Attributes      []Attribute
+	constantPool    *ConstantPool
 }
 
-// Read a Field from the given file
-func readField(file *os.File, field *Field) error {
-	if err := binary.Read(file, binary.BigEndian, &field.AccessFlags); err != nil {
+// Name returns the name of the field, or an empty string if the name index
+// does not point at a Utf8 constant.
+func (f *Field) Name() string {
+	return f.constantPool.GetConstantName(f.NameIndex)
+}
+
+// Descriptor returns the descriptor of the field, e.g. "[Ljava/lang/String;",
+// or an empty string if the descriptor index does not point at a Utf8 constant.
+func (f *Field) Descriptor() string {
+	return f.constantPool.GetConstantName(f.DescriptorIndex)
+}
+
+// Attribute returns the first field attribute with the given name, or nil.
+func (f *Field) Attribute(name string) *Attribute {
+	return findAttribute(f.Attributes, name)
+}
+
+// ConstantValue returns the ConstantValue attribute of the field, or nil.
+func (f *Field) ConstantValue() (*ConstantValueAttribute, error) {
+	value, err := attributeValue(f.Attributes, "ConstantValue")
+	attr, _ := value.(*ConstantValueAttribute)
+	return attr, err
+}
+
+// Signature returns the Signature attribute of the field, or nil.
+func (f *Field) Signature() (*SignatureAttribute, error) {
+	value, err := attributeValue(f.Attributes, "Signature")
+	attr, _ := value.(*SignatureAttribute)
+	return attr, err
+}
+
+// GenericType parses the Signature attribute of the field. It returns nil if
+// the field has no Signature attribute.
+func (f *Field) GenericType() (signature.ReferenceType, error) {
+	attr, err := f.Signature()
+	if attr == nil {
+		return nil, err
+	}
+	return signature.ParseField(attr.Signature)
+}
+
+// IsDeprecated reports whether the field has a Deprecated attribute.
+func (f *Field) IsDeprecated() bool {
+	return findAttribute(f.Attributes, "Deprecated") != nil
+}
+
+// IsSynthetic reports whether the field has a Synthetic attribute.
+func (f *Field) IsSynthetic() bool {
+	return findAttribute(f.Attributes, "Synthetic") != nil
+}
+
+// FieldType parses the descriptor of the field.
+func (f *Field) FieldType() (descriptor.FieldType, error) {
+	s, err := f.constantPool.utf8(f.DescriptorIndex)
+	if err != nil {
+		return descriptor.FieldType{}, err
+	}
+	return descriptor.ParseField(s)
+}
+
+// Read a Field from the given reader
+func readField(r io.Reader, field *Field, cp *ConstantPool, readAttr attributeReader) error {
+	field.constantPool = cp
+	if err := binary.Read(r, binary.BigEndian, &field.AccessFlags); err != nil {
 		return fmt.Errorf("reading access flags: %w", err)
 	}
 
-	if err := binary.Read(file, binary.BigEndian, &field.NameIndex); err != nil {
+	if err := binary.Read(r, binary.BigEndian, &field.NameIndex); err != nil {
 		return fmt.Errorf("reading name index: %w", err)
 	}
 
-	if err := binary.Read(file, binary.BigEndian, &field.DescriptorIndex); err != nil {
+	if err := binary.Read(r, binary.BigEndian, &field.DescriptorIndex); err != nil {
 		return fmt.Errorf("reading descriptor index: %w", err)
 	}
 
-	if err := binary.Read(file, binary.BigEndian, &field.AttributesCount); err != nil {
+	if err := binary.Read(r, binary.BigEndian, &field.AttributesCount); err != nil {
 		return fmt.Errorf("reading attributes count: %w", err)
 	}
 
 	field.Attributes = make([]Attribute, field.AttributesCount)
 	for i := range field.Attributes {
-		if err := readAttribute(file, &field.Attributes[i]); err != nil {
+		if err := readAttr(r, &field.Attributes[i], cp); err != nil {
 			return fmt.Errorf("reading attribute %d: %w", i, err)
 		}
 	}
@@ -45,7 +109,7 @@ func readField(file *os.File, field *Field) error {
 
 func (f Field) String() string {
 	var builder strings.Builder
-	fmt.Fprintf(&builder, "Access Flags: 0x%04X\n", f.AccessFlags)
+	fmt.Fprintf(&builder, "Access Flags: 0x%04X %s\n", uint16(f.AccessFlags), strings.Join(f.AccessFlags.Names(), ", "))
 	fmt.Fprintf(&builder, "Name Index: %d\n", f.NameIndex)
 	fmt.Fprintf(&builder, "Descriptor Index: %d\n", f.DescriptorIndex)
 	fmt.Fprintf(&builder, "Attributes Count: %d\n", f.AttributesCount)
@@ -0,0 +1,214 @@
+package class
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+)
+
+// InnerClass is a single entry of the InnerClasses attribute. OuterClassInfoIndex
+// is zero for top-level, local and anonymous classes, and InnerNameIndex is
+// zero for anonymous classes.
+type InnerClass struct {
+	InnerClassInfoIndex   uint16
+	OuterClassInfoIndex   uint16
+	InnerNameIndex        uint16
+	InnerClassAccessFlags InnerClassAccessFlags
+}
+
+// InnerClassesAttribute records every nested class that a class refers to or
+// declares, and the nesting of the class itself if it is nested.
+// See https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.6
+type InnerClassesAttribute struct {
+	Classes []InnerClass
+}
+
+func decodeInnerClassesAttribute(info []byte, cp *ConstantPool) (interface{}, error) {
+	reader := bytes.NewReader(info)
+	var count uint16
+	if err := binary.Read(reader, binary.BigEndian, &count); err != nil {
+		return nil, err
+	}
+	if err := checkCount(reader, uint32(count), 8); err != nil {
+		return nil, err
+	}
+	attr := &InnerClassesAttribute{Classes: make([]InnerClass, count)}
+	if err := binary.Read(reader, binary.BigEndian, attr.Classes); err != nil {
+		return nil, err
+	}
+	return attr, checkFullyRead(reader)
+}
+
+func (a *InnerClassesAttribute) encode(w *bytes.Buffer) error {
+	if err := putCount(w, len(a.Classes)); err != nil {
+		return err
+	}
+	put(w, a.Classes)
+	return nil
+}
+
+// InnerClassInfo is a resolved InnerClasses entry. OuterName is empty for
+// local and anonymous classes and SimpleName is empty for anonymous classes.
+type InnerClassInfo struct {
+	Name        string
+	OuterName   string
+	SimpleName  string
+	AccessFlags InnerClassAccessFlags
+}
+
+// EnclosingMethodRef is a resolved EnclosingMethod attribute. Name and
+// Descriptor are empty when the class is not enclosed by a method or
+// constructor, e.g. when it is declared in an initializer.
+type EnclosingMethodRef struct {
+	Class      string
+	Name       string
+	Descriptor string
+}
+
+// InnerClassEntries returns every entry of the InnerClasses attribute,
+// resolved, or nil if the class has none.
+func (c *Class) InnerClassEntries() ([]InnerClassInfo, error) {
+	value, err := attributeValue(c.Attributes, "InnerClasses")
+	attr, ok := value.(*InnerClassesAttribute)
+	if !ok {
+		return nil, err
+	}
+
+	entries := make([]InnerClassInfo, len(attr.Classes))
+	for i, inner := range attr.Classes {
+		info := &entries[i]
+		info.AccessFlags = inner.InnerClassAccessFlags
+		if info.Name, err = c.ConstantPool.className(inner.InnerClassInfoIndex); err != nil {
+			return nil, err
+		}
+		if inner.OuterClassInfoIndex != 0 {
+			if info.OuterName, err = c.ConstantPool.className(inner.OuterClassInfoIndex); err != nil {
+				return nil, err
+			}
+		}
+		if info.SimpleName, err = c.ConstantPool.optionalUtf8(inner.InnerNameIndex); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// InnerClasses returns the member classes declared by the class, that is the
+// InnerClasses entries whose outer class is this class. Local and anonymous
+// classes are not members; they name their declaring method through the
+// EnclosingMethod attribute instead.
+func (c *Class) InnerClasses() ([]InnerClassInfo, error) {
+	name, err := c.ConstantPool.className(c.ThisClass)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := c.InnerClassEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	var members []InnerClassInfo
+	for _, entry := range entries {
+		if entry.OuterName == name {
+			members = append(members, entry)
+		}
+	}
+	return members, nil
+}
+
+// ownInnerClassEntry returns the InnerClasses entry describing the class
+// itself, or nil if the class is not nested.
+func (c *Class) ownInnerClassEntry() (*InnerClassInfo, error) {
+	name, err := c.ConstantPool.className(c.ThisClass)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := c.InnerClassEntries()
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		if entries[i].Name == name {
+			return &entries[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// IsNested reports whether the class is a member, local or anonymous class.
+func (c *Class) IsNested() (bool, error) {
+	entry, err := c.ownInnerClassEntry()
+	return entry != nil, err
+}
+
+// IsAnonymous reports whether the class is an anonymous class.
+func (c *Class) IsAnonymous() (bool, error) {
+	entry, err := c.ownInnerClassEntry()
+	return entry != nil && entry.SimpleName == "", err
+}
+
+// IsLocal reports whether the class is a local class, that is a named class
+// declared inside a method, constructor or initializer.
+func (c *Class) IsLocal() (bool, error) {
+	entry, err := c.ownInnerClassEntry()
+	return entry != nil && entry.OuterName == "" && entry.SimpleName != "", err
+}
+
+// OuterClass returns the internal name of the class that immediately encloses
+// this class, or an empty string for a top-level class. Member classes are
+// resolved through InnerClasses and local and anonymous classes through
+// EnclosingMethod.
+func (c *Class) OuterClass() (string, error) {
+	entry, err := c.ownInnerClassEntry()
+	if err != nil {
+		return "", err
+	}
+	if entry != nil && entry.OuterName != "" {
+		return entry.OuterName, nil
+	}
+	attr, err := c.EnclosingMethod()
+	if attr == nil {
+		return "", err
+	}
+	return c.ConstantPool.className(attr.ClassIndex)
+}
+
+// EnclosingMethodRef resolves the EnclosingMethod attribute, tying a local or
+// anonymous class such as Foo$1 to the method that declares it. It returns nil
+// if the class has no EnclosingMethod attribute.
+func (c *Class) EnclosingMethodRef() (*EnclosingMethodRef, error) {
+	attr, err := c.EnclosingMethod()
+	if attr == nil {
+		return nil, err
+	}
+
+	ref := &EnclosingMethodRef{}
+	if ref.Class, err = c.ConstantPool.className(attr.ClassIndex); err != nil {
+		return nil, err
+	}
+	if attr.MethodIndex != 0 {
+		if ref.Name, ref.Descriptor, err = c.ConstantPool.nameAndType(attr.MethodIndex); err != nil {
+			return nil, err
+		}
+	}
+	return ref, nil
+}
+
+// SimpleName returns the name of the class as written in source: the inner
+// name of a nested class, an empty string for an anonymous class, or the
+// unqualified name of a top-level class.
+func (c *Class) SimpleName() (string, error) {
+	entry, err := c.ownInnerClassEntry()
+	if err != nil {
+		return "", err
+	}
+	if entry != nil {
+		return entry.SimpleName, nil
+	}
+
+	name, err := c.ConstantPool.className(c.ThisClass)
+	if err != nil {
+		return "", err
+	}
+	return name[strings.LastIndex(name, "/")+1:], nil
+}
@@ -0,0 +1,132 @@
+package class
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNestedClasses(t *testing.T) {
+	type want struct {
+		nested, anonymous, local bool
+		outer, simpleName        string
+		enclosing                *EnclosingMethodRef
+	}
+	tests := []struct {
+		name string
+		// setup adds the attributes of the class named name, given a
+		// function that adds Class constants
+		setup func(t *testing.T, c *Class, class func(string) uint16)
+		want  want
+	}{
+		{
+			name:  "com/example/TopLevel",
+			setup: func(t *testing.T, c *Class, class func(string) uint16) {},
+			want:  want{simpleName: "TopLevel"},
+		},
+		{
+			name: "Outer$Inner",
+			setup: func(t *testing.T, c *Class, class func(string) uint16) {
+				addClassAttribute(t, c, "InnerClasses", u2(1,
+					class("Outer$Inner"), class("Outer"), utf8Index(t, c, "Inner"), uint16(InnerClassStatic)))
+			},
+			want: want{nested: true, outer: "Outer", simpleName: "Inner"},
+		},
+		{
+			name: "Outer$1",
+			setup: func(t *testing.T, c *Class, class func(string) uint16) {
+				addClassAttribute(t, c, "InnerClasses", u2(1, class("Outer$1"), 0, 0, 0))
+				run, err := c.ConstantPool.AddNameAndType("run", "()V")
+				if err != nil {
+					t.Fatal(err)
+				}
+				addClassAttribute(t, c, "EnclosingMethod", u2(class("Outer"), run))
+			},
+			want: want{nested: true, anonymous: true, outer: "Outer",
+				enclosing: &EnclosingMethodRef{Class: "Outer", Name: "run", Descriptor: "()V"}},
+		},
+		{
+			// A local class declared in an initializer has no enclosing method
+			name: "Outer$1Local",
+			setup: func(t *testing.T, c *Class, class func(string) uint16) {
+				addClassAttribute(t, c, "InnerClasses", u2(1, class("Outer$1Local"), 0, utf8Index(t, c, "Local"), 0))
+				addClassAttribute(t, c, "EnclosingMethod", u2(class("Outer"), 0))
+			},
+			want: want{nested: true, local: true, outer: "Outer", simpleName: "Local",
+				enclosing: &EnclosingMethodRef{Class: "Outer"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := parseTestClass(t)
+			class := func(name string) uint16 {
+				index, err := c.ConstantPool.AddClass(name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return index
+			}
+			c.ThisClass = class(test.name)
+			test.setup(t, c, class)
+
+			var got want
+			var err error
+			if got.nested, err = c.IsNested(); err != nil {
+				t.Fatal(err)
+			}
+			if got.anonymous, err = c.IsAnonymous(); err != nil {
+				t.Fatal(err)
+			}
+			if got.local, err = c.IsLocal(); err != nil {
+				t.Fatal(err)
+			}
+			if got.outer, err = c.OuterClass(); err != nil {
+				t.Fatal(err)
+			}
+			if got.simpleName, err = c.SimpleName(); err != nil {
+				t.Fatal(err)
+			}
+			if got.enclosing, err = c.EnclosingMethodRef(); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestInnerClassesMembersOnly(t *testing.T) {
+	c := parseTestClass(t)
+	class := func(name string) uint16 {
+		index, err := c.ConstantPool.AddClass(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return index
+	}
+	// Test lists its member Test$Inner, its anonymous class Test$1 and the
+	// member Other$Nested of another class that it refers to
+	addClassAttribute(t, c, "InnerClasses", u2(3,
+		class("Test$Inner"), class("Test"), utf8Index(t, c, "Inner"), uint16(InnerClassPrivate),
+		class("Test$1"), 0, 0, 0,
+		class("Other$Nested"), class("Other"), utf8Index(t, c, "Nested"), 0))
+
+	entries, err := c.InnerClassEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("InnerClassEntries() = %+v, want 3 entries", entries)
+	}
+	members, err := c.InnerClasses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []InnerClassInfo{{Name: "Test$Inner", OuterName: "Test", SimpleName: "Inner", AccessFlags: InnerClassPrivate}}
+	if !reflect.DeepEqual(members, want) {
+		t.Errorf("InnerClasses() = %+v, want %+v", members, want)
+	}
+	if nested, err := c.IsNested(); err != nil || nested {
+		t.Errorf("IsNested() = %v, %v, want false for a class that only lists others", nested, err)
+	}
+}
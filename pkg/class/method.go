@@ -3,12 +3,14 @@ package class
 import (
 	"encoding/binary"
 	"fmt"
-	"os"
+	"io"
+	"lava-vm/pkg/descriptor"
+	"lava-vm/pkg/signature"
 	"strings"
 )
 
 type Method struct {
-	AccessFlags     uint16
+	AccessFlags     MethodAccessFlags
 	NameIndex       uint16
 	DescriptorIndex uint16
 	AttributesCount uint16
@@ -16,38 +18,110 @@ type Method struct {
 	constantPool    *ConstantPool
 }
 
+// Name returns the name of the method, or an empty string if the name index
+// does not point at a Utf8 constant.
+func (m *Method) Name() string {
+	return m.constantPool.GetConstantName(m.NameIndex)
+}
+
+// Descriptor returns the descriptor of the method, e.g. "([Ljava/lang/String;)V",
+// or an empty string if the descriptor index does not point at a Utf8 constant.
+func (m *Method) Descriptor() string {
+	return m.constantPool.GetConstantName(m.DescriptorIndex)
+}
+
 func (m *Method) GetCode() (*Code, error) {
-	for _, attr := range m.Attributes {
-		fmt.Printf("Getting attribute %+v\n", m.constantPool.GetConstantName(attr.AttributeNameIndex))
-		if m.constantPool.GetConstantName(attr.AttributeNameIndex) == "Code" {
-			return parseCodeAttribute(&attr)
-		}
+	attr := findAttribute(m.Attributes, "Code")
+	if attr == nil {
+		return nil, fmt.Errorf("Bytecode attribute not found")
+	}
+	value, err := attr.Decode()
+	if err != nil {
+		return nil, fmt.Errorf("decoding Code attribute: %w", err)
+	}
+	if code, ok := value.(*Code); ok {
+		return code, nil
 	}
 	return nil, fmt.Errorf("Bytecode attribute not found")
 }
 
-// Read a Method from the given file
-func readMethod(file *os.File, method *Method, cp *ConstantPool) error {
+// Attribute returns the first method attribute with the given name, or nil.
+func (m *Method) Attribute(name string) *Attribute {
+	return findAttribute(m.Attributes, name)
+}
+
+// Exceptions returns the Exceptions attribute of the method, or nil.
+func (m *Method) Exceptions() (*ExceptionsAttribute, error) {
+	value, err := attributeValue(m.Attributes, "Exceptions")
+	attr, _ := value.(*ExceptionsAttribute)
+	return attr, err
+}
+
+// MethodParameters returns the MethodParameters attribute of the method, or nil.
+func (m *Method) MethodParameters() (*MethodParametersAttribute, error) {
+	value, err := attributeValue(m.Attributes, "MethodParameters")
+	attr, _ := value.(*MethodParametersAttribute)
+	return attr, err
+}
+
+// Signature returns the Signature attribute of the method, or nil.
+func (m *Method) Signature() (*SignatureAttribute, error) {
+	value, err := attributeValue(m.Attributes, "Signature")
+	attr, _ := value.(*SignatureAttribute)
+	return attr, err
+}
+
+// GenericSignature parses the Signature attribute of the method. It returns
+// nil if the method has no Signature attribute.
+func (m *Method) GenericSignature() (*signature.MethodSignature, error) {
+	attr, err := m.Signature()
+	if attr == nil {
+		return nil, err
+	}
+	return signature.ParseMethod(attr.Signature)
+}
+
+// IsDeprecated reports whether the method has a Deprecated attribute.
+func (m *Method) IsDeprecated() bool {
+	return findAttribute(m.Attributes, "Deprecated") != nil
+}
+
+// IsSynthetic reports whether the method has a Synthetic attribute.
+func (m *Method) IsSynthetic() bool {
+	return findAttribute(m.Attributes, "Synthetic") != nil
+}
+
+// MethodDescriptor parses the descriptor of the method.
+func (m *Method) MethodDescriptor() (descriptor.MethodDescriptor, error) {
+	s, err := m.constantPool.utf8(m.DescriptorIndex)
+	if err != nil {
+		return descriptor.MethodDescriptor{}, err
+	}
+	return descriptor.ParseMethod(s)
+}
+
+// Read a Method from the given reader
+func readMethod(r io.Reader, method *Method, cp *ConstantPool, readAttr attributeReader) error {
 	method.constantPool = cp
-	if err := binary.Read(file, binary.BigEndian, &method.AccessFlags); err != nil {
+	if err := binary.Read(r, binary.BigEndian, &method.AccessFlags); err != nil {
 		return fmt.Errorf("reading access flags: %w", err)
 	}
 
-	if err := binary.Read(file, binary.BigEndian, &method.NameIndex); err != nil {
+	if err := binary.Read(r, binary.BigEndian, &method.NameIndex); err != nil {
 		return fmt.Errorf("reading name index: %w", err)
 	}
 
-	if err := binary.Read(file, binary.BigEndian, &method.DescriptorIndex); err != nil {
+	if err := binary.Read(r, binary.BigEndian, &method.DescriptorIndex); err != nil {
 		return fmt.Errorf("reading descriptor index: %w", err)
 	}
 
-	if err := binary.Read(file, binary.BigEndian, &method.AttributesCount); err != nil {
+	if err := binary.Read(r, binary.BigEndian, &method.AttributesCount); err != nil {
 		return fmt.Errorf("reading attributes count: %w", err)
 	}
 
 	method.Attributes = make([]Attribute, method.AttributesCount)
 	for i := range method.Attributes {
-		if err := readAttribute(file, &method.Attributes[i]); err != nil {
+		if err := readAttr(r, &method.Attributes[i], cp); err != nil {
 			return fmt.Errorf("reading attribute %d: %w", i, err)
 		}
 	}
@@ -57,7 +131,7 @@ func readMethod(file *os.File, method *Method, cp *ConstantPool) error {
 
 func (m Method) String() string {
 	var builder strings.Builder
-	fmt.Fprintf(&builder, "Access Flags: 0x%04X\n", m.AccessFlags)
+	fmt.Fprintf(&builder, "Access Flags: 0x%04X %s\n", uint16(m.AccessFlags), strings.Join(m.AccessFlags.Names(), ", "))
 	fmt.Fprintf(&builder, "Name Index: %d\n", m.NameIndex)
 	fmt.Fprintf(&builder, "Descriptor Index: %d\n", m.DescriptorIndex)
 	fmt.Fprintf(&builder, "Attributes Count: %d\n", m.AttributesCount)
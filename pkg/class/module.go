@@ -0,0 +1,413 @@
+package class
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+type ModuleRequires struct {
+	RequiresIndex        uint16
+	RequiresFlags        ModuleRequiresAccessFlags
+	RequiresVersionIndex uint16
+}
+
+type ModuleExports struct {
+	ExportsIndex   uint16
+	ExportsFlags   ModuleExportsAccessFlags
+	ExportsToIndex []uint16
+}
+
+type ModuleOpens struct {
+	OpensIndex   uint16
+	OpensFlags   ModuleExportsAccessFlags
+	OpensToIndex []uint16
+}
+
+type ModuleProvides struct {
+	ProvidesIndex     uint16
+	ProvidesWithIndex []uint16
+}
+
+// ModuleAttribute describes the module declared by a module-info class.
+// See https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.25
+type ModuleAttribute struct {
+	ModuleNameIndex    uint16
+	ModuleFlags        ModuleAccessFlags
+	ModuleVersionIndex uint16
+	Requires           []ModuleRequires
+	Exports            []ModuleExports
+	Opens              []ModuleOpens
+	UsesIndex          []uint16
+	Provides           []ModuleProvides
+}
+
+func decodeModuleAttribute(info []byte, cp *ConstantPool) (interface{}, error) {
+	reader := bytes.NewReader(info)
+	attr := &ModuleAttribute{}
+	if err := binary.Read(reader, binary.BigEndian, &attr.ModuleNameIndex); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(reader, binary.BigEndian, &attr.ModuleFlags); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(reader, binary.BigEndian, &attr.ModuleVersionIndex); err != nil {
+		return nil, err
+	}
+
+	var count uint16
+	if err := binary.Read(reader, binary.BigEndian, &count); err != nil {
+		return nil, err
+	}
+	if err := checkCount(reader, uint32(count), 6); err != nil {
+		return nil, err
+	}
+	attr.Requires = make([]ModuleRequires, count)
+	if err := binary.Read(reader, binary.BigEndian, attr.Requires); err != nil {
+		return nil, fmt.Errorf("reading requires: %w", err)
+	}
+
+	if err := binary.Read(reader, binary.BigEndian, &count); err != nil {
+		return nil, err
+	}
+	if err := checkCount(reader, uint32(count), 6); err != nil {
+		return nil, err
+	}
+	attr.Exports = make([]ModuleExports, count)
+	for i := range attr.Exports {
+		exports := &attr.Exports[i]
+		if err := binary.Read(reader, binary.BigEndian, &exports.ExportsIndex); err != nil {
+			return nil, err
+		}
+		if err := binary.Read(reader, binary.BigEndian, &exports.ExportsFlags); err != nil {
+			return nil, err
+		}
+		to, err := readUint16Table(reader)
+		if err != nil {
+			return nil, fmt.Errorf("reading exports %d: %w", i, err)
+		}
+		exports.ExportsToIndex = to
+	}
+
+	if err := binary.Read(reader, binary.BigEndian, &count); err != nil {
+		return nil, err
+	}
+	if err := checkCount(reader, uint32(count), 6); err != nil {
+		return nil, err
+	}
+	attr.Opens = make([]ModuleOpens, count)
+	for i := range attr.Opens {
+		opens := &attr.Opens[i]
+		if err := binary.Read(reader, binary.BigEndian, &opens.OpensIndex); err != nil {
+			return nil, err
+		}
+		if err := binary.Read(reader, binary.BigEndian, &opens.OpensFlags); err != nil {
+			return nil, err
+		}
+		to, err := readUint16Table(reader)
+		if err != nil {
+			return nil, fmt.Errorf("reading opens %d: %w", i, err)
+		}
+		opens.OpensToIndex = to
+	}
+
+	uses, err := readUint16Table(reader)
+	if err != nil {
+		return nil, fmt.Errorf("reading uses: %w", err)
+	}
+	attr.UsesIndex = uses
+
+	if err := binary.Read(reader, binary.BigEndian, &count); err != nil {
+		return nil, err
+	}
+	if err := checkCount(reader, uint32(count), 4); err != nil {
+		return nil, err
+	}
+	attr.Provides = make([]ModuleProvides, count)
+	for i := range attr.Provides {
+		provides := &attr.Provides[i]
+		if err := binary.Read(reader, binary.BigEndian, &provides.ProvidesIndex); err != nil {
+			return nil, err
+		}
+		with, err := readUint16Table(reader)
+		if err != nil {
+			return nil, fmt.Errorf("reading provides %d: %w", i, err)
+		}
+		provides.ProvidesWithIndex = with
+	}
+
+	return attr, checkFullyRead(reader)
+}
+
+func (a *ModuleAttribute) encode(w *bytes.Buffer) error {
+	put(w, a.ModuleNameIndex)
+	put(w, a.ModuleFlags)
+	put(w, a.ModuleVersionIndex)
+
+	if err := putCount(w, len(a.Requires)); err != nil {
+		return fmt.Errorf("writing requires: %w", err)
+	}
+	put(w, a.Requires)
+
+	if err := putCount(w, len(a.Exports)); err != nil {
+		return fmt.Errorf("writing exports: %w", err)
+	}
+	for i, exports := range a.Exports {
+		put(w, exports.ExportsIndex)
+		put(w, exports.ExportsFlags)
+		if err := putUint16Table(w, exports.ExportsToIndex); err != nil {
+			return fmt.Errorf("writing exports %d: %w", i, err)
+		}
+	}
+
+	if err := putCount(w, len(a.Opens)); err != nil {
+		return fmt.Errorf("writing opens: %w", err)
+	}
+	for i, opens := range a.Opens {
+		put(w, opens.OpensIndex)
+		put(w, opens.OpensFlags)
+		if err := putUint16Table(w, opens.OpensToIndex); err != nil {
+			return fmt.Errorf("writing opens %d: %w", i, err)
+		}
+	}
+
+	if err := putUint16Table(w, a.UsesIndex); err != nil {
+		return fmt.Errorf("writing uses: %w", err)
+	}
+
+	if err := putCount(w, len(a.Provides)); err != nil {
+		return fmt.Errorf("writing provides: %w", err)
+	}
+	for i, provides := range a.Provides {
+		put(w, provides.ProvidesIndex)
+		if err := putUint16Table(w, provides.ProvidesWithIndex); err != nil {
+			return fmt.Errorf("writing provides %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// ModulePackagesAttribute lists every package of a module, including those
+// that are neither exported nor opened. Each entry points to a Package constant.
+// See https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.26
+type ModulePackagesAttribute struct {
+	PackageIndex []uint16
+}
+
+func decodeModulePackagesAttribute(info []byte, cp *ConstantPool) (interface{}, error) {
+	reader := bytes.NewReader(info)
+	packages, err := readUint16Table(reader)
+	if err != nil {
+		return nil, err
+	}
+	return &ModulePackagesAttribute{PackageIndex: packages}, checkFullyRead(reader)
+}
+
+func (a *ModulePackagesAttribute) encode(w *bytes.Buffer) error {
+	return putUint16Table(w, a.PackageIndex)
+}
+
+// ModuleMainClassAttribute names the main class of a module.
+// See https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.27
+type ModuleMainClassAttribute struct {
+	MainClassIndex uint16
+}
+
+func decodeModuleMainClassAttribute(info []byte, cp *ConstantPool) (interface{}, error) {
+	reader := bytes.NewReader(info)
+	attr := &ModuleMainClassAttribute{}
+	if err := binary.Read(reader, binary.BigEndian, &attr.MainClassIndex); err != nil {
+		return nil, err
+	}
+	return attr, checkFullyRead(reader)
+}
+
+func (a *ModuleMainClassAttribute) encode(w *bytes.Buffer) error {
+	put(w, a.MainClassIndex)
+	return nil
+}
+
+// ModuleRequire is a resolved requires directive. Version is empty when the
+// class file does not record the version of the required module.
+type ModuleRequire struct {
+	Module  string
+	Flags   ModuleRequiresAccessFlags
+	Version string
+}
+
+// ModuleExport is a resolved exports or opens directive. To is empty for an
+// unqualified export.
+type ModuleExport struct {
+	Package string
+	Flags   ModuleExportsAccessFlags
+	To      []string
+}
+
+// ModuleProvide is a resolved provides directive.
+type ModuleProvide struct {
+	Service string
+	With    []string
+}
+
+// ModuleDescriptor is the resolved form of the Module, ModulePackages and
+// ModuleMainClass attributes of a module-info class. Package and class names
+// are in internal form, e.g. "java/util".
+type ModuleDescriptor struct {
+	Name      string
+	Flags     ModuleAccessFlags
+	Version   string
+	Requires  []ModuleRequire
+	Exports   []ModuleExport
+	Opens     []ModuleExport
+	Uses      []string
+	Provides  []ModuleProvide
+	Packages  []string
+	MainClass string
+}
+
+// Module returns the Module attribute of the class, or nil.
+func (c *Class) Module() (*ModuleAttribute, error) {
+	value, err := attributeValue(c.Attributes, "Module")
+	attr, _ := value.(*ModuleAttribute)
+	return attr, err
+}
+
+// ModuleDescriptor resolves the module declared by a module-info class. It
+// returns an error if the class has no Module attribute.
+func (c *Class) ModuleDescriptor() (*ModuleDescriptor, error) {
+	attr, err := c.Module()
+	if err != nil {
+		return nil, err
+	}
+	if attr == nil {
+		return nil, fmt.Errorf("class has no Module attribute")
+	}
+
+	cp := &c.ConstantPool
+	module := &ModuleDescriptor{Flags: attr.ModuleFlags}
+	if module.Name, err = cp.moduleName(attr.ModuleNameIndex); err != nil {
+		return nil, fmt.Errorf("resolving module name: %w", err)
+	}
+	if module.Version, err = cp.optionalUtf8(attr.ModuleVersionIndex); err != nil {
+		return nil, fmt.Errorf("resolving module version: %w", err)
+	}
+
+	module.Requires = make([]ModuleRequire, len(attr.Requires))
+	for i, requires := range attr.Requires {
+		require := &module.Requires[i]
+		require.Flags = requires.RequiresFlags
+		if require.Module, err = cp.moduleName(requires.RequiresIndex); err != nil {
+			return nil, fmt.Errorf("resolving requires %d: %w", i, err)
+		}
+		if require.Version, err = cp.optionalUtf8(requires.RequiresVersionIndex); err != nil {
+			return nil, fmt.Errorf("resolving requires %d version: %w", i, err)
+		}
+	}
+
+	module.Exports = make([]ModuleExport, len(attr.Exports))
+	for i, exports := range attr.Exports {
+		if module.Exports[i], err = cp.moduleExport(exports.ExportsIndex, exports.ExportsFlags, exports.ExportsToIndex); err != nil {
+			return nil, fmt.Errorf("resolving exports %d: %w", i, err)
+		}
+	}
+
+	module.Opens = make([]ModuleExport, len(attr.Opens))
+	for i, opens := range attr.Opens {
+		if module.Opens[i], err = cp.moduleExport(opens.OpensIndex, opens.OpensFlags, opens.OpensToIndex); err != nil {
+			return nil, fmt.Errorf("resolving opens %d: %w", i, err)
+		}
+	}
+
+	if module.Uses, err = cp.classNames(attr.UsesIndex); err != nil {
+		return nil, fmt.Errorf("resolving uses: %w", err)
+	}
+
+	module.Provides = make([]ModuleProvide, len(attr.Provides))
+	for i, provides := range attr.Provides {
+		provide := &module.Provides[i]
+		if provide.Service, err = cp.className(provides.ProvidesIndex); err != nil {
+			return nil, fmt.Errorf("resolving provides %d: %w", i, err)
+		}
+		if provide.With, err = cp.classNames(provides.ProvidesWithIndex); err != nil {
+			return nil, fmt.Errorf("resolving provides %d: %w", i, err)
+		}
+	}
+
+	value, err := attributeValue(c.Attributes, "ModulePackages")
+	if err != nil {
+		return nil, err
+	}
+	if packages, ok := value.(*ModulePackagesAttribute); ok {
+		module.Packages = make([]string, len(packages.PackageIndex))
+		for i, index := range packages.PackageIndex {
+			if module.Packages[i], err = cp.packageName(index); err != nil {
+				return nil, fmt.Errorf("resolving packages: %w", err)
+			}
+		}
+	}
+
+	if value, err = attributeValue(c.Attributes, "ModuleMainClass"); err != nil {
+		return nil, err
+	}
+	if mainClass, ok := value.(*ModuleMainClassAttribute); ok {
+		if module.MainClass, err = cp.className(mainClass.MainClassIndex); err != nil {
+			return nil, fmt.Errorf("resolving main class: %w", err)
+		}
+	}
+
+	return module, nil
+}
+
+// moduleName returns the name held by the Module entry at index.
+func (cp *ConstantPool) moduleName(index uint16) (string, error) {
+	entry, err := cp.entryWithTag(index, TagModule)
+	if err != nil {
+		return "", err
+	}
+	return cp.utf8(entry.Value.(*ConstantModuleValue).NameIndex)
+}
+
+// packageName returns the name held by the Package entry at index.
+func (cp *ConstantPool) packageName(index uint16) (string, error) {
+	entry, err := cp.entryWithTag(index, TagPackage)
+	if err != nil {
+		return "", err
+	}
+	return cp.utf8(entry.Value.(*ConstantPackageValue).NameIndex)
+}
+
+// optionalUtf8 returns the string held by the Utf8 entry at index, or an
+// empty string if index is zero.
+func (cp *ConstantPool) optionalUtf8(index uint16) (string, error) {
+	if index == 0 {
+		return "", nil
+	}
+	return cp.utf8(index)
+}
+
+// classNames returns the internal names held by the Class entries at indexes.
+func (cp *ConstantPool) classNames(indexes []uint16) ([]string, error) {
+	names := make([]string, len(indexes))
+	for i, index := range indexes {
+		name, err := cp.className(index)
+		if err != nil {
+			return nil, err
+		}
+		names[i] = name
+	}
+	return names, nil
+}
+
+func (cp *ConstantPool) moduleExport(packageIndex uint16, flags ModuleExportsAccessFlags, toIndexes []uint16) (ModuleExport, error) {
+	export := ModuleExport{Flags: flags, To: make([]string, len(toIndexes))}
+	var err error
+	if export.Package, err = cp.packageName(packageIndex); err != nil {
+		return ModuleExport{}, err
+	}
+	for i, index := range toIndexes {
+		if export.To[i], err = cp.moduleName(index); err != nil {
+			return ModuleExport{}, err
+		}
+	}
+	return export, nil
+}
@@ -0,0 +1,80 @@
+package class
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestModuleDescriptor(t *testing.T) {
+	c := parseTestClass(t)
+	cp := &c.ConstantPool
+	must := func(index uint16, err error) uint16 {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return index
+	}
+	name, base, friend := must(cp.AddModule("com.example")), must(cp.AddModule("java.base")), must(cp.AddModule("friend"))
+	api, impl := must(cp.AddPackage("com/example/api")), must(cp.AddPackage("com/example/impl"))
+	service, serviceImpl := must(cp.AddClass("com/example/Service")), must(cp.AddClass("com/example/impl/ServiceImpl"))
+	main := must(cp.AddClass("com/example/Main"))
+	version, baseVersion := utf8Index(t, c, "1.0"), utf8Index(t, c, "17")
+
+	addClassAttribute(t, c, "Module", u2(name, uint16(ModuleOpen), version,
+		1, base, uint16(RequiresMandated), baseVersion,
+		2, api, 0, 0, impl, 0, 1, friend,
+		1, impl, 0, 0,
+		1, service,
+		1, service, 1, serviceImpl))
+	addClassAttribute(t, c, "ModulePackages", u2(2, api, impl))
+	addClassAttribute(t, c, "ModuleMainClass", u2(main))
+
+	// Write and parse the class again, so that the Module and Package constants
+	// go through the parser too
+	data, err := c.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, err = ParseBytes(data); err != nil {
+		t.Fatal(err)
+	}
+
+	module, err := c.ModuleDescriptor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &ModuleDescriptor{
+		Name:     "com.example",
+		Flags:    ModuleOpen,
+		Version:  "1.0",
+		Requires: []ModuleRequire{{Module: "java.base", Flags: RequiresMandated, Version: "17"}},
+		Exports: []ModuleExport{
+			{Package: "com/example/api", To: []string{}},
+			{Package: "com/example/impl", To: []string{"friend"}},
+		},
+		Opens:     []ModuleExport{{Package: "com/example/impl", To: []string{}}},
+		Uses:      []string{"com/example/Service"},
+		Provides:  []ModuleProvide{{Service: "com/example/Service", With: []string{"com/example/impl/ServiceImpl"}}},
+		Packages:  []string{"com/example/api", "com/example/impl"},
+		MainClass: "com/example/Main",
+	}
+	if !reflect.DeepEqual(module, want) {
+		t.Errorf("ModuleDescriptor() = %+v, want %+v", module, want)
+	}
+}
+
+func TestModuleDescriptorErrors(t *testing.T) {
+	c := parseTestClass(t)
+	if _, err := c.ModuleDescriptor(); err == nil || !strings.Contains(err.Error(), "no Module attribute") {
+		t.Errorf("ModuleDescriptor() = %v, want an error for a class without a Module attribute", err)
+	}
+
+	// The module name must be a Module constant, not the Utf8 holding it
+	moduleName := utf8Index(t, c, "com.example")
+	addClassAttribute(t, c, "Module", u2(moduleName, 0, 0, 0, 0, 0, 0, 0))
+	if _, err := c.ModuleDescriptor(); err == nil || !strings.Contains(err.Error(), "resolving module name") {
+		t.Errorf("ModuleDescriptor() = %v, want an error resolving the module name", err)
+	}
+}
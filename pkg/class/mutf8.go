@@ -0,0 +1,115 @@
+package class
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Class files store strings in "modified UTF-8" (JVMS 4.4.7). It differs from
+// standard UTF-8 in two ways: the null character is encoded with the two bytes
+// C0 80 so that no encoded string contains a zero byte, and supplementary
+// characters are encoded as a UTF-16 surrogate pair with each surrogate taking
+// three bytes (as in CESU-8). Four byte forms never appear.
+
+var errTruncatedModifiedUTF8 = errors.New("truncated modified UTF-8 sequence")
+
+// DecodeModifiedUTF8ToUTF16 decodes modified UTF-8 bytes into the UTF-16 code
+// units they encode, which is how the JVM represents a java.lang.String.
+// Unpaired surrogates are preserved as is.
+func DecodeModifiedUTF8ToUTF16(b []byte) ([]uint16, error) {
+	units := make([]uint16, 0, len(b))
+	for i := 0; i < len(b); {
+		c := b[i]
+		switch {
+		case c == 0:
+			return nil, fmt.Errorf("invalid zero byte at offset %d in modified UTF-8", i)
+		case c < 0x80:
+			units = append(units, uint16(c))
+			i++
+		case c&0xE0 == 0xC0:
+			if i+1 >= len(b) {
+				return nil, errTruncatedModifiedUTF8
+			}
+			if b[i+1]&0xC0 != 0x80 {
+				return nil, fmt.Errorf("invalid continuation byte at offset %d in modified UTF-8", i+1)
+			}
+			unit := uint16(c&0x1F)<<6 | uint16(b[i+1]&0x3F)
+			// Only U+0000 may use the overlong two byte form.
+			if unit != 0 && unit < 0x80 {
+				return nil, fmt.Errorf("overlong encoding at offset %d in modified UTF-8", i)
+			}
+			units = append(units, unit)
+			i += 2
+		case c&0xF0 == 0xE0:
+			if i+2 >= len(b) {
+				return nil, errTruncatedModifiedUTF8
+			}
+			if b[i+1]&0xC0 != 0x80 || b[i+2]&0xC0 != 0x80 {
+				return nil, fmt.Errorf("invalid continuation byte after offset %d in modified UTF-8", i)
+			}
+			unit := uint16(c&0x0F)<<12 | uint16(b[i+1]&0x3F)<<6 | uint16(b[i+2]&0x3F)
+			if unit < 0x800 {
+				return nil, fmt.Errorf("overlong encoding at offset %d in modified UTF-8", i)
+			}
+			units = append(units, unit)
+			i += 3
+		default:
+			return nil, fmt.Errorf("invalid byte 0x%02X at offset %d in modified UTF-8", c, i)
+		}
+	}
+	return units, nil
+}
+
+// DecodeModifiedUTF8 decodes modified UTF-8 bytes into a Go string. Surrogate
+// pairs are combined into a single rune and unpaired surrogates are replaced
+// with utf8.RuneError.
+func DecodeModifiedUTF8(b []byte) (string, error) {
+	if isASCII(b) {
+		return string(b), nil
+	}
+	units, err := DecodeModifiedUTF8ToUTF16(b)
+	if err != nil {
+		return "", err
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+// EncodeModifiedUTF8FromUTF16 encodes UTF-16 code units as modified UTF-8.
+func EncodeModifiedUTF8FromUTF16(units []uint16) []byte {
+	b := make([]byte, 0, len(units))
+	for _, unit := range units {
+		switch {
+		case unit != 0 && unit < 0x80:
+			b = append(b, byte(unit))
+		case unit < 0x800:
+			b = append(b, 0xC0|byte(unit>>6), 0x80|byte(unit&0x3F))
+		default:
+			b = append(b, 0xE0|byte(unit>>12), 0x80|byte(unit>>6&0x3F), 0x80|byte(unit&0x3F))
+		}
+	}
+	return b
+}
+
+// EncodeModifiedUTF8 encodes a Go string as modified UTF-8. Invalid UTF-8 in s
+// is encoded as utf8.RuneError.
+func EncodeModifiedUTF8(s string) []byte {
+	for i := 0; i < len(s); i++ {
+		if s[i] == 0 || s[i] >= utf8.RuneSelf {
+			return EncodeModifiedUTF8FromUTF16(utf16.Encode([]rune(s)))
+		}
+	}
+	return []byte(s)
+}
+
+// isASCII reports whether b consists only of bytes that encode the same way in
+// standard and modified UTF-8.
+func isASCII(b []byte) bool {
+	for _, c := range b {
+		if c == 0 || c >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
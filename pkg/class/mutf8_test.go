@@ -0,0 +1,82 @@
+package class
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestModifiedUTF8RoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		encoded []byte
+	}{
+		{"empty", "", []byte{}},
+		{"ASCII", "Hello", []byte("Hello")},
+		{"embedded NUL", "a\x00b", []byte{'a', 0xC0, 0x80, 'b'}},
+		{"two bytes", "é", []byte{0xC3, 0xA9}},
+		{"three bytes", "€", []byte{0xE2, 0x82, 0xAC}},
+		// U+1F600 is the surrogate pair D83D DE00, three bytes each
+		{"supplementary", "😀", []byte{0xED, 0xA0, 0xBD, 0xED, 0xB8, 0x80}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			encoded := EncodeModifiedUTF8(test.s)
+			if !bytes.Equal(encoded, test.encoded) {
+				t.Errorf("EncodeModifiedUTF8(%q) = % X, want % X", test.s, encoded, test.encoded)
+			}
+			decoded, err := DecodeModifiedUTF8(test.encoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if decoded != test.s {
+				t.Errorf("DecodeModifiedUTF8(% X) = %q, want %q", test.encoded, decoded, test.s)
+			}
+		})
+	}
+}
+
+func TestModifiedUTF8UnpairedSurrogate(t *testing.T) {
+	encoded := []byte{'a', 0xED, 0xA0, 0xBD}
+	units, err := DecodeModifiedUTF8ToUTF16(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []uint16{'a', 0xD83D}; !reflect.DeepEqual(units, want) {
+		t.Errorf("DecodeModifiedUTF8ToUTF16() = %X, want %X", units, want)
+	}
+	if got := EncodeModifiedUTF8FromUTF16(units); !bytes.Equal(got, encoded) {
+		t.Errorf("EncodeModifiedUTF8FromUTF16() = % X, want % X", got, encoded)
+	}
+	if s, err := DecodeModifiedUTF8(encoded); err != nil || s != "a�" {
+		t.Errorf("DecodeModifiedUTF8() = %q, %v, want %q", s, err, "a�")
+	}
+}
+
+func TestDecodeModifiedUTF8Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded []byte
+		want    string
+	}{
+		{"zero byte", []byte{'a', 0x00}, "invalid zero byte at offset 1"},
+		{"four byte standard UTF-8", []byte{0xF0, 0x9F, 0x98, 0x80}, "invalid byte 0xF0 at offset 0"},
+		{"truncated two bytes", []byte{'a', 0xC3}, "truncated"},
+		{"truncated three bytes", []byte{0xE2, 0x82}, "truncated"},
+		{"truncated surrogate pair", []byte{0xED, 0xA0, 0xBD, 0xED, 0xB8}, "truncated"},
+		{"bad continuation", []byte{0xC3, 'A'}, "invalid continuation byte at offset 1"},
+		{"overlong two bytes", []byte{0xC1, 0x81}, "overlong encoding at offset 0"},
+		{"overlong three bytes", []byte{0xE0, 0x81, 0x81}, "overlong encoding at offset 0"},
+		{"lone continuation byte", []byte{0x80}, "invalid byte 0x80"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := DecodeModifiedUTF8(test.encoded)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("DecodeModifiedUTF8(% X) = %v, want an error containing %q", test.encoded, err, test.want)
+			}
+		})
+	}
+}
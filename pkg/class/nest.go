@@ -0,0 +1,104 @@
+package class
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// NestHostAttribute names the nest host of a class that is a nest member.
+// See https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.28
+type NestHostAttribute struct {
+	HostClassIndex uint16
+}
+
+func decodeNestHostAttribute(info []byte, cp *ConstantPool) (interface{}, error) {
+	reader := bytes.NewReader(info)
+	attr := &NestHostAttribute{}
+	if err := binary.Read(reader, binary.BigEndian, &attr.HostClassIndex); err != nil {
+		return nil, err
+	}
+	return attr, checkFullyRead(reader)
+}
+
+func (a *NestHostAttribute) encode(w *bytes.Buffer) error {
+	put(w, a.HostClassIndex)
+	return nil
+}
+
+// NestMembersAttribute lists the classes that a nest host authorizes as
+// members of its nest.
+// See https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.29
+type NestMembersAttribute struct {
+	Classes []uint16
+}
+
+func decodeNestMembersAttribute(info []byte, cp *ConstantPool) (interface{}, error) {
+	reader := bytes.NewReader(info)
+	classes, err := readUint16Table(reader)
+	if err != nil {
+		return nil, err
+	}
+	return &NestMembersAttribute{Classes: classes}, checkFullyRead(reader)
+}
+
+func (a *NestMembersAttribute) encode(w *bytes.Buffer) error {
+	return putUint16Table(w, a.Classes)
+}
+
+// PermittedSubclassesAttribute lists the classes and interfaces that may
+// directly extend or implement a sealed class or interface.
+// See https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.31
+type PermittedSubclassesAttribute struct {
+	Classes []uint16
+}
+
+func decodePermittedSubclassesAttribute(info []byte, cp *ConstantPool) (interface{}, error) {
+	reader := bytes.NewReader(info)
+	classes, err := readUint16Table(reader)
+	if err != nil {
+		return nil, err
+	}
+	return &PermittedSubclassesAttribute{Classes: classes}, checkFullyRead(reader)
+}
+
+func (a *PermittedSubclassesAttribute) encode(w *bytes.Buffer) error {
+	return putUint16Table(w, a.Classes)
+}
+
+// NestHost returns the internal name of the nest host of the class. A class
+// without a NestHost attribute is the host of its own nest.
+func (c *Class) NestHost() (string, error) {
+	value, err := attributeValue(c.Attributes, "NestHost")
+	if err != nil {
+		return "", err
+	}
+	if attr, ok := value.(*NestHostAttribute); ok {
+		return c.ConstantPool.className(attr.HostClassIndex)
+	}
+	return c.ConstantPool.className(c.ThisClass)
+}
+
+// NestMembers returns the internal names of the members of the nest hosted by
+// the class, or nil if the class has no NestMembers attribute.
+func (c *Class) NestMembers() ([]string, error) {
+	value, err := attributeValue(c.Attributes, "NestMembers")
+	if attr, ok := value.(*NestMembersAttribute); ok {
+		return c.ConstantPool.classNames(attr.Classes)
+	}
+	return nil, err
+}
+
+// IsSealed reports whether the class has a PermittedSubclasses attribute.
+func (c *Class) IsSealed() bool {
+	return findAttribute(c.Attributes, "PermittedSubclasses") != nil
+}
+
+// PermittedSubclasses returns the internal names of the permitted direct
+// subclasses of a sealed class, or nil if the class is not sealed.
+func (c *Class) PermittedSubclasses() ([]string, error) {
+	value, err := attributeValue(c.Attributes, "PermittedSubclasses")
+	if attr, ok := value.(*PermittedSubclassesAttribute); ok {
+		return c.ConstantPool.classNames(attr.Classes)
+	}
+	return nil, err
+}
@@ -0,0 +1,51 @@
+package class
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNestAndSealedClasses(t *testing.T) {
+	c := parseTestClass(t)
+	if host, err := c.NestHost(); err != nil || host != "Test" {
+		t.Errorf("NestHost() = %q, %v without the attribute, want the class itself", host, err)
+	}
+	if c.IsSealed() {
+		t.Error("IsSealed() = true without PermittedSubclasses")
+	}
+
+	must := func(index uint16, err error) uint16 {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return index
+	}
+	outer, inner := must(c.ConstantPool.AddClass("Outer")), must(c.ConstantPool.AddClass("Test$Inner"))
+	sub := must(c.ConstantPool.AddClass("Sub"))
+	addClassAttribute(t, c, "NestHost", u2(outer))
+	addClassAttribute(t, c, "NestMembers", u2(2, inner, sub))
+	addClassAttribute(t, c, "PermittedSubclasses", u2(1, sub))
+
+	if host, err := c.NestHost(); err != nil || host != "Outer" {
+		t.Errorf("NestHost() = %q, %v, want Outer", host, err)
+	}
+	if members, err := c.NestMembers(); err != nil || !reflect.DeepEqual(members, []string{"Test$Inner", "Sub"}) {
+		t.Errorf("NestMembers() = %v, %v, want [Test$Inner Sub]", members, err)
+	}
+	if !c.IsSealed() {
+		t.Error("IsSealed() = false, want true")
+	}
+	if permitted, err := c.PermittedSubclasses(); err != nil || !reflect.DeepEqual(permitted, []string{"Sub"}) {
+		t.Errorf("PermittedSubclasses() = %v, %v, want [Sub]", permitted, err)
+	}
+}
+
+func TestNestHostBadIndex(t *testing.T) {
+	c := parseTestClass(t)
+	// Index 8 is the Utf8 "Yes", not a Class constant
+	addClassAttribute(t, c, "NestHost", u2(8))
+	if host, err := c.NestHost(); err == nil {
+		t.Errorf("NestHost() = %q, want an error for a NestHost that is not a Class", host)
+	}
+}
@@ -0,0 +1,140 @@
+package class_test
+
+import (
+	"fmt"
+	"lava-vm/pkg/assembler"
+	"lava-vm/pkg/class"
+	"strings"
+	"testing"
+)
+
+// benchmarkMethods are the method bodies the corpus classes are made of, in
+// the shapes javac gives them: a loop, a switch and an exception handler,
+// each with the stack map frames, line numbers and local variables that
+// come with them.
+var benchmarkMethods = []string{`
+.method public static count%d(I)I
+    .line 10
+    iconst_0
+    istore_1
+Loop:
+    .frame append int
+    .line 11
+    iload_1
+    iload_0
+    if_icmpge Done
+    iinc 1 1
+    goto Loop
+Done:
+    .frame same
+    .line 13
+    iload_1
+    ireturn
+    .var 1 is i I from Loop to Done
+.end method
+`, `
+.method public static choose%d(I)I
+    .line 20
+    iload_0
+    tableswitch 0 2
+        A
+        B
+        C
+        default: C
+A:
+    .frame same
+    iconst_1
+    ireturn
+B:
+    .frame same
+    iconst_2
+    ireturn
+C:
+    .frame same
+    iconst_0
+    ireturn
+.end method
+`, `
+.method public static parse%d(Ljava/lang/String;)I
+    .throws java/lang/IllegalStateException
+    .catch java/lang/NumberFormatException from Start to End using Invalid
+Start:
+    .line 30
+    aload_0
+    invokestatic java/lang/Integer/parseInt(Ljava/lang/String;)I
+End:
+    ireturn
+Invalid:
+    .frame same_locals_1_stack_item class java/lang/NumberFormatException
+    .line 32
+    pop
+    iconst_m1
+    ireturn
+.end method
+`}
+
+// benchmarkCorpus assembles a corpus of classes of varying sizes with the
+// attributes classes on a classpath carry: signatures, annotations,
+// InnerClasses, constant values, and methods whose Code has StackMapTable,
+// LineNumberTable, LocalVariableTable and exception tables.
+func benchmarkCorpus(b *testing.B) [][]byte {
+	corpus := make([][]byte, 100)
+	for i := range corpus {
+		name := fmt.Sprintf("com/example/Class%d", i)
+		var source strings.Builder
+		fmt.Fprintf(&source, ".version 52\n.source Class%d.java\n.class public super %s\n", i, name)
+		source.WriteString(".signature \"<T:Ljava/lang/Object;>Ljava/lang/Object;Ljava/lang/Iterable<TT;>;\"\n")
+		source.WriteString(".implements java/lang/Iterable\n")
+		fmt.Fprintf(&source, ".innerclass public static %s$Entry of %s as Entry\n", name, name)
+		source.WriteString(".annotation visible Ljava/lang/Deprecated;\n.end annotation\n")
+		for j := 0; j < 5+i%10; j++ {
+			fmt.Fprintf(&source, ".field private static final LIMIT%d I = %d\n", j, j)
+			fmt.Fprintf(&source, ".field private items%d Ljava/util/List;\n.signature \"Ljava/util/List<TT;>;\"\n", j)
+		}
+		// Between 20 and 79 methods
+		for j := 0; j < 20+i*7%60; j++ {
+			fmt.Fprintf(&source, benchmarkMethods[j%len(benchmarkMethods)], j)
+		}
+
+		var err error
+		if corpus[i], err = assembler.Assemble(strings.NewReader(source.String())); err != nil {
+			b.Fatal(err)
+		}
+		if err := class.CheckBytes(corpus[i]); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return corpus
+}
+
+func benchmarkParse(b *testing.B, opts ...class.ParseOption) {
+	corpus := benchmarkCorpus(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, data := range corpus {
+			c, err := class.ParseBytes(data, opts...)
+			if err != nil {
+				b.Fatal(err)
+			}
+			// Look classes up the way a class loader scanning a classpath would
+			if _, err := c.ThisClassName(); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := c.SuperClassName(); err != nil {
+				b.Fatal(err)
+			}
+			for j := range c.Methods {
+				_, _ = c.Methods[j].Name(), c.Methods[j].Descriptor()
+			}
+		}
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	benchmarkParse(b)
+}
+
+func BenchmarkParseLazy(b *testing.B) {
+	benchmarkParse(b, class.WithLazyAttributes())
+}
@@ -0,0 +1,141 @@
+package class
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"lava-vm/pkg/signature"
+)
+
+// RecordComponentInfo describes a single component of a record class. Name
+// and Descriptor are resolved from the constant pool when the class is parsed.
+type RecordComponentInfo struct {
+	NameIndex       uint16
+	DescriptorIndex uint16
+	Name            string
+	Descriptor      string
+	AttributesCount uint16
+	Attributes      []Attribute
+}
+
+// Attribute returns the first attribute of the record component with the given name, or nil.
+func (r *RecordComponentInfo) Attribute(name string) *Attribute {
+	return findAttribute(r.Attributes, name)
+}
+
+// Signature returns the Signature attribute of the record component, or nil.
+func (r *RecordComponentInfo) Signature() (*SignatureAttribute, error) {
+	value, err := attributeValue(r.Attributes, "Signature")
+	attr, _ := value.(*SignatureAttribute)
+	return attr, err
+}
+
+// GenericType parses the Signature attribute of the record component. It
+// returns nil if the component has no Signature attribute.
+func (r *RecordComponentInfo) GenericType() (signature.ReferenceType, error) {
+	attr, err := r.Signature()
+	if attr == nil {
+		return nil, err
+	}
+	return signature.ParseField(attr.Signature)
+}
+
+// Annotations returns the visible and invisible annotations of the record component.
+func (r *RecordComponentInfo) Annotations() ([]Annotation, error) {
+	return annotations(r.Attributes)
+}
+
+// HasAnnotation reports whether the record component is annotated with the given type descriptor.
+func (r *RecordComponentInfo) HasAnnotation(descriptor string) (bool, error) {
+	return hasAnnotation(r.Attributes, descriptor)
+}
+
+// TypeAnnotations returns the visible and invisible type annotations of the record component.
+func (r *RecordComponentInfo) TypeAnnotations() ([]TypeAnnotation, error) {
+	return typeAnnotations(r.Attributes)
+}
+
+// RecordAttribute lists the components of a record class.
+// See https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.30
+type RecordAttribute struct {
+	Components []RecordComponentInfo
+}
+
+func decodeRecordAttribute(info []byte, cp *ConstantPool) (interface{}, error) {
+	reader := bytes.NewReader(info)
+	var count uint16
+	if err := binary.Read(reader, binary.BigEndian, &count); err != nil {
+		return nil, err
+	}
+	if err := checkCount(reader, uint32(count), 6); err != nil {
+		return nil, err
+	}
+	attr := &RecordAttribute{Components: make([]RecordComponentInfo, count)}
+	for i := range attr.Components {
+		if err := readRecordComponent(reader, &attr.Components[i], cp); err != nil {
+			return nil, fmt.Errorf("reading record component %d: %w", i, err)
+		}
+	}
+	return attr, checkFullyRead(reader)
+}
+
+func (a *RecordAttribute) encode(w *bytes.Buffer) error {
+	if err := putCount(w, len(a.Components)); err != nil {
+		return err
+	}
+	for i := range a.Components {
+		component := &a.Components[i]
+		put(w, component.NameIndex)
+		put(w, component.DescriptorIndex)
+		if err := writeAttributes(w, component.Attributes); err != nil {
+			return fmt.Errorf("writing record component %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func readRecordComponent(reader *bytes.Reader, component *RecordComponentInfo, cp *ConstantPool) error {
+	if err := binary.Read(reader, binary.BigEndian, &component.NameIndex); err != nil {
+		return fmt.Errorf("reading name index: %w", err)
+	}
+	if err := binary.Read(reader, binary.BigEndian, &component.DescriptorIndex); err != nil {
+		return fmt.Errorf("reading descriptor index: %w", err)
+	}
+
+	var err error
+	if component.Name, err = cp.utf8(component.NameIndex); err != nil {
+		return fmt.Errorf("resolving name: %w", err)
+	}
+	if component.Descriptor, err = cp.utf8(component.DescriptorIndex); err != nil {
+		return fmt.Errorf("resolving descriptor: %w", err)
+	}
+
+	if err := binary.Read(reader, binary.BigEndian, &component.AttributesCount); err != nil {
+		return fmt.Errorf("reading attributes count: %w", err)
+	}
+	if err := checkCount(reader, uint32(component.AttributesCount), 6); err != nil {
+		return err
+	}
+	component.Attributes = make([]Attribute, component.AttributesCount)
+	for i := range component.Attributes {
+		if err := readAttribute(reader, &component.Attributes[i], cp); err != nil {
+			return fmt.Errorf("reading attribute %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// IsRecord reports whether the class has a Record attribute.
+func (c *Class) IsRecord() bool {
+	return findAttribute(c.Attributes, "Record") != nil
+}
+
+// RecordComponents returns the components of a record class, or nil if the
+// class is not a record.
+func (c *Class) RecordComponents() ([]RecordComponentInfo, error) {
+	value, err := attributeValue(c.Attributes, "Record")
+	if attr, ok := value.(*RecordAttribute); ok {
+		return attr.Components, nil
+	}
+	return nil, err
+}
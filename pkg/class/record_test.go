@@ -0,0 +1,56 @@
+package class
+
+import "testing"
+
+func TestRecordComponents(t *testing.T) {
+	c := parseTestClass(t)
+	if c.IsRecord() {
+		t.Error("IsRecord() = true for a plain class")
+	}
+	if components, err := c.RecordComponents(); err != nil || components != nil {
+		t.Errorf("RecordComponents() = %v, %v for a plain class, want nil", components, err)
+	}
+
+	x, intType := utf8Index(t, c, "x"), utf8Index(t, c, "I")
+	items, listType := utf8Index(t, c, "items"), utf8Index(t, c, "Ljava/util/List;")
+	signatureName := utf8Index(t, c, "Signature")
+	listSignature := utf8Index(t, c, "Ljava/util/List<Ljava/lang/String;>;")
+	// record R(int x, List<String> items), where items carries a Signature attribute
+	addClassAttribute(t, c, "Record", u2(2,
+		x, intType, 0,
+		items, listType, 1, signatureName, 0, 2, listSignature))
+
+	if !c.IsRecord() {
+		t.Error("IsRecord() = false, want true")
+	}
+	components, err := c.RecordComponents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(components) != 2 {
+		t.Fatalf("RecordComponents() has %d components, want 2", len(components))
+	}
+	tests := []struct {
+		name, descriptor, genericType string
+	}{
+		{"x", "I", ""},
+		{"items", "Ljava/util/List;", "java.util.List<java.lang.String>"},
+	}
+	for i, test := range tests {
+		component := &components[i]
+		if component.Name != test.name || component.Descriptor != test.descriptor {
+			t.Errorf("component %d = %s %s, want %s %s", i, component.Name, component.Descriptor, test.name, test.descriptor)
+		}
+		generic, err := component.GenericType()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := ""
+		if generic != nil {
+			got = generic.String()
+		}
+		if got != test.genericType {
+			t.Errorf("component %s GenericType() = %q, want %q", test.name, got, test.genericType)
+		}
+	}
+}
@@ -0,0 +1,365 @@
+package class
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// Verification type tags, see JVMS 4.7.4
+const (
+	ItemTop               uint8 = 0
+	ItemInteger           uint8 = 1
+	ItemFloat             uint8 = 2
+	ItemDouble            uint8 = 3
+	ItemLong              uint8 = 4
+	ItemNull              uint8 = 5
+	ItemUninitializedThis uint8 = 6
+	ItemObject            uint8 = 7
+	ItemUninitialized     uint8 = 8
+)
+
+// VerificationTypeInfo is the type of a single local variable or operand stack
+// entry in a stack map frame. CpoolIndex is only meaningful for ItemObject and
+// points to a Class constant. Offset is only meaningful for ItemUninitialized
+// and is the bytecode offset of the new instruction that created the object.
+type VerificationTypeInfo struct {
+	Tag        uint8
+	CpoolIndex uint16
+	Offset     uint16
+}
+
+var verificationTypeNames = map[uint8]string{
+	ItemTop:               "top",
+	ItemInteger:           "int",
+	ItemFloat:             "float",
+	ItemDouble:            "double",
+	ItemLong:              "long",
+	ItemNull:              "null",
+	ItemUninitializedThis: "uninitialized_this",
+}
+
+func (v VerificationTypeInfo) String() string {
+	switch v.Tag {
+	case ItemObject:
+		return fmt.Sprintf("class #%d", v.CpoolIndex)
+	case ItemUninitialized:
+		return fmt.Sprintf("uninitialized %d", v.Offset)
+	}
+	if name, ok := verificationTypeNames[v.Tag]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", v.Tag)
+}
+
+// StackMapFrameKind identifies the form of a stack map frame, which is encoded
+// in ranges of its frame type.
+type StackMapFrameKind int
+
+const (
+	FrameSame StackMapFrameKind = iota
+	FrameSameLocals1StackItem
+	FrameSameLocals1StackItemExtended
+	FrameChop
+	FrameSameExtended
+	FrameAppend
+	FrameFull
+)
+
+var stackMapFrameKindNames = map[StackMapFrameKind]string{
+	FrameSame:                         "same_frame",
+	FrameSameLocals1StackItem:         "same_locals_1_stack_item_frame",
+	FrameSameLocals1StackItemExtended: "same_locals_1_stack_item_frame_extended",
+	FrameChop:                         "chop_frame",
+	FrameSameExtended:                 "same_frame_extended",
+	FrameAppend:                       "append_frame",
+	FrameFull:                         "full_frame",
+}
+
+func (k StackMapFrameKind) String() string {
+	return stackMapFrameKindNames[k]
+}
+
+// stackMapFrameKind returns the kind of frame encoded by frameType.
+func stackMapFrameKind(frameType uint8) (StackMapFrameKind, error) {
+	switch {
+	case frameType <= 63:
+		return FrameSame, nil
+	case frameType <= 127:
+		return FrameSameLocals1StackItem, nil
+	case frameType <= 246:
+		return 0, fmt.Errorf("reserved frame type %d", frameType)
+	case frameType == 247:
+		return FrameSameLocals1StackItemExtended, nil
+	case frameType <= 250:
+		return FrameChop, nil
+	case frameType == 251:
+		return FrameSameExtended, nil
+	case frameType <= 254:
+		return FrameAppend, nil
+	default:
+		return FrameFull, nil
+	}
+}
+
+// StackMapFrame is a single entry of a StackMapTable. Locals holds the
+// appended locals of an append frame or every local of a full frame, and Stack
+// holds the operand stack of same_locals_1_stack_item and full frames.
+type StackMapFrame struct {
+	FrameType   uint8
+	Kind        StackMapFrameKind
+	OffsetDelta uint16
+	// Offset is the absolute bytecode offset the frame applies to, resolved
+	// from the deltas of this and all preceding frames.
+	Offset int
+	Locals []VerificationTypeInfo
+	Stack  []VerificationTypeInfo
+}
+
+// ChopCount returns the number of locals removed by a chop frame.
+func (f *StackMapFrame) ChopCount() int {
+	if f.Kind != FrameChop {
+		return 0
+	}
+	return 251 - int(f.FrameType)
+}
+
+func (f *StackMapFrame) String() string {
+	return fmt.Sprintf("%s (type %d) offset %d, locals %v, stack %v", f.Kind, f.FrameType, f.Offset, f.Locals, f.Stack)
+}
+
+// StackMapTableAttribute holds the stack map frames used for type checking
+// verification of a method's code.
+// See https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.4
+type StackMapTableAttribute struct {
+	Entries []StackMapFrame
+}
+
+func decodeStackMapTableAttribute(info []byte, cp *ConstantPool) (interface{}, error) {
+	reader := bytes.NewReader(info)
+	var count uint16
+	if err := binary.Read(reader, binary.BigEndian, &count); err != nil {
+		return nil, err
+	}
+
+	if err := checkCount(reader, uint32(count), 1); err != nil {
+		return nil, err
+	}
+	attr := &StackMapTableAttribute{Entries: make([]StackMapFrame, count)}
+	offset := -1
+	for i := range attr.Entries {
+		frame := &attr.Entries[i]
+		if err := readStackMapFrame(reader, frame); err != nil {
+			return nil, fmt.Errorf("reading frame %d: %w", i, err)
+		}
+		// Every frame but the first adds one to its delta so that no two
+		// frames can share an offset.
+		offset += int(frame.OffsetDelta) + 1
+		frame.Offset = offset
+	}
+	return attr, checkFullyRead(reader)
+}
+
+func (a *StackMapTableAttribute) encode(w *bytes.Buffer) error {
+	if err := putCount(w, len(a.Entries)); err != nil {
+		return err
+	}
+	for i := range a.Entries {
+		if err := writeStackMapFrame(w, &a.Entries[i]); err != nil {
+			return fmt.Errorf("writing frame %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func readStackMapFrame(reader *bytes.Reader, frame *StackMapFrame) error {
+	if err := binary.Read(reader, binary.BigEndian, &frame.FrameType); err != nil {
+		return err
+	}
+	kind, err := stackMapFrameKind(frame.FrameType)
+	if err != nil {
+		return err
+	}
+	frame.Kind = kind
+
+	switch kind {
+	case FrameSame:
+		frame.OffsetDelta = uint16(frame.FrameType)
+	case FrameSameLocals1StackItem:
+		frame.OffsetDelta = uint16(frame.FrameType - 64)
+		frame.Stack, err = readVerificationTypes(reader, 1)
+	case FrameSameLocals1StackItemExtended:
+		if err = binary.Read(reader, binary.BigEndian, &frame.OffsetDelta); err == nil {
+			frame.Stack, err = readVerificationTypes(reader, 1)
+		}
+	case FrameChop, FrameSameExtended:
+		err = binary.Read(reader, binary.BigEndian, &frame.OffsetDelta)
+	case FrameAppend:
+		if err = binary.Read(reader, binary.BigEndian, &frame.OffsetDelta); err == nil {
+			frame.Locals, err = readVerificationTypes(reader, int(frame.FrameType)-251)
+		}
+	case FrameFull:
+		err = readFullFrame(reader, frame)
+	}
+	return err
+}
+
+func readFullFrame(reader *bytes.Reader, frame *StackMapFrame) error {
+	if err := binary.Read(reader, binary.BigEndian, &frame.OffsetDelta); err != nil {
+		return err
+	}
+
+	var numberOfLocals uint16
+	if err := binary.Read(reader, binary.BigEndian, &numberOfLocals); err != nil {
+		return err
+	}
+	locals, err := readVerificationTypes(reader, int(numberOfLocals))
+	if err != nil {
+		return err
+	}
+	frame.Locals = locals
+
+	var numberOfStackItems uint16
+	if err := binary.Read(reader, binary.BigEndian, &numberOfStackItems); err != nil {
+		return err
+	}
+	stack, err := readVerificationTypes(reader, int(numberOfStackItems))
+	if err != nil {
+		return err
+	}
+	frame.Stack = stack
+	return nil
+}
+
+func readVerificationTypes(reader *bytes.Reader, count int) ([]VerificationTypeInfo, error) {
+	if err := checkCount(reader, uint32(count), 1); err != nil {
+		return nil, err
+	}
+	types := make([]VerificationTypeInfo, count)
+	for i := range types {
+		if err := binary.Read(reader, binary.BigEndian, &types[i].Tag); err != nil {
+			return nil, err
+		}
+		switch types[i].Tag {
+		case ItemTop, ItemInteger, ItemFloat, ItemDouble, ItemLong, ItemNull, ItemUninitializedThis:
+		case ItemObject:
+			if err := binary.Read(reader, binary.BigEndian, &types[i].CpoolIndex); err != nil {
+				return nil, err
+			}
+		case ItemUninitialized:
+			if err := binary.Read(reader, binary.BigEndian, &types[i].Offset); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("invalid verification type tag %d", types[i].Tag)
+		}
+	}
+	return types, nil
+}
+
+// stackMapFrameType returns the frame type that encodes frame in the form
+// given by its Kind, or an error if the frame does not fit that form. The type
+// is derived from the offset delta and, for an append frame, the number of
+// locals; only a chop frame takes its type from FrameType, since that is
+// where the number of chopped locals is kept.
+func stackMapFrameType(frame *StackMapFrame) (uint8, error) {
+	if (frame.Kind == FrameSameLocals1StackItem || frame.Kind == FrameSameLocals1StackItemExtended) && len(frame.Stack) != 1 {
+		return 0, fmt.Errorf("%s has %d stack items, expected 1", frame.Kind, len(frame.Stack))
+	}
+	switch frame.Kind {
+	case FrameSame, FrameSameLocals1StackItem:
+		if frame.OffsetDelta > 63 {
+			return 0, fmt.Errorf("offset delta %d does not fit a %s, use the extended form", frame.OffsetDelta, frame.Kind)
+		}
+		if frame.Kind == FrameSame {
+			return uint8(frame.OffsetDelta), nil
+		}
+		return uint8(64 + frame.OffsetDelta), nil
+	case FrameSameLocals1StackItemExtended:
+		return 247, nil
+	case FrameChop:
+		if frame.FrameType < 248 || frame.FrameType > 250 {
+			return 0, fmt.Errorf("chop frame has type %d, expected 248 to 250", frame.FrameType)
+		}
+		return frame.FrameType, nil
+	case FrameSameExtended:
+		return 251, nil
+	case FrameAppend:
+		if len(frame.Locals) < 1 || len(frame.Locals) > 3 {
+			return 0, fmt.Errorf("append frame has %d locals, expected 1 to 3", len(frame.Locals))
+		}
+		return uint8(251 + len(frame.Locals)), nil
+	case FrameFull:
+		return 255, nil
+	default:
+		return 0, fmt.Errorf("invalid frame kind %v", frame.Kind)
+	}
+}
+
+// writeStackMapFrame writes a frame in the form given by its Kind, so that
+// frames keep their compact encodings.
+func writeStackMapFrame(w *bytes.Buffer, frame *StackMapFrame) error {
+	frameType, err := stackMapFrameType(frame)
+	if err != nil {
+		return err
+	}
+	put(w, frameType)
+	switch frame.Kind {
+	case FrameSame:
+	case FrameSameLocals1StackItem:
+		writeVerificationTypes(w, frame.Stack)
+	case FrameSameLocals1StackItemExtended:
+		put(w, frame.OffsetDelta)
+		writeVerificationTypes(w, frame.Stack)
+	case FrameChop, FrameSameExtended:
+		put(w, frame.OffsetDelta)
+	case FrameAppend:
+		put(w, frame.OffsetDelta)
+		writeVerificationTypes(w, frame.Locals)
+	case FrameFull:
+		put(w, frame.OffsetDelta)
+		if err := putCount(w, len(frame.Locals)); err != nil {
+			return err
+		}
+		writeVerificationTypes(w, frame.Locals)
+		if err := putCount(w, len(frame.Stack)); err != nil {
+			return err
+		}
+		writeVerificationTypes(w, frame.Stack)
+	}
+	return nil
+}
+
+func writeVerificationTypes(w *bytes.Buffer, types []VerificationTypeInfo) {
+	for _, info := range types {
+		put(w, info.Tag)
+		switch info.Tag {
+		case ItemObject:
+			put(w, info.CpoolIndex)
+		case ItemUninitialized:
+			put(w, info.Offset)
+		}
+	}
+}
+
+// StackMapTable returns the StackMapTable attribute of the code, or nil.
+func (c *Code) StackMapTable() (*StackMapTableAttribute, error) {
+	value, err := attributeValue(c.Attributes, "StackMapTable")
+	attr, _ := value.(*StackMapTableAttribute)
+	return attr, err
+}
+
+// StackMapFrameAt returns the stack map frame recorded for the instruction at
+// pc, or nil if there is none.
+func (c *Code) StackMapFrameAt(pc int) (*StackMapFrame, error) {
+	table, err := c.StackMapTable()
+	if table == nil {
+		return nil, err
+	}
+	for i := range table.Entries {
+		if table.Entries[i].Offset == pc {
+			return &table.Entries[i], nil
+		}
+	}
+	return nil, nil
+}
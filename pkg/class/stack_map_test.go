@@ -0,0 +1,146 @@
+package class
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// stackMapTableInfo holds one frame of every kind. The absolute offsets are 3,
+// 6, 307, 309, 310, 315 and 318.
+var stackMapTableInfo = []byte{
+	0, 7,
+	3,     // same_frame, delta 3
+	66, 1, // same_locals_1_stack_item_frame, delta 2, stack int
+	247, 1, 44, 7, 0, 10, // same_locals_1_stack_item_frame_extended, delta 300, stack class #10
+	249, 0, 1, // chop_frame, chop 2, delta 1
+	251, 0, 0, // same_frame_extended, delta 0
+	253, 0, 4, 4, 8, 0, 5, // append_frame, delta 4, locals long and uninitialized 5
+	255, 0, 2, 0, 1, 6, 0, 2, 5, 2, // full_frame, delta 2, locals uninitialized_this, stack null and float
+}
+
+func TestDecodeStackMapTable(t *testing.T) {
+	c := parseTestClass(t)
+	code := mainCode(t, c)
+	addAttribute(t, &c.ConstantPool, &code.Attributes, "StackMapTable", stackMapTableInfo)
+
+	table, err := code.StackMapTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []StackMapFrame{
+		{FrameType: 3, Kind: FrameSame, OffsetDelta: 3, Offset: 3},
+		{FrameType: 66, Kind: FrameSameLocals1StackItem, OffsetDelta: 2, Offset: 6,
+			Stack: []VerificationTypeInfo{{Tag: ItemInteger}}},
+		{FrameType: 247, Kind: FrameSameLocals1StackItemExtended, OffsetDelta: 300, Offset: 307,
+			Stack: []VerificationTypeInfo{{Tag: ItemObject, CpoolIndex: 10}}},
+		{FrameType: 249, Kind: FrameChop, OffsetDelta: 1, Offset: 309},
+		{FrameType: 251, Kind: FrameSameExtended, OffsetDelta: 0, Offset: 310},
+		{FrameType: 253, Kind: FrameAppend, OffsetDelta: 4, Offset: 315,
+			Locals: []VerificationTypeInfo{{Tag: ItemLong}, {Tag: ItemUninitialized, Offset: 5}}},
+		{FrameType: 255, Kind: FrameFull, OffsetDelta: 2, Offset: 318,
+			Locals: []VerificationTypeInfo{{Tag: ItemUninitializedThis}},
+			Stack:  []VerificationTypeInfo{{Tag: ItemNull}, {Tag: ItemFloat}}},
+	}
+	if len(table.Entries) != len(want) {
+		t.Fatalf("got %d frames, want %d", len(table.Entries), len(want))
+	}
+	for i := range want {
+		got := table.Entries[i]
+		// Decoding leaves the locals and stack of other kinds empty rather than nil
+		if len(got.Locals) == 0 {
+			got.Locals = nil
+		}
+		if len(got.Stack) == 0 {
+			got.Stack = nil
+		}
+		if !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("frame %d = %v, want %v", i, &got, &want[i])
+		}
+	}
+	if chop := table.Entries[3].ChopCount(); chop != 2 {
+		t.Errorf("ChopCount() = %d, want 2", chop)
+	}
+
+	for _, pc := range []int{3, 307, 318} {
+		if frame, err := code.StackMapFrameAt(pc); err != nil || frame == nil || frame.Offset != pc {
+			t.Errorf("StackMapFrameAt(%d) = %v, %v, want the frame at %d", pc, frame, err, pc)
+		}
+	}
+	if frame, err := code.StackMapFrameAt(4); err != nil || frame != nil {
+		t.Errorf("StackMapFrameAt(4) = %v, %v, want nil", frame, err)
+	}
+}
+
+func TestDecodeStackMapTableErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		info []byte
+		want string
+	}{
+		{"reserved frame type", []byte{0, 1, 128}, "reserved frame type 128"},
+		{"bad verification tag", []byte{0, 1, 64, 9}, "invalid verification type tag 9"},
+		{"truncated full frame", []byte{0, 1, 255, 0, 0, 0, 2, 1}, "reading frame 0"},
+		{"trailing bytes", []byte{0, 1, 0, 0}, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := decodeStackMapTableAttribute(test.info, nil)
+			if err == nil {
+				t.Fatal("decodeStackMapTableAttribute() succeeded, want an error")
+			}
+			if !strings.Contains(err.Error(), test.want) {
+				t.Errorf("decodeStackMapTableAttribute() = %v, want an error containing %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestWriteStackMapFrame(t *testing.T) {
+	integer := []VerificationTypeInfo{{Tag: ItemInteger}}
+	tests := []struct {
+		name  string
+		frame StackMapFrame
+		want  []byte
+		err   string
+	}{
+		// The frame type is derived from the kind and delta, whatever FrameType says
+		{"same", StackMapFrame{Kind: FrameSame, FrameType: 0, OffsetDelta: 5}, []byte{5}, ""},
+		{"same delta too large", StackMapFrame{Kind: FrameSame, OffsetDelta: 64}, nil, "offset delta 64 does not fit a same_frame"},
+		{"same_locals_1_stack_item", StackMapFrame{Kind: FrameSameLocals1StackItem, FrameType: 64, OffsetDelta: 3, Stack: integer},
+			[]byte{67, 1}, ""},
+		{"same_locals_1_stack_item without stack", StackMapFrame{Kind: FrameSameLocals1StackItem, OffsetDelta: 3}, nil,
+			"has 0 stack items, expected 1"},
+		{"same_locals_1_stack_item_extended", StackMapFrame{Kind: FrameSameLocals1StackItemExtended, OffsetDelta: 300, Stack: integer},
+			[]byte{247, 1, 44, 1}, ""},
+		{"chop", StackMapFrame{Kind: FrameChop, FrameType: 249, OffsetDelta: 1}, []byte{249, 0, 1}, ""},
+		{"chop type out of range", StackMapFrame{Kind: FrameChop, FrameType: 251, OffsetDelta: 1}, nil,
+			"chop frame has type 251, expected 248 to 250"},
+		{"same_frame_extended", StackMapFrame{Kind: FrameSameExtended, OffsetDelta: 2}, []byte{251, 0, 2}, ""},
+		{"append", StackMapFrame{Kind: FrameAppend, FrameType: 252, OffsetDelta: 1, Locals: []VerificationTypeInfo{{Tag: ItemInteger}, {Tag: ItemLong}}},
+			[]byte{253, 0, 1, 1, 4}, ""},
+		{"append too many locals", StackMapFrame{Kind: FrameAppend, Locals: make([]VerificationTypeInfo, 4)}, nil,
+			"append frame has 4 locals, expected 1 to 3"},
+		{"full", StackMapFrame{Kind: FrameFull, OffsetDelta: 0, Stack: integer}, []byte{255, 0, 0, 0, 0, 0, 1, 1}, ""},
+		{"invalid kind", StackMapFrame{Kind: StackMapFrameKind(9)}, nil, "invalid frame kind"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := writeStackMapFrame(&buf, &test.frame)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("writeStackMapFrame() = %v, want an error containing %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf.Bytes(), test.want) {
+				t.Errorf("writeStackMapFrame() wrote % x, want % x", buf.Bytes(), test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package class
+
+import "fmt"
+
+// The range of class file major versions that can be parsed, 49 = Java SE 5.0
+// through 65 = Java SE 21.
+const (
+	MinMajorVersion uint16 = 49
+	MaxMajorVersion uint16 = 65
+)
+
+// PreviewMinorVersion is the minor version of a class file that depends on
+// the preview features of the Java release given by its major version.
+const PreviewMinorVersion uint16 = 0xFFFF
+
+// firstPreviewMajorVersion is the first major version, Java SE 12, in which
+// the minor version is restricted to 0 and PreviewMinorVersion.
+const firstPreviewMajorVersion uint16 = 56
+
+// JavaRelease returns the name of the Java release that introduced the given
+// class file major version, such as "5.0", "1.4" or "21".
+func JavaRelease(majorVersion uint16) string {
+	switch {
+	case majorVersion < 45:
+		return "unknown"
+	case majorVersion == 45:
+		return "1.1"
+	case majorVersion < 49:
+		return fmt.Sprintf("1.%d", majorVersion-44)
+	case majorVersion == 49:
+		return "5.0"
+	default:
+		return fmt.Sprintf("%d", majorVersion-44)
+	}
+}
+
+// UnsupportedClassVersionError is returned when parsing a class file whose
+// version is outside MinMajorVersion through MaxMajorVersion, whose minor
+// version is not allowed for its major version, or that depends on preview
+// features when these are not enabled with WithPreview.
+type UnsupportedClassVersionError struct {
+	MajorVersion uint16
+	MinorVersion uint16
+	// Preview is set when the version is only rejected because the class
+	// depends on preview features that are not enabled.
+	Preview bool
+}
+
+func (e *UnsupportedClassVersionError) Error() string {
+	version := fmt.Sprintf("class file version %d.%d (Java %s)", e.MajorVersion, e.MinorVersion, JavaRelease(e.MajorVersion))
+	switch {
+	case e.Preview:
+		return fmt.Sprintf("%s depends on preview features, which are not enabled", version)
+	case e.MajorVersion >= MinMajorVersion && e.MajorVersion <= MaxMajorVersion && e.MinorVersion == PreviewMinorVersion:
+		return fmt.Sprintf("%s depends on preview features, which are only supported for Java %s", version, JavaRelease(MaxMajorVersion))
+	case e.MajorVersion >= MinMajorVersion && e.MajorVersion <= MaxMajorVersion:
+		return fmt.Sprintf("%s has an invalid minor version", version)
+	default:
+		return fmt.Sprintf("%s is not supported, only versions %d.0 (Java %s) through %d.0 (Java %s) are",
+			version, MinMajorVersion, JavaRelease(MinMajorVersion), MaxMajorVersion, JavaRelease(MaxMajorVersion))
+	}
+}
+
+// IsPreview reports whether the class depends on the preview features of the
+// Java release it was compiled for.
+func (c *Class) IsPreview() bool {
+	return c.MajorVersion >= firstPreviewMajorVersion && c.MinorVersion == PreviewMinorVersion
+}
+
+// checkVersion returns an UnsupportedClassVersionError if a class file with
+// the given version cannot be parsed. Preview features are only ever allowed
+// for MaxMajorVersion, since they are tied to a single Java release.
+func checkVersion(majorVersion, minorVersion uint16, allowPreview bool) error {
+	err := &UnsupportedClassVersionError{MajorVersion: majorVersion, MinorVersion: minorVersion}
+	if majorVersion < MinMajorVersion || majorVersion > MaxMajorVersion {
+		return err
+	}
+	if majorVersion < firstPreviewMajorVersion {
+		return nil
+	}
+
+	switch minorVersion {
+	case 0:
+		return nil
+	case PreviewMinorVersion:
+		if majorVersion != MaxMajorVersion {
+			return err
+		}
+		if !allowPreview {
+			err.Preview = true
+			return err
+		}
+		return nil
+	default:
+		return err
+	}
+}
+
+// attributeMinMajorVersions holds the first class file major version in which
+// a standard attribute is defined, from JVMS table 4.7-A. Attributes defined
+// in a version no later than MinMajorVersion are left out. An attribute that
+// appears in an older class file is not recognized, as the JVMS requires: it
+// is kept as raw Info with no Value.
+var attributeMinMajorVersions = map[string]uint16{
+	"StackMapTable":                   50,
+	"BootstrapMethods":                51,
+	"MethodParameters":                52,
+	"RuntimeVisibleTypeAnnotations":   52,
+	"RuntimeInvisibleTypeAnnotations": 52,
+	"Module":                          53,
+	"ModulePackages":                  53,
+	"ModuleMainClass":                 53,
+	"NestHost":                        55,
+	"NestMembers":                     55,
+	"Record":                          60,
+	"PermittedSubclasses":             61,
+}
@@ -0,0 +1,147 @@
+package class
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+// withVersion returns Test.class with its version set to major.minor.
+func withVersion(t *testing.T, major, minor uint16) []byte {
+	t.Helper()
+	data, err := os.ReadFile("../../tst/Test.class")
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary.BigEndian.PutUint16(data[4:], minor)
+	binary.BigEndian.PutUint16(data[6:], major)
+	return data
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		name         string
+		major, minor uint16
+		preview      bool
+		// want is empty when the version is accepted
+		want string
+	}{
+		{"Java 17", 61, 0, false, ""},
+		{"oldest", MinMajorVersion, 0, false, ""},
+		{"newest", MaxMajorVersion, 0, false, ""},
+		{"minor before Java 12", 50, 3, false, ""},
+		{"too old", 48, 0, false, "class file version 48.0 (Java 1.4) is not supported, only versions 49.0 (Java 5.0) through 65.0 (Java 21) are"},
+		{"too new", 66, 0, false, "class file version 66.0 (Java 22) is not supported, only versions 49.0 (Java 5.0) through 65.0 (Java 21) are"},
+		{"preview not enabled", MaxMajorVersion, PreviewMinorVersion, false,
+			"class file version 65.65535 (Java 21) depends on preview features, which are not enabled"},
+		{"preview", MaxMajorVersion, PreviewMinorVersion, true, ""},
+		{"preview of an older release", 61, PreviewMinorVersion, true,
+			"class file version 61.65535 (Java 17) depends on preview features, which are only supported for Java 21"},
+		{"invalid minor", 61, 3, false, "class file version 61.3 (Java 17) has an invalid minor version"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var opts []ParseOption
+			if test.preview {
+				opts = append(opts, WithPreview())
+			}
+			c, err := ParseBytes(withVersion(t, test.major, test.minor), opts...)
+			if test.want == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if preview := c.IsPreview(); preview != (test.minor == PreviewMinorVersion) {
+					t.Errorf("IsPreview() = %v", preview)
+				}
+				return
+			}
+			var versionErr *UnsupportedClassVersionError
+			if !errors.As(err, &versionErr) {
+				t.Fatalf("ParseBytes() = %v, want an *UnsupportedClassVersionError", err)
+			}
+			if versionErr.MajorVersion != test.major || versionErr.MinorVersion != test.minor {
+				t.Errorf("error version = %d.%d, want %d.%d", versionErr.MajorVersion, versionErr.MinorVersion, test.major, test.minor)
+			}
+			if versionErr.Preview != (test.minor == PreviewMinorVersion && !test.preview) {
+				t.Errorf("Preview = %v", versionErr.Preview)
+			}
+			if err.Error() != test.want {
+				t.Errorf("err = %q, want %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestJavaRelease(t *testing.T) {
+	tests := []struct {
+		major uint16
+		want  string
+	}{
+		{44, "unknown"},
+		{45, "1.1"},
+		{46, "1.2"},
+		{48, "1.4"},
+		{49, "5.0"},
+		{52, "8"},
+		{65, "21"},
+	}
+	for _, test := range tests {
+		if got := JavaRelease(test.major); got != test.want {
+			t.Errorf("JavaRelease(%d) = %q, want %q", test.major, got, test.want)
+		}
+	}
+}
+
+func TestVersionedFeatures(t *testing.T) {
+	// Test.class with a Dynamic constant and a NestHost attribute, both of
+	// which are Java 11 features
+	build := func(t *testing.T, major uint16) []byte {
+		c := parseTestClass(t)
+		c.MajorVersion = major
+		if _, err := c.ConstantPool.AddDynamic(0, "value", "I"); err != nil {
+			t.Fatal(err)
+		}
+		host, err := c.ConstantPool.AddClass("Outer")
+		if err != nil {
+			t.Fatal(err)
+		}
+		addClassAttribute(t, c, "NestHost", u2(host))
+		data, err := c.Bytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	c, err := ParseBytes(build(t, 55))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host, err := c.NestHost(); err != nil || host != "Outer" {
+		t.Errorf("NestHost() = %q, %v, want Outer", host, err)
+	}
+
+	_, err = ParseBytes(build(t, 54))
+	if err == nil || !strings.Contains(err.Error(), "tag 17 at index") || !strings.Contains(err.Error(), "requires class file version 55 or later, got 54") {
+		t.Errorf("ParseBytes() = %v, want an error for a Dynamic constant before version 55", err)
+	}
+
+	// Without the Dynamic constant, NestHost is not recognized before version
+	// 55 and is kept raw
+	c = parseTestClass(t)
+	c.MajorVersion = 54
+	addClassAttribute(t, c, "NestHost", u2(10))
+	data, err := c.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, err = ParseBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	attr := c.Attributes[len(c.Attributes)-1]
+	if attr.Name != "NestHost" || attr.Value != nil || len(attr.Info) != 2 {
+		t.Errorf("NestHost attribute = %+v, want it kept raw", attr)
+	}
+}
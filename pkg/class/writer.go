@@ -0,0 +1,187 @@
+package class
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// attributeEncoder is implemented by the typed attribute values produced by
+// the standard decoders. An attribute whose Value implements it is written by
+// encoding the Value, so that changes made to it are kept; any other attribute,
+// including unknown ones and those decoded by embedder-registered decoders, is
+// written from its Info bytes unchanged.
+type attributeEncoder interface {
+	encode(w *bytes.Buffer) error
+}
+
+// Write writes the class to w in class file format. Counts and lengths are
+// taken from the slices they describe rather than from the count fields, so a
+// class edited in place is written consistently. Writing a class that was
+// parsed and not modified gives back the original bytes.
+func (c *Class) Write(w io.Writer) error {
+	data, err := c.Bytes()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// Bytes returns the class in class file format, see Write.
+func (c *Class) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	put(&buf, c.Magic)
+	put(&buf, c.MinorVersion)
+	put(&buf, c.MajorVersion)
+
+	if err := writeConstantPool(&buf, &c.ConstantPool); err != nil {
+		return nil, fmt.Errorf("writing constant pool: %w", err)
+	}
+
+	put(&buf, c.AccessFlags)
+	put(&buf, c.ThisClass)
+	put(&buf, c.SuperClass)
+	if err := putUint16Table(&buf, c.Interfaces); err != nil {
+		return nil, fmt.Errorf("writing interfaces: %w", err)
+	}
+
+	if err := putCount(&buf, len(c.Fields)); err != nil {
+		return nil, fmt.Errorf("writing fields count: %w", err)
+	}
+	for i := range c.Fields {
+		field := &c.Fields[i]
+		put(&buf, field.AccessFlags)
+		put(&buf, field.NameIndex)
+		put(&buf, field.DescriptorIndex)
+		if err := writeAttributes(&buf, field.Attributes); err != nil {
+			return nil, fmt.Errorf("writing field %d: %w", i, err)
+		}
+	}
+
+	if err := putCount(&buf, len(c.Methods)); err != nil {
+		return nil, fmt.Errorf("writing methods count: %w", err)
+	}
+	for i := range c.Methods {
+		method := &c.Methods[i]
+		put(&buf, method.AccessFlags)
+		put(&buf, method.NameIndex)
+		put(&buf, method.DescriptorIndex)
+		if err := writeAttributes(&buf, method.Attributes); err != nil {
+			return nil, fmt.Errorf("writing method %d: %w", i, err)
+		}
+	}
+
+	if err := writeAttributes(&buf, c.Attributes); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeConstantPool writes the constant pool count followed by the entries.
+// The unusable slot after each Long and Double entry is skipped.
+func writeConstantPool(w *bytes.Buffer, cp *ConstantPool) error {
+	if len(cp.entries) == 0 {
+		// An empty pool still has a count of one, as index zero is never used
+		put(w, uint16(1))
+		return nil
+	}
+	if err := putCount(w, len(cp.entries)); err != nil {
+		return err
+	}
+
+	for i := 1; i < len(cp.entries); i++ {
+		entry := cp.entries[i]
+		put(w, entry.Tag)
+		switch value := entry.Value.(type) {
+		case *ConstantUtf8Value:
+			if len(value.Bytes) > math.MaxUint16 {
+				return fmt.Errorf("entry %d: string is %d bytes, longer than the maximum of %d", i, len(value.Bytes), math.MaxUint16)
+			}
+			put(w, uint16(len(value.Bytes)))
+			w.Write(value.Bytes)
+		case nil:
+			return fmt.Errorf("entry %d: missing value for tag %d", i, entry.Tag)
+		default:
+			// Every other constant is a fixed-size struct laid out as in the class file
+			put(w, value)
+		}
+
+		if entry.Tag == TagLong || entry.Tag == TagDouble {
+			i++
+		}
+	}
+	return nil
+}
+
+// writeAttributes writes an attributes_count followed by the attributes.
+func writeAttributes(w *bytes.Buffer, attributes []Attribute) error {
+	if err := putCount(w, len(attributes)); err != nil {
+		return fmt.Errorf("writing attributes count: %w", err)
+	}
+	for i := range attributes {
+		if err := writeAttribute(w, &attributes[i]); err != nil {
+			return fmt.Errorf("writing attribute %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// writeAttribute writes a single attribute, encoding its Value when it is one
+// of the standard typed attributes and writing Info otherwise.
+func writeAttribute(w *bytes.Buffer, attribute *Attribute) error {
+	info := attribute.Info
+	if encoder, ok := attribute.Value.(attributeEncoder); ok {
+		var encoded bytes.Buffer
+		if err := encoder.encode(&encoded); err != nil {
+			return fmt.Errorf("encoding %s attribute: %w", attribute.Name, err)
+		}
+		info = encoded.Bytes()
+	}
+	if uint64(len(info)) > math.MaxUint32 {
+		return fmt.Errorf("%s attribute is %d bytes, longer than the maximum of %d", attribute.Name, len(info), uint64(math.MaxUint32))
+	}
+
+	put(w, attribute.AttributeNameIndex)
+	put(w, uint32(len(info)))
+	w.Write(info)
+	return nil
+}
+
+// put writes fixed-size data to w in big-endian order. binary.Write only fails
+// for data that is not fixed-size, which would be a bug in the caller, and
+// writes to a bytes.Buffer cannot fail.
+func put(w *bytes.Buffer, data interface{}) {
+	if err := binary.Write(w, binary.BigEndian, data); err != nil {
+		panic(err)
+	}
+}
+
+// putCount writes n as a u2 count, failing if it does not fit.
+func putCount(w *bytes.Buffer, n int) error {
+	if n > math.MaxUint16 {
+		return fmt.Errorf("count %d is larger than the maximum of %d", n, math.MaxUint16)
+	}
+	put(w, uint16(n))
+	return nil
+}
+
+// putCount8 writes n as a u1 count, failing if it does not fit.
+func putCount8(w *bytes.Buffer, n int) error {
+	if n > math.MaxUint8 {
+		return fmt.Errorf("count %d is larger than the maximum of %d", n, math.MaxUint8)
+	}
+	put(w, uint8(n))
+	return nil
+}
+
+// putUint16Table writes a u2 count followed by the values, the inverse of readUint16Table.
+func putUint16Table(w *bytes.Buffer, table []uint16) error {
+	if err := putCount(w, len(table)); err != nil {
+		return err
+	}
+	put(w, table)
+	return nil
+}
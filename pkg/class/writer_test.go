@@ -0,0 +1,178 @@
+package class
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestBytesRoundTrip(t *testing.T) {
+	data, err := os.ReadFile("../../tst/Test.class")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opts []ParseOption
+	}{
+		{"eager", nil},
+		{"lazy", []ParseOption{WithLazyAttributes()}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			class, err := ParseBytes(data, test.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			written, err := class.Bytes()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(written, data) {
+				t.Errorf("Bytes() differs from the parsed class file:\ngot  %x\nwant %x", written, data)
+			}
+		})
+	}
+}
+
+// attributeFixtures returns the info of an instance of every standard
+// attribute, keyed by attribute name, using constants of Test.class.
+func attributeFixtures(t *testing.T, c *Class) map[string][]byte {
+	t.Helper()
+	x, intType := utf8Index(t, c, "x"), utf8Index(t, c, "I")
+	items, listType := utf8Index(t, c, "items"), utf8Index(t, c, "Ljava/util/List;")
+	signatureName := utf8Index(t, c, "Signature")
+	listSignature := utf8Index(t, c, "Ljava/util/List<Ljava/lang/String;>;")
+	typeA := utf8Index(t, c, "LA;")
+	main := c.FindMethod("main", "").Attribute("Code")
+
+	// type_parameter, supertype, type_parameter_bound, empty, formal_parameter,
+	// throws, localvar, catch, offset and type_argument targets, with a type path
+	typeAnnotations := info(uint16(10),
+		uint8(TargetClassTypeParameter), uint8(1), uint8(0), typeA, uint16(0),
+		uint8(TargetClassExtends), uint16(0xFFFF), uint8(0), typeA, uint16(0),
+		uint8(TargetMethodTypeParameterBound), uint8(0), uint8(1), uint8(0), typeA, uint16(0),
+		uint8(TargetField), uint8(2), uint8(3), uint8(0), uint8(0), uint8(1), typeA, uint16(1), x, uint8('s'), x,
+		uint8(TargetMethodFormalParameter), uint8(2), uint8(0), typeA, uint16(0),
+		uint8(TargetThrows), uint16(1), uint8(0), typeA, uint16(0),
+		uint8(TargetLocalVariable), uint16(2), uint16(0), uint16(5), uint16(1), uint16(5), uint16(3), uint16(2), uint8(0), typeA, uint16(0),
+		uint8(TargetExceptionParameter), uint16(0), uint8(0), typeA, uint16(0),
+		uint8(TargetNew), uint16(4), uint8(0), typeA, uint16(0),
+		uint8(TargetCast), uint16(7), uint8(1), uint8(0), typeA, uint16(0))
+
+	return map[string][]byte{
+		"AnnotationDefault": info(uint8('['), uint16(3),
+			uint8('e'), typeA, x,
+			uint8('c'), typeA,
+			uint8('@'), typeA, uint16(1), x, uint8('J'), uint16(7)),
+		"BootstrapMethods": u2(2, 1, 2, 7, 10, 1, 0),
+		"Code":             main.Info,
+		"ConstantValue":    u2(7),
+		"Deprecated":       {},
+		"EnclosingMethod":  u2(10, 3),
+		"Exceptions":       u2(2, 10, 2),
+		"InnerClasses":     u2(2, 10, 2, x, uint16(InnerClassStatic), 10, 0, 0, 0),
+		"LineNumberTable":  u2(2, 0, 7, 8, 8),
+		"LocalVariableTable": u2(2,
+			0, 14, x, listType, 0,
+			8, 6, items, intType, 1),
+		"LocalVariableTypeTable": u2(1, 0, 14, x, listSignature, 0),
+		"MethodParameters":       info(uint8(2), x, uint16(0x0010), uint16(0), uint16(0x1000)),
+		"Module": u2(1, 0x0020, 0,
+			1, 2, 0x8000, 0,
+			2, 3, 0, 0, 4, 0, 1, 5,
+			1, 4, 0, 0,
+			1, 6,
+			1, 6, 2, 7, 10),
+		"ModuleMainClass":     u2(10),
+		"ModulePackages":      u2(2, 3, 4),
+		"NestHost":            u2(10),
+		"NestMembers":         u2(2, 10, 2),
+		"PermittedSubclasses": u2(1, 10),
+		"Record": u2(2,
+			x, intType, 0,
+			items, listType, 1, signatureName, 0, 2, listSignature),
+		"RuntimeInvisibleAnnotations":          info(uint16(1), typeA, uint16(1), x, uint8('s'), x),
+		"RuntimeInvisibleParameterAnnotations": info(uint8(2), uint16(0), uint16(1), typeA, uint16(0)),
+		"RuntimeInvisibleTypeAnnotations":      typeAnnotations,
+		"RuntimeVisibleAnnotations": info(uint16(2),
+			typeA, uint16(2), x, uint8('I'), uint16(7), items, uint8('Z'), uint16(7),
+			typeA, uint16(0)),
+		"RuntimeVisibleParameterAnnotations": info(uint8(1), uint16(1), typeA, uint16(0)),
+		"RuntimeVisibleTypeAnnotations":      typeAnnotations,
+		"Signature":                          u2(listSignature),
+		"SourceDebugExtension":               []byte("SMAP\nTest.java\n"),
+		"SourceFile":                         u2(24),
+		"StackMapTable":                      stackMapTableInfo,
+		"Synthetic":                          {},
+	}
+}
+
+func TestAttributeEncodeRoundTrip(t *testing.T) {
+	c := parseTestClass(t)
+	fixtures := attributeFixtures(t, c)
+	for name := range attributeDecoders {
+		if _, ok := fixtures[name]; !ok {
+			t.Errorf("no fixture for the %s attribute", name)
+		}
+	}
+
+	for name, fixture := range fixtures {
+		t.Run(name, func(t *testing.T) {
+			var attributes []Attribute
+			addAttribute(t, &c.ConstantPool, &attributes, name, fixture)
+			attr := &attributes[0]
+			if _, ok := attr.Value.(attributeEncoder); !ok {
+				t.Fatalf("%T does not implement attributeEncoder", attr.Value)
+			}
+			// Drop Info so that the attribute can only be written from its Value
+			attr.Info = nil
+
+			var buf bytes.Buffer
+			if err := writeAttribute(&buf, attr); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.Bytes()[6:]; !bytes.Equal(got, fixture) {
+				t.Errorf("encoded\n% x\nwant\n% x", got, fixture)
+			}
+		})
+	}
+}
+
+// TestClassRoundTrip writes a class carrying every standard attribute, parses
+// it back and checks that writing it again gives the same bytes.
+func TestClassRoundTrip(t *testing.T) {
+	c := parseTestClass(t)
+	method := c.FindMethod("main", "")
+	code, err := method.GetCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, fixture := range attributeFixtures(t, c) {
+		switch name {
+		case "Code":
+			// main already has one
+		case "StackMapTable", "LineNumberTable", "LocalVariableTable", "LocalVariableTypeTable":
+			addAttribute(t, &c.ConstantPool, &code.Attributes, name, fixture)
+		default:
+			addClassAttribute(t, c, name, fixture)
+		}
+	}
+
+	data, err := c.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	written, err := parsed.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(written, data) {
+		t.Errorf("Bytes() of the parsed class differs from the class it was parsed from")
+	}
+}
@@ -0,0 +1,287 @@
+// Package descriptor parses the field and method descriptors of JVM class files.
+// See https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.3
+package descriptor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxArrayDimensions is the largest number of dimensions an array type may have.
+const MaxArrayDimensions = 255
+
+// BaseType is the descriptor character that identifies a type.
+type BaseType byte
+
+const (
+	Byte    BaseType = 'B'
+	Char    BaseType = 'C'
+	Double  BaseType = 'D'
+	Float   BaseType = 'F'
+	Int     BaseType = 'I'
+	Long    BaseType = 'J'
+	Short   BaseType = 'S'
+	Boolean BaseType = 'Z'
+	Object  BaseType = 'L'
+	// Void is only valid as the return type of a method
+	Void BaseType = 'V'
+)
+
+var baseTypeNames = map[BaseType]string{
+	Byte:    "byte",
+	Char:    "char",
+	Double:  "double",
+	Float:   "float",
+	Int:     "int",
+	Long:    "long",
+	Short:   "short",
+	Boolean: "boolean",
+	Void:    "void",
+}
+
+// FieldType is a parsed field descriptor. For an array type, Dimensions is
+// greater than zero and Base and ClassName describe the element type.
+// ClassName is the internal name of the class for object types, e.g.
+// "java/lang/String", and empty otherwise.
+type FieldType struct {
+	Base       BaseType
+	ClassName  string
+	Dimensions int
+}
+
+// IsArray reports whether the type is an array type.
+func (t FieldType) IsArray() bool {
+	return t.Dimensions > 0
+}
+
+// IsPrimitive reports whether the type is one of the primitive types.
+func (t FieldType) IsPrimitive() bool {
+	return t.Dimensions == 0 && t.Base != Object && t.Base != Void
+}
+
+// IsReference reports whether values of the type are references, i.e. the
+// type is an object or array type.
+func (t FieldType) IsReference() bool {
+	return t.Dimensions > 0 || t.Base == Object
+}
+
+// ElementType returns the type of the elements of an array type, which has
+// one dimension fewer. It returns t unchanged if t is not an array type.
+func (t FieldType) ElementType() FieldType {
+	if t.Dimensions > 0 {
+		t.Dimensions--
+	}
+	return t
+}
+
+// Slots returns the number of local variable or operand stack slots a value
+// of the type occupies: two for long and double, zero for void and one otherwise.
+func (t FieldType) Slots() int {
+	if t.Dimensions > 0 {
+		return 1
+	}
+	switch t.Base {
+	case Long, Double:
+		return 2
+	case Void:
+		return 0
+	default:
+		return 1
+	}
+}
+
+// String returns the type as a descriptor, e.g. "[Ljava/lang/String;".
+func (t FieldType) String() string {
+	var builder strings.Builder
+	for i := 0; i < t.Dimensions; i++ {
+		builder.WriteByte('[')
+	}
+	builder.WriteByte(byte(t.Base))
+	if t.Base == Object {
+		builder.WriteString(t.ClassName)
+		builder.WriteByte(';')
+	}
+	return builder.String()
+}
+
+// JavaName returns the type as it is written in Java source, e.g. "java.lang.String[]".
+func (t FieldType) JavaName() string {
+	var builder strings.Builder
+	if t.Base == Object {
+		builder.WriteString(strings.ReplaceAll(t.ClassName, "/", "."))
+	} else {
+		builder.WriteString(baseTypeNames[t.Base])
+	}
+	for i := 0; i < t.Dimensions; i++ {
+		builder.WriteString("[]")
+	}
+	return builder.String()
+}
+
+// MethodDescriptor is a parsed method descriptor. The return type has Base
+// Void for methods that return no value.
+type MethodDescriptor struct {
+	Params []FieldType
+	Return FieldType
+}
+
+// ArgSlots returns the number of local variable slots taken by the
+// parameters, counting long and double parameters as two slots. It does not
+// include the slot of the receiver of an instance method.
+func (m MethodDescriptor) ArgSlots() int {
+	slots := 0
+	for _, param := range m.Params {
+		slots += param.Slots()
+	}
+	return slots
+}
+
+// ReturnsVoid reports whether the method returns no value.
+func (m MethodDescriptor) ReturnsVoid() bool {
+	return m.Return.Base == Void && m.Return.Dimensions == 0
+}
+
+// String returns the method descriptor, e.g. "(I[Ljava/lang/String;J)V".
+func (m MethodDescriptor) String() string {
+	var builder strings.Builder
+	builder.WriteByte('(')
+	for _, param := range m.Params {
+		builder.WriteString(param.String())
+	}
+	builder.WriteByte(')')
+	builder.WriteString(m.Return.String())
+	return builder.String()
+}
+
+// JavaParams returns the parameter types as written in Java source, e.g.
+// "int, java.lang.String[], long".
+func (m MethodDescriptor) JavaParams() string {
+	names := make([]string, len(m.Params))
+	for i, param := range m.Params {
+		names[i] = param.JavaName()
+	}
+	return strings.Join(names, ", ")
+}
+
+// SyntaxError describes a malformed descriptor.
+type SyntaxError struct {
+	Descriptor string
+	Offset     int
+	Msg        string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid descriptor %q at offset %d: %s", e.Descriptor, e.Offset, e.Msg)
+}
+
+// ParseField parses a field descriptor such as "[[I" or "Ljava/lang/Object;".
+func ParseField(s string) (FieldType, error) {
+	p := parser{s: s}
+	t, err := p.fieldType()
+	if err != nil {
+		return FieldType{}, err
+	}
+	if p.pos != len(s) {
+		return FieldType{}, p.errorf("unexpected trailing characters")
+	}
+	return t, nil
+}
+
+// ParseMethod parses a method descriptor such as "(I[Ljava/lang/String;J)V".
+func ParseMethod(s string) (MethodDescriptor, error) {
+	p := parser{s: s}
+	if !p.consume('(') {
+		return MethodDescriptor{}, p.errorf("expected '('")
+	}
+
+	m := MethodDescriptor{Params: []FieldType{}}
+	for !p.consume(')') {
+		param, err := p.fieldType()
+		if err != nil {
+			return MethodDescriptor{}, err
+		}
+		m.Params = append(m.Params, param)
+	}
+
+	if p.consume('V') {
+		m.Return = FieldType{Base: Void}
+	} else {
+		ret, err := p.fieldType()
+		if err != nil {
+			return MethodDescriptor{}, err
+		}
+		m.Return = ret
+	}
+	if p.pos != len(s) {
+		return MethodDescriptor{}, p.errorf("unexpected trailing characters")
+	}
+	return m, nil
+}
+
+type parser struct {
+	s   string
+	pos int
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Descriptor: p.s, Offset: p.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) consume(c byte) bool {
+	if p.pos < len(p.s) && p.s[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) fieldType() (FieldType, error) {
+	t := FieldType{}
+	for p.consume('[') {
+		t.Dimensions++
+	}
+	if t.Dimensions > MaxArrayDimensions {
+		return FieldType{}, p.errorf("array type has %d dimensions, more than %d", t.Dimensions, MaxArrayDimensions)
+	}
+	if p.pos >= len(p.s) {
+		return FieldType{}, p.errorf("unexpected end of descriptor")
+	}
+
+	t.Base = BaseType(p.s[p.pos])
+	switch t.Base {
+	case Byte, Char, Double, Float, Int, Long, Short, Boolean:
+		p.pos++
+	case Object:
+		p.pos++
+		end := strings.IndexByte(p.s[p.pos:], ';')
+		if end < 0 {
+			return FieldType{}, p.errorf("unterminated class name")
+		}
+		name := p.s[p.pos : p.pos+end]
+		if err := p.checkClassName(name); err != nil {
+			return FieldType{}, err
+		}
+		t.ClassName = name
+		p.pos += end + 1
+	default:
+		return FieldType{}, p.errorf("invalid type character %q", p.s[p.pos])
+	}
+	return t, nil
+}
+
+// checkClassName checks that name is a valid binary class name in internal
+// form: slash separated identifiers that do not contain '.', ';' or '['.
+func (p *parser) checkClassName(name string) error {
+	if name == "" {
+		return p.errorf("empty class name")
+	}
+	for _, segment := range strings.Split(name, "/") {
+		if segment == "" {
+			return p.errorf("class name %q has an empty segment", name)
+		}
+		if strings.ContainsAny(segment, ".[") {
+			return p.errorf("class name %q contains an illegal character", name)
+		}
+	}
+	return nil
+}
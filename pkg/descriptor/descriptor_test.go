@@ -0,0 +1,151 @@
+package descriptor
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseField(t *testing.T) {
+	tests := []struct {
+		descriptor string
+		want       FieldType
+		javaName   string
+		slots      int
+	}{
+		{"I", FieldType{Base: Int}, "int", 1},
+		{"Z", FieldType{Base: Boolean}, "boolean", 1},
+		{"J", FieldType{Base: Long}, "long", 2},
+		{"D", FieldType{Base: Double}, "double", 2},
+		{"Ljava/lang/String;", FieldType{Base: Object, ClassName: "java/lang/String"}, "java.lang.String", 1},
+		{"[I", FieldType{Base: Int, Dimensions: 1}, "int[]", 1},
+		{"[[J", FieldType{Base: Long, Dimensions: 2}, "long[][]", 1},
+		{"[Ljava/lang/Object;", FieldType{Base: Object, ClassName: "java/lang/Object", Dimensions: 1}, "java.lang.Object[]", 1},
+	}
+	for _, test := range tests {
+		t.Run(test.descriptor, func(t *testing.T) {
+			got, err := ParseField(test.descriptor)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("ParseField() = %+v, want %+v", got, test.want)
+			}
+			if s := got.String(); s != test.descriptor {
+				t.Errorf("String() = %q, want %q", s, test.descriptor)
+			}
+			if name := got.JavaName(); name != test.javaName {
+				t.Errorf("JavaName() = %q, want %q", name, test.javaName)
+			}
+			if slots := got.Slots(); slots != test.slots {
+				t.Errorf("Slots() = %d, want %d", slots, test.slots)
+			}
+			if got.IsReference() == got.IsPrimitive() {
+				t.Errorf("IsReference() = IsPrimitive() = %v", got.IsPrimitive())
+			}
+		})
+	}
+}
+
+func TestElementType(t *testing.T) {
+	array, err := ParseField("[[Ljava/lang/String;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	element := array.ElementType()
+	if element.String() != "[Ljava/lang/String;" || !element.IsArray() {
+		t.Errorf("ElementType() = %v, want [Ljava/lang/String;", element)
+	}
+	if element = element.ElementType(); element.String() != "Ljava/lang/String;" || element.IsArray() {
+		t.Errorf("ElementType() = %v, want Ljava/lang/String;", element)
+	}
+}
+
+func TestParseMethod(t *testing.T) {
+	tests := []struct {
+		descriptor string
+		params     []string
+		ret        string
+		argSlots   int
+		javaParams string
+	}{
+		{"()V", nil, "V", 0, ""},
+		{"(I)I", []string{"I"}, "I", 1, "int"},
+		{"(JD)J", []string{"J", "D"}, "J", 4, "long, double"},
+		{"(IJLjava/lang/String;D[J)[Ljava/lang/Object;", []string{"I", "J", "Ljava/lang/String;", "D", "[J"},
+			"[Ljava/lang/Object;", 7, "int, long, java.lang.String, double, long[]"},
+	}
+	for _, test := range tests {
+		t.Run(test.descriptor, func(t *testing.T) {
+			got, err := ParseMethod(test.descriptor)
+			if err != nil {
+				t.Fatal(err)
+			}
+			params := make([]string, len(got.Params))
+			for i, param := range got.Params {
+				params[i] = param.String()
+			}
+			if len(test.params) == 0 {
+				test.params = []string{}
+			}
+			if !reflect.DeepEqual(params, test.params) {
+				t.Errorf("Params = %v, want %v", params, test.params)
+			}
+			if ret := got.Return.String(); ret != test.ret {
+				t.Errorf("Return = %q, want %q", ret, test.ret)
+			}
+			if got.ReturnsVoid() != (test.ret == "V") {
+				t.Errorf("ReturnsVoid() = %v", got.ReturnsVoid())
+			}
+			if slots := got.ArgSlots(); slots != test.argSlots {
+				t.Errorf("ArgSlots() = %d, want %d", slots, test.argSlots)
+			}
+			if javaParams := got.JavaParams(); javaParams != test.javaParams {
+				t.Errorf("JavaParams() = %q, want %q", javaParams, test.javaParams)
+			}
+			if s := got.String(); s != test.descriptor {
+				t.Errorf("String() = %q, want %q", s, test.descriptor)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		descriptor string
+		method     bool
+		want       string
+	}{
+		{"", false, "offset 0: unexpected end of descriptor"},
+		{"L;", false, "offset 1: empty class name"},
+		{"Ljava/lang/String", false, "unterminated class name"},
+		{"Ljava//String;", false, "has an empty segment"},
+		{"Ljava.lang.String;", false, "contains an illegal character"},
+		{"[", false, "offset 1: unexpected end of descriptor"},
+		{"V", false, "invalid type character 'V'"},
+		{"II", false, "offset 1: unexpected trailing characters"},
+		{strings.Repeat("[", 256) + "I", false, "256 dimensions"},
+		{"I)V", true, "offset 0: expected '('"},
+		{"(I", true, "offset 2: unexpected end of descriptor"},
+		{"(IV", true, "invalid type character 'V'"},
+		{"(V)V", true, "invalid type character 'V'"},
+		{"()", true, "unexpected end of descriptor"},
+		{"()VV", true, "unexpected trailing characters"},
+	}
+	for _, test := range tests {
+		t.Run(test.descriptor, func(t *testing.T) {
+			var err error
+			if test.method {
+				_, err = ParseMethod(test.descriptor)
+			} else {
+				_, err = ParseField(test.descriptor)
+			}
+			if _, ok := err.(*SyntaxError); !ok {
+				t.Fatalf("err = %v, want a *SyntaxError", err)
+			}
+			if !strings.Contains(err.Error(), test.want) {
+				t.Errorf("err = %v, want an error containing %q", err, test.want)
+			}
+		})
+	}
+}
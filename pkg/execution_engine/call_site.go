@@ -0,0 +1,20 @@
+package execution_engine
+
+import (
+	"fmt"
+	"lava-vm/pkg/bytecode"
+	"lava-vm/pkg/class"
+)
+
+type CallSite = class.CallSite
+
+// ResolveCallSite returns the call site metadata for an invokedynamic
+// instruction or for an ldc, ldc_w or ldc2_w instruction that loads a dynamic
+// constant.
+func ResolveCallSite(c *Class, instruction Instruction) (*CallSite, error) {
+	switch instruction.Opcode {
+	case bytecode.Ldc, bytecode.LdcW, bytecode.Ldc2W, bytecode.Invokedynamic:
+		return c.ResolveCallSite(instruction.ConstantIndex)
+	}
+	return nil, fmt.Errorf("%s does not reference a call site", instruction.Opcode)
+}
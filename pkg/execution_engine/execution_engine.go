@@ -3,6 +3,7 @@ package execution_engine
 import (
 	"errors"
 	"fmt"
+	"lava-vm/pkg/bytecode"
 	"lava-vm/pkg/class"
 )
 
@@ -74,32 +75,32 @@ func (e *ExecutionEngine) Execute() error {
 	fmt.Println("Parsed Instructions", instructions)
 	for _, instruction := range instructions {
 		switch instruction.Opcode {
-		case 0xbb: // "new" opcode
+		case bytecode.New:
 			objectRef := e.allocateObject()
 			e.stack.Push(objectRef)
 			fmt.Printf("Created new object with reference: %v\n", objectRef)
-		case 0x60: // "iadd" opcode
+		case bytecode.Iadd:
 			err := e.iadd()
 			if err != nil {
 				return err
 			}
-		case 0x64: // "isub" opcode
+		case bytecode.Isub:
 			err := e.isub()
 			if err != nil {
 				return err
 			}
-		case 0x68: // "imul" opcode
+		case bytecode.Imul:
 			err := e.imul()
 			if err != nil {
 				return err
 			}
-		case 0x6c: // "idiv" opcode
+		case bytecode.Idiv:
 			err := e.idiv()
 			if err != nil {
 				return err
 			}
 		default:
-			fmt.Printf("Unhandled instruction: %02x\n", instruction.Opcode)
+			fmt.Printf("Unhandled instruction: %s\n", instruction.Mnemonic())
 		}
 	}
 
@@ -116,19 +117,13 @@ func (e *ExecutionEngine) allocateObject() uint32 {
 }
 
 func (e *ExecutionEngine) getMainMethod() (Method, error) {
-	for _, method := range e.class.Methods {
-		name, err := e.class.GetConstantName(method.NameIndex)
-		if err != nil {
-			return Method{}, err
-		}
-
-		if name == "main" {
-			fmt.Printf("Found main file %+v\n", method)
-			return method, nil
-		}
+	method := e.class.FindMethod("main", "([Ljava/lang/String;)V")
+	if method == nil {
+		return Method{}, errors.New("main method not found in the class")
 	}
 
-	return Method{}, errors.New("main method not found in the class")
+	fmt.Printf("Found main file %+v\n", *method)
+	return *method, nil
 }
 
 func (e *ExecutionEngine) iadd() error {
@@ -0,0 +1,241 @@
+package javap
+
+import (
+	"fmt"
+	"lava-vm/pkg/class"
+	"lava-vm/pkg/descriptor"
+	"strconv"
+	"strings"
+)
+
+// annotations prints a Runtime*Annotations attribute. Each annotation is shown
+// twice, first with constant pool indexes and then resolved, e.g.
+//
+//	0: #19(#20=s#21)
+//	  java.lang.Deprecated(
+//	    since="9"
+//	  )
+func (p *printer) annotations(indent, name string, annotations []class.Annotation) {
+	p.println(indent + name + ":")
+	for i := range annotations {
+		p.annotationEntry(indent+"  ", strconv.Itoa(i)+": ", "", &annotations[i])
+	}
+}
+
+func (p *printer) parameterAnnotations(indent, name string, parameters [][]class.Annotation) {
+	p.println(indent + name + ":")
+	for param, annotations := range parameters {
+		p.printf("%s  parameter %d:", indent, param)
+		for i := range annotations {
+			p.annotationEntry(indent+"    ", strconv.Itoa(i)+": ", "", &annotations[i])
+		}
+	}
+}
+
+// typeAnnotations prints a Runtime*TypeAnnotations attribute, which javap
+// shows like other annotations with the annotated type use after the indexes.
+func (p *printer) typeAnnotations(indent, name string, annotations []class.TypeAnnotation) {
+	p.println(indent + name + ":")
+	for i := range annotations {
+		annotation := &annotations[i]
+		p.annotationEntry(indent+"  ", strconv.Itoa(i)+": ", ": "+typeAnnotationTarget(annotation), &annotation.Annotation)
+	}
+}
+
+// annotationEntry prints prefix and the annotation with its indexes, then
+// suffix, and below that the resolved annotation.
+func (p *printer) annotationEntry(indent, prefix, suffix string, annotation *class.Annotation) {
+	p.println(indent + prefix + annotationRefs(annotation) + suffix)
+	p.annotation(indent+"  ", "", annotation)
+}
+
+func annotationRefs(annotation *class.Annotation) string {
+	pairs := make([]string, len(annotation.ElementValuePairs))
+	for i, pair := range annotation.ElementValuePairs {
+		pairs[i] = ref(pair.ElementNameIndex) + "=" + elementValueRefs(&pair.Value)
+	}
+	return ref(annotation.TypeIndex) + "(" + strings.Join(pairs, ",") + ")"
+}
+
+func elementValueRefs(value *class.ElementValue) string {
+	switch value.Tag {
+	case 'e':
+		return fmt.Sprintf("e#%d.#%d", value.TypeNameIndex, value.ConstNameIndex)
+	case 'c':
+		return fmt.Sprintf("c#%d", value.ClassInfoIndex)
+	case '@':
+		return "@" + annotationRefs(value.AnnotationValue)
+	case '[':
+		values := make([]string, len(value.ArrayValue))
+		for i := range value.ArrayValue {
+			values[i] = elementValueRefs(&value.ArrayValue[i])
+		}
+		return "[" + strings.Join(values, ",") + "]"
+	}
+	return fmt.Sprintf("%c#%d", value.Tag, value.ConstValueIndex)
+}
+
+// annotation prints the resolved annotation, with one line per element. The
+// first line starts with prefix.
+func (p *printer) annotation(indent, prefix string, annotation *class.Annotation) {
+	typeName := descriptorName(annotation.Type)
+	if len(annotation.ElementValuePairs) == 0 {
+		p.println(indent + prefix + typeName)
+		return
+	}
+	p.println(indent + prefix + typeName + "(")
+	for i := range annotation.ElementValuePairs {
+		pair := &annotation.ElementValuePairs[i]
+		p.elementValue(indent+"  ", pair.ElementName+"=", &pair.Value)
+	}
+	p.println(indent + ")")
+}
+
+// elementValue prints a resolved element value. Nested annotations take
+// several lines; everything else fits on one.
+func (p *printer) elementValue(indent, prefix string, value *class.ElementValue) {
+	if value.Tag == '@' {
+		p.annotation(indent, prefix+"@", value.AnnotationValue)
+		return
+	}
+	p.println(indent + prefix + p.elementValueText(value))
+}
+
+func (p *printer) elementValueText(value *class.ElementValue) string {
+	switch value.Tag {
+	case 'B':
+		return "(byte) " + p.constantValue(value.ConstValueIndex)
+	case 'S':
+		return "(short) " + p.constantValue(value.ConstValueIndex)
+	case 'C':
+		if v, ok := p.class.ConstantPool.Get(value.ConstValueIndex).Value.(*class.ConstantIntegerValue); ok {
+			return "'" + escape(string(rune(uint16(v.Value)))) + "'"
+		}
+	case 'Z':
+		if v, ok := p.class.ConstantPool.Get(value.ConstValueIndex).Value.(*class.ConstantIntegerValue); ok {
+			return strconv.FormatBool(v.Value != 0)
+		}
+	case 'D', 'F', 'I', 'J':
+		return p.constantValue(value.ConstValueIndex)
+	case 's':
+		return `"` + escape(p.utf8(value.ConstValueIndex)) + `"`
+	case 'e':
+		return descriptorName(p.utf8(value.TypeNameIndex)) + "." + p.utf8(value.ConstNameIndex)
+	case 'c':
+		return "class " + descriptorName(p.utf8(value.ClassInfoIndex))
+	case '[':
+		values := make([]string, len(value.ArrayValue))
+		for i := range value.ArrayValue {
+			values[i] = p.elementValueText(&value.ArrayValue[i])
+		}
+		return "[" + strings.Join(values, ",") + "]"
+	case '@':
+		// Only annotations inside arrays are written on one line
+		annotation := value.AnnotationValue
+		pairs := make([]string, len(annotation.ElementValuePairs))
+		for i := range annotation.ElementValuePairs {
+			pair := &annotation.ElementValuePairs[i]
+			pairs[i] = pair.ElementName + "=" + p.elementValueText(&pair.Value)
+		}
+		return "@" + descriptorName(annotation.Type) + "(" + strings.Join(pairs, ",") + ")"
+	}
+	return elementValueRefs(value)
+}
+
+// constantValue returns a numeric constant as javap writes it, e.g. "5l".
+func (p *printer) constantValue(index uint16) string {
+	args, _ := p.constantArgs(p.class.ConstantPool.Get(index))
+	if args == "" {
+		return fmt.Sprintf("<invalid #%d>", index)
+	}
+	return args
+}
+
+// descriptorName returns a field descriptor as a Java type, e.g.
+// "java.lang.Deprecated", or the descriptor itself if it cannot be parsed.
+func descriptorName(s string) string {
+	fieldType, err := descriptor.ParseField(s)
+	if err != nil {
+		return s
+	}
+	return fieldType.JavaName()
+}
+
+var targetTypeNames = map[uint8]string{
+	class.TargetClassTypeParameter:           "CLASS_TYPE_PARAMETER",
+	class.TargetMethodTypeParameter:          "METHOD_TYPE_PARAMETER",
+	class.TargetClassExtends:                 "CLASS_EXTENDS",
+	class.TargetClassTypeParameterBound:      "CLASS_TYPE_PARAMETER_BOUND",
+	class.TargetMethodTypeParameterBound:     "METHOD_TYPE_PARAMETER_BOUND",
+	class.TargetField:                        "FIELD",
+	class.TargetMethodReturn:                 "METHOD_RETURN",
+	class.TargetMethodReceiver:               "METHOD_RECEIVER",
+	class.TargetMethodFormalParameter:        "METHOD_FORMAL_PARAMETER",
+	class.TargetThrows:                       "THROWS",
+	class.TargetLocalVariable:                "LOCAL_VARIABLE",
+	class.TargetResourceVariable:             "RESOURCE_VARIABLE",
+	class.TargetExceptionParameter:           "EXCEPTION_PARAMETER",
+	class.TargetInstanceOf:                   "INSTANCEOF",
+	class.TargetNew:                          "NEW",
+	class.TargetConstructorReference:         "CONSTRUCTOR_REFERENCE",
+	class.TargetMethodReference:              "METHOD_REFERENCE",
+	class.TargetCast:                         "CAST",
+	class.TargetConstructorInvocationTypeArg: "CONSTRUCTOR_INVOCATION_TYPE_ARGUMENT",
+	class.TargetMethodInvocationTypeArg:      "METHOD_INVOCATION_TYPE_ARGUMENT",
+	class.TargetConstructorReferenceTypeArg:  "CONSTRUCTOR_REFERENCE_TYPE_ARGUMENT",
+	class.TargetMethodReferenceTypeArg:       "METHOD_REFERENCE_TYPE_ARGUMENT",
+}
+
+var typePathKindNames = []string{"ARRAY", "INNER_TYPE", "WILDCARD", "TYPE_ARGUMENT"}
+
+// typeAnnotationTarget describes the annotated type use as javap does, e.g.
+// "METHOD_FORMAL_PARAMETER, param_index=0, location=[ARRAY]".
+func typeAnnotationTarget(annotation *class.TypeAnnotation) string {
+	name, ok := targetTypeNames[annotation.TargetType]
+	if !ok {
+		name = fmt.Sprintf("0x%02x", annotation.TargetType)
+	}
+	text := []string{name}
+	target := &annotation.TargetInfo
+	switch annotation.TargetType {
+	case class.TargetInstanceOf, class.TargetNew, class.TargetConstructorReference, class.TargetMethodReference:
+		text = append(text, fmt.Sprintf("offset=%d", target.Offset))
+	case class.TargetLocalVariable, class.TargetResourceVariable:
+		ranges := make([]string, len(target.LocalVarTable))
+		for i, entry := range target.LocalVarTable {
+			ranges[i] = fmt.Sprintf("start_pc=%d, length=%d, index=%d", entry.StartPc, entry.Length, entry.Index)
+		}
+		text = append(text, "{"+strings.Join(ranges, "; ")+"}")
+	case class.TargetExceptionParameter:
+		text = append(text, fmt.Sprintf("exception_index=%d", target.ExceptionTableIndex))
+	case class.TargetClassTypeParameter, class.TargetMethodTypeParameter:
+		text = append(text, fmt.Sprintf("param_index=%d", target.TypeParameterIndex))
+	case class.TargetClassTypeParameterBound, class.TargetMethodTypeParameterBound:
+		text = append(text, fmt.Sprintf("param_index=%d, bound_index=%d", target.TypeParameterIndex, target.BoundIndex))
+	case class.TargetClassExtends:
+		// 65535 stands for the superclass and is shown as -1
+		text = append(text, fmt.Sprintf("type_index=%d", int16(target.SupertypeIndex)))
+	case class.TargetThrows:
+		text = append(text, fmt.Sprintf("type_index=%d", target.ThrowsTypeIndex))
+	case class.TargetMethodFormalParameter:
+		text = append(text, fmt.Sprintf("param_index=%d", target.FormalParameterIndex))
+	case class.TargetCast, class.TargetConstructorInvocationTypeArg, class.TargetMethodInvocationTypeArg,
+		class.TargetConstructorReferenceTypeArg, class.TargetMethodReferenceTypeArg:
+		text = append(text, fmt.Sprintf("offset=%d, type_index=%d", target.Offset, target.TypeArgumentIndex))
+	}
+
+	if len(annotation.TargetPath) > 0 {
+		path := make([]string, len(annotation.TargetPath))
+		for i, entry := range annotation.TargetPath {
+			path[i] = fmt.Sprintf("%d", entry.TypePathKind)
+			if int(entry.TypePathKind) < len(typePathKindNames) {
+				path[i] = typePathKindNames[entry.TypePathKind]
+			}
+			if entry.TypePathKind == 3 {
+				path[i] += fmt.Sprintf("(%d)", entry.TypeArgumentIndex)
+			}
+		}
+		text = append(text, "location=["+strings.Join(path, ", ")+"]")
+	}
+	return strings.Join(text, ", ")
+}
@@ -0,0 +1,280 @@
+package javap
+
+import (
+	"fmt"
+	"lava-vm/pkg/class"
+	"strconv"
+	"strings"
+)
+
+func (p *printer) attributes(indent string, attributes []class.Attribute, method *class.Method) {
+	for i := range attributes {
+		p.attribute(indent, &attributes[i], method)
+	}
+}
+
+// attribute prints a single attribute the way javap -v does. Attributes with
+// no decoder are dumped as hex. The method is only used for Code.
+func (p *printer) attribute(indent string, attr *class.Attribute, method *class.Method) {
+	value, err := attr.Decode()
+	if err != nil {
+		p.printf("%s%s: error: %v", indent, attr.Name, err)
+		return
+	}
+
+	switch v := value.(type) {
+	case *class.Code:
+		p.code(indent, method, v)
+	case *class.LineNumberTableAttribute:
+		p.lineNumberTable(indent, v)
+	case *class.LocalVariableTableAttribute:
+		p.localVariableTable(indent, v)
+	case *class.LocalVariableTypeTableAttribute:
+		p.localVariableTypeTable(indent, v)
+	case *class.StackMapTableAttribute:
+		p.stackMapTable(indent, v)
+	case *class.SourceFileAttribute:
+		p.printf("%sSourceFile: %q", indent, p.utf8(v.SourceFileIndex))
+	case *class.SignatureAttribute:
+		p.printf("%s%-40s// %s", indent, fmt.Sprintf("Signature: #%d", v.SignatureIndex), p.utf8(v.SignatureIndex))
+	case *class.ConstantValueAttribute:
+		p.printf("%sConstantValue: %s", indent, p.constant(v.ConstantValueIndex))
+	case *class.ExceptionsAttribute:
+		names := make([]string, len(v.ExceptionIndexTable))
+		for i, index := range v.ExceptionIndexTable {
+			names[i] = javaName(p.className(index))
+		}
+		p.println(indent + "Exceptions:")
+		p.printf("%s  throws %s", indent, strings.Join(names, ", "))
+	case *class.DeprecatedAttribute:
+		p.println(indent + "Deprecated: true")
+	case *class.SyntheticAttribute:
+		p.println(indent + "Synthetic: true")
+	case *class.MethodParametersAttribute:
+		p.println(indent + "MethodParameters:")
+		p.printf("%s  %-30s %s", indent, "Name", "Flags")
+		for _, param := range v.Parameters {
+			name := "<no name>"
+			if param.NameIndex != 0 {
+				name = p.utf8(param.NameIndex)
+			}
+			p.printf("%s  %-30s %s", indent, name, param.AccessFlags)
+		}
+	case *class.EnclosingMethodAttribute:
+		comment := p.className(v.ClassIndex)
+		if v.MethodIndex != 0 {
+			comment += "." + p.constant(v.MethodIndex)
+		}
+		p.printf("%s%-40s// %s", indent, fmt.Sprintf("EnclosingMethod: #%d.#%d", v.ClassIndex, v.MethodIndex), comment)
+	case *class.NestHostAttribute:
+		p.printf("%sNestHost: class %s", indent, p.className(v.HostClassIndex))
+	case *class.NestMembersAttribute:
+		p.classList(indent, "NestMembers", v.Classes)
+	case *class.PermittedSubclassesAttribute:
+		p.classList(indent, "PermittedSubclasses", v.Classes)
+	case *class.InnerClassesAttribute:
+		p.innerClasses(indent, v)
+	case *class.BootstrapMethodsAttribute:
+		p.bootstrapMethods(indent, v)
+	case *class.RuntimeVisibleAnnotationsAttribute:
+		p.annotations(indent, attr.Name, v.Annotations)
+	case *class.RuntimeInvisibleAnnotationsAttribute:
+		p.annotations(indent, attr.Name, v.Annotations)
+	case *class.RuntimeVisibleParameterAnnotationsAttribute:
+		p.parameterAnnotations(indent, attr.Name, v.ParameterAnnotations)
+	case *class.RuntimeInvisibleParameterAnnotationsAttribute:
+		p.parameterAnnotations(indent, attr.Name, v.ParameterAnnotations)
+	case *class.RuntimeVisibleTypeAnnotationsAttribute:
+		p.typeAnnotations(indent, attr.Name, v.Annotations)
+	case *class.RuntimeInvisibleTypeAnnotationsAttribute:
+		p.typeAnnotations(indent, attr.Name, v.Annotations)
+	case *class.AnnotationDefaultAttribute:
+		p.println(indent + "AnnotationDefault:")
+		p.printf("%s  default_value: %s", indent, elementValueRefs(&v.DefaultValue))
+		p.elementValue(indent+"    ", "", &v.DefaultValue)
+	case *class.SourceDebugExtensionAttribute:
+		p.println(indent + "SourceDebugExtension:")
+		for _, line := range strings.FieldsFunc(string(v.DebugExtension), func(r rune) bool { return r == '\r' || r == '\n' }) {
+			p.println(indent + "  " + line)
+		}
+	case *class.RecordAttribute:
+		p.record(indent, v)
+	case *class.ModuleAttribute:
+		p.module(indent, v)
+	case *class.ModulePackagesAttribute:
+		p.println(indent + "ModulePackages:")
+		for _, index := range v.PackageIndex {
+			p.printf("%s  %-38s// %s", indent, ref(index), p.moduleConstant(index))
+		}
+	case *class.ModuleMainClassAttribute:
+		p.printf("%s%-40s// %s", indent, fmt.Sprintf("ModuleMainClass: #%d", v.MainClassIndex), p.className(v.MainClassIndex))
+	default:
+		p.printf("%s%s: length = 0x%x (unknown attribute)", indent, attr.Name, len(attr.Info))
+		p.hexDump(indent+"   ", attr.Info)
+	}
+}
+
+func (p *printer) classList(indent, name string, classes []uint16) {
+	p.println(indent + name + ":")
+	for _, index := range classes {
+		p.printf("%s  %s", indent, p.className(index))
+	}
+}
+
+// innerClasses prints each entry as javap does, e.g.
+//
+//	public static #8= #7 of #2;             // Inner=class Outer$Inner of class Outer
+func (p *printer) innerClasses(indent string, attr *class.InnerClassesAttribute) {
+	p.println(indent + "InnerClasses:")
+	for _, inner := range attr.Classes {
+		text := ref(inner.InnerClassInfoIndex)
+		comment := "class " + p.className(inner.InnerClassInfoIndex)
+		if inner.InnerNameIndex != 0 {
+			text = ref(inner.InnerNameIndex) + "= " + text
+			comment = p.utf8(inner.InnerNameIndex) + "=" + comment
+		}
+		if inner.OuterClassInfoIndex != 0 {
+			text += " of " + ref(inner.OuterClassInfoIndex)
+			comment += " of class " + p.className(inner.OuterClassInfoIndex)
+		}
+		if modifiers := inner.InnerClassAccessFlags.String(); modifiers != "" {
+			text = modifiers + " " + text
+		}
+		p.printf("%s  %-38s// %s", indent, text+";", comment)
+	}
+}
+
+func (p *printer) bootstrapMethods(indent string, attr *class.BootstrapMethodsAttribute) {
+	p.println(indent + "BootstrapMethods:")
+	for i, method := range attr.BootstrapMethods {
+		handle := p.constant(method.BootstrapMethodRef)
+		p.printf("%s  %d: #%d %s", indent, i, method.BootstrapMethodRef, strings.TrimPrefix(handle, "MethodHandle "))
+		p.println(indent + "    Method arguments:")
+		for _, arg := range method.BootstrapArguments {
+			p.printf("%s      #%d %s", indent, arg, p.bootstrapArgument(arg))
+		}
+	}
+}
+
+// bootstrapArgument returns a bootstrap argument as javap shows it, which
+// leaves the kind out for method types and handles.
+func (p *printer) bootstrapArgument(index uint16) string {
+	text := p.constant(index)
+	for _, prefix := range []string{"MethodType ", "MethodHandle "} {
+		if strings.HasPrefix(text, prefix) {
+			return strings.TrimPrefix(text, prefix)
+		}
+	}
+	return text
+}
+
+// record prints the components of a record as they are declared, each
+// followed by its descriptor and attributes.
+func (p *printer) record(indent string, attr *class.RecordAttribute) {
+	p.println(indent + "Record:")
+	for i := range attr.Components {
+		component := &attr.Components[i]
+		typeName := component.Descriptor
+		if generic, err := component.GenericType(); err == nil && generic != nil {
+			typeName = generic.String()
+		} else {
+			typeName = descriptorName(typeName)
+		}
+		p.printf("%s  %s %s;", indent, typeName, component.Name)
+		p.printf("%s    descriptor: %s", indent, component.Descriptor)
+		p.attributes(indent+"    ", component.Attributes, nil)
+		p.println("")
+	}
+}
+
+// module prints the Module attribute as javap does: the module itself, then
+// a count and the entries of each kind of directive, e.g.
+//
+//	1                                       // requires
+//	  #10,8000                              // java.base ACC_MANDATED
+//	  #12                                   // 17
+func (p *printer) module(indent string, attr *class.ModuleAttribute) {
+	p.println(indent + "Module:")
+	indent += "  "
+	p.tab(indent, "", fmt.Sprintf("#%d,%x", attr.ModuleNameIndex, uint16(attr.ModuleFlags)),
+		strings.Join(append([]string{p.moduleConstant(attr.ModuleNameIndex)}, attr.ModuleFlags.Names()...), " "))
+	p.version(indent, "", attr.ModuleVersionIndex)
+
+	p.tab(indent, "", strconv.Itoa(len(attr.Requires)), "requires")
+	for _, requires := range attr.Requires {
+		p.tab(indent, "  ", fmt.Sprintf("#%d,%x", requires.RequiresIndex, uint16(requires.RequiresFlags)),
+			strings.Join(append([]string{p.moduleConstant(requires.RequiresIndex)}, requires.RequiresFlags.Names()...), " "))
+		p.version(indent, "  ", requires.RequiresVersionIndex)
+	}
+
+	p.tab(indent, "", strconv.Itoa(len(attr.Exports)), "exports")
+	for _, exports := range attr.Exports {
+		p.exportsEntry(indent, exports.ExportsIndex, exports.ExportsFlags, exports.ExportsToIndex)
+	}
+	p.tab(indent, "", strconv.Itoa(len(attr.Opens)), "opens")
+	for _, opens := range attr.Opens {
+		p.exportsEntry(indent, opens.OpensIndex, opens.OpensFlags, opens.OpensToIndex)
+	}
+
+	p.tab(indent, "", strconv.Itoa(len(attr.UsesIndex)), "uses")
+	for _, index := range attr.UsesIndex {
+		p.tab(indent, "  ", ref(index), p.className(index))
+	}
+
+	p.tab(indent, "", strconv.Itoa(len(attr.Provides)), "provides")
+	for _, provides := range attr.Provides {
+		p.tab(indent, "  ", ref(provides.ProvidesIndex),
+			fmt.Sprintf("%s with ... %d", p.className(provides.ProvidesIndex), len(provides.ProvidesWithIndex)))
+		for _, index := range provides.ProvidesWithIndex {
+			p.tab(indent, "    ", ref(index), "... with "+p.className(index))
+		}
+	}
+}
+
+// tab prints text indented by indent and nested, followed by comment at the
+// column javap uses for the whole block.
+func (p *printer) tab(indent, nested, text, comment string) {
+	p.printf("%s%s%-*s// %s", indent, nested, 40-len(nested), text, comment)
+}
+
+// version prints the index of a module version, with the version itself when
+// there is one.
+func (p *printer) version(indent, nested string, index uint16) {
+	if index == 0 {
+		p.println(indent + nested + ref(index))
+		return
+	}
+	p.tab(indent, nested, ref(index), p.utf8(index))
+}
+
+func (p *printer) exportsEntry(indent string, index uint16, flags class.ModuleExportsAccessFlags, to []uint16) {
+	comment := strings.Join(append([]string{p.moduleConstant(index)}, flags.Names()...), " ")
+	if len(to) > 0 {
+		comment += fmt.Sprintf(" to ... %d", len(to))
+	}
+	p.tab(indent, "  ", fmt.Sprintf("#%d,%x", index, uint16(flags)), comment)
+	for _, module := range to {
+		p.tab(indent, "    ", ref(module), "... to "+p.moduleConstant(module))
+	}
+}
+
+// moduleConstant returns the name held by a Module or Package constant.
+func (p *printer) moduleConstant(index uint16) string {
+	switch v := p.class.ConstantPool.Get(index).Value.(type) {
+	case *class.ConstantModuleValue:
+		return p.utf8(v.NameIndex)
+	case *class.ConstantPackageValue:
+		return p.utf8(v.NameIndex)
+	}
+	return fmt.Sprintf("<invalid #%d>", index)
+}
+
+func (p *printer) hexDump(indent string, data []byte) {
+	for start := 0; start < len(data); start += 16 {
+		end := start + 16
+		if end > len(data) {
+			end = len(data)
+		}
+		p.printf("%s% x", indent, data[start:end])
+	}
+}
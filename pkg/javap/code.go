@@ -0,0 +1,197 @@
+package javap
+
+import (
+	"fmt"
+	"lava-vm/pkg/bytecode"
+	"lava-vm/pkg/class"
+	"strings"
+)
+
+// code prints a Code attribute. The bytecode and exception table are printed
+// with Code, and the debug tables with Lines; everything else needs Verbose.
+func (p *printer) code(indent string, method *class.Method, code *class.Code) {
+	inner := indent
+	if p.opts.Verbose {
+		inner += "  "
+	}
+
+	if p.opts.Code {
+		p.println(indent + "Code:")
+		if p.opts.Verbose {
+			p.printf("%sstack=%d, locals=%d, args_size=%d", inner, code.MaxStack, code.MaxLocals, argsSize(method))
+		}
+		p.instructions(inner, code)
+		p.exceptionTable(inner, code)
+	}
+
+	for i := range code.Attributes {
+		attr := &code.Attributes[i]
+		switch attr.Name {
+		case "LineNumberTable", "LocalVariableTable", "LocalVariableTypeTable":
+			if !p.opts.Lines {
+				continue
+			}
+		default:
+			if !p.opts.Verbose {
+				continue
+			}
+		}
+		p.attribute(inner, attr, method)
+	}
+}
+
+// argsSize returns the local variable slots taken by the arguments of the
+// method, including the receiver of an instance method.
+func argsSize(method *class.Method) int {
+	size := 0
+	if md, err := method.MethodDescriptor(); err == nil {
+		size = md.ArgSlots()
+	}
+	if !method.AccessFlags.IsStatic() {
+		size++
+	}
+	return size
+}
+
+func (p *printer) instructions(indent string, code *class.Code) {
+	index, err := code.Instructions()
+	if err != nil {
+		p.printf("%sError: %v", indent, err)
+		return
+	}
+
+	for _, instruction := range index.Instructions {
+		mnemonic := instruction.Mnemonic()
+		if instruction.Wide {
+			mnemonic += "_w"
+		}
+
+		if table := instruction.Switch; table != nil {
+			if instruction.Opcode == bytecode.Tableswitch {
+				p.printf("%s%4d: %-13s { // %d to %d", indent, instruction.Offset, mnemonic, table.Low, table.High)
+			} else {
+				p.printf("%s%4d: %-13s { // %d", indent, instruction.Offset, mnemonic, len(table.Keys))
+			}
+			for i, key := range table.Keys {
+				p.printf("%s%18d: %d", indent, key, table.Targets[i])
+			}
+			p.printf("%s%18s: %d", indent, "default", table.DefaultTarget)
+			p.printf("%s      }", indent)
+			continue
+		}
+
+		operands, comment := p.operands(instruction)
+		if comment == "" {
+			p.printf("%s%4d: %-13s %s", indent, instruction.Offset, mnemonic, operands)
+		} else {
+			p.printf("%s%4d: %-13s %-19s // %s", indent, instruction.Offset, mnemonic, operands, comment)
+		}
+	}
+}
+
+// operands returns the operands of an instruction as javap prints them, and
+// the resolved constant for instructions that refer to one.
+func (p *printer) operands(instruction bytecode.Instruction) (string, string) {
+	switch instruction.Opcode {
+	case bytecode.Ldc, bytecode.LdcW, bytecode.Ldc2W,
+		bytecode.Getstatic, bytecode.Putstatic, bytecode.Getfield, bytecode.Putfield,
+		bytecode.Invokevirtual, bytecode.Invokespecial, bytecode.Invokestatic,
+		bytecode.New, bytecode.Anewarray, bytecode.Checkcast, bytecode.Instanceof:
+		return ref(instruction.ConstantIndex), p.constant(instruction.ConstantIndex)
+	case bytecode.Invokeinterface, bytecode.Multianewarray:
+		return fmt.Sprintf("#%d,  %d", instruction.ConstantIndex, instruction.Value), p.constant(instruction.ConstantIndex)
+	case bytecode.Invokedynamic:
+		return fmt.Sprintf("#%d,  0", instruction.ConstantIndex), p.constant(instruction.ConstantIndex)
+	case bytecode.Bipush, bytecode.Sipush:
+		return fmt.Sprint(instruction.Value), ""
+	case bytecode.Newarray:
+		return bytecode.ArrayTypeName(instruction.Value), ""
+	case bytecode.Iinc:
+		return fmt.Sprintf("%d, %d", instruction.LocalIndex, instruction.Value), ""
+	case bytecode.Iload, bytecode.Lload, bytecode.Fload, bytecode.Dload, bytecode.Aload,
+		bytecode.Istore, bytecode.Lstore, bytecode.Fstore, bytecode.Dstore, bytecode.Astore, bytecode.Ret:
+		return fmt.Sprint(instruction.LocalIndex), ""
+	}
+	if instruction.Opcode.IsBranch() {
+		return fmt.Sprint(instruction.Target), ""
+	}
+	return "", ""
+}
+
+func (p *printer) exceptionTable(indent string, code *class.Code) {
+	if len(code.ExceptionTable) == 0 {
+		return
+	}
+	p.println(indent + "Exception table:")
+	p.println(indent + "   from    to  target type")
+	for _, entry := range code.ExceptionTable {
+		catchType := "any"
+		if entry.CatchType != 0 {
+			catchType = "Class " + p.className(entry.CatchType)
+		}
+		p.printf("%s   %5d %5d %5d   %s", indent, entry.StartPc, entry.EndPc, entry.HandlerPc, catchType)
+	}
+}
+
+func (p *printer) lineNumberTable(indent string, table *class.LineNumberTableAttribute) {
+	p.println(indent + "LineNumberTable:")
+	for _, entry := range table.LineNumberTable {
+		p.printf("%s  line %d: %d", indent, entry.LineNumber, entry.StartPc)
+	}
+}
+
+func (p *printer) localVariableTable(indent string, table *class.LocalVariableTableAttribute) {
+	p.println(indent + "LocalVariableTable:")
+	p.println(indent + "  Start  Length  Slot  Name   Signature")
+	for _, entry := range table.LocalVariableTable {
+		p.printf("%s  %5d %7d %5d %5s   %s", indent, entry.StartPc, entry.Length, entry.Index, p.utf8(entry.NameIndex), p.utf8(entry.DescriptorIndex))
+	}
+}
+
+func (p *printer) localVariableTypeTable(indent string, table *class.LocalVariableTypeTableAttribute) {
+	p.println(indent + "LocalVariableTypeTable:")
+	p.println(indent + "  Start  Length  Slot  Name   Signature")
+	for _, entry := range table.LocalVariableTypeTable {
+		p.printf("%s  %5d %7d %5d %5s   %s", indent, entry.StartPc, entry.Length, entry.Index, p.utf8(entry.NameIndex), p.utf8(entry.SignatureIndex))
+	}
+}
+
+// frameTypeNames are the names javap gives the kinds of stack map frame.
+var frameTypeNames = map[class.StackMapFrameKind]string{
+	class.FrameSame:                         "same",
+	class.FrameSameLocals1StackItem:         "same_locals_1_stack_item",
+	class.FrameSameLocals1StackItemExtended: "same_locals_1_stack_item_frame_extended",
+	class.FrameChop:                         "chop",
+	class.FrameSameExtended:                 "same_frame_extended",
+	class.FrameAppend:                       "append",
+	class.FrameFull:                         "full_frame",
+}
+
+func (p *printer) stackMapTable(indent string, table *class.StackMapTableAttribute) {
+	p.printf("%sStackMapTable: number_of_entries = %d", indent, len(table.Entries))
+	for i := range table.Entries {
+		frame := &table.Entries[i]
+		p.printf("%s  frame_type = %d /* %s */", indent, frame.FrameType, frameTypeNames[frame.Kind])
+		if frame.Kind != class.FrameSame && frame.Kind != class.FrameSameLocals1StackItem {
+			p.printf("%s    offset_delta = %d", indent, frame.OffsetDelta)
+		}
+		if frame.Kind == class.FrameAppend || frame.Kind == class.FrameFull {
+			p.printf("%s    locals = [ %s ]", indent, p.verificationTypes(frame.Locals))
+		}
+		if len(frame.Stack) > 0 || frame.Kind == class.FrameFull {
+			p.printf("%s    stack = [ %s ]", indent, p.verificationTypes(frame.Stack))
+		}
+	}
+}
+
+func (p *printer) verificationTypes(types []class.VerificationTypeInfo) string {
+	names := make([]string, len(types))
+	for i, t := range types {
+		if t.Tag == class.ItemObject {
+			names[i] = "class " + p.className(t.CpoolIndex)
+		} else {
+			names[i] = t.String()
+		}
+	}
+	return strings.Join(names, ", ")
+}
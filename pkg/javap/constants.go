@@ -0,0 +1,248 @@
+package javap
+
+import (
+	"fmt"
+	"lava-vm/pkg/class"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// constantPool prints the constant pool the way javap -v does, e.g.
+//
+//	#1 = Methodref          #2.#3          // java/lang/Object."<init>":()V
+func (p *printer) constantPool() {
+	cp := &p.class.ConstantPool
+	p.println("Constant pool:")
+	width := len(strconv.Itoa(cp.Len())) + 1
+	for i := 1; i < cp.Len(); i++ {
+		entry := cp.Get(uint16(i))
+		if entry.Value == nil {
+			// The unusable slot after a Long or Double
+			continue
+		}
+		args, comment := p.constantArgs(entry)
+		line := fmt.Sprintf("%*s = %-18s %s", width+2, "#"+strconv.Itoa(i), class.TagName(entry.Tag), args)
+		if comment != "" {
+			line = fmt.Sprintf("%*s = %-18s %-14s // %s", width+2, "#"+strconv.Itoa(i), class.TagName(entry.Tag), args, comment)
+		}
+		p.println(line)
+	}
+}
+
+// constantArgs returns the operands of a constant pool entry as javap lists
+// them, and the resolved value shown as a comment.
+func (p *printer) constantArgs(entry class.ConstantPoolEntry) (args, comment string) {
+	switch v := entry.Value.(type) {
+	case *class.ConstantUtf8Value:
+		return escape(v.String()), ""
+	case *class.ConstantIntegerValue:
+		return strconv.Itoa(int(v.Value)), ""
+	case *class.ConstantFloatValue:
+		return javaFloat(float64(v.Value), 32) + "f", ""
+	case *class.ConstantLongValue:
+		return strconv.FormatInt(v.Value, 10) + "l", ""
+	case *class.ConstantDoubleValue:
+		return javaFloat(v.Value, 64) + "d", ""
+	case *class.ConstantClassRefValue:
+		return ref(v.Index), quoteArray(p.utf8(v.Index))
+	case *class.ConstantStringRefValue:
+		return ref(v.Index), escape(p.utf8(v.Index))
+	case *class.ConstantFieldRefValue:
+		return ref(v.ClassIndex) + "." + ref(v.NameAndTypeIndex), p.member(v.ClassIndex, v.NameAndTypeIndex, false)
+	case *class.ConstantMethodRefValue:
+		return ref(v.ClassIndex) + "." + ref(v.NameAndTypeIndex), p.member(v.ClassIndex, v.NameAndTypeIndex, false)
+	case *class.ConstantInterfaceMethodRefValue:
+		return ref(v.ClassIndex) + "." + ref(v.NameAndTypeIndex), p.member(v.ClassIndex, v.NameAndTypeIndex, false)
+	case *class.ConstantNameAndTypeDescriptorValue:
+		return ref(v.NameIndex) + ":" + ref(v.DescriptorIndex), p.nameAndType(v.NameIndex, v.DescriptorIndex)
+	case *class.ConstantMethodHandleValue:
+		return fmt.Sprintf("%d:%s", v.ReferenceKind, ref(v.ReferenceIndex)), p.methodHandle(v)
+	case *class.ConstantMethodTypeValue:
+		return ref(v.DescriptorIndex), p.utf8(v.DescriptorIndex)
+	case *class.ConstantDynamicValue:
+		return fmt.Sprintf("#%d:%s", v.BootstrapMethodAttrIndex, ref(v.NameAndTypeIndex)), p.dynamic(v.BootstrapMethodAttrIndex, v.NameAndTypeIndex)
+	case *class.ConstantInvokeDynamicValue:
+		return fmt.Sprintf("#%d:%s", v.BootstrapMethodAttrIndex, ref(v.NameAndTypeIndex)), p.dynamic(v.BootstrapMethodAttrIndex, v.NameAndTypeIndex)
+	case *class.ConstantModuleValue:
+		return ref(v.NameIndex), p.utf8(v.NameIndex)
+	case *class.ConstantPackageValue:
+		return ref(v.NameIndex), p.utf8(v.NameIndex)
+	}
+	return "", ""
+}
+
+// constant returns the constant at index as javap describes it in the
+// comment of an instruction or attribute, e.g. "Method java/io/PrintStream.println:(Ljava/lang/String;)V".
+// Members of the class being printed are shown without their owner.
+func (p *printer) constant(index uint16) string {
+	entry := p.class.ConstantPool.Get(index)
+	switch v := entry.Value.(type) {
+	case *class.ConstantIntegerValue:
+		return "int " + strconv.Itoa(int(v.Value))
+	case *class.ConstantFloatValue:
+		return "float " + javaFloat(float64(v.Value), 32) + "f"
+	case *class.ConstantLongValue:
+		return "long " + strconv.FormatInt(v.Value, 10) + "l"
+	case *class.ConstantDoubleValue:
+		return "double " + javaFloat(v.Value, 64) + "d"
+	case *class.ConstantClassRefValue:
+		return "class " + quoteArray(p.utf8(v.Index))
+	case *class.ConstantStringRefValue:
+		return "String " + escape(p.utf8(v.Index))
+	case *class.ConstantFieldRefValue:
+		return "Field " + p.member(v.ClassIndex, v.NameAndTypeIndex, true)
+	case *class.ConstantMethodRefValue:
+		return "Method " + p.member(v.ClassIndex, v.NameAndTypeIndex, true)
+	case *class.ConstantInterfaceMethodRefValue:
+		return "InterfaceMethod " + p.member(v.ClassIndex, v.NameAndTypeIndex, true)
+	case *class.ConstantMethodHandleValue:
+		return "MethodHandle " + p.methodHandle(v)
+	case *class.ConstantMethodTypeValue:
+		return "MethodType " + p.utf8(v.DescriptorIndex)
+	case *class.ConstantDynamicValue:
+		return "Dynamic " + p.dynamic(v.BootstrapMethodAttrIndex, v.NameAndTypeIndex)
+	case *class.ConstantInvokeDynamicValue:
+		return "InvokeDynamic " + p.dynamic(v.BootstrapMethodAttrIndex, v.NameAndTypeIndex)
+	case *class.ConstantModuleValue:
+		return "Module " + p.utf8(v.NameIndex)
+	case *class.ConstantPackageValue:
+		return "Package " + p.utf8(v.NameIndex)
+	case *class.ConstantUtf8Value:
+		return escape(v.String())
+	}
+	return fmt.Sprintf("invalid constant #%d", index)
+}
+
+func ref(index uint16) string {
+	return "#" + strconv.Itoa(int(index))
+}
+
+// utf8 returns the Utf8 constant at index, or a placeholder naming the index.
+func (p *printer) utf8(index uint16) string {
+	s, err := p.class.ConstantPool.Utf8(index)
+	if err != nil {
+		return fmt.Sprintf("<invalid #%d>", index)
+	}
+	return s
+}
+
+// className returns the internal name of the Class constant at index,
+// quoted when it is an array descriptor as javap does.
+func (p *printer) className(index uint16) string {
+	name, err := p.class.ConstantPool.ClassRef(index)
+	if err != nil {
+		return fmt.Sprintf("<invalid #%d>", index)
+	}
+	return quoteArray(name)
+}
+
+func quoteArray(name string) string {
+	if strings.HasPrefix(name, "[") {
+		return strconv.Quote(name)
+	}
+	return name
+}
+
+// nameAndType returns name:descriptor, quoting special method names.
+func (p *printer) nameAndType(nameIndex, descriptorIndex uint16) string {
+	name := p.utf8(nameIndex)
+	if strings.HasPrefix(name, "<") {
+		name = `"` + name + `"`
+	}
+	return name + ":" + p.utf8(descriptorIndex)
+}
+
+// member returns owner.name:descriptor for a member reference. With
+// omitThis the owner is left out when it is the class being printed.
+func (p *printer) member(classIndex, nameAndTypeIndex uint16, omitThis bool) string {
+	entry := p.class.ConstantPool.Get(nameAndTypeIndex)
+	nat, ok := entry.Value.(*class.ConstantNameAndTypeDescriptorValue)
+	if !ok {
+		return fmt.Sprintf("<invalid #%d>", nameAndTypeIndex)
+	}
+	text := p.nameAndType(nat.NameIndex, nat.DescriptorIndex)
+	if omitThis && p.className(classIndex) == p.thisName {
+		return text
+	}
+	return p.className(classIndex) + "." + text
+}
+
+func (p *printer) methodHandle(v *class.ConstantMethodHandleValue) string {
+	entry := p.class.ConstantPool.Get(v.ReferenceIndex)
+	var member string
+	switch r := entry.Value.(type) {
+	case *class.ConstantFieldRefValue:
+		member = p.member(r.ClassIndex, r.NameAndTypeIndex, false)
+	case *class.ConstantMethodRefValue:
+		member = p.member(r.ClassIndex, r.NameAndTypeIndex, false)
+	case *class.ConstantInterfaceMethodRefValue:
+		member = p.member(r.ClassIndex, r.NameAndTypeIndex, false)
+	default:
+		member = fmt.Sprintf("<invalid #%d>", v.ReferenceIndex)
+	}
+	return v.ReferenceKind.String() + " " + member
+}
+
+func (p *printer) dynamic(bootstrapIndex, nameAndTypeIndex uint16) string {
+	entry := p.class.ConstantPool.Get(nameAndTypeIndex)
+	nat, ok := entry.Value.(*class.ConstantNameAndTypeDescriptorValue)
+	if !ok {
+		return fmt.Sprintf("#%d:<invalid #%d>", bootstrapIndex, nameAndTypeIndex)
+	}
+	return fmt.Sprintf("#%d:%s", bootstrapIndex, p.nameAndType(nat.NameIndex, nat.DescriptorIndex))
+}
+
+// javaFloat formats v as Java's Float.toString and Double.toString do, e.g.
+// "1.0", "1.0E10" or "NaN".
+func javaFloat(v float64, bitSize int) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "Infinity"
+	case math.IsInf(v, -1):
+		return "-Infinity"
+	}
+
+	if abs := math.Abs(v); abs == 0 || (abs >= 1e-3 && abs < 1e7) {
+		s := strconv.FormatFloat(v, 'f', -1, bitSize)
+		if !strings.Contains(s, ".") {
+			s += ".0"
+		}
+		return s
+	}
+
+	mantissa, exponent, _ := strings.Cut(strconv.FormatFloat(v, 'E', -1, bitSize), "E")
+	if !strings.Contains(mantissa, ".") {
+		mantissa += ".0"
+	}
+	n, _ := strconv.Atoi(exponent)
+	return mantissa + "E" + strconv.Itoa(n)
+}
+
+// escape escapes the control characters of a string constant.
+func escape(s string) string {
+	var builder strings.Builder
+	for _, r := range s {
+		switch r {
+		case '\t':
+			builder.WriteString(`\t`)
+		case '\n':
+			builder.WriteString(`\n`)
+		case '\r':
+			builder.WriteString(`\r`)
+		case '\b':
+			builder.WriteString(`\b`)
+		case '\f':
+			builder.WriteString(`\f`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&builder, `\u%04x`, r)
+			} else {
+				builder.WriteRune(r)
+			}
+		}
+	}
+	return builder.String()
+}
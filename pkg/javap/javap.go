@@ -0,0 +1,273 @@
+// Package javap prints class files in the format of the JDK javap tool, so
+// that classes can be inspected without a JDK installed.
+package javap
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"lava-vm/pkg/class"
+	"strings"
+	"time"
+)
+
+// Options select what Disassemble prints. They correspond to the javap flags
+// of the same letter.
+type Options struct {
+	// Code (-c) prints the disassembled bytecode and exception tables.
+	Code bool
+	// Verbose (-v) prints everything: the constant pool, flags, stack sizes,
+	// stack maps and every attribute. It implies Code, Lines and Descriptors.
+	Verbose bool
+	// Private (-p) prints private members, which are hidden otherwise.
+	Private bool
+	// Lines (-l) prints the line number and local variable tables.
+	Lines bool
+	// Descriptors (-s) prints the internal type descriptor of each member.
+	Descriptors bool
+}
+
+// printer writes one class. It keeps the first write error and drops the
+// output after it, which is reported by Disassemble.
+type printer struct {
+	w        *bufio.Writer
+	class    *class.Class
+	opts     Options
+	thisName string
+}
+
+func (p *printer) println(line string) {
+	p.w.WriteString(strings.TrimRight(line, " "))
+	p.w.WriteByte('\n')
+}
+
+func (p *printer) printf(format string, args ...interface{}) {
+	p.println(fmt.Sprintf(format, args...))
+}
+
+// FileHeader writes the lines javap -v prints about the class file itself:
+// its path, modification time, size and checksum.
+func FileHeader(w io.Writer, path string, modified time.Time, data []byte) error {
+	_, err := fmt.Fprintf(w, "Classfile %s\n  Last modified %s; size %d bytes\n  SHA-256 checksum %x\n",
+		path, modified.Format("Jan 2, 2006"), len(data), sha256.Sum256(data))
+	return err
+}
+
+// Disassemble writes c to w as javap would with the given options.
+func Disassemble(w io.Writer, c *class.Class, opts Options) error {
+	if opts.Verbose {
+		opts.Code, opts.Lines, opts.Descriptors = true, true, true
+	}
+	thisName, err := c.ThisClassName()
+	if err != nil {
+		return err
+	}
+
+	p := &printer{w: bufio.NewWriter(w), class: c, opts: opts, thisName: thisName}
+	p.classFile()
+	return p.w.Flush()
+}
+
+func (p *printer) classFile() {
+	c := p.class
+	if source, err := c.SourceFile(); err == nil && source != nil {
+		indent := ""
+		if p.opts.Verbose {
+			indent = "  "
+		}
+		p.printf("%sCompiled from %q", indent, p.utf8(source.SourceFileIndex))
+	}
+
+	if !p.opts.Verbose {
+		p.println(p.classDeclaration() + " {")
+	} else {
+		p.println(p.classDeclaration())
+		p.printf("  minor version: %d", c.MinorVersion)
+		p.printf("  major version: %d", c.MajorVersion)
+		p.printf("  flags: (0x%04x) %s", uint16(c.AccessFlags), strings.Join(c.AccessFlags.Names(), ", "))
+		p.printf("  %-40s// %s", fmt.Sprintf("this_class: #%d", c.ThisClass), p.className(c.ThisClass))
+		if c.SuperClass == 0 {
+			p.printf("  super_class: #0")
+		} else {
+			p.printf("  %-40s// %s", fmt.Sprintf("super_class: #%d", c.SuperClass), p.className(c.SuperClass))
+		}
+		p.printf("  interfaces: %d, fields: %d, methods: %d, attributes: %d", len(c.Interfaces), len(c.Fields), len(c.Methods), len(c.Attributes))
+		p.constantPool()
+		p.println("{")
+	}
+
+	first := true
+	separate := func() {
+		// Members are only separated when they print more than a declaration
+		if !first && (p.opts.Code || p.opts.Lines || p.opts.Descriptors) {
+			p.println("")
+		}
+		first = false
+	}
+	for i := range c.Fields {
+		field := &c.Fields[i]
+		if field.AccessFlags.IsPrivate() && !p.opts.Private {
+			continue
+		}
+		separate()
+		p.field(field)
+	}
+	for i := range c.Methods {
+		method := &c.Methods[i]
+		if method.AccessFlags.IsPrivate() && !p.opts.Private {
+			continue
+		}
+		separate()
+		p.method(method)
+	}
+	p.println("}")
+
+	if p.opts.Verbose {
+		p.attributes("", c.Attributes, nil)
+	}
+}
+
+// classDeclaration returns the class header, e.g.
+// "public class Foo extends Bar implements java.lang.Runnable".
+func (p *printer) classDeclaration() string {
+	c := p.class
+	flags := c.AccessFlags
+	name := javaName(p.thisName)
+	if flags.IsModule() {
+		if descriptor, err := c.ModuleDescriptor(); err == nil {
+			name = descriptor.Name
+		}
+		return "module " + name
+	}
+
+	keyword := "class"
+	if flags.IsInterface() {
+		keyword = "interface"
+	}
+	modifiers := flags.String()
+	if modifiers != "" {
+		modifiers += " "
+	}
+
+	if sig, err := c.GenericSignature(); err == nil && sig != nil {
+		return modifiers + keyword + " " + sig.Format(name, true)
+	}
+
+	declaration := modifiers + keyword + " " + name
+	if superName, err := c.SuperClassName(); err == nil && superName != "" && superName != "java/lang/Object" && !flags.IsInterface() {
+		declaration += " extends " + javaName(superName)
+	}
+	if interfaces, err := c.InterfaceNames(); err == nil && len(interfaces) > 0 {
+		names := make([]string, len(interfaces))
+		for i, iface := range interfaces {
+			names[i] = javaName(iface)
+		}
+		if flags.IsInterface() {
+			declaration += " extends "
+		} else {
+			declaration += " implements "
+		}
+		declaration += strings.Join(names, ", ")
+	}
+	return declaration
+}
+
+func (p *printer) field(field *class.Field) {
+	typeName := field.Descriptor()
+	if generic, err := field.GenericType(); err == nil && generic != nil {
+		typeName = generic.String()
+	} else if fieldType, err := field.FieldType(); err == nil {
+		typeName = fieldType.JavaName()
+	}
+	modifiers := field.AccessFlags.String()
+	if modifiers != "" {
+		modifiers += " "
+	}
+	p.printf("  %s%s %s;", modifiers, typeName, field.Name())
+
+	if p.opts.Descriptors {
+		p.printf("    descriptor: %s", field.Descriptor())
+	}
+	if p.opts.Verbose {
+		p.printf("    flags: (0x%04x) %s", uint16(field.AccessFlags), strings.Join(field.AccessFlags.Names(), ", "))
+		p.attributes("    ", field.Attributes, nil)
+	}
+}
+
+func (p *printer) method(method *class.Method) {
+	p.println("  " + p.methodDeclaration(method) + ";")
+	if p.opts.Descriptors {
+		p.printf("    descriptor: %s", method.Descriptor())
+	}
+	if p.opts.Verbose {
+		p.printf("    flags: (0x%04x) %s", uint16(method.AccessFlags), strings.Join(method.AccessFlags.Names(), ", "))
+		p.attributes("    ", method.Attributes, method)
+		return
+	}
+
+	if code, err := method.GetCode(); err == nil && (p.opts.Code || p.opts.Lines) {
+		p.code("    ", method, code)
+	}
+}
+
+// methodDeclaration returns the method as it is declared in Java source,
+// e.g. "public static void main(java.lang.String...)".
+func (p *printer) methodDeclaration(method *class.Method) string {
+	name := method.Name()
+	flags := method.AccessFlags
+	if name == "<clinit>" {
+		return "static {}"
+	}
+
+	modifiers := flags.String()
+	if p.class.AccessFlags.IsInterface() && !flags.IsStatic() && !flags.IsAbstract() && !flags.IsPrivate() {
+		modifiers = strings.TrimSpace(modifiers + " default")
+	}
+	if modifiers != "" {
+		modifiers += " "
+	}
+
+	isConstructor := name == "<init>"
+	if isConstructor {
+		name = javaName(p.thisName)
+	}
+
+	var declaration string
+	hasThrows := false
+	if sig, err := method.GenericSignature(); err == nil && sig != nil {
+		declaration = sig.Format(name, true)
+		if isConstructor {
+			declaration = strings.Replace(declaration, "void "+name+"(", name+"(", 1)
+		}
+		hasThrows = len(sig.Throws) > 0
+	} else if md, err := method.MethodDescriptor(); err == nil {
+		declaration = name + "(" + md.JavaParams() + ")"
+		if !isConstructor {
+			declaration = md.Return.JavaName() + " " + declaration
+		}
+	} else {
+		declaration = name + method.Descriptor()
+	}
+
+	if flags.IsVarargs() {
+		// The last parameter is an array written as T... in source
+		if end := strings.LastIndex(declaration, ")"); end >= 2 && declaration[end-2:end] == "[]" {
+			declaration = declaration[:end-2] + "..." + declaration[end:]
+		}
+	}
+
+	if exceptions, err := method.Exceptions(); err == nil && exceptions != nil && !hasThrows && len(exceptions.ExceptionIndexTable) > 0 {
+		names := make([]string, len(exceptions.ExceptionIndexTable))
+		for i, index := range exceptions.ExceptionIndexTable {
+			names[i] = javaName(p.className(index))
+		}
+		declaration += " throws " + strings.Join(names, ", ")
+	}
+	return modifiers + declaration
+}
+
+// javaName converts an internal class name to the form used in Java source.
+func javaName(internalName string) string {
+	return strings.ReplaceAll(internalName, "/", ".")
+}
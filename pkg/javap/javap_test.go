@@ -0,0 +1,78 @@
+package javap
+
+import (
+	"bytes"
+	"flag"
+	"lava-vm/pkg/assembler"
+	"lava-vm/pkg/class"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the .golden files in testdata")
+
+// TestGolden disassembles tst/Test.class with each option and the classes
+// assembled from testdata/*.j with -v -p, and compares the output with the
+// .golden file named after the class and the options.
+func TestGolden(t *testing.T) {
+	type goldenTest struct {
+		name  string
+		path  string
+		flags string
+		opts  Options
+	}
+	tests := []goldenTest{
+		{"Test", "../../tst/Test.class", "", Options{}},
+		{"Test", "../../tst/Test.class", "-c", Options{Code: true}},
+		{"Test", "../../tst/Test.class", "-v", Options{Verbose: true}},
+		{"Test", "../../tst/Test.class", "-p", Options{Private: true}},
+		{"Test", "../../tst/Test.class", "-l", Options{Lines: true}},
+		{"Test", "../../tst/Test.class", "-s", Options{Descriptors: true}},
+	}
+	sources, err := filepath.Glob("testdata/*.j")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, source := range sources {
+		name := filepath.Base(source[:len(source)-len(".j")])
+		tests = append(tests, goldenTest{name, source, "-v-p", Options{Verbose: true, Private: true}})
+	}
+
+	for _, test := range tests {
+		t.Run(test.name+test.flags, func(t *testing.T) {
+			data, err := os.ReadFile(test.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if filepath.Ext(test.path) == ".j" {
+				if data, err = assembler.Assemble(bytes.NewReader(data)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			c, err := class.ParseBytes(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := Disassemble(&buf, c, test.opts); err != nil {
+				t.Fatal(err)
+			}
+			got := buf.Bytes()
+
+			golden := filepath.Join("testdata", test.name+test.flags+".golden")
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("output differs from %s:\n%s", golden, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package signature
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SyntaxError describes a malformed signature.
+type SyntaxError struct {
+	Signature string
+	Offset    int
+	Msg       string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid signature %q at offset %d: %s", e.Signature, e.Offset, e.Msg)
+}
+
+// ParseClass parses a ClassSignature, e.g.
+// "<T:Ljava/lang/Object;>Ljava/util/AbstractList<TT;>;Ljava/io/Serializable;".
+func ParseClass(s string) (*ClassSignature, error) {
+	p := parser{s: s}
+	sig := &ClassSignature{}
+	var err error
+	if sig.TypeParams, err = p.typeParameters(); err != nil {
+		return nil, err
+	}
+	if sig.SuperClass, err = p.classType(); err != nil {
+		return nil, err
+	}
+	for !p.done() {
+		iface, err := p.classType()
+		if err != nil {
+			return nil, err
+		}
+		sig.Interfaces = append(sig.Interfaces, iface)
+	}
+	return sig, nil
+}
+
+// ParseMethod parses a MethodSignature, e.g.
+// "<T:Ljava/lang/Object;>([TT;)Ljava/util/List<TT;>;^Ljava/io/IOException;".
+func ParseMethod(s string) (*MethodSignature, error) {
+	p := parser{s: s}
+	sig := &MethodSignature{}
+	var err error
+	if sig.TypeParams, err = p.typeParameters(); err != nil {
+		return nil, err
+	}
+	if !p.consume('(') {
+		return nil, p.errorf("expected '('")
+	}
+	for !p.consume(')') {
+		param, err := p.javaType()
+		if err != nil {
+			return nil, err
+		}
+		sig.Params = append(sig.Params, param)
+	}
+	if !p.consume('V') {
+		if sig.Result, err = p.javaType(); err != nil {
+			return nil, err
+		}
+	}
+	for p.consume('^') {
+		var thrown ReferenceType
+		if p.peek() == 'T' {
+			thrown, err = p.typeVariable()
+		} else {
+			thrown, err = p.classType()
+		}
+		if err != nil {
+			return nil, err
+		}
+		sig.Throws = append(sig.Throws, thrown)
+	}
+	if !p.done() {
+		return nil, p.errorf("unexpected trailing characters")
+	}
+	return sig, nil
+}
+
+// ParseField parses a FieldSignature, which is also the form used by record
+// components and local variables, e.g. "Ljava/util/List<Ljava/lang/String;>;".
+func ParseField(s string) (ReferenceType, error) {
+	p := parser{s: s}
+	t, err := p.referenceType()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, p.errorf("unexpected trailing characters")
+	}
+	return t, nil
+}
+
+type parser struct {
+	s   string
+	pos int
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Signature: p.s, Offset: p.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.s)
+}
+
+func (p *parser) peek() byte {
+	if p.done() {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *parser) consume(c byte) bool {
+	if p.peek() == c && !p.done() {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// identifier reads an unqualified name, stopping at any of the characters
+// that may not appear in one.
+func (p *parser) identifier() (string, error) {
+	start := p.pos
+	for !p.done() && !strings.ContainsRune(".;[/<>:", rune(p.s[p.pos])) {
+		p.pos++
+	}
+	if p.pos == start {
+		return "", p.errorf("expected identifier")
+	}
+	return p.s[start:p.pos], nil
+}
+
+func (p *parser) typeParameters() ([]TypeParameter, error) {
+	if !p.consume('<') {
+		return nil, nil
+	}
+	var params []TypeParameter
+	for !p.consume('>') {
+		param := TypeParameter{}
+		var err error
+		if param.Name, err = p.identifier(); err != nil {
+			return nil, err
+		}
+		if !p.consume(':') {
+			return nil, p.errorf("expected ':' after type parameter %s", param.Name)
+		}
+		// The class bound may be empty when the parameter has only interface bounds
+		if c := p.peek(); c == 'L' || c == 'T' || c == '[' {
+			if param.ClassBound, err = p.referenceType(); err != nil {
+				return nil, err
+			}
+		}
+		for p.consume(':') {
+			bound, err := p.referenceType()
+			if err != nil {
+				return nil, err
+			}
+			param.InterfaceBounds = append(param.InterfaceBounds, bound)
+		}
+		params = append(params, param)
+	}
+	if len(params) == 0 {
+		return nil, p.errorf("empty type parameter list")
+	}
+	return params, nil
+}
+
+func (p *parser) javaType() (Type, error) {
+	if _, ok := baseTypeNames[BaseType(p.peek())]; ok {
+		t := BaseType(p.peek())
+		p.pos++
+		return t, nil
+	}
+	return p.referenceType()
+}
+
+func (p *parser) referenceType() (ReferenceType, error) {
+	switch p.peek() {
+	case 'L':
+		return p.classType()
+	case 'T':
+		return p.typeVariable()
+	case '[':
+		p.pos++
+		elem, err := p.javaType()
+		if err != nil {
+			return nil, err
+		}
+		return &ArrayType{Elem: elem}, nil
+	default:
+		return nil, p.errorf("expected reference type")
+	}
+}
+
+func (p *parser) typeVariable() (*TypeVariable, error) {
+	if !p.consume('T') {
+		return nil, p.errorf("expected type variable")
+	}
+	name, err := p.identifier()
+	if err != nil {
+		return nil, err
+	}
+	if !p.consume(';') {
+		return nil, p.errorf("expected ';' after type variable %s", name)
+	}
+	return &TypeVariable{Name: name}, nil
+}
+
+func (p *parser) classType() (*ClassType, error) {
+	if !p.consume('L') {
+		return nil, p.errorf("expected class type")
+	}
+
+	t := &ClassType{}
+	var segments []string
+	for {
+		name, err := p.identifier()
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume('/') {
+			t.Package = strings.Join(segments, "/")
+			t.Classes = []SimpleClassType{{Name: name}}
+			break
+		}
+		segments = append(segments, name)
+	}
+
+	for {
+		class := &t.Classes[len(t.Classes)-1]
+		args, err := p.typeArguments()
+		if err != nil {
+			return nil, err
+		}
+		class.TypeArgs = args
+
+		if !p.consume('.') {
+			break
+		}
+		name, err := p.identifier()
+		if err != nil {
+			return nil, err
+		}
+		t.Classes = append(t.Classes, SimpleClassType{Name: name})
+	}
+
+	if !p.consume(';') {
+		return nil, p.errorf("expected ';' after class type")
+	}
+	return t, nil
+}
+
+func (p *parser) typeArguments() ([]TypeArgument, error) {
+	if !p.consume('<') {
+		return nil, nil
+	}
+	var args []TypeArgument
+	for !p.consume('>') {
+		arg := TypeArgument{}
+		switch p.peek() {
+		case '*':
+			p.pos++
+			arg.Wildcard = Unbounded
+			args = append(args, arg)
+			continue
+		case '+', '-':
+			arg.Wildcard = Wildcard(p.peek())
+			p.pos++
+		}
+		var err error
+		if arg.Type, err = p.referenceType(); err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	if len(args) == 0 {
+		return nil, p.errorf("empty type argument list")
+	}
+	return args, nil
+}
@@ -0,0 +1,302 @@
+// Package signature parses the generic signatures held by Signature attributes
+// into a type tree that prints as Java source.
+// See https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.9.1
+package signature
+
+import (
+	"strings"
+)
+
+// Type is a JavaTypeSignature: a base type or a reference type.
+type Type interface {
+	// String returns the type as Java source with fully qualified class
+	// names, e.g. "java.util.Map<K, java.util.List<? extends V>>".
+	String() string
+	// SimpleString returns the type as Java source with unqualified class
+	// names, e.g. "Map<K, List<? extends V>>".
+	SimpleString() string
+	write(builder *strings.Builder, qualified bool)
+}
+
+// ReferenceType is a class type, type variable or array type.
+type ReferenceType interface {
+	Type
+	isReferenceType()
+}
+
+// BaseType is a primitive type, identified by its descriptor character.
+type BaseType byte
+
+var baseTypeNames = map[BaseType]string{
+	'B': "byte",
+	'C': "char",
+	'D': "double",
+	'F': "float",
+	'I': "int",
+	'J': "long",
+	'S': "short",
+	'Z': "boolean",
+}
+
+func (t BaseType) write(builder *strings.Builder, qualified bool) {
+	builder.WriteString(baseTypeNames[t])
+}
+
+func (t BaseType) String() string       { return format(t, true) }
+func (t BaseType) SimpleString() string { return format(t, false) }
+
+// SimpleClassType is one class in a possibly nested class type, with its type arguments.
+type SimpleClassType struct {
+	Name     string
+	TypeArgs []TypeArgument
+}
+
+// ClassType is a class or interface type. Package is in internal form, e.g.
+// "java/util", and empty for the unnamed package. Classes holds the outermost
+// class first, followed by each inner class that is written with its own type
+// arguments, as in Outer<T>.Inner<U>.
+type ClassType struct {
+	Package string
+	Classes []SimpleClassType
+}
+
+func (*ClassType) isReferenceType() {}
+
+func (t *ClassType) write(builder *strings.Builder, qualified bool) {
+	if qualified && t.Package != "" {
+		builder.WriteString(strings.ReplaceAll(t.Package, "/", "."))
+		builder.WriteByte('.')
+	}
+	for i, class := range t.Classes {
+		if i > 0 {
+			builder.WriteByte('.')
+		}
+		builder.WriteString(class.Name)
+		writeTypeArguments(builder, class.TypeArgs, qualified)
+	}
+}
+
+func (t *ClassType) String() string       { return format(t, true) }
+func (t *ClassType) SimpleString() string { return format(t, false) }
+
+// InternalName returns the erased class name in internal form, e.g. "java/util/Map$Entry".
+func (t *ClassType) InternalName() string {
+	var builder strings.Builder
+	if t.Package != "" {
+		builder.WriteString(t.Package)
+		builder.WriteByte('/')
+	}
+	for i, class := range t.Classes {
+		if i > 0 {
+			builder.WriteByte('$')
+		}
+		builder.WriteString(class.Name)
+	}
+	return builder.String()
+}
+
+// TypeVariable is a use of a type parameter, e.g. T.
+type TypeVariable struct {
+	Name string
+}
+
+func (*TypeVariable) isReferenceType() {}
+
+func (t *TypeVariable) write(builder *strings.Builder, qualified bool) {
+	builder.WriteString(t.Name)
+}
+
+func (t *TypeVariable) String() string       { return format(t, true) }
+func (t *TypeVariable) SimpleString() string { return format(t, false) }
+
+// ArrayType is an array of Elem.
+type ArrayType struct {
+	Elem Type
+}
+
+func (*ArrayType) isReferenceType() {}
+
+func (t *ArrayType) write(builder *strings.Builder, qualified bool) {
+	t.Elem.write(builder, qualified)
+	builder.WriteString("[]")
+}
+
+func (t *ArrayType) String() string       { return format(t, true) }
+func (t *ArrayType) SimpleString() string { return format(t, false) }
+
+// Wildcard is the wildcard indicator of a type argument.
+type Wildcard byte
+
+const (
+	// NoWildcard is an exact type argument, e.g. List<String>
+	NoWildcard Wildcard = 0
+	// Extends is an upper bounded wildcard, e.g. List<? extends Number>
+	Extends Wildcard = '+'
+	// Super is a lower bounded wildcard, e.g. List<? super Integer>
+	Super Wildcard = '-'
+	// Unbounded is an unbounded wildcard, e.g. List<?>
+	Unbounded Wildcard = '*'
+)
+
+// TypeArgument is a single type argument. Type is nil for an unbounded wildcard.
+type TypeArgument struct {
+	Wildcard Wildcard
+	Type     ReferenceType
+}
+
+func (a TypeArgument) write(builder *strings.Builder, qualified bool) {
+	switch a.Wildcard {
+	case Unbounded:
+		builder.WriteByte('?')
+		return
+	case Extends:
+		builder.WriteString("? extends ")
+	case Super:
+		builder.WriteString("? super ")
+	}
+	a.Type.write(builder, qualified)
+}
+
+func writeTypeArguments(builder *strings.Builder, args []TypeArgument, qualified bool) {
+	if len(args) == 0 {
+		return
+	}
+	builder.WriteByte('<')
+	for i, arg := range args {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+		arg.write(builder, qualified)
+	}
+	builder.WriteByte('>')
+}
+
+// TypeParameter is a formal type parameter of a generic class or method.
+// ClassBound is nil when the parameter is bounded only by interfaces.
+type TypeParameter struct {
+	Name            string
+	ClassBound      ReferenceType
+	InterfaceBounds []ReferenceType
+}
+
+func (p TypeParameter) write(builder *strings.Builder, qualified bool) {
+	builder.WriteString(p.Name)
+	bounds := p.InterfaceBounds
+	if p.ClassBound != nil {
+		bounds = append([]ReferenceType{p.ClassBound}, bounds...)
+	}
+	for i, bound := range bounds {
+		if i == 0 {
+			builder.WriteString(" extends ")
+		} else {
+			builder.WriteString(" & ")
+		}
+		bound.write(builder, qualified)
+	}
+}
+
+func writeTypeParameters(builder *strings.Builder, params []TypeParameter, qualified bool) {
+	if len(params) == 0 {
+		return
+	}
+	builder.WriteByte('<')
+	for i, param := range params {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+		param.write(builder, qualified)
+	}
+	builder.WriteByte('>')
+}
+
+// ClassSignature is the signature of a generic class or interface, or of a
+// class that extends or implements a parameterized type.
+type ClassSignature struct {
+	TypeParams []TypeParameter
+	SuperClass *ClassType
+	Interfaces []*ClassType
+}
+
+// Format returns the class header as Java source, e.g.
+// "Foo<T> extends java.util.AbstractList<T> implements java.io.Serializable".
+// The name is used as written.
+func (s *ClassSignature) Format(name string, qualified bool) string {
+	var builder strings.Builder
+	builder.WriteString(name)
+	writeTypeParameters(&builder, s.TypeParams, qualified)
+	if s.SuperClass != nil {
+		builder.WriteString(" extends ")
+		s.SuperClass.write(&builder, qualified)
+	}
+	for i, iface := range s.Interfaces {
+		if i == 0 {
+			builder.WriteString(" implements ")
+		} else {
+			builder.WriteString(", ")
+		}
+		iface.write(&builder, qualified)
+	}
+	return builder.String()
+}
+
+// String returns the signature as Java source without a class name, e.g.
+// "<T> extends java.util.AbstractList<T>".
+func (s *ClassSignature) String() string {
+	return strings.TrimPrefix(s.Format("", true), " ")
+}
+
+// MethodSignature is the signature of a generic method or of a method whose
+// parameter, return or throws types use type variables or parameterized types.
+// Result is nil for methods that return void.
+type MethodSignature struct {
+	TypeParams []TypeParameter
+	Params     []Type
+	Result     Type
+	Throws     []ReferenceType
+}
+
+// Format returns the method declaration as Java source, e.g.
+// "<T> java.util.List<T> copy(T[]) throws java.io.IOException".
+func (s *MethodSignature) Format(name string, qualified bool) string {
+	var builder strings.Builder
+	if len(s.TypeParams) > 0 {
+		writeTypeParameters(&builder, s.TypeParams, qualified)
+		builder.WriteByte(' ')
+	}
+	if s.Result == nil {
+		builder.WriteString("void")
+	} else {
+		s.Result.write(&builder, qualified)
+	}
+	builder.WriteByte(' ')
+	builder.WriteString(name)
+	builder.WriteByte('(')
+	for i, param := range s.Params {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+		param.write(&builder, qualified)
+	}
+	builder.WriteByte(')')
+	for i, thrown := range s.Throws {
+		if i == 0 {
+			builder.WriteString(" throws ")
+		} else {
+			builder.WriteString(", ")
+		}
+		thrown.write(&builder, qualified)
+	}
+	return builder.String()
+}
+
+// String returns the signature as Java source without a method name, e.g.
+// "<T> java.util.List<T> (T[])".
+func (s *MethodSignature) String() string {
+	return s.Format("", true)
+}
+
+func format(t Type, qualified bool) string {
+	var builder strings.Builder
+	t.write(&builder, qualified)
+	return builder.String()
+}
@@ -0,0 +1,158 @@
+package signature
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseClass(t *testing.T) {
+	tests := []struct {
+		signature string
+		name      string
+		qualified string
+		simple    string
+	}{
+		{
+			"<T:Ljava/lang/Object;>Ljava/util/AbstractList<TT;>;Ljava/io/Serializable;",
+			"Foo",
+			"Foo<T extends java.lang.Object> extends java.util.AbstractList<T> implements java.io.Serializable",
+			"Foo<T extends Object> extends AbstractList<T> implements Serializable",
+		},
+		{
+			// An interface bound only, and a class bound with an extra interface bound
+			"<T::Ljava/lang/Comparable<-TT;>;U:Ljava/lang/Number;:Ljava/lang/Runnable;>Ljava/lang/Object;",
+			"Pair",
+			"Pair<T extends java.lang.Comparable<? super T>, U extends java.lang.Number & java.lang.Runnable> extends java.lang.Object",
+			"Pair<T extends Comparable<? super T>, U extends Number & Runnable> extends Object",
+		},
+		{
+			"Ljava/lang/Object;Ljava/util/Map<Ljava/lang/String;+Ljava/util/List<*>;>;",
+			"Table",
+			"Table extends java.lang.Object implements java.util.Map<java.lang.String, ? extends java.util.List<?>>",
+			"Table extends Object implements Map<String, ? extends List<?>>",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sig, err := ParseClass(test.signature)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := sig.Format(test.name, true); got != test.qualified {
+				t.Errorf("Format(%q, true) = %q, want %q", test.name, got, test.qualified)
+			}
+			if got := sig.Format(test.name, false); got != test.simple {
+				t.Errorf("Format(%q, false) = %q, want %q", test.name, got, test.simple)
+			}
+		})
+	}
+}
+
+func TestParseMethod(t *testing.T) {
+	tests := []struct {
+		signature string
+		want      string
+	}{
+		{"()V", "void m()"},
+		{"<T:Ljava/lang/Object;>([TT;I)Ljava/util/List<TT;>;^Ljava/io/IOException;",
+			"<T extends java.lang.Object> java.util.List<T> m(T[], int) throws java.io.IOException"},
+		{"<X:Ljava/lang/Throwable;>(Ljava/util/function/Supplier<+TX;>;)J^TX;^Ljava/lang/Error;",
+			"<X extends java.lang.Throwable> long m(java.util.function.Supplier<? extends X>) throws X, java.lang.Error"},
+		{"(Ljava/util/Map<TK;TV;>.Entry<TK;TV;>;)[[D",
+			"double[][] m(java.util.Map<K, V>.Entry<K, V>)"},
+	}
+	for _, test := range tests {
+		t.Run(test.signature, func(t *testing.T) {
+			sig, err := ParseMethod(test.signature)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := sig.Format("m", true); got != test.want {
+				t.Errorf("Format() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseField(t *testing.T) {
+	tests := []struct {
+		signature    string
+		qualified    string
+		simple       string
+		internalName string
+	}{
+		{"TT;", "T", "T", ""},
+		{"[TT;", "T[]", "T[]", ""},
+		{"Ljava/util/List<Ljava/lang/String;>;", "java.util.List<java.lang.String>", "List<String>", "java/util/List"},
+		{"Ljava/util/Map$Entry<TK;TV;>;", "java.util.Map$Entry<K, V>", "Map$Entry<K, V>", "java/util/Map$Entry"},
+		{"LOuter<TT;>.Inner<-Ljava/lang/Integer;>.Deep;", "Outer<T>.Inner<? super java.lang.Integer>.Deep",
+			"Outer<T>.Inner<? super Integer>.Deep", "Outer$Inner$Deep"},
+	}
+	for _, test := range tests {
+		t.Run(test.signature, func(t *testing.T) {
+			typ, err := ParseField(test.signature)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := typ.String(); got != test.qualified {
+				t.Errorf("String() = %q, want %q", got, test.qualified)
+			}
+			if got := typ.SimpleString(); got != test.simple {
+				t.Errorf("SimpleString() = %q, want %q", got, test.simple)
+			}
+			if class, ok := typ.(*ClassType); ok {
+				if got := class.InternalName(); got != test.internalName {
+					t.Errorf("InternalName() = %q, want %q", got, test.internalName)
+				}
+			} else if test.internalName != "" {
+				t.Errorf("ParseField() = %T, want a *ClassType", typ)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		signature string
+		parse     func(string) error
+		want      string
+	}{
+		{"Ljava/util/List<>;", parseField, "offset 17: empty type argument list"},
+		{"Ljava/util/List", parseField, "expected ';' after class type"},
+		{"TT", parseField, "expected ';' after type variable T"},
+		{"I", parseField, "expected reference type"},
+		{"Ljava/lang/String;X", parseField, "unexpected trailing characters"},
+		{"<>Ljava/lang/Object;", parseClass, "empty type parameter list"},
+		{"<T>Ljava/lang/Object;", parseClass, "expected ':' after type parameter T"},
+		{"I)V", parseMethod, "expected '('"},
+		{"(I", parseMethod, "offset 2"},
+		{"()V^I", parseMethod, "offset 4: expected class type"},
+		{"()VX", parseMethod, "unexpected trailing characters"},
+	}
+	for _, test := range tests {
+		t.Run(test.signature, func(t *testing.T) {
+			err := test.parse(test.signature)
+			if _, ok := err.(*SyntaxError); !ok {
+				t.Fatalf("err = %v, want a *SyntaxError", err)
+			}
+			if !strings.Contains(err.Error(), test.want) {
+				t.Errorf("err = %v, want an error containing %q", err, test.want)
+			}
+		})
+	}
+}
+
+func parseField(s string) error {
+	_, err := ParseField(s)
+	return err
+}
+
+func parseClass(s string) error {
+	_, err := ParseClass(s)
+	return err
+}
+
+func parseMethod(s string) error {
+	_, err := ParseMethod(s)
+	return err
+}